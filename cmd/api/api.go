@@ -1,15 +1,19 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/mubashshir3767/currencyExchange/internal/events"
+	"github.com/mubashshir3767/currencyExchange/internal/i18n"
+	"github.com/mubashshir3767/currencyExchange/internal/openapi"
+	"github.com/mubashshir3767/currencyExchange/internal/receipt"
 	"github.com/mubashshir3767/currencyExchange/internal/service"
 	"github.com/mubashshir3767/currencyExchange/internal/store"
 	"github.com/mubashshir3767/currencyExchange/internal/store/cache"
@@ -17,18 +21,32 @@ import (
 )
 
 type application struct {
-	Pagination types.Pagination
 	config     config
 	store      store.Storage
 	service    service.Service
 	cacheStore cache.Storage
+	events     events.Bus
+	receipts   *receipt.Renderer
+	signer     *receipt.Signer
 }
 
 type config struct {
-	addr        string
-	db          dbConfig
-	redisConfig redisConfig
-	env         string
+	addr               string
+	db                 dbConfig
+	redisConfig        redisConfig
+	env                string
+	auditRetentionDays int
+	eventsRedis        bool
+	telegramBot        string
+	publicURL          string
+	receiptVerifyLimit int
+	statementLimit     int
+	// trustedProxies are the peers whose X-Forwarded-For and X-Real-IP
+	// headers are believed.
+	trustedProxies []*net.IPNet
+
+	reconcileIntervalHours int
+	snapshotBackfillDays   int
 }
 
 type dbConfig struct {
@@ -49,23 +67,38 @@ func (app *application) mount() *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(app.RealIPMiddleware)
 	r.Use(middleware.Recoverer)
+	r.Use(app.LanguageMiddleware)
 	r.Use(middleware.Logger)
 	// r.Use(chi.MiddlewareFunc(http.StripPrefix))
 
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(app.TimeoutMiddleware(60 * time.Second))
+
+	doc, err := openapi.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.Use(app.OpenAPIMiddleware(r, doc))
 
 	r.Route("/api/v1", func(r chi.Router) {
 
+		r.Get("/openapi.json", app.OpenAPIHandler)
+
 		r.Post("/company", app.CreateCompanyHandler)
 
 		r.Post("/users/register", app.CreateUserHandler)
 		r.Post("/users/login", app.LoginUserHandler)
 
+		r.With(app.RateLimitMiddleware("receipt-verify", app.config.receiptVerifyLimit, time.Minute)).
+			Get("/receipts/verify/{token}", app.VerifyReceiptHandler)
+		r.With(app.RateLimitMiddleware("statement", app.config.statementLimit, time.Minute)).
+			Get("/statements/{token}", app.SharedStatementHandler)
+
 		r.With(app.JWTUserMiddleware()).Route("/user", func(r chi.Router) {
 
 			r.Get("/all", app.GetAllUserHandler)
+			r.Get("/events", app.EventsHandler)
 			r.Put("/{id}", app.UpdateUserHandler)
 			r.Delete("/{id}", app.DeleteUserHandler)
 
@@ -83,8 +116,12 @@ func (app *application) mount() *chi.Mux {
 				r.Get("/all", app.GetAllBalanceHandler)
 				r.Get("/user/{id}", app.GetBalanceByUserIdHandler)
 				r.Get("/company/{id}", app.GetBalanceByCompanyIdHandler)
+				r.Get("/thresholds", app.GetBalanceThresholdsHandler)
+				r.Put("/thresholds", app.SetBalanceThresholdHandler)
+				r.Delete("/thresholds/{id}", app.DeleteBalanceThresholdHandler)
 				r.Route("/{id}", func(r chi.Router) {
 					r.Get("/", app.GetBalanceByIdHandler)
+					r.Get("/statement", app.GetBalanceStatementHandler)
 					r.Put("/", app.UpdateBalanceHandler)
 					r.Delete("/", app.DeleteBalanceHandler)
 				})
@@ -93,17 +130,22 @@ func (app *application) mount() *chi.Mux {
 			r.Route("/exchanges", func(r chi.Router) {
 				r.Post("/", app.CreateExchangeHandler)
 				r.Post("/filter", app.GetExchangesHandler)
+				r.Post("/export", app.ExportExchangesHandler)
+				r.Post("/search", app.SearchExchangesHandler)
 				r.Post("/archive", app.ArchiveExchangesHandler)
 				r.Get("/archived", app.ArchivedExchangesHandler)
 				r.Route("/{id}", func(r chi.Router) {
 					r.Put("/", app.UpdateExchangeHandler)
 					r.Delete("/", app.DeleteExchangeHandler)
+					r.Get("/receipt", app.GetExchangeReceiptHandler)
 				})
 			})
 
 			r.Route("/balance-records", func(r chi.Router) {
 				r.Post("/", app.CreateBalanceRecordHandler)
 				r.Post("/filter", app.GetBalanceRecordsHandler)
+				r.Post("/export", app.ExportBalanceRecordsHandler)
+				r.Post("/search", app.SearchBalanceRecordsHandler)
 				r.Post("/archive", app.ArchiveBalanceRecordsHandler)
 				r.Get("/archived", app.ArchivedBalanceRecordsHandler)
 				r.Route("/{id}", func(r chi.Router) {
@@ -116,13 +158,20 @@ func (app *application) mount() *chi.Mux {
 				r.Post("/create", app.CreateDebtorsHandler)
 				r.Post("/transaction", app.CreateDebtorTransactionHandler)
 				r.Get("/company/{id}", app.GetDebtorsByCompanyIdHandler)
+				r.Post("/search", app.SearchDebtorsHandler)
+				r.Post("/debts/search", app.SearchDebtsHandler)
+				r.Post("/export", app.ExportDebtorsHandler)
+				r.Post("/debts/export", app.ExportDebtsHandler)
 				r.Get("/info/{id}", app.GetDebtorsTotalBalanceInfo)
 				r.Delete("/{id}", app.DeleteDebtorsHandler)
+				r.Get("/{id}/statement", app.GetDebtorStatementHandler)
+				r.Post("/{id}/statement/share", app.ShareDebtorStatementHandler)
 
 				r.Route("/debts/{id}", func(r chi.Router) {
 					r.Get("/", app.GetDebtsByDebtorIdHandler)
 					r.Put("/", app.UpdateDebtsHandler)
 					r.Delete("/", app.DeleteDebtsHandler)
+					r.Get("/receipt", app.GetDebtReceiptHandler)
 				})
 			})
 
@@ -135,12 +184,68 @@ func (app *application) mount() *chi.Mux {
 				r.Get("/show/info/{date}", app.GetInfosByCompanyIdHandler)
 				r.Post("/fetch.by.field", app.GetTransactionsByFieldHandler)
 				r.Post("/fetch.by.field-and-date", app.GetTransactionsByFieldAndDateHandler)
+				r.Post("/search", app.SearchTransactionsHandler)
+				r.Post("/export", app.ExportTransactionsHandler)
 				r.Route("/{id}", func(r chi.Router) {
 					r.Put("/", app.UpdateTransactionHandler)
 					r.Delete("/", app.DeleteTransactionHandler)
+					r.Get("/sms", app.GetTransactionSMSHandler)
+					r.Get("/receipt", app.GetTransactionReceiptHandler)
 				})
 			})
 
+			r.Route("/change-requests", func(r chi.Router) {
+				r.Get("/", app.GetChangeRequestsHandler)
+				r.Route("/{id}", func(r chi.Router) {
+					r.Get("/", app.GetChangeRequestByIdHandler)
+					r.Post("/approve", app.ApproveChangeRequestHandler)
+					r.Post("/reject", app.RejectChangeRequestHandler)
+				})
+			})
+
+			r.Route("/notifications", func(r chi.Router) {
+				r.Get("/", app.GetNotificationsHandler)
+				r.Get("/unread-count", app.GetUnreadNotificationsCountHandler)
+				r.Get("/preferences", app.GetNotificationPreferencesHandler)
+				r.Put("/preferences", app.UpdateNotificationPreferenceHandler)
+				r.Post("/read-all", app.ReadAllNotificationsHandler)
+				r.Post("/{id}/read", app.ReadNotificationHandler)
+				r.Get("/outbox", app.GetNotificationOutboxHandler)
+				r.Get("/outbox/{id}", app.GetNotificationOutboxByIdHandler)
+			})
+
+			r.Route("/telegram", func(r chi.Router) {
+				r.Post("/link", app.CreateTelegramLinkHandler)
+				r.Delete("/link", app.DeleteTelegramLinkHandler)
+			})
+
+			r.Route("/webhooks", func(r chi.Router) {
+				r.Post("/", app.CreateWebhookHandler)
+				r.Get("/", app.GetWebhooksHandler)
+				r.Get("/deliveries", app.GetWebhookDeliveriesHandler)
+				r.Post("/deliveries/{id}/redeliver", app.RedeliverWebhookHandler)
+				r.Delete("/{id}", app.DeleteWebhookHandler)
+			})
+
+			r.Get("/dashboard", app.GetDashboardHandler)
+
+			r.Route("/analytics", func(r chi.Router) {
+				r.Get("/balances", app.GetBalanceHistoryHandler)
+				r.Post("/snapshots", app.BackfillBalanceSnapshotsHandler)
+			})
+
+			r.Route("/reconciliation", func(r chi.Router) {
+				r.Get("/runs", app.GetReconciliationRunsHandler)
+				r.Post("/runs", app.RunReconciliationHandler)
+				r.Get("/discrepancies", app.GetDiscrepanciesHandler)
+				r.Post("/discrepancies/{id}/repair", app.RepairDiscrepancyHandler)
+			})
+
+			r.Route("/audit", func(r chi.Router) {
+				r.Get("/entity/{type}/{id}", app.GetAuditLogsByEntityHandler)
+				r.Get("/user/{id}", app.GetAuditLogsByUserHandler)
+			})
+
 			r.Route("/companies", func(r chi.Router) {
 				r.Post("/", app.CreateCompanyHandler)
 				r.Get("/all", app.GetAllCompanyHandler)
@@ -154,6 +259,13 @@ func (app *application) mount() *chi.Mux {
 
 	})
 
+	if err := checkRoutes(r, doc); err != nil {
+		if app.isDevelopment() {
+			log.Fatal(err)
+		}
+		log.Print(err)
+	}
+
 	return r
 }
 
@@ -180,31 +292,49 @@ func getIDFromContext(r *http.Request) int64 {
 	return ID
 }
 
-func (app *application) LoadPaginationInfo(r *http.Request, ctx context.Context) {
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+// readPagination parses page, limit, order_by and cursor for one request.
+// A cursor takes precedence over page and is only used by lists sorted by
+// created_at DESC, the default for most of them.
+func (app *application) readPagination(r *http.Request, sorts store.SortFields) (*types.Pagination, error) {
+	query := r.URL.Query()
+
+	page, err := strconv.Atoi(query.Get("page"))
 	if err != nil || page < 1 {
 		page = 1
 	}
-	app.Pagination.Page = page
 
-	orderBy := r.URL.Query().Get("order_by")
-	if orderBy != "" {
-		app.Pagination.OrderBy = orderBy
-	} else {
-		app.Pagination.OrderBy = "balance DESC"
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit < 1 {
+		limit = types.DEFAULT_LIMIT
+	}
+	if limit > types.MAX_LIMIT {
+		limit = types.MAX_LIMIT
 	}
 
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil || limit < 1 {
-		limit = 10
+	orderBy := query.Get("order_by")
+	if err := sorts.Validate(orderBy); err != nil {
+		return nil, err
 	}
-	app.Pagination.Limit = limit
 
-	offset := (page - 1) * limit
-	app.Pagination.Offset = offset
+	language := string(i18n.FromContext(r.Context()))
+	userID, _ := r.Context().Value(UserKey).(int64)
+
+	pagination := &types.Pagination{
+		Page:       page,
+		Limit:      limit,
+		Offset:     (page - 1) * limit,
+		OrderBy:    orderBy,
+		UserId:     userID,
+		Language:   &language,
+		CountTotal: true,
+	}
 
-	userID, _ := ctx.Value(UserKey).(int)
-	app.Pagination.UserId = int64(userID)
+	if cursor := query.Get("cursor"); cursor != "" {
+		if pagination.Cursor, err = types.DecodeCursor(cursor); err != nil {
+			return nil, err
+		}
+		pagination.Offset = 0
+	}
 
-	log.Printf("pagination  page: %v,  limit = %v", app.Pagination.Limit, app.Pagination.Offset)
+	return pagination, nil
 }
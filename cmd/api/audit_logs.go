@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+// DeviceIDHeader carries the device_id of the caller's user session so audit
+// entries can be tied to a device.
+const DeviceIDHeader = "X-Device-ID"
+
+// audit records a mutating call and drops the cached dashboards of the
+// company it changed. Failures are logged and never fail the request, since
+// the change itself has already been committed.
+func (app *application) audit(r *http.Request, entityType, action string, entityID int64, before, after any) {
+	entry := app.auditEntry(r)
+	entry.EntityType = entityType
+	entry.Action = action
+
+	if entityID != 0 {
+		entry.EntityID = &entityID
+	}
+
+	var err error
+	if entry.Before, err = marshalAuditValue(before); err != nil {
+		log.Printf("audit: failed to marshal before for %s %s: %v", action, entityType, err)
+	}
+	if entry.After, err = marshalAuditValue(after); err != nil {
+		log.Printf("audit: failed to marshal after for %s %s: %v", action, entityType, err)
+	}
+
+	if entry.CompanyID == nil {
+		entry.CompanyID = companyIDOf(after)
+	}
+
+	if err := app.store.AuditLogs.Create(context.Background(), entry); err != nil {
+		log.Printf("audit: failed to record %s %s %d: %v", action, entityType, entityID, err)
+	}
+
+	if entry.CompanyID != nil {
+		app.invalidateDashboards(context.Background(), *entry.CompanyID)
+	}
+}
+
+// auditEntry is an audit entry carrying who made the call and from where,
+// for services that write their own entry inside a transaction.
+func (app *application) auditEntry(r *http.Request) *store.AuditLog {
+	entry := &store.AuditLog{
+		IP:     r.RemoteAddr,
+		Method: r.Method,
+		Route:  r.URL.Path,
+	}
+
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		entry.Route = rctx.RoutePattern()
+	}
+
+	if deviceID := r.Header.Get(DeviceIDHeader); deviceID != "" {
+		entry.DeviceID = &deviceID
+	}
+
+	if userId, ok := r.Context().Value(UserKey).(int64); ok {
+		entry.ActorID = &userId
+		if user, err := app.GetUser(userId); err == nil {
+			entry.CompanyID = &user.CompanyId
+		}
+	}
+
+	return entry
+}
+
+func marshalAuditValue(value any) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if raw, ok := value.(json.RawMessage); ok {
+		return raw, nil
+	}
+	return json.Marshal(value)
+}
+
+// companyIDOf is used for calls made without a logged in user, such as
+// registration, where the company is only known from the created entity.
+func companyIDOf(value any) *int64 {
+	switch v := value.(type) {
+	case *store.Company:
+		return &v.ID
+	case *store.User:
+		return &v.CompanyId
+	}
+	return nil
+}
+
+func (app *application) GetAuditLogsByEntityHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.AuditLogSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if user.Role != store.ROLE_OWNER {
+		app.forbiddenResponse(w, r, fmt.Errorf("only owners can read the audit log"))
+		return
+	}
+
+	logs, err := app.store.AuditLogs.GetByEntity(r.Context(), user.CompanyId, chi.URLParam(r, "type"), getIDFromContext(r), pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writePage(w, http.StatusOK, logs, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) GetAuditLogsByUserHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.AuditLogSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if user.Role != store.ROLE_OWNER {
+		app.forbiddenResponse(w, r, fmt.Errorf("only owners can read the audit log"))
+		return
+	}
+
+	logs, err := app.store.AuditLogs.GetByActor(r.Context(), user.CompanyId, getIDFromContext(r), pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writePage(w, http.StatusOK, logs, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// runAuditRetention deletes audit entries older than the configured
+// retention once a day.
+func (app *application) runAuditRetention() {
+	if app.config.auditRetentionDays <= 0 {
+		return
+	}
+
+	retention := time.Duration(app.config.auditRetentionDays) * 24 * time.Hour
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := app.store.AuditLogs.DeleteOlderThan(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("audit retention: %v", err)
+		} else if deleted > 0 {
+			log.Printf("audit retention: deleted %d entries", deleted)
+		}
+
+		<-ticker.C
+	}
+}
@@ -26,6 +26,7 @@ func (app *application) CreateBalanceRecordHandler(w http.ResponseWriter, r *htt
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_BALANCE_RECORD, store.ACTION_CREATE, 0, nil, payload)
 
 	if err := app.writeResponse(w, http.StatusOK, payload); err != nil {
 		app.internalServerError(w, r, err)
@@ -35,19 +36,23 @@ func (app *application) CreateBalanceRecordHandler(w http.ResponseWriter, r *htt
 
 func (app *application) GetBalanceRecordsByUserIdHandler(w http.ResponseWriter, r *http.Request) {
 	var payload FieldRequestPayload
-	app.LoadPaginationInfo(r, r.Context())
+	pagination, err := app.readPagination(r, store.BalanceRecordSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
 	if err := readJSON(w, r, &payload); err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	records, err := app.store.BalanceRecords.GetByField(r.Context(), payload.FieldName, payload.FieldValue, app.Pagination)
+	records, err := app.store.BalanceRecords.GetByField(r.Context(), payload.FieldName, payload.FieldValue, pagination)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.writeResponse(w, http.StatusOK, records); err != nil {
+	if err := app.writePage(w, http.StatusOK, records, pagination); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -55,20 +60,55 @@ func (app *application) GetBalanceRecordsByUserIdHandler(w http.ResponseWriter,
 
 func (app *application) GetBalanceRecordsHandler(w http.ResponseWriter, r *http.Request) {
 	var payload FieldRequestPayload
-	app.LoadPaginationInfo(r, r.Context())
+	pagination, err := app.readPagination(r, store.BalanceRecordSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
 
 	if err := readJSON(w, r, &payload); err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	records, err := app.store.BalanceRecords.GetByField(r.Context(), payload.FieldName, payload.FieldValue, app.Pagination)
+	records, err := app.store.BalanceRecords.GetByField(r.Context(), payload.FieldName, payload.FieldValue, pagination)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.writeResponse(w, http.StatusOK, records); err != nil {
+	if err := app.writePage(w, http.StatusOK, records, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) SearchBalanceRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.BalanceRecordSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	filter, err := readFilter(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	records, err := app.store.BalanceRecords.Filter(r.Context(), user.CompanyId, filter, pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writePage(w, http.StatusOK, records, pagination); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -83,10 +123,17 @@ func (app *application) UpdateBalanceRecordHandler(w http.ResponseWriter, r *htt
 
 	payload.ID = getIDFromContext(r)
 
+	before, err := app.store.BalanceRecords.GetByField(r.Context(), "id", payload.ID, &types.Pagination{Limit: 1, Offset: 0})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.service.BalanceRecords.UpdateRecord(r.Context(), payload); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_BALANCE_RECORD, store.ACTION_UPDATE, payload.ID, before, payload)
 
 	if err := app.writeResponse(w, http.StatusOK, payload); err != nil {
 		app.internalServerError(w, r, err)
@@ -96,11 +143,21 @@ func (app *application) UpdateBalanceRecordHandler(w http.ResponseWriter, r *htt
 
 func (app *application) DeleteBalanceRecordHandler(w http.ResponseWriter, r *http.Request) {
 	id := getIDFromContext(r)
+	if app.requireApproval(w, r, store.ENTITY_BALANCE_RECORD, store.ACTION_DELETE, id, nil) {
+		return
+	}
+
+	before, err := app.store.BalanceRecords.GetByField(r.Context(), "id", id, &types.Pagination{Limit: 1, Offset: 0})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
 	if err := app.service.BalanceRecords.RollbackBalanceRecord(r.Context(), id); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_BALANCE_RECORD, store.ACTION_DELETE, id, before, nil)
 
 	if err := app.writeResponse(w, http.StatusOK, "DELETED"); err != nil {
 		app.internalServerError(w, r, err)
@@ -121,6 +178,7 @@ func (app *application) ArchiveBalanceRecordsHandler(w http.ResponseWriter, r *h
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_BALANCE_RECORD, store.ACTION_ARCHIVE, 0, nil, map[string]any{"company_id": user.CompanyId})
 
 	if err := app.writeResponse(w, http.StatusOK, "ARCHIVED"); err != nil {
 		app.internalServerError(w, r, err)
@@ -129,15 +187,19 @@ func (app *application) ArchiveBalanceRecordsHandler(w http.ResponseWriter, r *h
 }
 
 func (app *application) ArchivedBalanceRecordsHandler(w http.ResponseWriter, r *http.Request) {
-	app.LoadPaginationInfo(r, r.Context())
-	balanceRecords, err := app.store.BalanceRecords.Archived(r.Context(), app.Pagination)
+	pagination, err := app.readPagination(r, store.BalanceRecordSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	balanceRecords, err := app.store.BalanceRecords.Archived(r.Context(), pagination)
 
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.writeResponse(w, http.StatusOK, balanceRecords); err != nil {
+	if err := app.writePage(w, http.StatusOK, balanceRecords, pagination); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// MAX_SNAPSHOT_DAYS is how many days one backfill may cover.
+const MAX_SNAPSHOT_DAYS = 366
+
+// GetBalanceHistoryHandler returns the end-of-day balances of the user's
+// company per currency for the days from ?from to ?to, optionally for one
+// ?currency or ?user_id.
+func (app *application) GetBalanceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := readPeriod(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var userId *int64
+	if raw := r.URL.Query().Get("user_id"); raw != "" {
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid user_id %q", raw))
+			return
+		}
+		userId = &value
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	history, err := app.store.BalanceSnapshots.History(r.Context(), user.CompanyId, r.URL.Query().Get("currency"), userId, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	history.From = from.Format("2006-01-02")
+	history.To = to.Format("2006-01-02")
+
+	if err := app.writeResponse(w, http.StatusOK, history); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+type SnapshotResult struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Taken int64  `json:"taken"`
+}
+
+// BackfillBalanceSnapshotsHandler takes the missing end-of-day balances of
+// the owner's company for the days from ?from to ?to, yesterday by default.
+func (app *application) BackfillBalanceSnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := readPeriod(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	yesterday := tashkentToday().AddDate(0, 0, -1)
+	if r.URL.Query().Get("to") == "" {
+		to = yesterday
+	}
+	if from.After(to) || to.After(yesterday) || to.Sub(from) >= MAX_SNAPSHOT_DAYS*24*time.Hour {
+		app.internalServerError(w, r, types.ErrSnapshotRange)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if user.Role != store.ROLE_OWNER {
+		app.forbiddenResponse(w, r, fmt.Errorf("only owners can backfill balance snapshots"))
+		return
+	}
+
+	taken, err := app.service.Balances.Snapshot(r.Context(), &user.CompanyId, from, to)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	result := SnapshotResult{From: from.Format("2006-01-02"), To: to.Format("2006-01-02"), Taken: taken}
+	app.audit(r, store.ENTITY_SNAPSHOT, store.ACTION_CREATE, 0, nil, result)
+
+	if err := app.writeResponse(w, http.StatusCreated, result); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// runBalanceSnapshots takes the end-of-day balances of every company once a
+// day has ended in Tashkent. Each time it also fills in the days before,
+// back to the configured number of days, in case an earlier run was missed.
+func (app *application) runBalanceSnapshots() {
+	if app.config.snapshotBackfillDays <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	var taken time.Time
+	for {
+		yesterday := tashkentToday().AddDate(0, 0, -1)
+		if !yesterday.Equal(taken) {
+			from := yesterday.AddDate(0, 0, 1-app.config.snapshotBackfillDays)
+			n, err := app.service.Balances.Snapshot(context.Background(), nil, from, yesterday)
+			if err != nil {
+				log.Printf("balance snapshots: %v", err)
+			} else {
+				taken = yesterday
+				if n > 0 {
+					log.Printf("balance snapshots: took %d up to %s", n, yesterday.Format("2006-01-02"))
+				}
+			}
+		}
+
+		<-ticker.C
+	}
+}
+
+func tashkentToday() time.Time {
+	now := time.Now().In(tashkent())
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
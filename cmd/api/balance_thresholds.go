@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// BalanceThresholdPayload sets the limits of one scope. Without user_id the
+// limits apply to the sum of the company's balances in the currency.
+type BalanceThresholdPayload struct {
+	UserID     *int64 `json:"user_id"`
+	Currency   string `json:"currency" validate:"required,max=255"`
+	MinBalance *int64 `json:"min_balance" validate:"required_without=MaxBalance"`
+	MaxBalance *int64 `json:"max_balance" validate:"required_without=MinBalance"`
+}
+
+// GetBalanceThresholdsHandler lists the thresholds of the user's company.
+func (app *application) GetBalanceThresholdsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	thresholds, err := app.store.BalanceThresholds.GetByCompanyId(r.Context(), user.CompanyId)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if thresholds == nil {
+		thresholds = []store.BalanceThreshold{}
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, thresholds); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// SetBalanceThresholdHandler creates or replaces the threshold of a scope.
+// Only owners may change thresholds.
+func (app *application) SetBalanceThresholdHandler(w http.ResponseWriter, r *http.Request) {
+	var payload BalanceThresholdPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.MinBalance != nil && payload.MaxBalance != nil && *payload.MinBalance > *payload.MaxBalance {
+		app.internalServerError(w, r, types.ErrBalanceThresholdRange)
+		return
+	}
+
+	user, ok := app.thresholdOwner(w, r)
+	if !ok {
+		return
+	}
+
+	if payload.UserID != nil {
+		target, err := app.store.Users.GetById(r.Context(), payload.UserID)
+		if err != nil || target.CompanyId != user.CompanyId {
+			app.internalServerError(w, r, types.ErrUserNotFound)
+			return
+		}
+	}
+
+	threshold := &store.BalanceThreshold{
+		CompanyID:  user.CompanyId,
+		UserID:     payload.UserID,
+		Currency:   payload.Currency,
+		MinBalance: payload.MinBalance,
+		MaxBalance: payload.MaxBalance,
+	}
+
+	if err := app.store.BalanceThresholds.Set(r.Context(), threshold); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.audit(r, store.ENTITY_THRESHOLD, store.ACTION_UPDATE, threshold.ID, nil, threshold)
+
+	if err := app.writeResponse(w, http.StatusOK, threshold); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) DeleteBalanceThresholdHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.thresholdOwner(w, r)
+	if !ok {
+		return
+	}
+
+	id := getIDFromContext(r)
+	if err := app.store.BalanceThresholds.Delete(r.Context(), user.CompanyId, id); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.audit(r, store.ENTITY_THRESHOLD, store.ACTION_DELETE, id, nil, nil)
+
+	if err := app.writeResponse(w, http.StatusOK, "DELETED"); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) thresholdOwner(w http.ResponseWriter, r *http.Request) (*store.User, bool) {
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return nil, false
+	}
+
+	if user.Role != store.ROLE_OWNER {
+		app.forbiddenResponse(w, r, fmt.Errorf("only owners can manage balance thresholds"))
+		return nil, false
+	}
+
+	return user, true
+}
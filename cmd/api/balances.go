@@ -39,10 +39,11 @@ func (app *application) CreateBalanceHandler(w http.ResponseWriter, r *http.Requ
 		CompanyId: user.CompanyId,
 	}
 
-	if err := app.store.Balances.Create(r.Context(), balance); err != nil {
+	if err := app.service.Balances.Create(r.Context(), balance); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_BALANCE, store.ACTION_CREATE, balance.ID, nil, balance)
 
 	if err := app.writeResponse(w, http.StatusOK, balance); err != nil {
 		app.internalServerError(w, r, err)
@@ -117,17 +118,25 @@ func (app *application) UpdateBalanceHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	id := getIDFromContext(r)
 	balance := &store.Balance{
+		ID:       id,
 		Balance:  payload.Balance,
-		UserId:   payload.UserId,
 		InOutLay: payload.InOutLay,
 		OutInLay: payload.OutInLay,
 	}
 
-	if err := app.store.Balances.Update(r.Context(), balance); err != nil {
+	before, err := app.store.Balances.GetById(r.Context(), &id)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.service.Balances.Update(r.Context(), balance); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_BALANCE, store.ACTION_UPDATE, id, before, balance)
 
 	if err := app.writeResponse(w, http.StatusOK, balance); err != nil {
 		app.internalServerError(w, r, err)
@@ -137,10 +146,17 @@ func (app *application) UpdateBalanceHandler(w http.ResponseWriter, r *http.Requ
 
 func (app *application) DeleteBalanceHandler(w http.ResponseWriter, r *http.Request) {
 	id := getIDFromContext(r)
+	before, err := app.store.Balances.GetById(r.Context(), &id)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.store.Balances.Delete(r.Context(), id); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_BALANCE, store.ACTION_DELETE, id, before, nil)
 
 	if err := app.writeResponse(w, http.StatusOK, "DELETED"); err != nil {
 		app.internalServerError(w, r, err)
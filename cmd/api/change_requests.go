@@ -0,0 +1,170 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+type ReviewChangeRequestPayload struct {
+	Note *string `json:"note"`
+}
+
+// requireApproval queues an edit or delete as a change request when the
+// current user is not an owner. It returns true when the response has
+// already been written and the caller must not apply the change itself.
+func (app *application) requireApproval(w http.ResponseWriter, r *http.Request, entityType, action string, entityID int64, proposed any) bool {
+	userId := r.Context().Value(UserKey).(int64)
+
+	user, err := app.GetUser(userId)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return true
+	}
+
+	if user.Role == store.ROLE_OWNER {
+		return false
+	}
+
+	changeRequest := &store.ChangeRequest{
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Action:      action,
+		RequestedBy: userId,
+	}
+
+	if proposed != nil {
+		data, err := json.Marshal(proposed)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return true
+		}
+		changeRequest.Proposed = data
+	}
+
+	if err := app.service.ChangeRequests.Request(r.Context(), changeRequest); err != nil {
+		app.internalServerError(w, r, err)
+		return true
+	}
+	app.audit(r, store.ENTITY_CHANGE_REQUEST, store.ACTION_CREATE, changeRequest.ID, nil, changeRequest)
+
+	if err := app.writeResponse(w, http.StatusAccepted, changeRequest); err != nil {
+		app.internalServerError(w, r, err)
+	}
+	return true
+}
+
+func (app *application) GetChangeRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.ChangeRequestSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	userId := r.Context().Value(UserKey).(int64)
+
+	user, err := app.GetUser(userId)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var status *int64
+	if raw := r.URL.Query().Get("status"); raw != "" {
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid status %q", raw))
+			return
+		}
+		status = &value
+	}
+
+	requests, err := app.store.ChangeRequests.GetByCompanyId(r.Context(), user.CompanyId, status, pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writePage(w, http.StatusOK, requests, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) GetChangeRequestByIdHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(UserKey).(int64)
+
+	user, err := app.GetUser(userId)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	changeRequest, err := app.store.ChangeRequests.GetById(r.Context(), getIDFromContext(r))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = types.ErrChangeRequestNotFound
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if changeRequest.CompanyID != user.CompanyId {
+		app.forbiddenResponse(w, r, fmt.Errorf("change request belongs to another company"))
+		return
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, changeRequest); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) ApproveChangeRequestHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ReviewChangeRequestPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userId := r.Context().Value(UserKey).(int64)
+
+	changeRequest, err := app.service.ChangeRequests.Approve(r.Context(), getIDFromContext(r), userId, payload.Note)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.audit(r, store.ENTITY_CHANGE_REQUEST, store.ACTION_APPROVE, changeRequest.ID, nil, changeRequest)
+	app.audit(r, changeRequest.EntityType, changeRequest.Action, changeRequest.EntityID, changeRequest.Before, changeRequest.Proposed)
+
+	if err := app.writeResponse(w, http.StatusOK, changeRequest); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) RejectChangeRequestHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ReviewChangeRequestPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userId := r.Context().Value(UserKey).(int64)
+
+	changeRequest, err := app.service.ChangeRequests.Reject(r.Context(), getIDFromContext(r), userId, payload.Note)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.audit(r, store.ENTITY_CHANGE_REQUEST, store.ACTION_REJECT, changeRequest.ID, nil, changeRequest)
+
+	if err := app.writeResponse(w, http.StatusOK, changeRequest); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
@@ -34,6 +34,7 @@ func (app *application) CreateCompanyHandler(w http.ResponseWriter, r *http.Requ
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_COMPANY, store.ACTION_CREATE, company.ID, nil, company)
 
 	if err := app.writeResponse(w, http.StatusOK, company); err != nil {
 		app.internalServerError(w, r, err)
@@ -87,10 +88,18 @@ func (app *application) UpdateCompanyHandler(w http.ResponseWriter, r *http.Requ
 		Password: payload.Password,
 	}
 
+	id := getIDFromContext(r)
+	before, err := app.store.Companies.GetById(r.Context(), &id)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.store.Companies.Update(r.Context(), company); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_COMPANY, store.ACTION_UPDATE, id, before, company)
 
 	if err := app.writeResponse(w, http.StatusOK, company); err != nil {
 		app.internalServerError(w, r, err)
@@ -101,10 +110,17 @@ func (app *application) UpdateCompanyHandler(w http.ResponseWriter, r *http.Requ
 func (app *application) DeleteCompanyHandler(w http.ResponseWriter, r *http.Request) {
 	id := getIDFromContext(r)
 
+	before, err := app.store.Companies.GetById(r.Context(), &id)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.store.Companies.Delete(r.Context(), &id); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_COMPANY, store.ACTION_DELETE, id, before, nil)
 
 	if err := app.writeResponse(w, http.StatusOK, "DELETED"); err != nil {
 		app.internalServerError(w, r, err)
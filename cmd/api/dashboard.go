@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/events"
+	"github.com/mubashshir3767/currencyExchange/internal/store/cache"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// GetDashboardHandler sums up the user's company for ?date, today by
+// default, or for the days from ?from to ?to. Dashboards are cached until
+// a write to the company invalidates them.
+func (app *application) GetDashboardHandler(w http.ResponseWriter, r *http.Request) {
+	from, to, err := readDashboardPeriod(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	period := from.Format("2006-01-02") + "_" + to.Format("2006-01-02")
+	dashboard, version, err := app.cacheStore.Dashboards.Get(r.Context(), user.CompanyId, period)
+	if err != nil {
+		log.Printf("dashboard cache: %v", err)
+	}
+
+	if dashboard == nil {
+		dashboard, err = app.service.Dashboard.Get(r.Context(), user.CompanyId, from, to.AddDate(0, 0, 1))
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		dashboard.From = from.Format("2006-01-02")
+		dashboard.To = to.Format("2006-01-02")
+
+		if err := app.cacheStore.Dashboards.Set(r.Context(), user.CompanyId, version, period, dashboard); err != nil {
+			log.Printf("dashboard cache: %v", err)
+		}
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, dashboard); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// readDashboardPeriod reads ?date as a single day in Tashkent, or ?from and
+// ?to like a statement when either is given.
+func readDashboardPeriod(r *http.Request) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+	if query.Get("from") != "" || query.Get("to") != "" {
+		return readPeriod(r)
+	}
+
+	day := tashkentToday()
+	if raw := query.Get("date"); raw != "" {
+		t, err := time.ParseInLocation("2006-01-02", raw, tashkent())
+		if err != nil {
+			return time.Time{}, time.Time{}, types.ErrInvalidRequest.Wrap(fmt.Errorf("date must be a date like 2006-01-02"))
+		}
+		day = t
+	}
+	return day, day, nil
+}
+
+// invalidateDashboards drops the cached dashboards of a company. Failures
+// are logged; the cached copies expire on their own.
+func (app *application) invalidateDashboards(ctx context.Context, companyID int64) {
+	if err := app.cacheStore.Dashboards.Invalidate(ctx, companyID); err != nil {
+		log.Printf("dashboard cache: failed to invalidate company %d: %v", companyID, err)
+	}
+}
+
+// dashboardBus invalidates the dashboards of every company an event is
+// published to, before passing it on. Events also reach the other company
+// of a transfer, whose writes are not audited under its name.
+type dashboardBus struct {
+	events.Bus
+	cacheStore cache.Storage
+}
+
+func (b dashboardBus) Publish(ctx context.Context, event events.Event) {
+	if err := b.cacheStore.Dashboards.Invalidate(ctx, event.CompanyID); err != nil {
+		log.Printf("dashboard cache: failed to invalidate company %d: %v", event.CompanyID, err)
+	}
+	b.Bus.Publish(ctx, event)
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/mubashshir3767/currencyExchange/internal/store"
 	"github.com/mubashshir3767/currencyExchange/internal/types"
@@ -19,6 +21,7 @@ type DebtorPayload struct {
 	Phone           string                  `json:"phone"`
 	IsBalanceEffect int                     `json:"is_balance_effect"`
 	Type            int                     `json:"type"`
+	DueAt           *time.Time              `json:"due_at"`
 }
 
 func (app *application) CreateDebtorsHandler(w http.ResponseWriter, r *http.Request) {
@@ -42,12 +45,14 @@ func (app *application) CreateDebtorsHandler(w http.ResponseWriter, r *http.Requ
 		Phone:           payload.Phone,
 		IsBalanceEffect: payload.IsBalanceEffect,
 		Type:            payload.Type,
+		DueAt:           payload.DueAt,
 	}
 
 	if err := app.service.Debts.Create(r.Context(), debtor); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_DEBT, store.ACTION_CREATE, debtor.ID, nil, debtor)
 
 	if err := app.writeResponse(w, http.StatusOK, debtor); err != nil {
 		app.internalServerError(w, r, err)
@@ -70,6 +75,7 @@ func (app *application) CreateDebtorTransactionHandler(w http.ResponseWriter, r
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_DEBT, store.ACTION_CREATE, payload.ID, nil, payload)
 
 	if err := app.writeResponse(w, http.StatusOK, payload); err != nil {
 		app.internalServerError(w, r, err)
@@ -96,12 +102,20 @@ func (app *application) UpdateDebtsHandler(w http.ResponseWriter, r *http.Reques
 		IsBalanceEffect: payload.IsBalanceEffect,
 		Type:            payload.Type,
 		DebtorID:        payload.DebtorID,
+		DueAt:           payload.DueAt,
+	}
+
+	before, err := app.store.Debts.GetByID(r.Context(), debt.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
 	}
 
 	if err := app.service.Debts.Update(r.Context(), debt); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_DEBT, store.ACTION_UPDATE, debt.ID, before, debt)
 
 	if err := app.writeResponse(w, http.StatusOK, payload); err != nil {
 		app.internalServerError(w, r, err)
@@ -110,7 +124,11 @@ func (app *application) UpdateDebtsHandler(w http.ResponseWriter, r *http.Reques
 }
 
 func (app *application) GetDebtorsByCompanyIdHandler(w http.ResponseWriter, r *http.Request) {
-	app.LoadPaginationInfo(r, r.Context())
+	pagination, err := app.readPagination(r, store.DebtorSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
 	search := r.URL.Query().Get("search")
 	date := r.URL.Query().Get("date")
 
@@ -128,13 +146,44 @@ func (app *application) GetDebtorsByCompanyIdHandler(w http.ResponseWriter, r *h
 		dateSearch = &date
 	}
 
-	debtors, err := app.service.Debtors.GetByCompanyId(r.Context(), getIDFromContext(r), textSeach, dateSearch, app.Pagination)
+	debtors, err := app.service.Debtors.GetByCompanyId(r.Context(), getIDFromContext(r), textSeach, dateSearch, pagination)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.writeResponse(w, http.StatusOK, debtors); err != nil {
+	if err := app.writePage(w, http.StatusOK, debtors, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) SearchDebtorsHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.DebtorSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	filter, err := readFilter(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	debtors, err := app.service.Debtors.Filter(r.Context(), user.CompanyId, filter, pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writePage(w, http.StatusOK, debtors, pagination); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -154,14 +203,49 @@ func (app *application) GetDebtorsTotalBalanceInfo(w http.ResponseWriter, r *htt
 }
 
 func (app *application) GetDebtsByDebtorIdHandler(w http.ResponseWriter, r *http.Request) {
-	app.LoadPaginationInfo(r, r.Context())
-	debtors, err := app.store.Debts.GetByDebtorID(r.Context(), getIDFromContext(r), app.Pagination)
+	pagination, err := app.readPagination(r, store.DebtSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	debtors, err := app.store.Debts.GetByDebtorID(r.Context(), getIDFromContext(r), pagination)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.writeResponse(w, http.StatusOK, debtors); err != nil {
+	if err := app.writePage(w, http.StatusOK, debtors, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) SearchDebtsHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.DebtSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	filter, err := readFilter(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	debts, err := app.store.Debts.Filter(r.Context(), user.CompanyId, filter, pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writePage(w, http.StatusOK, debts, pagination); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -182,11 +266,21 @@ func (app *application) GetDebtorsByIdHandler(w http.ResponseWriter, r *http.Req
 
 func (app *application) DeleteDebtsHandler(w http.ResponseWriter, r *http.Request) {
 	id := getIDFromContext(r)
+	if app.requireApproval(w, r, store.ENTITY_DEBT, store.ACTION_DELETE, id, nil) {
+		return
+	}
+
+	before, err := app.store.Debts.GetByID(r.Context(), id)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
 	if err := app.service.Debts.Delete(r.Context(), id); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_DEBT, store.ACTION_DELETE, id, before, nil)
 
 	if err := app.writeResponse(w, http.StatusOK, "DELETED"); err != nil {
 		app.internalServerError(w, r, err)
@@ -197,13 +291,37 @@ func (app *application) DeleteDebtsHandler(w http.ResponseWriter, r *http.Reques
 func (app *application) DeleteDebtorsHandler(w http.ResponseWriter, r *http.Request) {
 	id := getIDFromContext(r)
 
+	before, err := app.store.Debtors.GetById(r.Context(), id)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.store.Debtors.Delete(r.Context(), id); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_DEBTOR, store.ACTION_DELETE, id, before, nil)
 
 	if err := app.writeResponse(w, http.StatusOK, "DELETED"); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
+
+// runDebtReminders checks for debts that fell due every hour.
+func (app *application) runDebtReminders() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		n, err := app.service.Debts.NotifyDue(context.Background())
+		if err != nil {
+			log.Printf("debt reminders: %v", err)
+		} else if n > 0 {
+			log.Printf("debt reminders: %d debts due", n)
+		}
+
+		<-ticker.C
+	}
+}
@@ -1,21 +1,63 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
 )
 
+// errorResponse writes err using its own status when it is a domain error.
+// Anything else is treated as fallback, so raw SQL or driver errors are only
+// logged and never sent to the client.
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, err error, fallback *types.AppError) {
+	appErr := toAppError(err, fallback)
+
+	log.Printf("%s error: %s path: %s request_id: %s err: %s", appErr.Code, r.Method, r.URL.Path, middleware.GetReqID(r.Context()), err)
+	writeError(w, r, appErr)
+}
+
+func toAppError(err error, fallback *types.AppError) *types.AppError {
+	var appErr *types.AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.ErrNotFound.Wrap(err)
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		appErr = types.ErrValidation.Wrap(err)
+		for _, fe := range validationErrors {
+			appErr = appErr.WithField(strings.ToLower(fe.Field()), fe.Tag())
+		}
+		return appErr
+	}
+
+	return fallback.Wrap(err)
+}
+
 func (app *application) internalServerError(w http.ResponseWriter, r *http.Request, err error) {
-	log.Printf("MESSAGE: %s path: %s err: %s", r.Method, r.URL.Path, err)
-	writeError(w, http.StatusBadRequest, err.Error())
+	app.errorResponse(w, r, err, types.ErrInternal)
 }
 
+// badRequestResponse keeps the original error as a detail for errors that
+// are not domain errors, since those come from decoding or parsing the request.
 func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
-	log.Printf("MESSAGE: %s path: %s err: %s", r.Method, r.URL.Path, err)
-	writeError(w, http.StatusBadRequest, err.Error())
+	app.errorResponse(w, r, err, types.ErrInvalidRequest.WithField("detail", err.Error()))
 }
 
 func (app *application) unauthorizedErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	log.Printf("unauthorized error: %s path: %s err: %s", r.Method, r.URL.Path, err)
-	writeError(w, http.StatusUnauthorized, "Unauthorized method used")
+	app.errorResponse(w, r, err, types.ErrUnauthorized)
+}
+
+func (app *application) forbiddenResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorResponse(w, r, err, types.ErrForbidden.WithField("detail", err.Error()))
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/events"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+const EVENTS_KEEPALIVE = 15 * time.Second
+
+// publishBalanceChanges turns every committed balance update into an event.
+func publishBalanceChanges(st store.Storage, bus events.Bus) {
+	st.Hooks.OnBalanceChanged(func(ctx context.Context, balance store.Balance) {
+		events.Publish(ctx, bus, events.BALANCE_CHANGED, []int64{balance.CompanyId}, balance)
+	})
+}
+
+// EventsHandler streams the events of the user's company as server-sent
+// events until the client goes away.
+func (app *application) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The server's write timeout is meant for ordinary requests.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("events: cannot lift the write deadline: %v", err)
+	}
+
+	ch, unsubscribe := app.events.Subscribe(user.CompanyId)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(EVENTS_KEEPALIVE)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("events: cannot encode %s: %v", event.Type, err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
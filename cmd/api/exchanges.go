@@ -37,6 +37,7 @@ func (app *application) CreateExchangeHandler(w http.ResponseWriter, r *http.Req
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_EXCHANGE, store.ACTION_CREATE, exchange.ID, nil, exchange)
 
 	if err := app.writeResponse(w, http.StatusOK, payload); err != nil {
 		app.internalServerError(w, r, err)
@@ -46,20 +47,55 @@ func (app *application) CreateExchangeHandler(w http.ResponseWriter, r *http.Req
 
 func (app *application) GetExchangesHandler(w http.ResponseWriter, r *http.Request) {
 	var payload FieldRequestPayload
-	app.LoadPaginationInfo(r, r.Context())
+	pagination, err := app.readPagination(r, store.ExchangeSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
 
 	if err := readJSON(w, r, &payload); err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	records, err := app.store.Exchanges.GetByField(r.Context(), payload.FieldName, payload.FieldValue, app.Pagination)
+	records, err := app.store.Exchanges.GetByField(r.Context(), payload.FieldName, payload.FieldValue, pagination)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.writeResponse(w, http.StatusOK, records); err != nil {
+	if err := app.writePage(w, http.StatusOK, records, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) SearchExchangesHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.ExchangeSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	filter, err := readFilter(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	exchanges, err := app.store.Exchanges.Filter(r.Context(), user.CompanyId, filter, pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writePage(w, http.StatusOK, exchanges, pagination); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -83,10 +119,17 @@ func (app *application) UpdateExchangeHandler(w http.ResponseWriter, r *http.Req
 		CompanyID:        *payload.CompanyID,
 	}
 
+	before, err := app.store.Exchanges.GetById(r.Context(), exchange.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.service.Exchanges.Update(r.Context(), exchange); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_EXCHANGE, store.ACTION_UPDATE, exchange.ID, before, exchange)
 
 	if err := app.writeResponse(w, http.StatusOK, payload); err != nil {
 		app.internalServerError(w, r, err)
@@ -96,11 +139,21 @@ func (app *application) UpdateExchangeHandler(w http.ResponseWriter, r *http.Req
 
 func (app *application) DeleteExchangeHandler(w http.ResponseWriter, r *http.Request) {
 	id := getIDFromContext(r)
+	if app.requireApproval(w, r, store.ENTITY_EXCHANGE, store.ACTION_DELETE, id, nil) {
+		return
+	}
+
+	before, err := app.store.Exchanges.GetById(r.Context(), id)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
 
 	if err := app.service.Exchanges.Delete(r.Context(), id); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_EXCHANGE, store.ACTION_DELETE, id, before, nil)
 
 	if err := app.writeResponse(w, http.StatusOK, "DELETED"); err != nil {
 		app.internalServerError(w, r, err)
@@ -121,6 +174,7 @@ func (app *application) ArchiveExchangesHandler(w http.ResponseWriter, r *http.R
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_EXCHANGE, store.ACTION_ARCHIVE, 0, nil, map[string]any{"company_id": user.CompanyId})
 
 	if err := app.writeResponse(w, http.StatusOK, "ARCHIVED"); err != nil {
 		app.internalServerError(w, r, err)
@@ -129,15 +183,19 @@ func (app *application) ArchiveExchangesHandler(w http.ResponseWriter, r *http.R
 }
 
 func (app *application) ArchivedExchangesHandler(w http.ResponseWriter, r *http.Request) {
-	app.LoadPaginationInfo(r, r.Context())
+	pagination, err := app.readPagination(r, store.ExchangeSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
 
-	Exchanges, err := app.store.Exchanges.Archived(r.Context(), app.Pagination)
+	Exchanges, err := app.store.Exchanges.Archived(r.Context(), pagination)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.writeResponse(w, http.StatusOK, Exchanges); err != nil {
+	if err := app.writePage(w, http.StatusOK, Exchanges, pagination); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/export"
+	"github.com/mubashshir3767/currencyExchange/internal/i18n"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// EXPORT_PAGE is how many rows an export reads from the database at a time.
+const EXPORT_PAGE = 500
+
+// ExportPayload takes the filters of both /search (where, search) and
+// fetch.by.field (field_name, field_value, from, to). They are combined, and
+// the export is always limited to the user's company.
+type ExportPayload struct {
+	Where      []types.Condition `json:"where" validate:"dive"`
+	Search     *string           `json:"search"`
+	FieldName  string            `json:"field_name"`
+	FieldValue any               `json:"field_value"`
+	From       *string           `json:"from"`
+	To         *string           `json:"to"`
+}
+
+func (p ExportPayload) filter() *types.Filter {
+	filter := &types.Filter{Where: p.Where, Search: p.Search}
+	if p.FieldName != "" {
+		filter.And(p.FieldName, types.FILTER_EQ, p.FieldValue)
+	}
+	switch {
+	case p.From != nil && p.To != nil:
+		filter.And("created_at", types.FILTER_BETWEEN, []any{*p.From, *p.To})
+	case p.From != nil:
+		filter.And("created_at", types.FILTER_GTE, *p.From)
+	case p.To != nil:
+		filter.And("created_at", types.FILTER_LTE, *p.To)
+	}
+	return filter
+}
+
+func (app *application) ExportTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	columns := []string{"id", "number", "created_at", "type", "status", "received", "delivered", "service_fee", "phone", "details"}
+
+	streamExport(app, w, r, "transactions", columns, app.store.Transactions.Filter, func(t store.Transaction) []export.Cell {
+		received := make([]string, 0, len(t.ReceivedIncomes))
+		for _, income := range t.ReceivedIncomes {
+			received = append(received, export.FormatAmount(income.ReceivedAmount, income.ReceivedCurrency))
+		}
+		delivered := make([]string, 0, len(t.DeliveredOutcomes))
+		for _, outcome := range t.DeliveredOutcomes {
+			delivered = append(delivered, export.FormatAmount(outcome.DeliveredAmount, outcome.DeliveredCurrency))
+		}
+
+		return []export.Cell{
+			export.Int(t.ID),
+			export.Int(t.Number),
+			export.Text(t.CreatedAtFormatted),
+			export.Int(t.Type),
+			export.Int(t.Status),
+			export.Text(strings.Join(received, ", ")),
+			export.Text(strings.Join(delivered, ", ")),
+			export.Text(t.ServiceFee),
+			export.Text(t.Phone),
+			export.Text(t.Details),
+		}
+	})
+}
+
+func (app *application) ExportExchangesHandler(w http.ResponseWriter, r *http.Request) {
+	columns := []string{"id", "created_at", "received_amount", "received_currency", "selled_amount", "selled_currency", "details"}
+
+	streamExport(app, w, r, "exchanges", columns, app.store.Exchanges.Filter, func(e store.Exchange) []export.Cell {
+		return []export.Cell{
+			export.Int(e.ID),
+			export.Text(e.CreatedAtFormatted),
+			export.Amount(e.ReceivedMoney, e.ReceivedCurrency),
+			export.Text(e.ReceivedCurrency),
+			export.Amount(e.SelledMoney, e.SelledCurrency),
+			export.Text(e.SelledCurrency),
+			export.Text(e.Details),
+		}
+	})
+}
+
+func (app *application) ExportBalanceRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	columns := []string{"id", "created_at", "type", "amount", "currency", "transaction_id", "debt_id", "exchange_id", "details"}
+
+	streamExport(app, w, r, "balance-records", columns, app.store.BalanceRecords.Filter, func(b store.BalanceRecord) []export.Cell {
+		return []export.Cell{
+			export.Int(b.ID),
+			export.Text(b.CreatedAtFormatted),
+			export.Int(b.Type),
+			export.Amount(b.Amount, b.Currency),
+			export.Text(b.Currency),
+			export.OptionalInt(b.TransactionId),
+			export.OptionalInt(b.DebtId),
+			export.OptionalInt(b.ExchangeId),
+			export.Text(b.Details),
+		}
+	})
+}
+
+func (app *application) ExportDebtsHandler(w http.ResponseWriter, r *http.Request) {
+	columns := []string{"id", "created_at", "full_name", "phone", "type", "state", "amount", "currency", "details"}
+
+	streamExport(app, w, r, "debts", columns, app.store.Debts.Filter, func(d store.Debts) []export.Cell {
+		return []export.Cell{
+			export.Int(d.ID),
+			export.Text(d.CreatedAtFormatted),
+			export.Text(d.FullName),
+			export.Text(d.Phone),
+			export.Int(int64(d.Type)),
+			export.Int(d.State),
+			export.Amount(d.DebtedAmount, d.DebtedCurrency),
+			export.Text(d.DebtedCurrency),
+			export.Text(d.Details),
+		}
+	})
+}
+
+func (app *application) ExportDebtorsHandler(w http.ResponseWriter, r *http.Request) {
+	columns := []string{"id", "created_at", "full_name", "phone", "balance", "currency"}
+
+	streamExport(app, w, r, "debtors", columns, app.store.Debtors.Filter, func(d store.Debtors) []export.Cell {
+		return []export.Cell{
+			export.Int(d.ID),
+			export.Text(d.CreatedAtFormatted),
+			export.Text(d.FullName),
+			export.Text(d.Phone),
+			export.Amount(d.Balance, d.Currency),
+			export.Text(d.Currency),
+		}
+	})
+}
+
+// streamExport writes every row matching the request's filters as
+// ?format=csv (the default) or xlsx. Rows are read a page at a time in
+// created_at order and flushed to the client after each page, so the export
+// never sits in memory as a whole.
+func streamExport[T any](
+	app *application,
+	w http.ResponseWriter,
+	r *http.Request,
+	name string,
+	columns []string,
+	fetch func(context.Context, int64, *types.Filter, *types.Pagination) ([]T, error),
+	row func(T) []export.Cell,
+) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FORMAT_CSV
+	}
+	if format != export.FORMAT_CSV && format != export.FORMAT_XLSX {
+		app.badRequestResponse(w, r, fmt.Errorf("format must be csv or xlsx"))
+		return
+	}
+
+	var payload ExportPayload
+	if r.ContentLength != 0 {
+		if err := readJSON(w, r, &payload); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	filter := payload.filter()
+	pagination := &types.Pagination{Limit: EXPORT_PAGE, OrderBy: "created_at DESC"}
+
+	// The first page is read before anything is written, so a bad filter
+	// still gets an ordinary error response.
+	items, err := fetch(r.Context(), user.CompanyId, filter, pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("export: cannot lift the write deadline: %v", err)
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("2006-01-02"), format)
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	out, err := export.New(format, w)
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+
+	lang := i18n.FromContext(r.Context())
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = i18n.T(lang, "export."+column)
+	}
+	if err := out.Header(headers); err != nil {
+		return
+	}
+
+	for rows := 0; ; {
+		for _, item := range items {
+			if err := out.Row(row(item)); err != nil {
+				return
+			}
+		}
+		rows += len(items)
+
+		if err := out.Flush(); err != nil {
+			return
+		}
+		rc.Flush()
+
+		if !pagination.HasMore || pagination.NextCursor == nil {
+			break
+		}
+		if pagination.Cursor, err = types.DecodeCursor(*pagination.NextCursor); err == nil {
+			items, err = fetch(r.Context(), user.CompanyId, filter, pagination)
+		}
+		if err != nil {
+			// The status is already sent; cutting the connection is the only
+			// way left to tell the client the file is incomplete.
+			log.Printf("export %s: failed after %d rows: %v", name, rows, err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		log.Printf("export %s: %v", name, err)
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
+	"github.com/mubashshir3767/currencyExchange/internal/i18n"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
 )
 
 var Validate *validator.Validate
@@ -19,9 +22,12 @@ func writeJSON(w http.ResponseWriter, status int, data any) error {
 	return json.NewEncoder(w).Encode(data)
 }
 
+// maxBodyBytes is how much of a request body is read, here and by the
+// OpenAPI validation before it.
+const maxBodyBytes = 1_048_578 //mb
+
 func readJSON(w http.ResponseWriter, r *http.Request, data any) error {
-	maxBytes := 1_048_578 //mb
-	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
 
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
@@ -29,11 +35,27 @@ func readJSON(w http.ResponseWriter, r *http.Request, data any) error {
 	return decoder.Decode(data)
 }
 
-func writeError(w http.ResponseWriter, status int, message string) error {
+func writeError(w http.ResponseWriter, r *http.Request, appErr *types.AppError) error {
+	type body struct {
+		Code      string            `json:"code"`
+		Message   string            `json:"message"`
+		Fields    map[string]string `json:"fields,omitempty"`
+		RequestID string            `json:"request_id,omitempty"`
+	}
 	type envelop struct {
-		Error string
+		Error body `json:"error"`
 	}
-	return writeJSON(w, status, &envelop{Error: message})
+	message, ok := i18n.Lookup(i18n.FromContext(r.Context()), appErr.Code)
+	if !ok {
+		message = appErr.Message
+	}
+
+	return writeJSON(w, appErr.Status, &envelop{Error: body{
+		Code:      appErr.Code,
+		Message:   message,
+		Fields:    appErr.Fields,
+		RequestID: middleware.GetReqID(r.Context()),
+	}})
 }
 
 func (app *application) writeResponse(w http.ResponseWriter, status int, data any) error {
@@ -42,3 +64,38 @@ func (app *application) writeResponse(w http.ResponseWriter, status int, data an
 	}
 	return writeJSON(w, status, &envelop{Data: data})
 }
+
+// writePage wraps a list the same way as writeResponse and adds what the
+// client needs to ask for the next page.
+func (app *application) writePage(w http.ResponseWriter, status int, data any, pagination *types.Pagination) error {
+	type page struct {
+		Page       int     `json:"page"`
+		Limit      int     `json:"limit"`
+		Total      int64   `json:"total"`
+		HasMore    bool    `json:"has_more"`
+		NextCursor *string `json:"next_cursor"`
+	}
+	type envelop struct {
+		Data       any  `json:"data"`
+		Pagination page `json:"pagination"`
+	}
+	return writeJSON(w, status, &envelop{Data: data, Pagination: page{
+		Page:       pagination.Page,
+		Limit:      pagination.Limit,
+		Total:      pagination.Total,
+		HasMore:    pagination.HasMore,
+		NextCursor: pagination.NextCursor,
+	}})
+}
+
+// readFilter decodes and validates the body of a /search endpoint.
+func readFilter(w http.ResponseWriter, r *http.Request) (*types.Filter, error) {
+	var filter types.Filter
+	if err := readJSON(w, r, &filter); err != nil {
+		return nil, err
+	}
+	if err := Validate.Struct(filter); err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
@@ -1,18 +1,26 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/mubashshir3767/currencyExchange/internal/db"
 	"github.com/mubashshir3767/currencyExchange/internal/env"
+	"github.com/mubashshir3767/currencyExchange/internal/events"
 	"github.com/mubashshir3767/currencyExchange/internal/fcm"
 	"github.com/mubashshir3767/currencyExchange/internal/notify"
+	"github.com/mubashshir3767/currencyExchange/internal/receipt"
 	"github.com/mubashshir3767/currencyExchange/internal/service"
+	"github.com/mubashshir3767/currencyExchange/internal/sms"
 	"github.com/mubashshir3767/currencyExchange/internal/store"
 	"github.com/mubashshir3767/currencyExchange/internal/store/cache"
+	"github.com/mubashshir3767/currencyExchange/internal/telegram"
 )
 
 func main() {
@@ -40,8 +48,23 @@ func main() {
 			db:      env.GetInt("REDIS_DB", 0),
 			enabled: env.GetBool("REDIS_ENABLED", true),
 		},
-		env: env.GetString("ENV", "PROD"),
+		env:                env.GetString("ENV", "PROD"),
+		auditRetentionDays: env.GetInt("AUDIT_RETENTION_DAYS", 365),
+		eventsRedis:        env.GetBool("EVENTS_REDIS_FANOUT", false),
+		telegramBot:        env.GetString("TELEGRAM_BOT_USERNAME", ""),
+		publicURL:          strings.TrimSuffix(env.GetString("PUBLIC_URL", ""), "/"),
+		receiptVerifyLimit: env.GetInt("RECEIPT_VERIFY_RATE_LIMIT", 30),
+		statementLimit:     env.GetInt("STATEMENT_RATE_LIMIT", 30),
+
+		reconcileIntervalHours: env.GetInt("RECONCILE_INTERVAL_HOURS", 24),
+		snapshotBackfillDays:   env.GetInt("SNAPSHOT_BACKFILL_DAYS", 7),
+	}
+
+	trustedProxies, err := parseProxies(env.GetString("TRUSTED_PROXIES", ""))
+	if err != nil {
+		log.Fatalf("TRUSTED_PROXIES: %v", err)
 	}
+	cfg.trustedProxies = trustedProxies
 
 	rdb := cache.NewRedisClient(cfg.redisConfig.addr, cfg.redisConfig.pw, cfg.redisConfig.db)
 	log.Println("redis cache connection established")
@@ -62,26 +85,147 @@ func main() {
 
 	store := store.NewStorage(db)
 
-	var delivered notify.DeliveredUser = notify.NoopDeliveredUser{}
+	if len(os.Args) > 1 && os.Args[1] == "-rebuild-company-amounts" {
+		transactions := service.NewTransactionService(store, events.NewLocalBus())
+		if err := transactions.RebuildCompanyAmounts(context.Background()); err != nil {
+			log.Fatalf("failed to rebuild company amounts: %v", err)
+		}
+		log.Println("company amounts rebuilt")
+		return
+	}
+
+	notifier := notify.NewNotifier(store.Users, store.NotificationPreferences)
 	if creds := env.GetString("FIREBASE_CREDENTIALS_PATH", ""); creds != "" {
-		n, err := fcm.NewDeliveredNotifier(creds, store)
+		channel, err := fcm.NewChannel(creds, store)
 		if err != nil {
 			log.Printf("FCM notifier disabled: %v", err)
 		} else {
-			delivered = n
+			notifier.Register(notify.CHANNEL_FCM, channel)
 		}
 	}
+	if token := env.GetString("TELEGRAM_BOT_TOKEN", ""); token != "" {
+		bot := telegram.NewClient(token)
+		notifier.Register(notify.CHANNEL_TELEGRAM, notify.Telegram{Bot: bot})
+		if env.GetBool("TELEGRAM_BOT_POLLING", true) {
+			go telegram.NewBot(bot, store, env.GetInt("TELEGRAM_DIGEST_HOUR", 20)).Run(context.Background())
+		}
+	}
+	if addr := env.GetString("SMTP_ADDR", ""); addr != "" {
+		notifier.Register(notify.CHANNEL_EMAIL, notify.Email{
+			Addr:     addr,
+			From:     env.GetString("SMTP_FROM", ""),
+			Username: env.GetString("SMTP_USERNAME", ""),
+			Password: env.GetString("SMTP_PASSWORD", ""),
+		})
+	}
+
+	var smsProvider sms.Provider
+	switch env.GetString("SMS_PROVIDER", "") {
+	case "file":
+		smsProvider = &sms.FileProvider{Path: env.GetString("SMS_FILE_PATH", "sms.log")}
+	case "http":
+		smsProvider = sms.NewHTTPProvider(env.GetString("SMS_HTTP_URL", ""), env.GetString("SMS_HTTP_TOKEN", ""))
+	}
+	if smsProvider != nil {
+		notifier.Register(notify.CHANNEL_SMS, notify.SMS{Provider: smsProvider})
+		go sms.NewDispatcher(store.SMS, smsProvider).Run(context.Background())
+	}
 
-	service := service.NewService(store, delivered)
 	cacheStore := cache.NewRedisStorage(rdb)
 
+	var bus events.Bus = events.NewLocalBus()
+	if cfg.redisConfig.enabled && cfg.eventsRedis {
+		redisBus := events.NewRedisBus(rdb)
+		go redisBus.Run(context.Background())
+		bus = redisBus
+	}
+	bus = dashboardBus{Bus: bus, cacheStore: cacheStore}
+	publishBalanceChanges(store, bus)
+
+	go notify.NewDispatcher(store.NotificationOutbox, store.Notifications, notifier).Run(context.Background())
+
+	largeExchange, err := parseAmounts(env.GetString("LARGE_EXCHANGE_LIMITS", ""))
+	if err != nil {
+		log.Fatalf("LARGE_EXCHANGE_LIMITS: %v", err)
+	}
+
+	service := service.NewService(store, bus, largeExchange)
+
+	receipts, err := receipt.NewRenderer(
+		env.GetString("RECEIPT_FONT_PATH", "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"),
+		env.GetString("RECEIPT_FONT_BOLD_PATH", "/usr/share/fonts/truetype/dejavu/DejaVuSans-Bold.ttf"),
+	)
+	if err != nil {
+		log.Printf("PDF receipts disabled: %v", err)
+	}
+
+	var receiptSigner *receipt.Signer
+	if keys := env.GetString("RECEIPT_SIGNING_KEYS", ""); keys != "" {
+		if receiptSigner, err = receipt.NewSigner(keys); err != nil {
+			log.Fatalf("RECEIPT_SIGNING_KEYS: %v", err)
+		}
+	}
+
 	app := application{
 		config:     cfg,
 		store:      store,
 		service:    service,
 		cacheStore: cacheStore,
+		events:     bus,
+		receipts:   receipts,
+		signer:     receiptSigner,
 	}
 
+	go app.runAuditRetention()
+	go app.runWebhooks()
+	go app.runReconciliation()
+	go app.runBalanceSnapshots()
+	go app.runDebtReminders()
+
 	mux := app.mount()
 	log.Fatal(app.run(mux))
 }
+
+// parseAmounts reads comma separated CURRENCY:amount pairs, such as
+// "USD:10000,SUM:120000000".
+func parseAmounts(list string) (map[string]int64, error) {
+	amounts := map[string]int64{}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		currency, value, ok := strings.Cut(entry, ":")
+		amount, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if !ok || currency == "" || err != nil || amount <= 0 {
+			return nil, fmt.Errorf("%q: want CURRENCY:amount with a positive amount", entry)
+		}
+		amounts[strings.TrimSpace(currency)] = amount
+	}
+	return amounts, nil
+}
+
+// parseProxies reads comma separated addresses or CIDR ranges, such as
+// "10.0.0.5,172.18.0.0/16".
+func parseProxies(list string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, proxy, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q: want an address or a CIDR range", entry)
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/mubashshir3767/currencyExchange/internal/i18n"
 	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
 )
 
 func (app *application) JWTUserMiddleware() func(http.Handler) http.Handler {
@@ -53,11 +58,106 @@ func (app *application) JWTUserMiddleware() func(http.Handler) http.Handler {
 			}
 
 			ctx := context.WithValue(r.Context(), UserKey, user.ID)
+			if user.Language != nil {
+				ctx = i18n.WithLang(ctx, i18n.FromPreference(user.Language))
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// LanguageMiddleware selects the response language from Accept-Language.
+// JWTUserMiddleware replaces it with the user's saved preference, if any.
+func (app *application) LanguageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang, ok := i18n.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+		if !ok {
+			lang = i18n.DEFAULT
+		}
+		w.Header().Set("Content-Language", string(lang))
+		next.ServeHTTP(w, r.WithContext(i18n.WithLang(r.Context(), lang)))
+	})
+}
+
+// TimeoutMiddleware is middleware.Timeout for everything except event
+// streams, which are meant to stay open, and exports, which stream for as
+// long as there are rows.
+func (app *application) TimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withTimeout := middleware.Timeout(timeout)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.Header.Get("Accept"), "text/event-stream") || isExport(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			withTimeout.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isExport reports whether r is for one of the POST .../export routes.
+func isExport(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/export")
+}
+
+// RealIPMiddleware is middleware.RealIP for requests from a trusted proxy.
+// Anyone else can put what they like in the forwarding headers, so their
+// own address is kept for rate limits and audit logs.
+func (app *application) RealIPMiddleware(next http.Handler) http.Handler {
+	realIP := middleware.RealIP(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.isTrustedProxy(r.RemoteAddr) {
+			realIP.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) isTrustedProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range app.config.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitMiddleware allows each client IP limit requests per window on
+// the routes it wraps; name keeps the counts of different routes apart. The
+// IP is the peer address unless RealIPMiddleware took it from a trusted
+// proxy, so forged X-Forwarded-For headers get no fresh quota. When
+// Redis is unreachable requests are let through rather than refused.
+func (app *application) RateLimitMiddleware(name string, limit int, window time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+
+			allowed, retryAfter, err := app.cacheStore.RateLimits.Allow(r.Context(), name+"-"+ip, limit, window)
+			if err != nil {
+				log.Printf("rate limit %s: %v", name, err)
+			} else if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+				app.errorResponse(w, r, types.ErrRateLimited, types.ErrRateLimited)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (app *application) GetUser(id int64) (*store.User, error) {
 	user, err := app.cacheStore.Users.Get(context.Background(), id)
 	if err != nil {
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/mubashshir3767/currencyExchange/internal/i18n"
+	"github.com/mubashshir3767/currencyExchange/internal/notify"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// GetNotificationOutboxHandler lists push notifications and their delivery
+// status. Owners see the whole company and may pass ?user_id=, everyone else
+// only sees their own. ?status= and ?transaction_id= narrow the list.
+func (app *application) GetNotificationOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.NotificationOutboxSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	var filters [3]*int64
+	for i, name := range []string{"user_id", "status", "transaction_id"} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid %s %q", name, raw))
+			return
+		}
+		filters[i] = &value
+	}
+
+	userId, status, transactionId := filters[0], filters[1], filters[2]
+	if user.Role != store.ROLE_OWNER {
+		userId = &user.ID
+	}
+
+	notifications, err := app.store.NotificationOutbox.GetByCompanyId(r.Context(), user.CompanyId, userId, status, transactionId, pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writePage(w, http.StatusOK, notifications, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) GetNotificationOutboxByIdHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	notification, err := app.store.NotificationOutbox.GetById(r.Context(), getIDFromContext(r))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = types.ErrNotificationNotFound
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if notification.CompanyID != user.CompanyId || (user.Role != store.ROLE_OWNER && notification.UserID != user.ID) {
+		app.forbiddenResponse(w, r, fmt.Errorf("notification belongs to another user"))
+		return
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, notification); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// GetNotificationsHandler lists the current user's inbox, newest first.
+// ?unread=true leaves out what was already read.
+func (app *application) GetNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.NotificationSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	unreadOnly, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+
+	notifications, err := app.store.Notifications.GetByUserId(r.Context(), r.Context().Value(UserKey).(int64), unreadOnly, pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	lang := i18n.FromContext(r.Context())
+	for i := range notifications {
+		data := map[string]string{}
+		json.Unmarshal(notifications[i].Data, &data)
+		notifications[i].Title, notifications[i].Body = notify.Text(lang, notifications[i].Kind, data)
+	}
+
+	if err := app.writePage(w, http.StatusOK, notifications, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) GetUnreadNotificationsCountHandler(w http.ResponseWriter, r *http.Request) {
+	count, err := app.store.Notifications.UnreadCount(r.Context(), r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, map[string]int64{"unread": count}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) ReadNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	if err := app.store.Notifications.MarkRead(r.Context(), r.Context().Value(UserKey).(int64), getIDFromContext(r)); err != nil {
+		if err == sql.ErrNoRows {
+			err = types.ErrNotificationNotFound
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, "READ"); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) ReadAllNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	updated, err := app.store.Notifications.MarkAllRead(r.Context(), r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, map[string]int64{"updated": updated}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+type NotificationPreferencePayload struct {
+	Kind     string   `json:"kind" validate:"required,oneof=transaction_pending transaction_completed transaction_cancelled low_balance high_balance debt_due large_exchange new_device_login"`
+	Channels []string `json:"channels" validate:"dive,oneof=fcm sms telegram email"`
+}
+
+type NotificationPreference struct {
+	Kind     string   `json:"kind"`
+	Channels []string `json:"channels"`
+	Default  bool     `json:"default"`
+}
+
+// GetNotificationPreferencesHandler returns the channels of every kind for
+// the current user, marking the kinds that still use the defaults.
+func (app *application) GetNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	saved, err := app.store.NotificationPreferences.GetByUserId(r.Context(), r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	preferences := make([]NotificationPreference, 0, len(notify.KINDS))
+	for _, kind := range notify.KINDS {
+		channels, ok := saved[kind]
+		if !ok {
+			channels = notify.DefaultChannels(kind)
+		}
+		preferences = append(preferences, NotificationPreference{Kind: kind, Channels: channels, Default: !ok})
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, preferences); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) UpdateNotificationPreferenceHandler(w http.ResponseWriter, r *http.Request) {
+	var payload NotificationPreferencePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.NotificationPreferences.Set(r.Context(), r.Context().Value(UserKey).(int64), payload.Kind, payload.Channels); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if payload.Channels == nil {
+		payload.Channels = []string{}
+	}
+	if err := app.writeResponse(w, http.StatusOK, NotificationPreference{Kind: payload.Kind, Channels: payload.Channels}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mubashshir3767/currencyExchange/internal/openapi"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+func (app *application) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openapi.Spec())
+}
+
+func (app *application) isDevelopment() bool {
+	return app.config.env != "PROD"
+}
+
+// OpenAPIMiddleware validates requests against the document before they
+// reach a handler. Outside production it also validates JSON responses and
+// logs any drift from the document.
+func (app *application) OpenAPIMiddleware(mux *chi.Mux, doc *openapi.Document) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.NewRouteContext()
+			pattern := mux.Find(rctx, r.Method, r.URL.Path)
+
+			op := doc.Operation(r.Method, pattern)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := doc.ValidateRequest(w, r, op, rctx.URLParam, maxBodyBytes); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					app.badRequestResponse(w, r, err)
+					return
+				}
+				app.errorResponse(w, r, err, validationError(err))
+				return
+			}
+
+			if !app.isDevelopment() {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if rec.passthrough {
+				return
+			}
+
+			if err := doc.ValidateResponse(op, rec.status, rec.body.Bytes()); err != nil {
+				log.Printf("openapi: response of %s %s does not match the spec: %v", r.Method, pattern, err)
+				w.Header().Set("X-OpenAPI-Violation", err.Error())
+			}
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+		})
+	}
+}
+
+func validationError(err error) *types.AppError {
+	appErr := types.ErrValidation.Wrap(err)
+	if errs, ok := err.(openapi.Errors); ok {
+		for location, detail := range errs {
+			appErr = appErr.WithField(location, detail)
+		}
+	}
+	return appErr
+}
+
+// responseRecorder buffers JSON responses so they can be validated before
+// they are sent. Anything else, such as streams, is passed straight through.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+	passthrough bool
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = status
+
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		rec.passthrough = true
+		rec.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.passthrough {
+		return rec.ResponseWriter.Write(data)
+	}
+	return rec.body.Write(data)
+}
+
+func (rec *responseRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok && rec.passthrough {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the connection.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// checkRoutes compares the mounted routes with the document in both
+// directions, so a handler cannot be added or removed without the spec.
+func checkRoutes(mux *chi.Mux, doc *openapi.Document) error {
+	mounted := map[string]bool{}
+	var missing []string
+
+	err := chi.Walk(mux, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = openapi.NormalizePath(route)
+		mounted[method+" "+route] = true
+		if doc.Operation(method, route) == nil {
+			missing = append(missing, method+" "+route)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	for path, item := range doc.Paths {
+		path = openapi.NormalizePath(path)
+		for method, op := range map[string]*openapi.Operation{
+			http.MethodGet:    item.Get,
+			http.MethodPost:   item.Post,
+			http.MethodPut:    item.Put,
+			http.MethodPatch:  item.Patch,
+			http.MethodDelete: item.Delete,
+		} {
+			if op != nil && !mounted[method+" "+path] {
+				stale = append(stale, method+" "+path)
+			}
+		}
+	}
+
+	if len(missing) == 0 && len(stale) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+	sort.Strings(stale)
+	return fmt.Errorf("openapi: routes missing from the spec: %v; operations without a route: %v", missing, stale)
+}
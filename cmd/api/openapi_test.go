@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mubashshir3767/currencyExchange/internal/openapi"
+	"github.com/mubashshir3767/currencyExchange/internal/service"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/store/cache"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// The fakes embed the real storages, without a database, and override what
+// the handlers under test call; anything else would panic.
+
+type fakeUsers struct {
+	*store.UserStorage
+	users []store.User
+}
+
+func (f fakeUsers) GetById(_ context.Context, id *int64) (*store.User, error) {
+	for _, user := range f.users {
+		if user.ID == *id {
+			return &user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f fakeUsers) GetAll(context.Context) ([]store.User, error) {
+	return f.users, nil
+}
+
+func (f fakeUsers) Login(_ context.Context, user *store.User) error {
+	*user = f.users[0]
+	return nil
+}
+
+type fakeCompanies struct {
+	*store.CompanyStorage
+	company store.Company
+}
+
+func (f fakeCompanies) GetAll(context.Context) ([]store.Company, error) {
+	return []store.Company{f.company}, nil
+}
+
+func (f fakeCompanies) GetById(context.Context, *int64) (*store.Company, error) {
+	return &f.company, nil
+}
+
+type fakeSessions struct {
+	*store.UserSessionStorage
+}
+
+func (fakeSessions) ListByUserID(_ context.Context, userID int64) ([]store.UserSession, error) {
+	platform := "android"
+	return []store.UserSession{{ID: 1, UserID: userID, DeviceID: "device-1", FCMToken: "token", Platform: &platform}}, nil
+}
+
+type fakeNotifications struct {
+	*store.NotificationStorage
+}
+
+func (fakeNotifications) UnreadCount(context.Context, int64) (int64, error) {
+	return 3, nil
+}
+
+type fakePreferences struct {
+	*store.NotificationPreferenceStorage
+}
+
+func (fakePreferences) GetByUserId(context.Context, int64) (map[string][]string, error) {
+	return map[string][]string{store.NOTIFICATION_LOW_BALANCE: {"telegram"}}, nil
+}
+
+type fakeTransactions struct {
+	*store.TransactionStorage
+}
+
+func testTransaction() store.Transaction {
+	deliveredUser := int64(1)
+	return store.Transaction{
+		ID:                 7,
+		Number:             1007,
+		ReceivedCompanyId:  1,
+		ReceivedUserId:     1,
+		ReceivedIncomes:    []types.ReceivedIncomes{{ReceivedAmount: 100, ReceivedCurrency: "USD"}},
+		DeliveredOutcomes:  []types.DeliveredOutcomes{{DeliveredAmount: 1250000, DeliveredCurrency: "SUM"}},
+		DeliveredCompanyId: 1,
+		DeliveredUserId:    &deliveredUser,
+		ServiceFee:         "0",
+		Phone:              "998901234567",
+		Details:            "transfer",
+		Status:             1,
+		Type:               1,
+		CreatedAtFormatted: "2026-01-02 10:00:00",
+	}
+}
+
+func (fakeTransactions) GetById(context.Context, int64) (*store.Transaction, error) {
+	transaction := testTransaction()
+	return &transaction, nil
+}
+
+func (fakeTransactions) GetByField(context.Context, *string, string, any, *types.Pagination) ([]store.Transaction, error) {
+	return []store.Transaction{testTransaction()}, nil
+}
+
+type fakeExchanges struct {
+	*store.ExchangeStorage
+}
+
+func (fakeExchanges) GetByField(context.Context, string, any, *types.Pagination) ([]store.Exchange, error) {
+	return []store.Exchange{{
+		ID: 3, ReceivedMoney: 100, ReceivedCurrency: "USD", SelledMoney: 1250000, SelledCurrency: "SUM",
+		UserId: 1, CompanyID: 1, Status: 1, CreatedAtFormatted: "2026-01-02 10:00:00",
+	}}, nil
+}
+
+type fakeDebts struct {
+	*store.DebtsStorage
+}
+
+func (fakeDebts) GetByDebtorID(_ context.Context, debtorID int64, _ *types.Pagination) ([]store.Debts, error) {
+	return []store.Debts{{
+		ID: 5, FullName: "Debtor", ReceivedIncomes: []types.ReceivedIncomes{{ReceivedAmount: 50, ReceivedCurrency: "USD"}},
+		DebtedAmount: 50, DebtedCurrency: "USD", UserID: 1, CompanyID: 1, DebtorID: debtorID,
+		Phone: "998901234567", Type: 1, CreatedAtFormatted: "2026-01-02 10:00:00",
+	}}, nil
+}
+
+// The write services would need a database transaction, so they only
+// accept what the handlers pass them.
+
+type fakeTransactionService struct {
+	*service.TransactionService
+}
+
+func (fakeTransactionService) PerformTransaction(_ context.Context, transaction *store.Transaction) error {
+	transaction.ID = 8
+	return nil
+}
+
+func (fakeTransactionService) CompleteTransaction(context.Context, types.TransactionComplete) error {
+	return nil
+}
+
+type fakeExchangeService struct {
+	*service.ExchangeService
+}
+
+func (fakeExchangeService) Create(_ context.Context, exchange *store.Exchange) error {
+	exchange.ID = 4
+	return nil
+}
+
+type fakeDebtsService struct {
+	*service.DebtsService
+}
+
+func (fakeDebtsService) Transaction(_ context.Context, debt *store.Debts) error {
+	debt.ID = 6
+	return nil
+}
+
+type fakeAuditLogs struct {
+	*store.AuditLogStorage
+	entries *[]store.AuditLog
+}
+
+func (f fakeAuditLogs) Create(_ context.Context, entry *store.AuditLog) error {
+	*f.entries = append(*f.entries, *entry)
+	return nil
+}
+
+type fakeDashboards struct{}
+
+func (fakeDashboards) Get(context.Context, int64, string) (*store.Dashboard, int64, error) {
+	return nil, 0, nil
+}
+func (fakeDashboards) Set(context.Context, int64, int64, string, *store.Dashboard) error { return nil }
+func (fakeDashboards) Invalidate(context.Context, int64) error                           { return nil }
+
+type fakeUserCache struct{}
+
+func (fakeUserCache) Get(context.Context, int64) (*store.User, error) { return nil, nil }
+func (fakeUserCache) Set(context.Context, *store.User) error          { return nil }
+
+func newTestApp() *application {
+	language := "en"
+	st := store.Storage{
+		Users: fakeUsers{users: []store.User{
+			{ID: 1, Username: "owner", Phone: "998901234567", Role: store.ROLE_OWNER, CompanyId: 1, Language: &language, CreatedAt: "2026-01-01 10:00:00"},
+		}},
+		Companies:               fakeCompanies{company: store.Company{ID: 1, Name: "Main", CreatedAt: "2026-01-01 10:00:00"}},
+		UserSessions:            fakeSessions{},
+		Notifications:           fakeNotifications{},
+		NotificationPreferences: fakePreferences{},
+		Transactions:            fakeTransactions{},
+		Exchanges:               fakeExchanges{},
+		Debts:                   fakeDebts{},
+		AuditLogs:               fakeAuditLogs{entries: &[]store.AuditLog{}},
+	}
+
+	services := service.NewService(st, nil, nil)
+	services.Transactions = fakeTransactionService{services.Transactions.(*service.TransactionService)}
+	services.Exchanges = fakeExchangeService{services.Exchanges.(*service.ExchangeService)}
+	services.Debts = fakeDebtsService{services.Debts.(*service.DebtsService)}
+
+	return &application{
+		// PROD, so mount reports route drift through checkRoutes instead of
+		// exiting; the tests check it themselves.
+		config:     config{env: "PROD"},
+		store:      st,
+		service:    services,
+		cacheStore: cache.Storage{Users: fakeUserCache{}, Dashboards: fakeDashboards{}},
+	}
+}
+
+func TestRoutesMatchSpec(t *testing.T) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkRoutes(newTestApp().mount(), doc); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const (
+	transactionBody = `{"service_fee":0,"received_incomes":[{"received_amount":100,"received_currency":"USD"}],` +
+		`"delivered_outcomes":[{"delivered_amount":1250000,"delivered_currency":"SUM"}],"received_company_id":1,` +
+		`"delivered_company_id":1,"received_user_id":1,"delivered_user_id":1,"phone":"998901234567","details":"transfer","type":1}`
+	exchangeBody = `{"received_money":100,"received_currency":"USD","selled_money":1250000,"selled_currency":"SUM","user_id":1,"details":"cash"}`
+	debtBody     = `{"full_name":"Debtor","received_incomes":[{"received_amount":50,"received_currency":"USD"}],` +
+		`"debted_amount":50,"debted_currency":"USD","user_id":1,"debtor_id":2,"phone":"998901234567","type":1}`
+)
+
+func TestResponsesMatchSpec(t *testing.T) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := newTestApp().mount()
+
+	token, err := JWTCreate([]byte("secret"), 1, "userID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		auth   bool
+		status int
+	}{
+		{"login", http.MethodPost, "/api/v1/users/login", `{"phone":"998901234567","password":"secret"}`, false, http.StatusOK},
+		{"login with a numeric phone", http.MethodPost, "/api/v1/users/login", `{"phone":998901234567,"password":"secret"}`, false, http.StatusUnprocessableEntity},
+		{"login with a huge body", http.MethodPost, "/api/v1/users/login", `{"phone":"` + strings.Repeat("9", maxBodyBytes) + `"}`, false, http.StatusBadRequest},
+		{"users", http.MethodGet, "/api/v1/user/all", "", true, http.StatusOK},
+		{"users without token", http.MethodGet, "/api/v1/user/all", "", false, http.StatusUnauthorized},
+		{"companies", http.MethodGet, "/api/v1/user/companies/all", "", true, http.StatusOK},
+		{"company", http.MethodGet, "/api/v1/user/companies/1", "", true, http.StatusOK},
+		{"sessions", http.MethodGet, "/api/v1/user/sessions", "", true, http.StatusOK},
+		{"unread count", http.MethodGet, "/api/v1/user/notifications/unread-count", "", true, http.StatusOK},
+		{"preferences", http.MethodGet, "/api/v1/user/notifications/preferences", "", true, http.StatusOK},
+
+		{"create transaction", http.MethodPost, "/api/v1/user/transactions/create", transactionBody, true, http.StatusOK},
+		{"create transaction with a text amount", http.MethodPost, "/api/v1/user/transactions/create", strings.Replace(transactionBody, `"received_amount":100`, `"received_amount":"100"`, 1), true, http.StatusUnprocessableEntity},
+		{"complete transaction", http.MethodPost, "/api/v1/user/transactions/complete", `{"transactionID":7,"delivered_user_id":1,"received_service_fee":0}`, true, http.StatusOK},
+		{"transactions by field", http.MethodPost, "/api/v1/user/transactions/fetch.by.field", `{"field_name":"received_company_id","field_value":1}`, true, http.StatusOK},
+		{"transactions by field without a name", http.MethodPost, "/api/v1/user/transactions/fetch.by.field", `{"field_name":1,"field_value":1}`, true, http.StatusUnprocessableEntity},
+		{"transactions in process", http.MethodGet, "/api/v1/user/transactions/show/process/1", "", true, http.StatusOK},
+		{"create exchange", http.MethodPost, "/api/v1/user/exchanges/", exchangeBody, true, http.StatusOK},
+		{"create exchange with a text amount", http.MethodPost, "/api/v1/user/exchanges/", strings.Replace(exchangeBody, `"received_money":100`, `"received_money":"100"`, 1), true, http.StatusUnprocessableEntity},
+		{"exchanges by field", http.MethodPost, "/api/v1/user/exchanges/filter", `{"field_name":"company_id","field_value":1}`, true, http.StatusOK},
+		{"debt transaction", http.MethodPost, "/api/v1/user/debtors/transaction", debtBody, true, http.StatusOK},
+		{"debt transaction with a text amount", http.MethodPost, "/api/v1/user/debtors/transaction", strings.Replace(debtBody, `"debted_amount":50`, `"debted_amount":"50"`, 1), true, http.StatusUnprocessableEntity},
+		{"debts of a debtor", http.MethodGet, "/api/v1/user/debtors/debts/2", "", true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.auth {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req.WithContext(ctx))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+
+			pattern := mux.Find(chi.NewRouteContext(), tt.method, tt.path)
+			op := doc.Operation(tt.method, pattern)
+			if op == nil {
+				t.Fatalf("no operation for %s %s", tt.method, pattern)
+			}
+			if err := doc.ValidateResponse(op, rec.Code, rec.Body.Bytes()); err != nil {
+				t.Errorf("response does not match the spec: %v\n%s", err, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mubashshir3767/currencyExchange/internal/export"
+	"github.com/mubashshir3767/currencyExchange/internal/i18n"
+	"github.com/mubashshir3767/currencyExchange/internal/receipt"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// GetTransactionReceiptHandler prints a transaction as a PDF receipt from
+// the side of the user's company.
+func (app *application) GetTransactionReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	app.writeReceipt(w, r, store.ENTITY_TRANSACTION, func(user *store.User, lang i18n.Lang) (*receipt.Receipt, error) {
+		items, err := app.store.Transactions.Filter(r.Context(), user.CompanyId, types.Where("id", getIDFromContext(r)), &types.Pagination{Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return nil, types.ErrTransactionNotFound
+		}
+		t := items[0]
+
+		cashierID := t.ReceivedUserId
+		if user.CompanyId == t.DeliveredCompanyId && t.DeliveredUserId != nil {
+			cashierID = *t.DeliveredUserId
+		}
+
+		received := make([]string, 0, len(t.ReceivedIncomes))
+		for _, income := range t.ReceivedIncomes {
+			received = append(received, export.FormatAmount(income.ReceivedAmount, income.ReceivedCurrency))
+		}
+		delivered := make([]string, 0, len(t.DeliveredOutcomes))
+		for _, outcome := range t.DeliveredOutcomes {
+			delivered = append(delivered, export.FormatAmount(outcome.DeliveredAmount, outcome.DeliveredCurrency))
+		}
+
+		return &receipt.Receipt{
+			Title: i18n.T(lang, "receipt.title"),
+			Rows: []receipt.Row{
+				{Label: i18n.T(lang, "receipt.number"), Value: strconv.FormatInt(t.Number, 10)},
+				{Label: i18n.T(lang, "receipt.date"), Value: t.CreatedAtFormatted},
+				{Label: i18n.T(lang, "receipt.cashier"), Value: app.cashierName(r, cashierID)},
+				{Label: i18n.T(lang, "receipt.status"), Value: i18n.T(lang, fmt.Sprintf("receipt.status_%d", t.Status))},
+			},
+			Sections: []receipt.Section{
+				{Title: i18n.T(lang, "receipt.received"), Amounts: received},
+				{Title: i18n.T(lang, "receipt.delivered"), Amounts: delivered},
+			},
+			Notes: []receipt.Row{
+				{Label: i18n.T(lang, "receipt.service_fee"), Value: t.ServiceFee},
+				{Label: i18n.T(lang, "receipt.phone"), Value: t.Phone},
+				{Label: i18n.T(lang, "receipt.details"), Value: t.Details},
+			},
+		}, nil
+	})
+}
+
+// GetExchangeReceiptHandler prints a currency exchange as a PDF receipt.
+func (app *application) GetExchangeReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	app.writeReceipt(w, r, store.ENTITY_EXCHANGE, func(user *store.User, lang i18n.Lang) (*receipt.Receipt, error) {
+		items, err := app.store.Exchanges.Filter(r.Context(), user.CompanyId, types.Where("id", getIDFromContext(r)), &types.Pagination{Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return nil, types.ErrExchangeNotFound
+		}
+		e := items[0]
+
+		return &receipt.Receipt{
+			Title: i18n.T(lang, "receipt.exchange_title"),
+			Rows: []receipt.Row{
+				{Label: i18n.T(lang, "receipt.number"), Value: strconv.FormatInt(e.ID, 10)},
+				{Label: i18n.T(lang, "receipt.date"), Value: e.CreatedAtFormatted},
+				{Label: i18n.T(lang, "receipt.cashier"), Value: app.cashierName(r, e.UserId)},
+			},
+			Sections: []receipt.Section{
+				{Title: i18n.T(lang, "receipt.received"), Amounts: []string{export.FormatAmount(e.ReceivedMoney, e.ReceivedCurrency)}},
+				{Title: i18n.T(lang, "receipt.delivered"), Amounts: []string{export.FormatAmount(e.SelledMoney, e.SelledCurrency)}},
+			},
+			Notes: []receipt.Row{
+				{Label: i18n.T(lang, "receipt.details"), Value: e.Details},
+			},
+		}, nil
+	})
+}
+
+// GetDebtReceiptHandler prints a debt entry, money lent or paid back, as a
+// PDF receipt.
+func (app *application) GetDebtReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	app.writeReceipt(w, r, store.ENTITY_DEBT, func(user *store.User, lang i18n.Lang) (*receipt.Receipt, error) {
+		items, err := app.store.Debts.Filter(r.Context(), user.CompanyId, types.Where("id", getIDFromContext(r)), &types.Pagination{Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return nil, types.ErrDebtNotFound
+		}
+		d := items[0]
+
+		// Amounts are stored signed by direction; the receipt names the
+		// direction instead.
+		payments := make([]string, 0, len(d.ReceivedIncomes))
+		for _, income := range d.ReceivedIncomes {
+			payments = append(payments, export.FormatAmount(abs(income.ReceivedAmount), income.ReceivedCurrency))
+		}
+		paid := i18n.T(lang, "receipt.received")
+		if d.Type == types.TYPE_SELL {
+			paid = i18n.T(lang, "receipt.delivered")
+		}
+
+		return &receipt.Receipt{
+			Title: i18n.T(lang, "receipt.debt_title"),
+			Rows: []receipt.Row{
+				{Label: i18n.T(lang, "receipt.number"), Value: strconv.FormatInt(d.ID, 10)},
+				{Label: i18n.T(lang, "receipt.date"), Value: d.CreatedAtFormatted},
+				{Label: i18n.T(lang, "receipt.cashier"), Value: app.cashierName(r, d.UserID)},
+				{Label: i18n.T(lang, "receipt.customer"), Value: d.FullName},
+			},
+			Sections: []receipt.Section{
+				{Title: i18n.T(lang, "receipt.debt"), Amounts: []string{export.FormatAmount(abs(d.DebtedAmount), d.DebtedCurrency)}},
+				{Title: paid, Amounts: payments},
+			},
+			Notes: []receipt.Row{
+				{Label: i18n.T(lang, "receipt.phone"), Value: d.Phone},
+				{Label: i18n.T(lang, "receipt.details"), Value: d.Details},
+			},
+		}, nil
+	})
+}
+
+// writeReceipt renders the receipt build returns for ?size=58, 80 (the
+// default) or a4, headed with the user's company and carrying a QR code
+// that links to the public verification page.
+func (app *application) writeReceipt(
+	w http.ResponseWriter,
+	r *http.Request,
+	entity string,
+	build func(*store.User, i18n.Lang) (*receipt.Receipt, error),
+) {
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		size = receipt.SIZE_80
+	}
+	if !receipt.ValidSize(size) {
+		app.badRequestResponse(w, r, fmt.Errorf("size must be 58, 80 or a4"))
+		return
+	}
+
+	if app.receipts == nil {
+		app.internalServerError(w, r, types.ErrReceiptsUnavailable)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	lang := i18n.FromContext(r.Context())
+	rec, err := build(user, lang)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	company, err := app.store.Companies.GetById(r.Context(), &user.CompanyId)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	rec.Company = company.Name
+	rec.CompanyDetails = company.Details
+
+	id := getIDFromContext(r)
+	verify, err := app.receiptVerifyURL(receipt.Claims{Entity: entity, ID: id, CompanyID: user.CompanyId})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if verify != "" {
+		rec.VerifyURL = verify
+		rec.VerifyLabel = i18n.T(lang, "receipt.verify")
+	}
+
+	var pdf bytes.Buffer
+	if err := app.receipts.Render(&pdf, rec, size); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%d-%s.pdf", entity, id, size)
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="`+filename+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(pdf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(pdf.Bytes())
+}
+
+// receiptVerifyURL is the link printed as the receipt's QR code, or "" when
+// PUBLIC_URL or the signing keys are not configured.
+func (app *application) receiptVerifyURL(claims receipt.Claims) (string, error) {
+	if app.config.publicURL == "" || app.signer == nil {
+		return "", nil
+	}
+
+	token, err := app.signer.Sign(claims)
+	if err != nil {
+		return "", err
+	}
+	return app.config.publicURL + "/api/v1/receipts/verify/" + token, nil
+}
+
+// VerifyReceiptHandler is the public page behind a receipt's QR code. It
+// tells whether the receipt is genuine and what it is for, without
+// authentication and without internal ids.
+func (app *application) VerifyReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	if app.signer == nil {
+		app.internalServerError(w, r, types.ErrReceiptNotFound)
+		return
+	}
+
+	claims, err := app.signer.Verify(chi.URLParam(r, "token"))
+	if err != nil {
+		app.internalServerError(w, r, types.ErrReceiptNotFound.Wrap(err))
+		return
+	}
+
+	status, err := app.store.Receipts.GetStatus(r.Context(), claims.Entity, claims.ID, claims.CompanyID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, status); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// cashierName is the username of the user who made the entry. A receipt
+// is still printed when the user has since been deleted.
+func (app *application) cashierName(r *http.Request, userID int64) string {
+	user, err := app.store.Users.GetById(r.Context(), &userID)
+	if err != nil {
+		return ""
+	}
+	return user.Username
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+func (app *application) reconciliationOwner(w http.ResponseWriter, r *http.Request) (*store.User, bool) {
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return nil, false
+	}
+
+	if user.Role != store.ROLE_OWNER {
+		app.forbiddenResponse(w, r, fmt.Errorf("only owners can reconcile balances"))
+		return nil, false
+	}
+
+	return user, true
+}
+
+func (app *application) GetReconciliationRunsHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.ReconciliationRunSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, ok := app.reconciliationOwner(w, r)
+	if !ok {
+		return
+	}
+
+	runs, err := app.store.Reconciliation.GetRuns(r.Context(), user.CompanyId, pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writePage(w, http.StatusOK, runs, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// RunReconciliationHandler checks the company's balances now. Discrepancies
+// are only reported; each is repaired on its own by RepairDiscrepancyHandler.
+func (app *application) RunReconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.reconciliationOwner(w, r)
+	if !ok {
+		return
+	}
+
+	run := &store.ReconciliationRun{CompanyID: user.CompanyId, StartedBy: &user.ID}
+	if _, err := app.service.Reconciliation.Run(r.Context(), run); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.audit(r, store.ENTITY_RECONCILIATION, store.ACTION_CREATE, run.ID, nil, run)
+
+	if err := app.writeResponse(w, http.StatusCreated, run); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// GetDiscrepanciesHandler lists the discrepancies of ?run_id, or of the
+// latest finished run.
+func (app *application) GetDiscrepanciesHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.DiscrepancySorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, ok := app.reconciliationOwner(w, r)
+	if !ok {
+		return
+	}
+
+	var runId int64
+	if raw := r.URL.Query().Get("run_id"); raw != "" {
+		runId, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || runId <= 0 {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid run_id %q", raw))
+			return
+		}
+	}
+
+	discrepancies, err := app.store.Reconciliation.GetDiscrepancies(r.Context(), user.CompanyId, runId, pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writePage(w, http.StatusOK, discrepancies, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// RepairDiscrepancyHandler sets the balance of a discrepancy to the sum of
+// its records. The change is audited by the service.
+func (app *application) RepairDiscrepancyHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.reconciliationOwner(w, r)
+	if !ok {
+		return
+	}
+
+	discrepancy, err := app.service.Reconciliation.Repair(r.Context(), user.CompanyId, getIDFromContext(r), *app.auditEntry(r))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, discrepancy); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// runReconciliation checks the balances of every company once per
+// configured interval. Each company's run takes the interval's slot, so
+// several API instances do not check the same company twice. It never
+// repairs; that is left to an owner, one discrepancy at a time.
+func (app *application) runReconciliation() {
+	if app.config.reconcileIntervalHours <= 0 {
+		return
+	}
+
+	interval := time.Duration(app.config.reconcileIntervalHours) * time.Hour
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ctx := context.Background()
+		slot := time.Now().Truncate(interval)
+
+		companies, err := app.store.Companies.GetAll(ctx)
+		if err != nil {
+			log.Printf("reconciliation: %v", err)
+		}
+		for _, company := range companies {
+			run := &store.ReconciliationRun{CompanyID: company.ID, Slot: &slot}
+
+			started, err := app.service.Reconciliation.Run(ctx, run)
+			if err != nil {
+				log.Printf("reconciliation: company %d: %v", company.ID, err)
+			} else if started && run.Discrepancies > 0 {
+				log.Printf("reconciliation: company %d: %d discrepancies", company.ID, run.Discrepancies)
+			}
+		}
+
+		<-ticker.C
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+// GetTransactionSMSHandler lists the texts sent to the recipient of a
+// transaction and their delivery status. Either side of the transaction
+// may look.
+func (app *application) GetTransactionSMSHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	messages, err := app.store.SMS.GetByTransactionId(r.Context(), user.CompanyId, getIDFromContext(r))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if messages == nil {
+		messages = []store.SMSMessage{}
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, messages); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mubashshir3767/currencyExchange/internal/export"
+	"github.com/mubashshir3767/currencyExchange/internal/i18n"
+	"github.com/mubashshir3767/currencyExchange/internal/receipt"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// STATEMENT_LINK_TTL is how long a shared statement link can be opened.
+const STATEMENT_LINK_TTL = 7 * 24 * time.Hour
+
+const (
+	FORMAT_JSON = "json"
+	FORMAT_PDF  = "pdf"
+)
+
+// GetDebtorStatementHandler returns a debtor's statement for
+// ?from=2006-01-02&to=2006-01-02 as ?format=json (the default), pdf, csv or
+// xlsx.
+func (app *application) GetDebtorStatementHandler(w http.ResponseWriter, r *http.Request) {
+	format, err := readStatementFormat(r, FORMAT_JSON)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from, to, err := readPeriod(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.writeDebtorStatement(w, r, user.CompanyId, getIDFromContext(r), from, to, format)
+}
+
+type StatementLink struct {
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// ShareDebtorStatementHandler makes a link to the statement for the same
+// period that the debtor can open without an account, until it expires.
+func (app *application) ShareDebtorStatementHandler(w http.ResponseWriter, r *http.Request) {
+	if app.config.publicURL == "" || app.signer == nil {
+		app.internalServerError(w, r, types.ErrSharingUnavailable)
+		return
+	}
+
+	from, to, err := readPeriod(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	debtorID := getIDFromContext(r)
+	statement, err := app.store.Statements.Debtor(r.Context(), user.CompanyId, debtorID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	expires := time.Now().Add(STATEMENT_LINK_TTL)
+	token, err := app.signer.SignStatement(receipt.StatementClaims{
+		DebtorID:  debtorID,
+		CompanyID: user.CompanyId,
+		From:      from,
+		To:        to,
+		Expires:   expires,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	link := &StatementLink{
+		URL:       app.config.publicURL + "/api/v1/statements/" + token,
+		ExpiresAt: expires.In(tashkent()).Format("2006-01-02 15:04:05"),
+	}
+	app.audit(r, store.ENTITY_DEBTOR, store.ACTION_SHARE, debtorID, nil, map[string]any{
+		"from":       from.Format("2006-01-02"),
+		"to":         to.Format("2006-01-02"),
+		"expires_at": link.ExpiresAt,
+		"full_name":  statement.Debtor.FullName,
+	})
+
+	if err := app.writeResponse(w, http.StatusOK, link); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// SharedStatementHandler opens a shared statement link, as ?format=pdf (the
+// default), csv, xlsx or json. It needs no authentication; the token is the
+// permission.
+func (app *application) SharedStatementHandler(w http.ResponseWriter, r *http.Request) {
+	format, err := readStatementFormat(r, FORMAT_PDF)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if app.signer == nil {
+		app.internalServerError(w, r, types.ErrStatementNotFound)
+		return
+	}
+	claims, err := app.signer.VerifyStatement(chi.URLParam(r, "token"), time.Now())
+	if err != nil {
+		app.internalServerError(w, r, types.ErrStatementNotFound.Wrap(err))
+		return
+	}
+
+	loc := tashkent()
+	from := time.Date(claims.From.Year(), claims.From.Month(), claims.From.Day(), 0, 0, 0, 0, loc)
+	to := time.Date(claims.To.Year(), claims.To.Month(), claims.To.Day(), 0, 0, 0, 0, loc)
+
+	app.writeDebtorStatement(w, r, claims.CompanyID, claims.DebtorID, from, to, format)
+}
+
+// GetBalanceStatementHandler returns the statement of one of the company's
+// balances for ?from and ?to, in the same formats as debtor statements.
+func (app *application) GetBalanceStatementHandler(w http.ResponseWriter, r *http.Request) {
+	format, err := readStatementFormat(r, FORMAT_JSON)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	from, to, err := readPeriod(r)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	statement, err := app.service.Balances.Statement(r.Context(), user.CompanyId, getIDFromContext(r), from, to.AddDate(0, 0, 1))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	statement.From = from.Format("2006-01-02")
+	statement.To = to.Format("2006-01-02")
+
+	lang := i18n.FromContext(r.Context())
+	filename := fmt.Sprintf("balance-%d-%s-%s.%s", statement.Balance.ID, statement.From, statement.To, format)
+
+	app.writeStatement(w, r, format, filename, user.CompanyId, statement,
+		func(company *store.Company) *receipt.Statement {
+			return balanceStatementDocument(statement, company, app.cashierName(r, statement.Balance.UserId), lang)
+		},
+		func(out export.Writer) error {
+			return writeBalanceStatementTable(out, statement, lang)
+		},
+	)
+}
+
+// writeDebtorStatement writes the statement for the days from to to, both
+// included.
+func (app *application) writeDebtorStatement(w http.ResponseWriter, r *http.Request, companyID, debtorID int64, from, to time.Time, format string) {
+	statement, err := app.store.Statements.Debtor(r.Context(), companyID, debtorID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	statement.From = from.Format("2006-01-02")
+	statement.To = to.Format("2006-01-02")
+
+	lang := i18n.FromContext(r.Context())
+	filename := fmt.Sprintf("statement-%d-%s-%s.%s", debtorID, statement.From, statement.To, format)
+
+	app.writeStatement(w, r, format, filename, companyID, statement,
+		func(company *store.Company) *receipt.Statement {
+			return debtorStatementDocument(statement, company, lang)
+		},
+		func(out export.Writer) error {
+			return writeDebtorStatementTable(out, statement, lang)
+		},
+	)
+}
+
+// writeStatement sends statement as JSON, as the PDF document makes of it
+// under the company's letterhead, or as the spreadsheet table writes.
+func (app *application) writeStatement(
+	w http.ResponseWriter,
+	r *http.Request,
+	format, filename string,
+	companyID int64,
+	statement any,
+	document func(*store.Company) *receipt.Statement,
+	table func(export.Writer) error,
+) {
+	switch format {
+	case FORMAT_JSON:
+		if err := app.writeResponse(w, http.StatusOK, statement); err != nil {
+			app.internalServerError(w, r, err)
+		}
+
+	case FORMAT_PDF:
+		if app.receipts == nil {
+			app.internalServerError(w, r, types.ErrReceiptsUnavailable)
+			return
+		}
+
+		company, err := app.store.Companies.GetById(r.Context(), &companyID)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		var pdf bytes.Buffer
+		if err := app.receipts.RenderStatement(&pdf, document(company)); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `inline; filename="`+filename+`"`)
+		w.Header().Set("Content-Length", strconv.Itoa(pdf.Len()))
+		w.WriteHeader(http.StatusOK)
+		w.Write(pdf.Bytes())
+
+	default:
+		var buf bytes.Buffer
+		out, err := export.New(format, &buf)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		if err := table(out); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", export.ContentType(format))
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	}
+}
+
+// debtorStatementDocument lays the statement out with money given and
+// received in separate columns, opening and closing balances in bold.
+func debtorStatementDocument(statement *store.DebtorStatement, company *store.Company, lang i18n.Lang) *receipt.Statement {
+	doc := &receipt.Statement{
+		Company:        company.Name,
+		CompanyDetails: company.Details,
+		Title:          i18n.T(lang, "statement.title"),
+		Rows: []receipt.Row{
+			{Label: i18n.T(lang, "statement.debtor"), Value: statement.Debtor.FullName},
+			{Label: i18n.T(lang, "receipt.phone"), Value: statement.Debtor.Phone},
+			{Label: i18n.T(lang, "statement.period"), Value: statement.From + " — " + statement.To},
+		},
+		Columns: []receipt.Column{
+			{Title: i18n.T(lang, "receipt.date"), Width: 20},
+			{Title: i18n.T(lang, "receipt.number"), Width: 7},
+			{Title: i18n.T(lang, "receipt.details"), Width: 23},
+			{Title: i18n.T(lang, "statement.given"), Width: 16, Right: true},
+			{Title: i18n.T(lang, "statement.received"), Width: 16, Right: true},
+			{Title: i18n.T(lang, "statement.balance"), Width: 16, Right: true},
+		},
+	}
+
+	for _, opening := range statement.Opening {
+		doc.Lines = append(doc.Lines, receipt.Line{
+			Cells: []string{statement.From, "", i18n.T(lang, "statement.opening"), "", "", export.FormatAmount(opening.Balance, opening.Currency)},
+			Bold:  true,
+		})
+	}
+	for _, entry := range statement.Entries {
+		given, received := "", export.FormatAmount(entry.Amount, entry.Currency)
+		if entry.Amount < 0 {
+			given, received = export.FormatAmount(-entry.Amount, entry.Currency), ""
+		}
+		doc.Lines = append(doc.Lines, receipt.Line{Cells: []string{
+			entry.CreatedAtFormatted,
+			strconv.FormatInt(entry.DebtID, 10),
+			entry.Details,
+			given,
+			received,
+			export.FormatAmount(entry.Balance, entry.Currency),
+		}})
+	}
+	for _, closing := range statement.Closing {
+		doc.Lines = append(doc.Lines, receipt.Line{
+			Cells: []string{statement.To, "", i18n.T(lang, "statement.closing"), "", "", export.FormatAmount(closing.Balance, closing.Currency)},
+			Bold:  true,
+		})
+	}
+
+	return doc
+}
+
+// writeDebtorStatementTable writes the statement as a spreadsheet, one line
+// per opening balance, debt and closing balance.
+func writeDebtorStatementTable(out export.Writer, statement *store.DebtorStatement, lang i18n.Lang) error {
+	columns := []string{"created_at", "id", "type", "details", "amount", "currency", "balance"}
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = i18n.T(lang, "export."+column)
+	}
+	if err := out.Header(headers); err != nil {
+		return err
+	}
+
+	for _, opening := range statement.Opening {
+		if err := out.Row([]export.Cell{
+			export.Text(statement.From),
+			{},
+			export.Text(i18n.T(lang, "statement.opening")),
+			{},
+			{},
+			export.Text(opening.Currency),
+			export.Amount(opening.Balance, opening.Currency),
+		}); err != nil {
+			return err
+		}
+	}
+	for _, entry := range statement.Entries {
+		kind := i18n.T(lang, "statement.received")
+		if entry.Type == types.TYPE_SELL {
+			kind = i18n.T(lang, "statement.given")
+		}
+		if err := out.Row([]export.Cell{
+			export.Text(entry.CreatedAtFormatted),
+			export.Int(entry.DebtID),
+			export.Text(kind),
+			export.Text(entry.Details),
+			export.Amount(entry.Amount, entry.Currency),
+			export.Text(entry.Currency),
+			export.Amount(entry.Balance, entry.Currency),
+		}); err != nil {
+			return err
+		}
+	}
+	for _, closing := range statement.Closing {
+		if err := out.Row([]export.Cell{
+			export.Text(statement.To),
+			{},
+			export.Text(i18n.T(lang, "statement.closing")),
+			{},
+			{},
+			export.Text(closing.Currency),
+			export.Amount(closing.Balance, closing.Currency),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return out.Close()
+}
+
+// balanceStatementDocument lays the statement out with money in and out in
+// separate columns and each movement named by its source.
+func balanceStatementDocument(statement *store.BalanceStatement, company *store.Company, owner string, lang i18n.Lang) *receipt.Statement {
+	currency := statement.Balance.Currency
+	doc := &receipt.Statement{
+		Company:        company.Name,
+		CompanyDetails: company.Details,
+		Title:          i18n.T(lang, "statement.balance_title"),
+		Rows: []receipt.Row{
+			{Label: i18n.T(lang, "receipt.cashier"), Value: owner},
+			{Label: i18n.T(lang, "export.currency"), Value: currency},
+			{Label: i18n.T(lang, "statement.period"), Value: statement.From + " — " + statement.To},
+		},
+		Columns: []receipt.Column{
+			{Title: i18n.T(lang, "receipt.date"), Width: 19},
+			{Title: i18n.T(lang, "statement.source"), Width: 17},
+			{Title: i18n.T(lang, "receipt.details"), Width: 20},
+			{Title: i18n.T(lang, "statement.in"), Width: 15, Right: true},
+			{Title: i18n.T(lang, "statement.out"), Width: 15, Right: true},
+			{Title: i18n.T(lang, "statement.balance"), Width: 15, Right: true},
+		},
+	}
+
+	doc.Lines = append(doc.Lines, receipt.Line{
+		Cells: []string{statement.From, i18n.T(lang, "statement.opening"), "", "", "", export.FormatAmount(statement.Opening, currency)},
+		Bold:  true,
+	})
+	for _, entry := range statement.Entries {
+		in, out := export.FormatAmount(entry.Amount, currency), ""
+		if entry.Amount < 0 {
+			in, out = "", export.FormatAmount(-entry.Amount, currency)
+		}
+		doc.Lines = append(doc.Lines, receipt.Line{Cells: []string{
+			entry.CreatedAtFormatted,
+			statementSource(entry, lang),
+			entry.Details,
+			in,
+			out,
+			export.FormatAmount(entry.Balance, currency),
+		}})
+	}
+	doc.Lines = append(doc.Lines, receipt.Line{
+		Cells: []string{statement.To, i18n.T(lang, "statement.closing"), "", "", "", export.FormatAmount(statement.Closing, currency)},
+		Bold:  true,
+	})
+
+	return doc
+}
+
+// writeBalanceStatementTable writes the statement as a spreadsheet with the
+// ids of each movement's source.
+func writeBalanceStatementTable(out export.Writer, statement *store.BalanceStatement, lang i18n.Lang) error {
+	columns := []string{"created_at", "id", "type", "transaction_id", "debt_id", "exchange_id", "details", "amount", "currency", "balance"}
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = i18n.T(lang, "export."+column)
+	}
+	if err := out.Header(headers); err != nil {
+		return err
+	}
+
+	currency := statement.Balance.Currency
+	if err := out.Row([]export.Cell{
+		export.Text(statement.From), {}, export.Text(i18n.T(lang, "statement.opening")), {}, {}, {}, {}, {},
+		export.Text(currency),
+		export.Amount(statement.Opening, currency),
+	}); err != nil {
+		return err
+	}
+	for _, entry := range statement.Entries {
+		ids := map[string]export.Cell{entry.Source: export.OptionalInt(entry.SourceID)}
+		if err := out.Row([]export.Cell{
+			export.Text(entry.CreatedAtFormatted),
+			export.Int(entry.RecordID),
+			export.Text(i18n.T(lang, "statement.source_"+entry.Source)),
+			ids[store.ENTITY_TRANSACTION],
+			ids[store.ENTITY_DEBT],
+			ids[store.ENTITY_EXCHANGE],
+			export.Text(entry.Details),
+			export.Amount(entry.Amount, currency),
+			export.Text(currency),
+			export.Amount(entry.Balance, currency),
+		}); err != nil {
+			return err
+		}
+	}
+	if err := out.Row([]export.Cell{
+		export.Text(statement.To), {}, export.Text(i18n.T(lang, "statement.closing")), {}, {}, {}, {}, {},
+		export.Text(currency),
+		export.Amount(statement.Closing, currency),
+	}); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// statementSource names where a movement came from, such as "Transaction
+// #1024". Transactions go by the number customers know them by.
+func statementSource(entry store.BalanceStatementEntry, lang i18n.Lang) string {
+	name := i18n.T(lang, "statement.source_"+entry.Source)
+	switch {
+	case entry.Number != nil:
+		return fmt.Sprintf("%s #%d", name, *entry.Number)
+	case entry.SourceID != nil:
+		return fmt.Sprintf("%s #%d", name, *entry.SourceID)
+	}
+	return name
+}
+
+func readStatementFormat(r *http.Request, fallback string) (string, error) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = fallback
+	}
+	switch format {
+	case FORMAT_JSON, FORMAT_PDF, export.FORMAT_CSV, export.FORMAT_XLSX:
+		return format, nil
+	}
+	return "", fmt.Errorf("format must be json, pdf, csv or xlsx")
+}
+
+// readPeriod reads ?from and ?to as days in Tashkent. to defaults to today
+// and from to the first day of to's month.
+func readPeriod(r *http.Request) (time.Time, time.Time, error) {
+	loc := tashkent()
+	now := time.Now().In(loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		t, err := time.ParseInLocation("2006-01-02", raw, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, types.ErrInvalidRequest.Wrap(fmt.Errorf("to must be a date like 2006-01-02"))
+		}
+		to = t
+	}
+
+	from := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, loc)
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		t, err := time.ParseInLocation("2006-01-02", raw, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, types.ErrInvalidRequest.Wrap(fmt.Errorf("from must be a date like 2006-01-02"))
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, types.ErrStatementRange
+	}
+	return from, to, nil
+}
+
+func tashkent() *time.Location {
+	loc, err := time.LoadLocation("Asia/Tashkent")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/telegram"
+)
+
+// CreateTelegramLinkHandler issues a code that links the owner's Telegram
+// chat to their account. Earlier codes stop working.
+func (app *application) CreateTelegramLinkHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if user.Role != store.ROLE_OWNER {
+		app.forbiddenResponse(w, r, fmt.Errorf("only owners can link the telegram bot"))
+		return
+	}
+
+	code, err := telegram.NewLinkCode()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	link, err := app.store.Telegram.CreateLinkCode(r.Context(), user.ID, code, telegram.LINK_CODE_TTL)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if app.config.telegramBot != "" {
+		deepLink := "https://t.me/" + url.PathEscape(app.config.telegramBot) + "?start=" + code
+		link.URL = &deepLink
+	}
+
+	if err := app.writeResponse(w, http.StatusCreated, link); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// DeleteTelegramLinkHandler stops the bot from messaging the user.
+func (app *application) DeleteTelegramLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserKey).(int64)
+
+	if err := app.store.Telegram.UnlinkUser(r.Context(), userID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, "DELETED"); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"database/sql"
 	"fmt"
 	"net/http"
 
@@ -48,6 +47,7 @@ func (app *application) CreateTransactionHandler(w http.ResponseWriter, r *http.
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_TRANSACTION, store.ACTION_CREATE, transaction.ID, nil, transaction)
 
 	if err := app.writeResponse(w, http.StatusOK, ""); err != nil {
 		app.internalServerError(w, r, err)
@@ -77,10 +77,21 @@ func (app *application) UpdateTransactionHandler(w http.ResponseWriter, r *http.
 		Status:             1,
 	}
 
+	if app.requireApproval(w, r, store.ENTITY_TRANSACTION, store.ACTION_UPDATE, transaction.ID, transaction) {
+		return
+	}
+
+	before, err := app.store.Transactions.GetById(r.Context(), transaction.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.service.Transactions.Update(r.Context(), transaction); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_TRANSACTION, store.ACTION_UPDATE, transaction.ID, before, transaction)
 
 	if err := app.writeResponse(w, http.StatusOK, transaction); err != nil {
 		app.internalServerError(w, r, err)
@@ -95,14 +106,13 @@ func (app *application) CompleteTransactionHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
+	before, _ := app.store.Transactions.GetById(r.Context(), payload.TransactionID)
+
 	if err := app.service.Transactions.CompleteTransaction(r.Context(), payload); err != nil {
-		if err == sql.ErrNoRows {
-			app.badRequestResponse(w, r, fmt.Errorf("BUYURTMA ALLAQACHON YAKUNLANGAN"))
-		} else {
-			app.internalServerError(w, r, err)
-		}
+		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_TRANSACTION, store.ACTION_COMPLETE, payload.TransactionID, before, payload)
 
 	if err := app.writeResponse(w, http.StatusOK, "SUCCESS"); err != nil {
 		app.internalServerError(w, r, err)
@@ -111,7 +121,11 @@ func (app *application) CompleteTransactionHandler(w http.ResponseWriter, r *htt
 }
 
 func (app *application) GetTransactionsByFieldHandler(w http.ResponseWriter, r *http.Request) {
-	app.LoadPaginationInfo(r, r.Context())
+	pagination, err := app.readPagination(r, store.TransactionSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
 	src := r.URL.Query().Get("search")
 	var search *string
 	if src != "" && src != "null" {
@@ -124,27 +138,62 @@ func (app *application) GetTransactionsByFieldHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	transactions, err := app.service.Transactions.GetByField(r.Context(), search, payload.FieldName, payload.FieldValue, app.Pagination)
+	transactions, err := app.service.Transactions.GetByField(r.Context(), search, payload.FieldName, payload.FieldValue, pagination)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.writeResponse(w, http.StatusOK, transactions); err != nil {
+	if err := app.writePage(w, http.StatusOK, transactions, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) SearchTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.TransactionSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	filter, err := readFilter(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	transactions, err := app.service.Transactions.Filter(r.Context(), user.CompanyId, filter, pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writePage(w, http.StatusOK, transactions, pagination); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
 
 func (app *application) GetTransactionsCompanyIdHandler(w http.ResponseWriter, r *http.Request) {
-	app.LoadPaginationInfo(r, r.Context())
-	transactions, err := app.service.Transactions.GetByCompanyId(r.Context(), getIDFromContext(r), app.Pagination)
+	pagination, err := app.readPagination(r, store.TransactionSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	transactions, err := app.service.Transactions.GetByCompanyId(r.Context(), getIDFromContext(r), pagination)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.writeResponse(w, http.StatusOK, transactions); err != nil {
+	if err := app.writePage(w, http.StatusOK, transactions, pagination); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -164,20 +213,24 @@ func (app *application) GetInfosByCompanyIdHandler(w http.ResponseWriter, r *htt
 }
 
 func (app *application) GetTransactionsByFieldAndDateHandler(w http.ResponseWriter, r *http.Request) {
-	app.LoadPaginationInfo(r, r.Context())
+	pagination, err := app.readPagination(r, store.TransactionSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
 	var payload FieldRequestPayload
 	if err := readJSON(w, r, &payload); err != nil {
 		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	transactions, err := app.store.Transactions.GetByFieldAndDate(r.Context(), payload.FieldName, *payload.From, *payload.To, payload.FieldValue, app.Pagination)
+	transactions, err := app.store.Transactions.GetByFieldAndDate(r.Context(), payload.FieldName, *payload.From, *payload.To, payload.FieldValue, pagination)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.writeResponse(w, http.StatusOK, transactions); err != nil {
+	if err := app.writePage(w, http.StatusOK, transactions, pagination); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -196,6 +249,7 @@ func (app *application) ArchiveTransactionsHandler(w http.ResponseWriter, r *htt
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_TRANSACTION, store.ACTION_ARCHIVE, 0, nil, map[string]any{"company_id": user.CompanyId})
 
 	if err := app.writeResponse(w, http.StatusOK, "ARCHIVED"); err != nil {
 		app.internalServerError(w, r, err)
@@ -204,14 +258,18 @@ func (app *application) ArchiveTransactionsHandler(w http.ResponseWriter, r *htt
 }
 
 func (app *application) ArchivedTransactionsHandler(w http.ResponseWriter, r *http.Request) {
-	app.LoadPaginationInfo(r, r.Context())
-	transactions, err := app.service.Transactions.Archived(r.Context(), app.Pagination)
+	pagination, err := app.readPagination(r, store.TransactionSorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	transactions, err := app.service.Transactions.Archived(r.Context(), pagination)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.writeResponse(w, http.StatusOK, transactions); err != nil {
+	if err := app.writePage(w, http.StatusOK, transactions, pagination); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -219,10 +277,21 @@ func (app *application) ArchivedTransactionsHandler(w http.ResponseWriter, r *ht
 
 func (app *application) DeleteTransactionHandler(w http.ResponseWriter, r *http.Request) {
 	id := getIDFromContext(r)
+	if app.requireApproval(w, r, store.ENTITY_TRANSACTION, store.ACTION_DELETE, id, nil) {
+		return
+	}
+
+	before, err := app.store.Transactions.GetById(r.Context(), id)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.service.Transactions.Delete(r.Context(), &id); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_TRANSACTION, store.ACTION_DELETE, id, before, nil)
 
 	if err := app.writeResponse(w, http.StatusOK, "DELETED"); err != nil {
 		app.internalServerError(w, r, err)
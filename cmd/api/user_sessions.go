@@ -38,10 +38,11 @@ func (app *application) UpsertUserSessionHandler(w http.ResponseWriter, r *http.
 		UserAgent:    payload.UserAgent,
 	}
 
-	if err := app.store.UserSessions.Upsert(r.Context(), row); err != nil {
+	if err := app.service.UserSessions.Upsert(r.Context(), row); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_USER_SESSION, store.ACTION_CREATE, row.ID, nil, row)
 
 	if err := app.writeResponse(w, http.StatusOK, row); err != nil {
 		app.internalServerError(w, r, err)
@@ -83,6 +84,12 @@ func (app *application) UpdateUserSessionHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	before, err := app.store.UserSessions.GetByIDForUser(r.Context(), id, userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.store.UserSessions.UpdateFCM(r.Context(), id, userID, payload.FCMToken, payload.RefreshToken); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -93,6 +100,7 @@ func (app *application) UpdateUserSessionHandler(w http.ResponseWriter, r *http.
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_USER_SESSION, store.ACTION_UPDATE, id, before, updated)
 
 	if err := app.writeResponse(w, http.StatusOK, updated); err != nil {
 		app.internalServerError(w, r, err)
@@ -104,10 +112,17 @@ func (app *application) DeleteUserSessionHandler(w http.ResponseWriter, r *http.
 	userID := r.Context().Value(UserKey).(int64)
 	id := getIDFromContext(r)
 
+	before, err := app.store.UserSessions.GetByIDForUser(r.Context(), id, userID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.store.UserSessions.Delete(r.Context(), id, userID); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_USER_SESSION, store.ACTION_DELETE, id, before, nil)
 
 	if err := app.writeResponse(w, http.StatusOK, id); err != nil {
 		app.internalServerError(w, r, err)
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/mubashshir3767/currencyExchange/internal/env"
@@ -11,10 +12,12 @@ import (
 type UserPayload struct {
 	Username  string  `json:"username"`
 	Phone     string  `json:"phone"`
-	Role      int64   `json:"role"`
+	Role      int64   `json:"role" validate:"omitempty,oneof=1 2"`
 	Password  string  `json:"password"`
 	CompanyId int64   `json:"company_id"`
 	Avatar    *string `json:"avatar"`
+	Language  *string `json:"language" validate:"omitempty,oneof=uz uz-Cyrl ru en"`
+	Email     *string `json:"email" validate:"omitempty,email,max=255"`
 }
 
 type LoginUserPayload struct {
@@ -22,6 +25,8 @@ type LoginUserPayload struct {
 	Password string `json:"password"`
 }
 
+// CreateUserHandler registers a user. Any role in the payload is ignored;
+// see UserService.Register.
 func (app *application) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	var payload UserPayload
 	if err := readJSON(w, r, &payload); err != nil {
@@ -37,12 +42,13 @@ func (app *application) CreateUserHandler(w http.ResponseWriter, r *http.Request
 	user := &store.User{
 		Username:  payload.Username,
 		Phone:     payload.Phone,
-		Role:      payload.Role,
 		Password:  payload.Password,
 		CompanyId: payload.CompanyId,
+		Language:  payload.Language,
+		Email:     payload.Email,
 	}
 
-	if err := app.store.Users.Create(r.Context(), user); err != nil {
+	if err := app.service.Users.Register(r.Context(), user); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
@@ -70,6 +76,7 @@ func (app *application) CreateUserHandler(w http.ResponseWriter, r *http.Request
 		app.badRequestResponse(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_USER, store.ACTION_CREATE, user.ID, nil, auditedUser(user))
 
 	if err := app.writeResponse(w, http.StatusOK, user); err != nil {
 		app.internalServerError(w, r, err)
@@ -127,6 +134,8 @@ func (app *application) GetAllUserHandler(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// UpdateUserHandler edits a user. A role of 0 keeps the current one; only
+// an owner of the user's company may change it.
 func (app *application) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
 	id := getIDFromContext(r)
 	var payload UserPayload
@@ -140,19 +149,42 @@ func (app *application) UpdateUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	before, err := app.store.Users.GetById(r.Context(), &id)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	role := before.Role
+	if payload.Role != 0 && payload.Role != before.Role {
+		actor, err := app.GetUser(r.Context().Value(UserKey).(int64))
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		if actor.Role != store.ROLE_OWNER || actor.CompanyId != before.CompanyId {
+			app.forbiddenResponse(w, r, fmt.Errorf("only owners can change roles"))
+			return
+		}
+		role = payload.Role
+	}
+
 	user := &store.User{
 		ID:        id,
 		Username:  payload.Username,
-		Role:      payload.Role,
+		Role:      role,
 		Password:  payload.Password,
 		Avatar:    payload.Avatar,
 		CompanyId: payload.CompanyId,
+		Language:  payload.Language,
+		Email:     payload.Email,
 	}
 
 	if err := app.store.Users.Update(r.Context(), user); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_USER, store.ACTION_UPDATE, id, auditedUser(before), auditedUser(user))
 
 	if err := app.writeResponse(w, http.StatusOK, user); err != nil {
 		app.internalServerError(w, r, err)
@@ -163,13 +195,28 @@ func (app *application) UpdateUserHandler(w http.ResponseWriter, r *http.Request
 func (app *application) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	id := getIDFromContext(r)
 
+	before, err := app.store.Users.GetById(r.Context(), &id)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
 	if err := app.store.Users.Delete(r.Context(), &id); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	app.audit(r, store.ENTITY_USER, store.ACTION_DELETE, id, auditedUser(before), nil)
 
 	if err := app.writeResponse(w, http.StatusOK, "DELETED"); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
+
+// auditedUser is a copy of user without the password, which must never
+// reach the audit log.
+func auditedUser(user *store.User) *store.User {
+	audited := *user
+	audited.Password = ""
+	return &audited
+}
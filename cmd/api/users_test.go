@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+func (fakeUsers) Update(context.Context, *store.User) error { return nil }
+func (fakeUsers) Delete(context.Context, *int64) error      { return nil }
+
+func TestUserAuditHasNoPassword(t *testing.T) {
+	app := newTestApp()
+	users := app.store.Users.(fakeUsers)
+	users.users[0].Password = "stored-secret"
+
+	var entries []store.AuditLog
+	app.store.AuditLogs = fakeAuditLogs{entries: &entries}
+	mux := app.mount()
+
+	token, err := JWTCreate([]byte("secret"), 1, "userID")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method string
+		body   string
+	}{
+		{http.MethodPut, `{"username":"owner","password":"new-secret","company_id":1}`},
+		{http.MethodDelete, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/v1/user/1", strings.NewReader(tt.body))
+		if tt.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", tt.method, rec.Code, rec.Body.String())
+		}
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("audited %d entries, want 2", len(entries))
+	}
+	for _, entry := range entries {
+		for _, payload := range [][]byte{entry.Before, entry.After} {
+			if strings.Contains(string(payload), "secret") {
+				t.Errorf("%s audit has the password: %s", entry.Action, payload)
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+	"github.com/mubashshir3767/currencyExchange/internal/webhooks"
+)
+
+type CreateWebhookPayload struct {
+	URL        string   `json:"url" validate:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,oneof=transaction.created transaction.completed"`
+}
+
+// webhookOwner loads the current user and rejects everyone but owners, since
+// webhooks send company data to third parties.
+func (app *application) webhookOwner(w http.ResponseWriter, r *http.Request) (*store.User, bool) {
+	user, err := app.GetUser(r.Context().Value(UserKey).(int64))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return nil, false
+	}
+
+	if user.Role != store.ROLE_OWNER {
+		app.forbiddenResponse(w, r, fmt.Errorf("only owners can manage webhooks"))
+		return nil, false
+	}
+
+	return user, true
+}
+
+func (app *application) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateWebhookPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, ok := app.webhookOwner(w, r)
+	if !ok {
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	endpoint := &store.WebhookEndpoint{
+		CompanyID:  user.CompanyId,
+		URL:        payload.URL,
+		Secret:     secret,
+		EventTypes: payload.EventTypes,
+	}
+
+	if err := app.store.Webhooks.Create(r.Context(), endpoint); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	audited := *endpoint
+	audited.Secret = ""
+	app.audit(r, store.ENTITY_WEBHOOK, store.ACTION_CREATE, endpoint.ID, nil, audited)
+
+	if err := app.writeResponse(w, http.StatusCreated, endpoint); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.webhookOwner(w, r)
+	if !ok {
+		return
+	}
+
+	endpoints, err := app.store.Webhooks.GetByCompanyId(r.Context(), user.CompanyId)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeResponse(w, http.StatusOK, endpoints); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.webhookOwner(w, r)
+	if !ok {
+		return
+	}
+
+	id := getIDFromContext(r)
+	if err := app.store.Webhooks.Delete(r.Context(), user.CompanyId, id); err != nil {
+		if err == sql.ErrNoRows {
+			err = types.ErrWebhookNotFound
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.audit(r, store.ENTITY_WEBHOOK, store.ACTION_DELETE, id, nil, nil)
+
+	if err := app.writeResponse(w, http.StatusOK, "DELETED"); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// GetWebhookDeliveriesHandler lists deliveries of the company's endpoints.
+// ?status=3 is the dead-letter list.
+func (app *application) GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	pagination, err := app.readPagination(r, store.WebhookDeliverySorts)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, ok := app.webhookOwner(w, r)
+	if !ok {
+		return
+	}
+
+	var status *int64
+	if raw := r.URL.Query().Get("status"); raw != "" {
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("invalid status %q", raw))
+			return
+		}
+		status = &value
+	}
+
+	deliveries, err := app.store.Webhooks.GetDeliveries(r.Context(), user.CompanyId, status, pagination)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writePage(w, http.StatusOK, deliveries, pagination); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.webhookOwner(w, r)
+	if !ok {
+		return
+	}
+
+	id := getIDFromContext(r)
+	if err := app.store.Webhooks.Redeliver(r.Context(), user.CompanyId, id); err != nil {
+		if err == sql.ErrNoRows {
+			err = types.ErrWebhookDeliveryNotFound
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.writeResponse(w, http.StatusAccepted, "delivery queued"); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// runWebhooks sends outbox events to partner endpoints until the process
+// exits.
+func (app *application) runWebhooks() {
+	webhooks.NewDispatcher(app.store.Webhooks).Run(context.Background())
+}
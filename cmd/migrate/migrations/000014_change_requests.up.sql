@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS change_requests (
+    id bigserial PRIMARY KEY,
+    company_id bigint REFERENCES companies(id) ON DELETE SET NULL,
+    entity_type varchar(64) NOT NULL,
+    entity_id bigint NOT NULL,
+    action varchar(32) NOT NULL,
+    before jsonb DEFAULT NULL,
+    proposed jsonb DEFAULT NULL,
+    status bigint NOT NULL DEFAULT 1,
+    requested_by bigint REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_by bigint DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+    review_note varchar(255) DEFAULT NULL,
+    reviewed_at timestamp(0) with time zone DEFAULT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_change_requests_company_status ON change_requests (company_id, status);
+CREATE INDEX IF NOT EXISTS idx_change_requests_entity ON change_requests (entity_type, entity_id);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id bigserial PRIMARY KEY,
+    actor_id bigint DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+    company_id bigint DEFAULT NULL REFERENCES companies(id) ON DELETE SET NULL,
+    device_id varchar(255) DEFAULT NULL,
+    ip varchar(64),
+    method varchar(16),
+    route varchar(255),
+    entity_type varchar(64) NOT NULL,
+    entity_id bigint DEFAULT NULL,
+    action varchar(32) NOT NULL,
+    before jsonb DEFAULT NULL,
+    after jsonb DEFAULT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs (entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
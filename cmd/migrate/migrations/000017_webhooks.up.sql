@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id bigserial PRIMARY KEY,
+    company_id bigint NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    url varchar(2048) NOT NULL,
+    secret varchar(128) NOT NULL,
+    event_types text[] NOT NULL,
+    is_active boolean NOT NULL DEFAULT true,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_company_id ON webhook_endpoints (company_id);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id bigserial PRIMARY KEY,
+    company_id bigint NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    event_type varchar(64) NOT NULL,
+    payload jsonb NOT NULL,
+    processed_at timestamp(0) with time zone DEFAULT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unprocessed ON outbox_events (id) WHERE processed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id bigserial PRIMARY KEY,
+    endpoint_id bigint NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    outbox_event_id bigint NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+    status bigint NOT NULL DEFAULT 1,
+    attempts int NOT NULL DEFAULT 0,
+    next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    response_status int DEFAULT NULL,
+    last_error text DEFAULT NULL,
+    delivered_at timestamp(0) with time zone DEFAULT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    UNIQUE (endpoint_id, outbox_event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 1;
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint_status ON webhook_deliveries (endpoint_id, status);
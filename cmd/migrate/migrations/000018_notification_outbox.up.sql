@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    company_id bigint NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    kind varchar(64) NOT NULL,
+    transaction_id bigint DEFAULT NULL,
+    payload jsonb NOT NULL DEFAULT '{}',
+    status bigint NOT NULL DEFAULT 1,
+    attempts int NOT NULL DEFAULT 0,
+    next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    last_error text DEFAULT NULL,
+    sent_at timestamp(0) with time zone DEFAULT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_due ON notification_outbox (next_attempt_at) WHERE status = 1;
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_company_id ON notification_outbox (company_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_transaction_id ON notification_outbox (transaction_id);
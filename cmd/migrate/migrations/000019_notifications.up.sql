@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    company_id bigint NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    outbox_id bigint DEFAULT NULL UNIQUE REFERENCES notification_outbox(id) ON DELETE SET NULL,
+    kind varchar(64) NOT NULL,
+    transaction_id bigint DEFAULT NULL,
+    data jsonb NOT NULL DEFAULT '{}',
+    read_at timestamp(0) with time zone DEFAULT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notification_preferences;
+ALTER TABLE users DROP COLUMN IF EXISTS telegram_chat_id;
+ALTER TABLE users DROP COLUMN IF EXISTS email;
@@ -0,0 +1,10 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email varchar(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS telegram_chat_id bigint;
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind varchar(64) NOT NULL,
+    channels text[] NOT NULL,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, kind)
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS sms_messages (
+    id bigserial PRIMARY KEY,
+    company_id bigint NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    transaction_id bigint DEFAULT NULL REFERENCES transactions(id) ON DELETE SET NULL,
+    phone varchar(32) NOT NULL,
+    kind varchar(64) NOT NULL,
+    body text NOT NULL,
+    pickup_code varchar(16) DEFAULT NULL,
+    status bigint NOT NULL DEFAULT 1,
+    attempts int NOT NULL DEFAULT 0,
+    next_attempt_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    provider_message_id varchar(255) DEFAULT NULL,
+    last_error text DEFAULT NULL,
+    sent_at timestamp(0) with time zone DEFAULT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sms_messages_due ON sms_messages (next_attempt_at) WHERE status = 1;
+CREATE INDEX IF NOT EXISTS idx_sms_messages_transaction_id ON sms_messages (transaction_id);
+CREATE INDEX IF NOT EXISTS idx_sms_messages_phone ON sms_messages (phone, created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_telegram_chat_id;
+DROP TABLE IF EXISTS telegram_digests;
+DROP TABLE IF EXISTS telegram_link_codes;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS telegram_link_codes (
+    code varchar(16) PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at timestamp(0) with time zone NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_telegram_link_codes_user_id ON telegram_link_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS telegram_digests (
+    user_id bigint NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day date NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_telegram_chat_id ON users (telegram_chat_id) WHERE telegram_chat_id IS NOT NULL;
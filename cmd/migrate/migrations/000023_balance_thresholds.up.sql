@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS balance_thresholds (
+    id bigserial PRIMARY KEY,
+    company_id bigint NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    user_id bigint DEFAULT NULL REFERENCES users(id) ON DELETE CASCADE,
+    currency varchar(255) NOT NULL,
+    min_balance bigint DEFAULT NULL,
+    max_balance bigint DEFAULT NULL,
+    state smallint NOT NULL DEFAULT 0,
+    alerted_at timestamp(0) with time zone DEFAULT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+-- A NULL user_id is the company-wide threshold of the currency.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balance_thresholds_scope ON balance_thresholds (company_id, COALESCE(user_id, 0), currency);
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id bigserial PRIMARY KEY,
+    company_id bigint NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    -- Scheduled runs carry the start of their interval, so that only one
+    -- instance runs each; runs started by hand have none.
+    slot timestamp(0) with time zone DEFAULT NULL,
+    started_by bigint DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+    status smallint NOT NULL DEFAULT 1,
+    balances_checked integer NOT NULL DEFAULT 0,
+    discrepancies integer NOT NULL DEFAULT 0,
+    error text DEFAULT NULL,
+    finished_at timestamp(0) with time zone DEFAULT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reconciliation_runs_slot ON reconciliation_runs (company_id, slot);
+CREATE INDEX IF NOT EXISTS idx_reconciliation_runs_company_id ON reconciliation_runs (company_id, created_at);
+
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    id bigserial PRIMARY KEY,
+    run_id bigint NOT NULL REFERENCES reconciliation_runs(id) ON DELETE CASCADE,
+    company_id bigint NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    balance_id bigint NOT NULL REFERENCES balances(id) ON DELETE CASCADE,
+    user_id bigint DEFAULT NULL,
+    currency varchar(255) NOT NULL,
+    stored_balance bigint NOT NULL,
+    computed_balance bigint NOT NULL,
+    records jsonb NOT NULL DEFAULT '[]'::jsonb,
+    repaired_at timestamp(0) with time zone DEFAULT NULL,
+    repaired_by bigint DEFAULT NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_discrepancies_run_id ON reconciliation_discrepancies (run_id);
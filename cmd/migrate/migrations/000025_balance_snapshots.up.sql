@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    id bigserial PRIMARY KEY,
+    -- The day in Asia/Tashkent whose end the balance is taken at.
+    day date NOT NULL,
+    -- Kept when the balance is deleted, so company history stays whole.
+    balance_id bigint DEFAULT NULL REFERENCES balances(id) ON DELETE SET NULL,
+    company_id bigint NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    user_id bigint DEFAULT NULL,
+    currency varchar(255) NOT NULL,
+    balance bigint NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balance_snapshots_balance_day ON balance_snapshots (balance_id, day);
+CREATE INDEX IF NOT EXISTS idx_balance_snapshots_company_day ON balance_snapshots (company_id, currency, day);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS company_amount_totals;
+DROP TABLE IF EXISTS company_daily_amounts;
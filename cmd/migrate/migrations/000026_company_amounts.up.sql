@@ -0,0 +1,52 @@
+-- What each company took in (olingan) and gave out (berilgan) per currency,
+-- per day in Asia/Tashkent and in total. Kept up to date by the transaction
+-- write paths; rebuilt from transactions here and by -rebuild-company-amounts.
+CREATE TABLE IF NOT EXISTS company_daily_amounts (
+    company_id bigint NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    currency varchar(255) NOT NULL,
+    day date NOT NULL,
+    olingan_amount bigint NOT NULL DEFAULT 0,
+    berilgan_amount bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (company_id, currency, day)
+);
+
+CREATE TABLE IF NOT EXISTS company_amount_totals (
+    company_id bigint NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    currency varchar(255) NOT NULL,
+    olingan_amount bigint NOT NULL DEFAULT 0,
+    berilgan_amount bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (company_id, currency)
+);
+
+INSERT INTO company_daily_amounts (company_id, currency, day, olingan_amount, berilgan_amount)
+SELECT company_id, currency, day, SUM(olingan), SUM(berilgan)
+FROM (
+    SELECT
+        t.delivered_company_id AS company_id,
+        o->>'delivered_currency' AS currency,
+        (t.created_at AT TIME ZONE 'Asia/Tashkent')::date AS day,
+        CASE WHEN t.type = 1 THEN (o->>'delivered_amount')::bigint ELSE 0 END AS olingan,
+        CASE WHEN t.type = 2 THEN (o->>'delivered_amount')::bigint ELSE 0 END AS berilgan
+    FROM transactions t
+    CROSS JOIN jsonb_array_elements(CASE WHEN jsonb_typeof(t.delivered_outcomes) = 'array' THEN t.delivered_outcomes ELSE '[]'::jsonb END) o
+
+    UNION ALL
+
+    SELECT
+        t.received_company_id,
+        i->>'received_currency',
+        (t.created_at AT TIME ZONE 'Asia/Tashkent')::date,
+        CASE WHEN t.type = 2 THEN (i->>'received_amount')::bigint ELSE 0 END,
+        CASE WHEN t.type = 1 THEN (i->>'received_amount')::bigint ELSE 0 END
+    FROM transactions t
+    CROSS JOIN jsonb_array_elements(CASE WHEN jsonb_typeof(t.received_incomes) = 'array' THEN t.received_incomes ELSE '[]'::jsonb END) i
+) a
+WHERE company_id IS NOT NULL AND currency IS NOT NULL
+GROUP BY company_id, currency, day
+ON CONFLICT DO NOTHING;
+
+INSERT INTO company_amount_totals (company_id, currency, olingan_amount, berilgan_amount)
+SELECT company_id, currency, SUM(olingan_amount), SUM(berilgan_amount)
+FROM company_daily_amounts
+GROUP BY company_id, currency
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,22 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS received_incomes jsonb DEFAULT '[]'::jsonb;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS delivered_outcomes jsonb DEFAULT '[]'::jsonb;
+ALTER TABLE debts ADD COLUMN IF NOT EXISTS received_incomes jsonb DEFAULT '[]'::jsonb;
+
+UPDATE transactions t SET
+    received_incomes = COALESCE((
+        SELECT jsonb_agg(jsonb_build_object('received_amount', l.amount, 'received_currency', l.currency) ORDER BY l.position)
+        FROM transaction_lines l WHERE l.transaction_id = t.id AND l.direction = 'in'
+    ), '[]'::jsonb),
+    delivered_outcomes = COALESCE((
+        SELECT jsonb_agg(jsonb_build_object('delivered_amount', l.amount, 'delivered_currency', l.currency) ORDER BY l.position)
+        FROM transaction_lines l WHERE l.transaction_id = t.id AND l.direction = 'out'
+    ), '[]'::jsonb);
+
+UPDATE debts d SET
+    received_incomes = COALESCE((
+        SELECT jsonb_agg(jsonb_build_object('received_amount', l.amount, 'received_currency', l.currency) ORDER BY l.position)
+        FROM debt_lines l WHERE l.debt_id = d.id AND l.direction = 'in'
+    ), '[]'::jsonb);
+
+DROP TABLE IF EXISTS debt_lines;
+DROP TABLE IF EXISTS transaction_lines;
@@ -0,0 +1,50 @@
+-- The amounts of transactions and debts, one row per currency, replacing the
+-- received_incomes and delivered_outcomes JSONB arrays. Direction is 'in'
+-- for received incomes and 'out' for delivered outcomes; position keeps the
+-- order they were entered in.
+CREATE TABLE IF NOT EXISTS transaction_lines (
+    id bigserial PRIMARY KEY,
+    transaction_id bigint NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    direction varchar(3) NOT NULL CHECK (direction IN ('in', 'out')),
+    position int NOT NULL,
+    currency varchar(255) NOT NULL,
+    amount bigint NOT NULL,
+    UNIQUE (transaction_id, direction, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_lines_currency ON transaction_lines (currency, direction);
+
+-- Debt amounts keep the sign they were stored with.
+CREATE TABLE IF NOT EXISTS debt_lines (
+    id bigserial PRIMARY KEY,
+    debt_id bigint NOT NULL REFERENCES debts(id) ON DELETE CASCADE,
+    direction varchar(3) NOT NULL CHECK (direction IN ('in', 'out')),
+    position int NOT NULL,
+    currency varchar(255) NOT NULL,
+    amount bigint NOT NULL,
+    UNIQUE (debt_id, direction, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_debt_lines_currency ON debt_lines (currency, direction);
+
+INSERT INTO transaction_lines (transaction_id, direction, position, currency, amount)
+SELECT t.id, 'in', e.position, COALESCE(e.line->>'received_currency', ''), COALESCE((e.line->>'received_amount')::bigint, 0)
+FROM transactions t
+CROSS JOIN jsonb_array_elements(CASE WHEN jsonb_typeof(t.received_incomes) = 'array' THEN t.received_incomes ELSE '[]'::jsonb END)
+    WITH ORDINALITY AS e(line, position);
+
+INSERT INTO transaction_lines (transaction_id, direction, position, currency, amount)
+SELECT t.id, 'out', e.position, COALESCE(e.line->>'delivered_currency', ''), COALESCE((e.line->>'delivered_amount')::bigint, 0)
+FROM transactions t
+CROSS JOIN jsonb_array_elements(CASE WHEN jsonb_typeof(t.delivered_outcomes) = 'array' THEN t.delivered_outcomes ELSE '[]'::jsonb END)
+    WITH ORDINALITY AS e(line, position);
+
+INSERT INTO debt_lines (debt_id, direction, position, currency, amount)
+SELECT d.id, 'in', e.position, COALESCE(e.line->>'received_currency', ''), COALESCE((e.line->>'received_amount')::bigint, 0)
+FROM debts d
+CROSS JOIN jsonb_array_elements(CASE WHEN jsonb_typeof(d.received_incomes) = 'array' THEN d.received_incomes ELSE '[]'::jsonb END)
+    WITH ORDINALITY AS e(line, position);
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS received_incomes;
+ALTER TABLE transactions DROP COLUMN IF EXISTS delivered_outcomes;
+ALTER TABLE debts DROP COLUMN IF EXISTS received_incomes;
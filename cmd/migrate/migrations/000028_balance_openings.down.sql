@@ -0,0 +1,3 @@
+-- The opening records are kept: they cannot be told apart from the ones
+-- written since, and the balances are their sum.
+SELECT 1;
@@ -0,0 +1,12 @@
+-- Balances used to be opened by hand without a balance record, so
+-- reconciliation reported every such amount as a discrepancy. Opening and
+-- editing a balance now write a record. Balances with no records at all get
+-- a single opening record, dated when the balance was created. Balances that
+-- do have records are left alone: any drift there is for reconciliation to
+-- report and for the audited repair to fix.
+INSERT INTO balance_records (amount, user_id, balance_id, company_id, details, currency, type, status, created_at)
+SELECT ABS(b.balance), b.user_id, b.id, b.company_id, 'opening balance', b.currency,
+    CASE WHEN b.balance < 0 THEN 1 ELSE 2 END, 1, b.created_at
+FROM balances b
+WHERE COALESCE(b.balance, 0) <> 0
+    AND NOT EXISTS (SELECT 1 FROM balance_records r WHERE r.balance_id = b.id);
@@ -0,0 +1,4 @@
+-- users.role: 1 = owner, 2 = cashier (store.ROLE_OWNER, store.ROLE_CASHIER).
+COMMENT ON COLUMN users.role IS '1 = owner, 2 = cashier';
+
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN (1, 2)) NOT VALID;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_debts_due;
+
+ALTER TABLE debts DROP COLUMN IF EXISTS due_notified_at;
+ALTER TABLE debts DROP COLUMN IF EXISTS due_at;
@@ -0,0 +1,6 @@
+-- When a debt is due, and when its debt_due notification was queued. A new
+-- due date clears due_notified_at so the debt is reminded of again.
+ALTER TABLE debts ADD COLUMN IF NOT EXISTS due_at timestamp(0) with time zone DEFAULT NULL;
+ALTER TABLE debts ADD COLUMN IF NOT EXISTS due_notified_at timestamp(0) with time zone DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_debts_due ON debts (due_at) WHERE due_notified_at IS NULL;
@@ -0,0 +1,107 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	TRANSACTION_CREATED   = "transaction.created"
+	TRANSACTION_COMPLETED = "transaction.completed"
+	BALANCE_CHANGED       = "balance.changed"
+	DEBT_POSTED           = "debt.posted"
+)
+
+// Event is what subscribers of a company receive. Data is the stored entity
+// as the API returns it elsewhere.
+type Event struct {
+	Type      string    `json:"type"`
+	CompanyID int64     `json:"company_id"`
+	Data      any       `json:"data"`
+	At        time.Time `json:"at"`
+}
+
+// Bus delivers events to the subscribers of the event's company.
+type Bus interface {
+	Publish(ctx context.Context, event Event)
+	Subscribe(companyID int64) (<-chan Event, func())
+}
+
+// SUBSCRIBER_BUFFER is how many events a slow subscriber may fall behind
+// before new ones are dropped for it.
+const SUBSCRIBER_BUFFER = 32
+
+// LocalBus fans events out to subscribers of this process.
+type LocalBus struct {
+	mu          sync.RWMutex
+	subscribers map[int64]map[chan Event]struct{}
+}
+
+func NewLocalBus() *LocalBus {
+	return &LocalBus{subscribers: map[int64]map[chan Event]struct{}{}}
+}
+
+var _ Bus = (*LocalBus)(nil)
+
+func (b *LocalBus) Publish(_ context.Context, event Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[event.CompanyID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("events: dropped %s for a slow subscriber of company %d", event.Type, event.CompanyID)
+		}
+	}
+}
+
+// Subscribe returns the events of one company and a function that stops the
+// subscription and closes the channel.
+func (b *LocalBus) Subscribe(companyID int64) (<-chan Event, func()) {
+	ch := make(chan Event, SUBSCRIBER_BUFFER)
+
+	b.mu.Lock()
+	if b.subscribers[companyID] == nil {
+		b.subscribers[companyID] = map[chan Event]struct{}{}
+	}
+	b.subscribers[companyID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers[companyID], ch)
+			if len(b.subscribers[companyID]) == 0 {
+				delete(b.subscribers, companyID)
+			}
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends the event once to each distinct company, such as both sides
+// of a transfer. A nil bus is ignored so callers do not need to check whether
+// events are configured.
+func Publish(ctx context.Context, bus Bus, eventType string, companyIDs []int64, data any) {
+	if bus == nil {
+		return
+	}
+
+	seen := map[int64]bool{}
+	for _, id := range companyIDs {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		bus.Publish(ctx, Event{Type: eventType, CompanyID: id, Data: data, At: time.Now()})
+	}
+}
@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const REDIS_CHANNEL = "currency-exchange:events"
+
+// RedisBus shares events between API replicas. Publish goes through Redis
+// pub/sub and every replica, this one included, hands what it receives to
+// its LocalBus.
+type RedisBus struct {
+	local *LocalBus
+	rdb   *redis.Client
+}
+
+func NewRedisBus(rdb *redis.Client) *RedisBus {
+	return &RedisBus{local: NewLocalBus(), rdb: rdb}
+}
+
+var _ Bus = (*RedisBus)(nil)
+
+func (b *RedisBus) Publish(ctx context.Context, event Event) {
+	payload, err := json.Marshal(event)
+	if err == nil {
+		err = b.rdb.Publish(ctx, REDIS_CHANNEL, payload).Err()
+	}
+	if err != nil {
+		// Local subscribers should still hear about it.
+		log.Printf("events: redis publish failed, delivering locally: %v", err)
+		b.local.Publish(ctx, event)
+	}
+}
+
+func (b *RedisBus) Subscribe(companyID int64) (<-chan Event, func()) {
+	return b.local.Subscribe(companyID)
+}
+
+// Run forwards events from Redis to local subscribers until ctx is done.
+func (b *RedisBus) Run(ctx context.Context) {
+	sub := b.rdb.Subscribe(ctx, REDIS_CHANNEL)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("events: invalid message on %s: %v", REDIS_CHANNEL, err)
+				continue
+			}
+			b.local.Publish(ctx, event)
+		}
+	}
+}
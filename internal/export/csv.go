@@ -0,0 +1,53 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSV(w io.Writer) (*csvWriter, error) {
+	// Excel only reads a CSV as UTF-8 when it starts with a byte order mark.
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: csv.NewWriter(w)}, nil
+}
+
+func (c *csvWriter) Header(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) Row(cells []Cell) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		if cell.Number == nil {
+			record[i] = escapeFormula(cell.Text)
+		} else {
+			record[i] = cell.String()
+		}
+	}
+	return c.w.Write(record)
+}
+
+// escapeFormula quotes text that a spreadsheet would run as a formula, such
+// as details or a name starting with "=", so it is shown as written.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
@@ -0,0 +1,116 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	FORMAT_CSV  = "csv"
+	FORMAT_XLSX = "xlsx"
+)
+
+// Writer writes a table row by row, so an export never holds more than the
+// row being written.
+type Writer interface {
+	Header(columns []string) error
+	Row(cells []Cell) error
+	// Flush pushes what was written so far to the underlying writer.
+	Flush() error
+	Close() error
+}
+
+// New returns the writer for format.
+func New(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FORMAT_CSV:
+		return newCSV(w)
+	case FORMAT_XLSX:
+		return newXLSX(w)
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// ContentType is the MIME type of format.
+func ContentType(format string) string {
+	if format == FORMAT_XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Cell is one value of a row. Numbers stay numbers in XLSX so they can be
+// summed; Decimals is how many places they are shown with.
+type Cell struct {
+	Text     string
+	Number   *int64
+	Decimals int
+}
+
+func Text(s string) Cell {
+	return Cell{Text: s}
+}
+
+func Int(n int64) Cell {
+	return Cell{Number: &n}
+}
+
+// OptionalInt is an empty cell for nil.
+func OptionalInt(n *int64) Cell {
+	if n == nil {
+		return Cell{}
+	}
+	return Int(*n)
+}
+
+// Amount is a sum of money in currency, shown with the currency's
+// precision.
+func Amount(amount int64, currency string) Cell {
+	return Cell{Number: &amount, Decimals: Precision(currency)}
+}
+
+// String is the cell as CSV shows it.
+func (c Cell) String() string {
+	if c.Number == nil {
+		return c.Text
+	}
+	return formatNumber(*c.Number, c.Decimals)
+}
+
+// precisions lists the currencies that are not shown with two decimals,
+// under the codes balances are kept in: so'm is "SUM".
+var precisions = map[string]int{
+	"SUM": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"KZT": 0,
+	"KGS": 0,
+	"TJS": 0,
+	"BHD": 3,
+	"KWD": 3,
+	"OMR": 3,
+}
+
+// Precision is how many decimals currency is shown with. Amounts are kept
+// in whole units, so the decimals only fix how they are written.
+func Precision(currency string) int {
+	if p, ok := precisions[strings.ToUpper(strings.TrimSpace(currency))]; ok {
+		return p
+	}
+	return 2
+}
+
+// FormatAmount writes amount the way exports show it, such as "100.00 USD".
+func FormatAmount(amount int64, currency string) string {
+	return formatNumber(amount, Precision(currency)) + " " + currency
+}
+
+func formatNumber(n int64, decimals int) string {
+	s := strconv.FormatInt(n, 10)
+	if decimals > 0 {
+		s += "." + strings.Repeat("0", decimals)
+	}
+	return s
+}
@@ -0,0 +1,74 @@
+package fcm
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/messaging"
+	"google.golang.org/api/option"
+
+	"github.com/mubashshir3767/currencyExchange/internal/notify"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+// Channel pushes notifications to every device session of the user.
+type Channel struct {
+	store  store.Storage
+	client *messaging.Client
+}
+
+func NewChannel(credsPath string, st store.Storage) (*Channel, error) {
+	app, err := firebase.NewApp(context.Background(), nil, option.WithCredentialsFile(credsPath))
+	if err != nil {
+		return nil, err
+	}
+	client, err := app.Messaging(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &Channel{store: st, client: client}, nil
+}
+
+var _ notify.Channel = (*Channel)(nil)
+
+// Send fails when FCM could not be reached or no device accepted the message
+// for a reason other than a stale token, so the outbox tries again later.
+func (c *Channel) Send(ctx context.Context, to notify.Recipient, msg notify.Message) error {
+	tokens, err := c.store.UserSessions.FCMTokensByUserID(ctx, to.UserID)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return notify.ErrNoAddress
+	}
+
+	br, err := c.client.SendEachForMulticast(ctx, &messaging.MulticastMessage{
+		Tokens:       tokens,
+		Notification: &messaging.Notification{Title: msg.Title, Body: msg.Body},
+		Data:         msg.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("fcm SendEachForMulticast: %w", err)
+	}
+
+	var retry error
+	for i, resp := range br.Responses {
+		if resp.Success {
+			continue
+		}
+		if messaging.IsRegistrationTokenNotRegistered(resp.Error) || messaging.IsInvalidArgument(resp.Error) {
+			if err := c.store.UserSessions.DeleteByFCMToken(ctx, tokens[i]); err != nil {
+				log.Printf("fcm cleanup token: %v", err)
+			}
+			continue
+		}
+		retry = resp.Error
+	}
+
+	if br.SuccessCount == 0 && retry != nil {
+		return fmt.Errorf("fcm send: %w", retry)
+	}
+	return nil
+}
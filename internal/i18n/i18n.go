@@ -0,0 +1,127 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Lang string
+
+const (
+	UZ      Lang = "uz"
+	UZ_CYRL Lang = "uz-Cyrl"
+	RU      Lang = "ru"
+	EN      Lang = "en"
+
+	DEFAULT = UZ
+)
+
+type langKey struct{}
+
+func WithLang(ctx context.Context, lang Lang) context.Context {
+	return context.WithValue(ctx, langKey{}, lang)
+}
+
+// FromContext returns the language selected for the request, or DEFAULT.
+func FromContext(ctx context.Context) Lang {
+	if lang, ok := ctx.Value(langKey{}).(Lang); ok {
+		return lang
+	}
+	return DEFAULT
+}
+
+// Normalize maps a language tag such as "ru-RU" or "uz-Cyrl-UZ" to one of
+// the supported languages.
+func Normalize(tag string) (Lang, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	tag = strings.ReplaceAll(tag, "_", "-")
+
+	switch {
+	case tag == "":
+		return "", false
+	case strings.HasPrefix(tag, "uz-cyrl"):
+		return UZ_CYRL, true
+	case tag == "uz" || strings.HasPrefix(tag, "uz-"):
+		return UZ, true
+	case tag == "ru" || strings.HasPrefix(tag, "ru-"):
+		return RU, true
+	case tag == "en" || strings.HasPrefix(tag, "en-"):
+		return EN, true
+	}
+	return "", false
+}
+
+// FromPreference returns the user's saved language, or DEFAULT when it is
+// not set or no longer supported.
+func FromPreference(language *string) Lang {
+	if language == nil {
+		return DEFAULT
+	}
+	if lang, ok := Normalize(*language); ok {
+		return lang
+	}
+	return DEFAULT
+}
+
+// ParseAcceptLanguage picks the supported language with the highest q value
+// from an Accept-Language header.
+func ParseAcceptLanguage(header string) (Lang, bool) {
+	type candidate struct {
+		lang Lang
+		q    float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		lang, ok := Normalize(tag)
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{lang: lang, q: q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].lang, true
+}
+
+// Lookup returns the message for key in lang, falling back to DEFAULT.
+func Lookup(lang Lang, key string) (string, bool) {
+	if message, ok := catalogs[lang][key]; ok {
+		return message, true
+	}
+	message, ok := catalogs[DEFAULT][key]
+	return message, ok
+}
+
+// T formats the message for key in lang. Unknown keys are returned as is.
+func T(lang Lang, key string, args ...any) string {
+	message, ok := Lookup(lang, key)
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
@@ -0,0 +1,554 @@
+package i18n
+
+// Error messages are keyed by types.AppError codes so the API can localize
+// any domain error without knowing where it came from.
+var catalogs = map[Lang]map[string]string{
+	UZ: {
+		"INVALID_REQUEST":                 "So'rov noto'g'ri",
+		"INVALID_CURSOR":                  "Kursor noto'g'ri",
+		"INVALID_SORT":                    "Saralash maydoni noto'g'ri",
+		"INVALID_FILTER":                  "Filtr qiymati noto'g'ri",
+		"INVALID_FIELD":                   "Maydon nomi noto'g'ri",
+		"UNKNOWN_TYPE":                    "Noma'lum tur",
+		"UNAUTHORIZED":                    "Avtorizatsiyadan o'tilmagan",
+		"FORBIDDEN":                       "Ruxsat berilmagan",
+		"NOT_FOUND":                       "Topilmadi",
+		"CONFLICT":                        "Ziddiyat yuz berdi",
+		"RATE_LIMITED":                    "So'rovlar juda ko'p, birozdan keyin urinib ko'ring",
+		"VALIDATION_FAILED":               "Ma'lumotlar tekshiruvdan o'tmadi",
+		"INTERNAL_ERROR":                  "Serverda xatolik yuz berdi",
+		"BALANCE_NO_ENOUGH_MONEY":         "Hisobda yetarlik mablag' mavjud emas",
+		"DEBTOR_NO_ENOUGH_MONEY":          "Qarzdorda yetarlik mablag' mavjud emas",
+		"BALANCE_CURRENCY_NOT_FOUND":      "Bunday valyutalik hisob mavjud emas",
+		"BALANCE_ALREADY_EXISTS":          "Bu valyutada hisob allaqachon mavjud",
+		"CURRENCY_MISMATCH":               "Qarz valyutalari mos kelmaydi",
+		"EMPTY_INCOMES":                   "Qabul qilingan summalar bo'sh bo'lishi mumkin emas",
+		"TRANSACTION_NOT_FOUND":           "Buyurtma topilmadi",
+		"TRANSACTION_ALREADY_COMPLETED":   "Buyurtma allaqachon yakunlangan",
+		"EXCHANGE_NOT_FOUND":              "Ayirboshlash topilmadi",
+		"BALANCE_NOT_FOUND":               "Hisob topilmadi",
+		"BALANCE_RECORD_NOT_FOUND":        "Hisob yozuvi topilmadi",
+		"DEBT_NOT_FOUND":                  "Qarz topilmadi",
+		"DEBTOR_NOT_FOUND":                "Qarzdor topilmadi",
+		"USER_NOT_FOUND":                  "Foydalanuvchi topilmadi",
+		"COMPANY_NOT_FOUND":               "Kompaniya topilmadi",
+		"CHANGE_REQUEST_NOT_FOUND":        "O'zgartirish so'rovi topilmadi",
+		"CHANGE_REQUEST_ALREADY_REVIEWED": "O'zgartirish so'rovi allaqachon ko'rib chiqilgan",
+		"NOTIFICATION_NOT_FOUND":          "Bildirishnoma topilmadi",
+		"WEBHOOK_NOT_FOUND":               "Vebxuk topilmadi",
+		"WEBHOOK_DELIVERY_NOT_FOUND":      "Yetkazish topilmadi yoki allaqachon navbatda",
+		"BALANCE_THRESHOLD_NOT_FOUND":     "Chegara topilmadi",
+		"BALANCE_THRESHOLD_RANGE":         "Eng kam qiymat eng ko'p qiymatdan katta bo'lmasligi kerak",
+		"RECEIPTS_UNAVAILABLE":            "Kvitansiya yaratib bo'lmadi: shrift o'rnatilmagan",
+		"RECEIPT_NOT_FOUND":               "Kvitansiya haqiqiy emas yoki bekor qilingan",
+		"STATEMENT_RANGE":                 "Boshlanish sanasi tugash sanasidan keyin bo'lmasligi kerak",
+		"STATEMENT_NOT_FOUND":             "Hisob ko'chirmasi havolasi noto'g'ri yoki muddati o'tgan",
+		"SHARING_UNAVAILABLE":             "Ulashish sozlanmagan",
+		"DISCREPANCY_NOT_FOUND":           "Nomuvofiqlik topilmadi",
+		"DISCREPANCY_RESOLVED":            "Balans allaqachon yozuvlarga mos keladi yoki tuzatilgan",
+		"SNAPSHOT_RANGE":                  "Qoldiq faqat tugagan kunlar uchun, bir martada ko'pi bilan bir yilga olinadi",
+
+		"notification.transaction_pending.title":   "Yangi tranzaksiya",
+		"notification.transaction_pending.body":    "Yetkazib berish uchun yangi buyurtma",
+		"notification.transaction_completed.title": "Tranzaksiya yakunlandi",
+		"notification.transaction_completed.body":  "Tranzaksiya muvaffaqiyatli yakunlandi",
+		"notification.transaction_cancelled.title": "Tranzaksiya bekor qilindi",
+		"notification.transaction_cancelled.body":  "#%s tranzaksiya bekor qilindi",
+		"notification.low_balance.title":           "Balans kam",
+		"notification.low_balance.body":            "%s balansi %s ga tushdi",
+		"notification.high_balance.title":          "Balans ko'p",
+		"notification.high_balance.body":           "%s balansi %s ga ko'tarildi",
+		"notification.debt_due.title":              "Qarz muddati keldi",
+		"notification.debt_due.body":               "%s qarzi: %s %s",
+		"notification.large_exchange.title":        "Katta ayirboshlash",
+		"notification.large_exchange.body":         "%s %s miqdorida ayirboshlash",
+		"notification.new_device_login.title":      "Yangi qurilmadan kirish",
+		"notification.new_device_login.body":       "Hisobingizga %s qurilmasidan kirildi",
+
+		"sms.transaction_created":   "Sizga #%s pul o'tkazmasi keldi. Olish kodi: %s",
+		"sms.transaction_completed": "#%s pul o'tkazmasi topshirildi. Rahmat!",
+
+		"telegram.help":        "Buyruqlar:\n/balance — kassa qoldiqlari\n/debtors — qarzdorlar jami\n/digest — bugungi hisobot\n/unlink — chatni uzish",
+		"telegram.linked":      "Hisob ulandi. Har kuni shu yerga hisobot yuboriladi.",
+		"telegram.link_failed": "Kod noto'g'ri yoki muddati o'tgan. Ilovadan yangi kod oling.",
+		"telegram.not_linked":  "Bu chat ulanmagan. Ilovadan kod oling va /start KOD yuboring.",
+		"telegram.unlinked":    "Chat uzildi.",
+		"telegram.balances":    "Kassa qoldiqlari:",
+		"telegram.debtors":     "Qarzdorlar:",
+		"telegram.debtor_line": "%v: +%v / %v",
+		"telegram.digest":      "%s — %s hisoboti",
+		"telegram.digest_line": "%s: olingan %s, berilgan %s, qolgan %s",
+		"telegram.empty":       "Ma'lumot yo'q",
+
+		"export.id":                "ID",
+		"export.number":            "Raqam",
+		"export.created_at":        "Sana",
+		"export.type":              "Turi",
+		"export.status":            "Holati",
+		"export.received":          "Qabul qilindi",
+		"export.delivered":         "Topshirildi",
+		"export.service_fee":       "Xizmat haqi",
+		"export.phone":             "Telefon",
+		"export.details":           "Izoh",
+		"export.received_amount":   "Olingan summa",
+		"export.received_currency": "Olingan valyuta",
+		"export.selled_amount":     "Sotilgan summa",
+		"export.selled_currency":   "Sotilgan valyuta",
+		"export.amount":            "Summa",
+		"export.currency":          "Valyuta",
+		"export.transaction_id":    "Buyurtma ID",
+		"export.debt_id":           "Qarz ID",
+		"export.exchange_id":       "Ayirboshlash ID",
+		"export.full_name":         "F.I.Sh.",
+		"export.state":             "Holati",
+		"export.balance":           "Balans",
+
+		"receipt.title":       "Kvitansiya",
+		"receipt.number":      "Raqam",
+		"receipt.date":        "Sana",
+		"receipt.received":    "Qabul qilindi",
+		"receipt.delivered":   "Topshirildi",
+		"receipt.service_fee": "Xizmat haqi",
+		"receipt.phone":       "Telefon",
+		"receipt.details":     "Izoh",
+		"receipt.verify":      "Kvitansiyani tekshirish",
+
+		"receipt.exchange_title": "Valyuta ayirboshlash kvitansiyasi",
+		"receipt.debt_title":     "Qarz kvitansiyasi",
+		"receipt.cashier":        "Kassir",
+		"receipt.customer":       "Mijoz",
+		"receipt.debt":           "Qarz summasi",
+		"receipt.status":         "Holati",
+		"receipt.status_1":       "Kutilmoqda",
+		"receipt.status_2":       "Yakunlangan",
+
+		"statement.title":    "Qarzdor hisob ko'chirmasi",
+		"statement.debtor":   "Qarzdor",
+		"statement.period":   "Davr",
+		"statement.opening":  "Boshlang'ich qoldiq",
+		"statement.closing":  "Yakuniy qoldiq",
+		"statement.given":    "Berildi",
+		"statement.received": "Olindi",
+		"statement.balance":  "Qoldiq",
+
+		"statement.balance_title":         "Balans ko'chirmasi",
+		"statement.in":                    "Kirim",
+		"statement.out":                   "Chiqim",
+		"statement.source":                "Manba",
+		"statement.source_transaction":    "Buyurtma",
+		"statement.source_exchange":       "Ayirboshlash",
+		"statement.source_debt":           "Qarz",
+		"statement.source_balance_record": "Qo'lda kiritilgan yozuv",
+	},
+	UZ_CYRL: {
+		"INVALID_REQUEST":                 "Сўров нотўғри",
+		"INVALID_CURSOR":                  "Курсор нотўғри",
+		"INVALID_SORT":                    "Саралаш майдони нотўғри",
+		"INVALID_FILTER":                  "Филтр қиймати нотўғри",
+		"INVALID_FIELD":                   "Майдон номи нотўғри",
+		"UNKNOWN_TYPE":                    "Номаълум тур",
+		"UNAUTHORIZED":                    "Авторизациядан ўтилмаган",
+		"FORBIDDEN":                       "Рухсат берилмаган",
+		"NOT_FOUND":                       "Топилмади",
+		"CONFLICT":                        "Зиддият юз берди",
+		"RATE_LIMITED":                    "Сўровлар жуда кўп, бироздан кейин уриниб кўринг",
+		"VALIDATION_FAILED":               "Маълумотлар текширувдан ўтмади",
+		"INTERNAL_ERROR":                  "Серверда хатолик юз берди",
+		"BALANCE_NO_ENOUGH_MONEY":         "Ҳисобда етарлик маблағ мавжуд эмас",
+		"DEBTOR_NO_ENOUGH_MONEY":          "Қарздорда етарлик маблағ мавжуд эмас",
+		"BALANCE_CURRENCY_NOT_FOUND":      "Бундай валютали ҳисоб мавжуд эмас",
+		"BALANCE_ALREADY_EXISTS":          "Бу валютада ҳисоб аллақачон мавжуд",
+		"CURRENCY_MISMATCH":               "Қарз валюталари мос келмайди",
+		"EMPTY_INCOMES":                   "Қабул қилинган суммалар бўш бўлиши мумкин эмас",
+		"TRANSACTION_NOT_FOUND":           "Буюртма топилмади",
+		"TRANSACTION_ALREADY_COMPLETED":   "Буюртма аллақачон якунланган",
+		"EXCHANGE_NOT_FOUND":              "Айирбошлаш топилмади",
+		"BALANCE_NOT_FOUND":               "Ҳисоб топилмади",
+		"BALANCE_RECORD_NOT_FOUND":        "Ҳисоб ёзуви топилмади",
+		"DEBT_NOT_FOUND":                  "Қарз топилмади",
+		"DEBTOR_NOT_FOUND":                "Қарздор топилмади",
+		"USER_NOT_FOUND":                  "Фойдаланувчи топилмади",
+		"COMPANY_NOT_FOUND":               "Компания топилмади",
+		"CHANGE_REQUEST_NOT_FOUND":        "Ўзгартириш сўрови топилмади",
+		"CHANGE_REQUEST_ALREADY_REVIEWED": "Ўзгартириш сўрови аллақачон кўриб чиқилган",
+		"NOTIFICATION_NOT_FOUND":          "Билдиришнома топилмади",
+		"WEBHOOK_NOT_FOUND":               "Вебхук топилмади",
+		"WEBHOOK_DELIVERY_NOT_FOUND":      "Етказиш топилмади ёки аллақачон навбатда",
+		"BALANCE_THRESHOLD_NOT_FOUND":     "Чегара топилмади",
+		"BALANCE_THRESHOLD_RANGE":         "Энг кам қиймат энг кўп қийматдан катта бўлмаслиги керак",
+		"RECEIPTS_UNAVAILABLE":            "Квитанция яратиб бўлмади: шрифт ўрнатилмаган",
+		"RECEIPT_NOT_FOUND":               "Квитанция ҳақиқий эмас ёки бекор қилинган",
+		"STATEMENT_RANGE":                 "Бошланиш санаси тугаш санасидан кейин бўлмаслиги керак",
+		"STATEMENT_NOT_FOUND":             "Ҳисоб кўчирмаси ҳаволаси нотўғри ёки муддати ўтган",
+		"SHARING_UNAVAILABLE":             "Улашиш созланмаган",
+		"DISCREPANCY_NOT_FOUND":           "Номувофиқлик топилмади",
+		"DISCREPANCY_RESOLVED":            "Баланс аллақачон ёзувларга мос келади ёки тузатилган",
+		"SNAPSHOT_RANGE":                  "Қолдиқ фақат тугаган кунлар учун, бир марта кўпи билан бир йилга олинади",
+
+		"notification.transaction_pending.title":   "Янги транзакция",
+		"notification.transaction_pending.body":    "Етказиб бериш учун янги буюртма",
+		"notification.transaction_completed.title": "Транзакция якунланди",
+		"notification.transaction_completed.body":  "Транзакция муваффақиятли якунланди",
+		"notification.transaction_cancelled.title": "Транзакция бекор қилинди",
+		"notification.transaction_cancelled.body":  "#%s транзакция бекор қилинди",
+		"notification.low_balance.title":           "Баланс кам",
+		"notification.low_balance.body":            "%s баланси %s га тушди",
+		"notification.high_balance.title":          "Баланс кўп",
+		"notification.high_balance.body":           "%s баланси %s га кўтарилди",
+		"notification.debt_due.title":              "Қарз муддати келди",
+		"notification.debt_due.body":               "%s қарзи: %s %s",
+		"notification.large_exchange.title":        "Катта айирбошлаш",
+		"notification.large_exchange.body":         "%s %s миқдорида айирбошлаш",
+		"notification.new_device_login.title":      "Янги қурилмадан кириш",
+		"notification.new_device_login.body":       "Ҳисобингизга %s қурилмасидан кирилди",
+
+		"sms.transaction_created":   "Сизга #%s пул ўтказмаси келди. Олиш коди: %s",
+		"sms.transaction_completed": "#%s пул ўтказмаси топширилди. Раҳмат!",
+
+		"telegram.help":        "Буйруқлар:\n/balance — касса қолдиқлари\n/debtors — қарздорлар жами\n/digest — бугунги ҳисобот\n/unlink — чатни узиш",
+		"telegram.linked":      "Ҳисоб уланди. Ҳар куни шу ерга ҳисобот юборилади.",
+		"telegram.link_failed": "Код нотўғри ёки муддати ўтган. Иловадан янги код олинг.",
+		"telegram.not_linked":  "Бу чат уланмаган. Иловадан код олинг ва /start КОД юборинг.",
+		"telegram.unlinked":    "Чат узилди.",
+		"telegram.balances":    "Касса қолдиқлари:",
+		"telegram.debtors":     "Қарздорлар:",
+		"telegram.debtor_line": "%v: +%v / %v",
+		"telegram.digest":      "%s — %s ҳисоботи",
+		"telegram.digest_line": "%s: олинган %s, берилган %s, қолган %s",
+		"telegram.empty":       "Маълумот йўқ",
+
+		"export.id":                "ID",
+		"export.number":            "Рақам",
+		"export.created_at":        "Сана",
+		"export.type":              "Тури",
+		"export.status":            "Ҳолати",
+		"export.received":          "Қабул қилинди",
+		"export.delivered":         "Топширилди",
+		"export.service_fee":       "Хизмат ҳақи",
+		"export.phone":             "Телефон",
+		"export.details":           "Изоҳ",
+		"export.received_amount":   "Олинган сумма",
+		"export.received_currency": "Олинган валюта",
+		"export.selled_amount":     "Сотилган сумма",
+		"export.selled_currency":   "Сотилган валюта",
+		"export.amount":            "Сумма",
+		"export.currency":          "Валюта",
+		"export.transaction_id":    "Буюртма ID",
+		"export.debt_id":           "Қарз ID",
+		"export.exchange_id":       "Айирбошлаш ID",
+		"export.full_name":         "Ф.И.Ш.",
+		"export.state":             "Ҳолати",
+		"export.balance":           "Баланс",
+
+		"receipt.title":       "Квитанция",
+		"receipt.number":      "Рақам",
+		"receipt.date":        "Сана",
+		"receipt.received":    "Қабул қилинди",
+		"receipt.delivered":   "Топширилди",
+		"receipt.service_fee": "Хизмат ҳақи",
+		"receipt.phone":       "Телефон",
+		"receipt.details":     "Изоҳ",
+		"receipt.verify":      "Квитанцияни текшириш",
+
+		"receipt.exchange_title": "Валюта айирбошлаш квитанцияси",
+		"receipt.debt_title":     "Қарз квитанцияси",
+		"receipt.cashier":        "Кассир",
+		"receipt.customer":       "Мижоз",
+		"receipt.debt":           "Қарз суммаси",
+		"receipt.status":         "Ҳолати",
+		"receipt.status_1":       "Кутилмоқда",
+		"receipt.status_2":       "Якунланган",
+
+		"statement.title":    "Қарздор ҳисоб кўчирмаси",
+		"statement.debtor":   "Қарздор",
+		"statement.period":   "Давр",
+		"statement.opening":  "Бошланғич қолдиқ",
+		"statement.closing":  "Якуний қолдиқ",
+		"statement.given":    "Берилди",
+		"statement.received": "Олинди",
+		"statement.balance":  "Қолдиқ",
+
+		"statement.balance_title":         "Баланс кўчирмаси",
+		"statement.in":                    "Кирим",
+		"statement.out":                   "Чиқим",
+		"statement.source":                "Манба",
+		"statement.source_transaction":    "Буюртма",
+		"statement.source_exchange":       "Айирбошлаш",
+		"statement.source_debt":           "Қарз",
+		"statement.source_balance_record": "Қўлда киритилган ёзув",
+	},
+	RU: {
+		"INVALID_REQUEST":                 "Некорректный запрос",
+		"INVALID_CURSOR":                  "Некорректный курсор",
+		"INVALID_SORT":                    "Недопустимое поле сортировки",
+		"INVALID_FILTER":                  "Некорректное значение фильтра",
+		"INVALID_FIELD":                   "Некорректное имя поля",
+		"UNKNOWN_TYPE":                    "Неизвестный тип",
+		"UNAUTHORIZED":                    "Требуется авторизация",
+		"FORBIDDEN":                       "Доступ запрещён",
+		"NOT_FOUND":                       "Не найдено",
+		"CONFLICT":                        "Конфликт данных",
+		"RATE_LIMITED":                    "Слишком много запросов, попробуйте позже",
+		"VALIDATION_FAILED":               "Данные не прошли проверку",
+		"INTERNAL_ERROR":                  "Внутренняя ошибка сервера",
+		"BALANCE_NO_ENOUGH_MONEY":         "Недостаточно средств на счёте",
+		"DEBTOR_NO_ENOUGH_MONEY":          "У должника недостаточно средств",
+		"BALANCE_CURRENCY_NOT_FOUND":      "Счёт в этой валюте не найден",
+		"BALANCE_ALREADY_EXISTS":          "Счёт в этой валюте уже существует",
+		"CURRENCY_MISMATCH":               "Валюты долга не совпадают",
+		"EMPTY_INCOMES":                   "Полученные суммы не могут быть пустыми",
+		"TRANSACTION_NOT_FOUND":           "Заказ не найден",
+		"TRANSACTION_ALREADY_COMPLETED":   "Заказ уже завершён",
+		"EXCHANGE_NOT_FOUND":              "Обмен не найден",
+		"BALANCE_NOT_FOUND":               "Счёт не найден",
+		"BALANCE_RECORD_NOT_FOUND":        "Запись по счёту не найдена",
+		"DEBT_NOT_FOUND":                  "Долг не найден",
+		"DEBTOR_NOT_FOUND":                "Должник не найден",
+		"USER_NOT_FOUND":                  "Пользователь не найден",
+		"COMPANY_NOT_FOUND":               "Компания не найдена",
+		"CHANGE_REQUEST_NOT_FOUND":        "Запрос на изменение не найден",
+		"CHANGE_REQUEST_ALREADY_REVIEWED": "Запрос на изменение уже рассмотрен",
+		"NOTIFICATION_NOT_FOUND":          "Уведомление не найдено",
+		"WEBHOOK_NOT_FOUND":               "Вебхук не найден",
+		"WEBHOOK_DELIVERY_NOT_FOUND":      "Доставка не найдена или уже в очереди",
+		"BALANCE_THRESHOLD_NOT_FOUND":     "Порог не найден",
+		"BALANCE_THRESHOLD_RANGE":         "Минимум не может быть больше максимума",
+		"RECEIPTS_UNAVAILABLE":            "Квитанция недоступна: шрифт не установлен",
+		"RECEIPT_NOT_FOUND":               "Квитанция недействительна или аннулирована",
+		"STATEMENT_RANGE":                 "Дата начала не может быть позже даты окончания",
+		"STATEMENT_NOT_FOUND":             "Ссылка на выписку недействительна или устарела",
+		"SHARING_UNAVAILABLE":             "Общий доступ не настроен",
+		"DISCREPANCY_NOT_FOUND":           "Расхождение не найдено",
+		"DISCREPANCY_RESOLVED":            "Баланс уже совпадает с записями или исправлен",
+		"SNAPSHOT_RANGE":                  "Снимки остатков делаются только за завершённые дни, не более чем за год за раз",
+
+		"notification.transaction_pending.title":   "Новая транзакция",
+		"notification.transaction_pending.body":    "Новый заказ на выдачу",
+		"notification.transaction_completed.title": "Транзакция завершена",
+		"notification.transaction_completed.body":  "Транзакция успешно завершена",
+		"notification.transaction_cancelled.title": "Транзакция отменена",
+		"notification.transaction_cancelled.body":  "Транзакция #%s отменена",
+		"notification.low_balance.title":           "Низкий баланс",
+		"notification.low_balance.body":            "Баланс %s опустился до %s",
+		"notification.high_balance.title":          "Баланс высокий",
+		"notification.high_balance.body":           "Баланс %s вырос до %s",
+		"notification.debt_due.title":              "Срок долга",
+		"notification.debt_due.body":               "Долг %s: %s %s",
+		"notification.large_exchange.title":        "Крупный обмен",
+		"notification.large_exchange.body":         "Обмен на сумму %s %s",
+		"notification.new_device_login.title":      "Вход с нового устройства",
+		"notification.new_device_login.body":       "В ваш аккаунт вошли с устройства %s",
+
+		"sms.transaction_created":   "Вам поступил перевод #%s. Код получения: %s",
+		"sms.transaction_completed": "Перевод #%s выдан. Спасибо!",
+
+		"telegram.help":        "Команды:\n/balance — остатки кассы\n/debtors — итоги по должникам\n/digest — отчёт за сегодня\n/unlink — отвязать чат",
+		"telegram.linked":      "Аккаунт привязан. Ежедневный отчёт будет приходить сюда.",
+		"telegram.link_failed": "Код неверный или устарел. Получите новый код в приложении.",
+		"telegram.not_linked":  "Этот чат не привязан. Получите код в приложении и отправьте /start КОД.",
+		"telegram.unlinked":    "Чат отвязан.",
+		"telegram.balances":    "Остатки кассы:",
+		"telegram.debtors":     "Должники:",
+		"telegram.debtor_line": "%v: +%v / %v",
+		"telegram.digest":      "%s — отчёт за %s",
+		"telegram.digest_line": "%s: получено %s, выдано %s, остаток %s",
+		"telegram.empty":       "Нет данных",
+
+		"export.id":                "ID",
+		"export.number":            "Номер",
+		"export.created_at":        "Дата",
+		"export.type":              "Тип",
+		"export.status":            "Статус",
+		"export.received":          "Принято",
+		"export.delivered":         "Выдано",
+		"export.service_fee":       "Комиссия",
+		"export.phone":             "Телефон",
+		"export.details":           "Примечание",
+		"export.received_amount":   "Получено",
+		"export.received_currency": "Валюта получения",
+		"export.selled_amount":     "Продано",
+		"export.selled_currency":   "Валюта продажи",
+		"export.amount":            "Сумма",
+		"export.currency":          "Валюта",
+		"export.transaction_id":    "ID перевода",
+		"export.debt_id":           "ID долга",
+		"export.exchange_id":       "ID обмена",
+		"export.full_name":         "ФИО",
+		"export.state":             "Состояние",
+		"export.balance":           "Баланс",
+
+		"receipt.title":       "Квитанция",
+		"receipt.number":      "Номер",
+		"receipt.date":        "Дата",
+		"receipt.received":    "Принято",
+		"receipt.delivered":   "Выдано",
+		"receipt.service_fee": "Комиссия",
+		"receipt.phone":       "Телефон",
+		"receipt.details":     "Примечание",
+		"receipt.verify":      "Проверить квитанцию",
+
+		"receipt.exchange_title": "Квитанция обмена валюты",
+		"receipt.debt_title":     "Квитанция по долгу",
+		"receipt.cashier":        "Кассир",
+		"receipt.customer":       "Клиент",
+		"receipt.debt":           "Сумма долга",
+		"receipt.status":         "Статус",
+		"receipt.status_1":       "Ожидает выдачи",
+		"receipt.status_2":       "Завершена",
+
+		"statement.title":    "Выписка по должнику",
+		"statement.debtor":   "Должник",
+		"statement.period":   "Период",
+		"statement.opening":  "Начальный остаток",
+		"statement.closing":  "Конечный остаток",
+		"statement.given":    "Выдано",
+		"statement.received": "Получено",
+		"statement.balance":  "Остаток",
+
+		"statement.balance_title":         "Выписка по балансу",
+		"statement.in":                    "Приход",
+		"statement.out":                   "Расход",
+		"statement.source":                "Источник",
+		"statement.source_transaction":    "Транзакция",
+		"statement.source_exchange":       "Обмен",
+		"statement.source_debt":           "Долг",
+		"statement.source_balance_record": "Ручная запись",
+	},
+	EN: {
+		"INVALID_REQUEST":                 "Invalid request",
+		"INVALID_CURSOR":                  "Invalid cursor",
+		"INVALID_SORT":                    "Invalid sort field",
+		"INVALID_FILTER":                  "Invalid filter value",
+		"INVALID_FIELD":                   "Invalid field name",
+		"UNKNOWN_TYPE":                    "Unknown type",
+		"UNAUTHORIZED":                    "Unauthorized",
+		"FORBIDDEN":                       "Access denied",
+		"NOT_FOUND":                       "Not found",
+		"CONFLICT":                        "Conflict",
+		"RATE_LIMITED":                    "Too many requests, please try again later",
+		"VALIDATION_FAILED":               "Validation failed",
+		"INTERNAL_ERROR":                  "Internal server error",
+		"BALANCE_NO_ENOUGH_MONEY":         "Not enough money on the balance",
+		"DEBTOR_NO_ENOUGH_MONEY":          "The debtor does not have enough money",
+		"BALANCE_CURRENCY_NOT_FOUND":      "No balance in this currency",
+		"BALANCE_ALREADY_EXISTS":          "A balance in this currency already exists",
+		"CURRENCY_MISMATCH":               "Debt currencies do not match",
+		"EMPTY_INCOMES":                   "Received incomes cannot be empty",
+		"TRANSACTION_NOT_FOUND":           "Transaction not found",
+		"TRANSACTION_ALREADY_COMPLETED":   "Transaction is already completed",
+		"EXCHANGE_NOT_FOUND":              "Exchange not found",
+		"BALANCE_NOT_FOUND":               "Balance not found",
+		"BALANCE_RECORD_NOT_FOUND":        "Balance record not found",
+		"DEBT_NOT_FOUND":                  "Debt not found",
+		"DEBTOR_NOT_FOUND":                "Debtor not found",
+		"USER_NOT_FOUND":                  "User not found",
+		"COMPANY_NOT_FOUND":               "Company not found",
+		"CHANGE_REQUEST_NOT_FOUND":        "Change request not found",
+		"CHANGE_REQUEST_ALREADY_REVIEWED": "Change request is already reviewed",
+		"NOTIFICATION_NOT_FOUND":          "Notification not found",
+		"WEBHOOK_NOT_FOUND":               "Webhook not found",
+		"WEBHOOK_DELIVERY_NOT_FOUND":      "Webhook delivery not found or already queued",
+		"BALANCE_THRESHOLD_NOT_FOUND":     "Balance threshold not found",
+		"BALANCE_THRESHOLD_RANGE":         "The minimum must not be greater than the maximum",
+		"RECEIPTS_UNAVAILABLE":            "Receipts are unavailable: the font is not installed",
+		"RECEIPT_NOT_FOUND":               "The receipt is not genuine or is no longer valid",
+		"STATEMENT_RANGE":                 "The start date must not be after the end date",
+		"STATEMENT_NOT_FOUND":             "The statement link is invalid or has expired",
+		"SHARING_UNAVAILABLE":             "Sharing is not configured",
+		"DISCREPANCY_NOT_FOUND":           "Discrepancy not found",
+		"DISCREPANCY_RESOLVED":            "The balance already matches its records or was repaired",
+		"SNAPSHOT_RANGE":                  "Snapshots can only be taken for days that have ended, up to a year at a time",
+
+		"notification.transaction_pending.title":   "New transaction",
+		"notification.transaction_pending.body":    "New order to deliver",
+		"notification.transaction_completed.title": "Transaction completed",
+		"notification.transaction_completed.body":  "Transaction completed successfully",
+		"notification.transaction_cancelled.title": "Transaction cancelled",
+		"notification.transaction_cancelled.body":  "Transaction #%s was cancelled",
+		"notification.low_balance.title":           "Low balance",
+		"notification.low_balance.body":            "%s balance dropped to %s",
+		"notification.high_balance.title":          "High balance",
+		"notification.high_balance.body":           "%s balance rose to %s",
+		"notification.debt_due.title":              "Debt due",
+		"notification.debt_due.body":               "%s owes %s %s",
+		"notification.large_exchange.title":        "Large exchange",
+		"notification.large_exchange.body":         "Exchange of %s %s",
+		"notification.new_device_login.title":      "New device login",
+		"notification.new_device_login.body":       "Your account was signed in on %s",
+
+		"sms.transaction_created":   "Transfer #%s is waiting for you. Pickup code: %s",
+		"sms.transaction_completed": "Transfer #%s has been paid out. Thank you!",
+
+		"telegram.help":        "Commands:\n/balance — cash balances\n/debtors — debtor totals\n/digest — today's summary\n/unlink — unlink this chat",
+		"telegram.linked":      "Account linked. The daily summary will come here.",
+		"telegram.link_failed": "The code is wrong or expired. Get a new one in the app.",
+		"telegram.not_linked":  "This chat is not linked. Get a code in the app and send /start CODE.",
+		"telegram.unlinked":    "Chat unlinked.",
+		"telegram.balances":    "Cash balances:",
+		"telegram.debtors":     "Debtors:",
+		"telegram.debtor_line": "%v: +%v / %v",
+		"telegram.digest":      "%s — summary for %s",
+		"telegram.digest_line": "%s: received %s, paid %s, remaining %s",
+		"telegram.empty":       "No data",
+
+		"export.id":                "ID",
+		"export.number":            "Number",
+		"export.created_at":        "Date",
+		"export.type":              "Type",
+		"export.status":            "Status",
+		"export.received":          "Received",
+		"export.delivered":         "Delivered",
+		"export.service_fee":       "Service fee",
+		"export.phone":             "Phone",
+		"export.details":           "Details",
+		"export.received_amount":   "Received amount",
+		"export.received_currency": "Received currency",
+		"export.selled_amount":     "Sold amount",
+		"export.selled_currency":   "Sold currency",
+		"export.amount":            "Amount",
+		"export.currency":          "Currency",
+		"export.transaction_id":    "Transaction ID",
+		"export.debt_id":           "Debt ID",
+		"export.exchange_id":       "Exchange ID",
+		"export.full_name":         "Full name",
+		"export.state":             "State",
+		"export.balance":           "Balance",
+
+		"receipt.title":       "Receipt",
+		"receipt.number":      "Number",
+		"receipt.date":        "Date",
+		"receipt.received":    "Received",
+		"receipt.delivered":   "Delivered",
+		"receipt.service_fee": "Service fee",
+		"receipt.phone":       "Phone",
+		"receipt.details":     "Details",
+		"receipt.verify":      "Verify receipt",
+
+		"receipt.exchange_title": "Currency exchange receipt",
+		"receipt.debt_title":     "Debt receipt",
+		"receipt.cashier":        "Cashier",
+		"receipt.customer":       "Customer",
+		"receipt.debt":           "Debt amount",
+		"receipt.status":         "Status",
+		"receipt.status_1":       "Pending",
+		"receipt.status_2":       "Completed",
+
+		"statement.title":    "Debtor statement",
+		"statement.debtor":   "Debtor",
+		"statement.period":   "Period",
+		"statement.opening":  "Opening balance",
+		"statement.closing":  "Closing balance",
+		"statement.given":    "Given",
+		"statement.received": "Received",
+		"statement.balance":  "Balance",
+
+		"statement.balance_title":         "Balance statement",
+		"statement.in":                    "In",
+		"statement.out":                   "Out",
+		"statement.source":                "Source",
+		"statement.source_transaction":    "Transaction",
+		"statement.source_exchange":       "Exchange",
+		"statement.source_debt":           "Debt",
+		"statement.source_balance_record": "Manual record",
+	},
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+// SMSProvider sends a text message to a phone number and returns the
+// gateway's id for it.
+type SMSProvider interface {
+	SendSMS(ctx context.Context, phone, text string) (string, error)
+}
+
+// SMS sends the title and body as one text message to the user's phone.
+type SMS struct {
+	Provider SMSProvider
+}
+
+func (c SMS) Send(ctx context.Context, to Recipient, msg Message) error {
+	if to.Phone == "" {
+		return ErrNoAddress
+	}
+	_, err := c.Provider.SendSMS(ctx, to.Phone, msg.Title+": "+msg.Body)
+	return err
+}
+
+// TelegramSender sends a text message to a Telegram chat.
+type TelegramSender interface {
+	SendMessage(ctx context.Context, chatID int64, text string) error
+}
+
+// Telegram sends to the chat the user linked with the bot.
+type Telegram struct {
+	Bot TelegramSender
+}
+
+func (c Telegram) Send(ctx context.Context, to Recipient, msg Message) error {
+	if to.TelegramChatID == nil {
+		return ErrNoAddress
+	}
+	return c.Bot.SendMessage(ctx, *to.TelegramChatID, msg.Title+"\n"+msg.Body)
+}
+
+// Email sends plain text mail through an SMTP server.
+type Email struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (c Email) Send(_ context.Context, to Recipient, msg Message) error {
+	if to.Email == nil || *to.Email == "" {
+		return ErrNoAddress
+	}
+
+	var auth smtp.Auth
+	if c.Username != "" {
+		host, _, _ := strings.Cut(c.Addr, ":")
+		auth = smtp.PlainAuth("", c.Username, c.Password, host)
+	}
+
+	body := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		c.From, *to.Email, mime.QEncoding.Encode("UTF-8", msg.Title), msg.Body,
+	)
+	return smtp.SendMail(c.Addr, auth, c.From, []string{*to.Email}, []byte(body))
+}
+
+// Fake records what it is asked to send instead of sending it. It stands in
+// for a real channel in tests and local runs; set Err to make it fail.
+type Fake struct {
+	mu   sync.Mutex
+	Err  error
+	Sent []Sent
+}
+
+type Sent struct {
+	To      Recipient
+	Message Message
+}
+
+func (c *Fake) Send(_ context.Context, to Recipient, msg Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Err != nil {
+		return c.Err
+	}
+	c.Sent = append(c.Sent, Sent{To: to, Message: msg})
+	return nil
+}
+
+var (
+	_ Channel = SMS{}
+	_ Channel = Telegram{}
+	_ Channel = Email{}
+	_ Channel = (*Fake)(nil)
+)
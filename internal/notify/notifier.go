@@ -1,15 +1,167 @@
 package notify
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
 
-// DeliveredUser sends FCM notifications to transaction delivered users (delivered_user_id).
-type DeliveredUser interface {
-	NotifyPendingDelivery(ctx context.Context, deliveredUserID *int64, txnID int64, phone, details string)
-	NotifyDeliveryCompleted(ctx context.Context, deliveredUserID int64, txnID int64, details string)
+	"github.com/mubashshir3767/currencyExchange/internal/i18n"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+const (
+	CHANNEL_FCM      = "fcm"
+	CHANNEL_SMS      = "sms"
+	CHANNEL_TELEGRAM = "telegram"
+	CHANNEL_EMAIL    = "email"
+)
+
+// KINDS lists every notification kind users can set preferences for.
+var KINDS = []string{
+	store.NOTIFICATION_TRANSACTION_PENDING,
+	store.NOTIFICATION_TRANSACTION_COMPLETED,
+	store.NOTIFICATION_TRANSACTION_CANCELLED,
+	store.NOTIFICATION_LOW_BALANCE,
+	store.NOTIFICATION_HIGH_BALANCE,
+	store.NOTIFICATION_DEBT_DUE,
+	store.NOTIFICATION_LARGE_EXCHANGE,
+	store.NOTIFICATION_NEW_DEVICE_LOGIN,
+}
+
+// DefaultChannels is where a kind goes when the user has not chosen. Every
+// notification also lands in the in-app inbox, whatever the channels.
+func DefaultChannels(kind string) []string {
+	switch kind {
+	case store.NOTIFICATION_NEW_DEVICE_LOGIN:
+		return []string{CHANNEL_FCM, CHANNEL_TELEGRAM}
+	}
+	return []string{CHANNEL_FCM}
+}
+
+// ErrNoAddress is returned by a channel when the recipient has no address
+// for it, such as no email or no linked Telegram chat. It is not a failure.
+var ErrNoAddress = errors.New("recipient has no address for this channel")
+
+// Recipient is the user a message goes to, with every address a channel
+// might need.
+type Recipient struct {
+	UserID         int64
+	Phone          string
+	Email          *string
+	TelegramChatID *int64
+	Language       i18n.Lang
+}
+
+// Message is a notification rendered in the recipient's language. Data is
+// passed along to channels that carry structured data, such as FCM.
+type Message struct {
+	Kind  string
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// Channel delivers a message over one medium.
+type Channel interface {
+	Send(ctx context.Context, to Recipient, msg Message) error
+}
+
+// Sender is what the outbox dispatcher hands notifications to.
+type Sender interface {
+	Notify(ctx context.Context, userID int64, kind string, data map[string]string) error
+}
+
+type UserStore interface {
+	GetById(context.Context, *int64) (*store.User, error)
 }
 
-type NoopDeliveredUser struct{}
+type PreferenceStore interface {
+	GetByUserId(context.Context, int64) (map[string][]string, error)
+}
+
+// Notifier routes a notification to the channels the user prefers for its
+// kind. Channels that are not registered are skipped, so a deployment
+// without SMS or email simply does not send them.
+type Notifier struct {
+	users       UserStore
+	preferences PreferenceStore
+	channels    map[string]Channel
+}
+
+func NewNotifier(users UserStore, preferences PreferenceStore) *Notifier {
+	return &Notifier{users: users, preferences: preferences, channels: map[string]Channel{}}
+}
+
+// Register adds or replaces the channel used for name.
+func (n *Notifier) Register(name string, channel Channel) *Notifier {
+	n.channels[name] = channel
+	return n
+}
+
+var _ Sender = (*Notifier)(nil)
 
-func (NoopDeliveredUser) NotifyPendingDelivery(context.Context, *int64, int64, string, string) {}
+// Notify sends the notification on each preferred channel. It only fails
+// when every channel that was tried failed, so a retry does not repeat an
+// SMS because the push bounced.
+func (n *Notifier) Notify(ctx context.Context, userID int64, kind string, data map[string]string) error {
+	user, err := n.users.GetById(ctx, &userID)
+	if err != nil {
+		return fmt.Errorf("notify: load user %d: %w", userID, err)
+	}
 
-func (NoopDeliveredUser) NotifyDeliveryCompleted(context.Context, int64, int64, string) {}
+	channels, err := n.channelsFor(ctx, userID, kind)
+	if err != nil {
+		return err
+	}
+
+	to := Recipient{
+		UserID:         user.ID,
+		Phone:          user.Phone,
+		Email:          user.Email,
+		TelegramChatID: user.TelegramChatID,
+		Language:       i18n.FromPreference(user.Language),
+	}
+
+	title, body := Text(to.Language, kind, data)
+	msg := Message{Kind: kind, Title: title, Body: body, Data: map[string]string{"type": kind}}
+	for k, v := range data {
+		msg.Data[k] = v
+	}
+
+	var sent int
+	var errs []error
+	for _, name := range channels {
+		channel, ok := n.channels[name]
+		if !ok {
+			continue
+		}
+		if err := channel.Send(ctx, to, msg); err != nil {
+			if !errors.Is(err, ErrNoAddress) {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+			continue
+		}
+		sent++
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	if sent == 0 {
+		return errors.Join(errs...)
+	}
+	log.Printf("notify: %s to user %d partly failed: %v", kind, userID, errors.Join(errs...))
+	return nil
+}
+
+func (n *Notifier) channelsFor(ctx context.Context, userID int64, kind string) ([]string, error) {
+	preferences, err := n.preferences.GetByUserId(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("notify: load preferences of user %d: %w", userID, err)
+	}
+	if channels, ok := preferences[kind]; ok {
+		return channels, nil
+	}
+	return DefaultChannels(kind), nil
+}
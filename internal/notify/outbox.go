@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+const (
+	// MAX_ATTEMPTS is how many times a notification is tried before it is
+	// marked failed.
+	MAX_ATTEMPTS = 8
+	BATCH_SIZE   = 50
+	POLL_EVERY   = 3 * time.Second
+	SEND_TIMEOUT = 25 * time.Second
+	BASE_BACKOFF = 10 * time.Second
+	MAX_BACKOFF  = 30 * time.Minute
+)
+
+// OutboxStore is the part of store.Storage the dispatcher uses.
+type OutboxStore interface {
+	Claim(context.Context, int, time.Duration) ([]store.NotificationOutbox, error)
+	MarkSent(context.Context, int64) error
+	MarkFailed(context.Context, int64, string, *time.Time) error
+}
+
+// InboxStore keeps the in-app copy of every notification.
+type InboxStore interface {
+	Create(context.Context, *store.Notification) error
+}
+
+// Dispatcher sends the notifications written to the outbox. Each one is put
+// in the user's inbox before the push is tried, so the inbox has it even if
+// the push never succeeds.
+type Dispatcher struct {
+	store  OutboxStore
+	inbox  InboxStore
+	sender Sender
+}
+
+func NewDispatcher(st OutboxStore, inbox InboxStore, sender Sender) *Dispatcher {
+	return &Dispatcher{store: st, inbox: inbox, sender: sender}
+}
+
+// Run polls until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(POLL_EVERY)
+	defer ticker.Stop()
+
+	for {
+		d.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) sendDue(ctx context.Context) {
+	notifications, err := d.store.Claim(ctx, BATCH_SIZE, 2*SEND_TIMEOUT)
+	if err != nil {
+		log.Printf("notify: %v", err)
+		return
+	}
+
+	for _, n := range notifications {
+		sendCtx, cancel := context.WithTimeout(ctx, SEND_TIMEOUT)
+		err := d.send(sendCtx, n)
+		cancel()
+
+		if err == nil {
+			err = d.store.MarkSent(ctx, n.ID)
+		} else {
+			var retryAt *time.Time
+			if n.Attempts < MAX_ATTEMPTS {
+				next := time.Now().Add(Backoff(n.Attempts))
+				retryAt = &next
+			}
+			err = d.store.MarkFailed(ctx, n.ID, err.Error(), retryAt)
+		}
+		if err != nil {
+			log.Printf("notify: notification %d: %v", n.ID, err)
+		}
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, n store.NotificationOutbox) error {
+	data := map[string]string{}
+	if err := json.Unmarshal(n.Payload, &data); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	if n.TransactionID != nil {
+		data["transaction_id"] = strconv.FormatInt(*n.TransactionID, 10)
+	}
+
+	if err := d.inbox.Create(ctx, &store.Notification{
+		UserID:        n.UserID,
+		CompanyID:     n.CompanyID,
+		OutboxID:      &n.ID,
+		Kind:          n.Kind,
+		TransactionID: n.TransactionID,
+		Data:          n.Payload,
+	}); err != nil {
+		return err
+	}
+
+	return d.sender.Notify(ctx, n.UserID, n.Kind, data)
+}
+
+// Backoff is the wait after the given failed attempt: 10s, 20s, 40s, ...
+// capped at half an hour.
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	wait := BASE_BACKOFF
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if wait >= MAX_BACKOFF {
+			return MAX_BACKOFF
+		}
+	}
+	return wait
+}
@@ -0,0 +1,34 @@
+package notify
+
+import (
+	"github.com/mubashshir3767/currencyExchange/internal/i18n"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+// textArgs names the data fields each kind's body is formatted with, in the
+// order of the %s verbs in its message.
+var textArgs = map[string][]string{
+	store.NOTIFICATION_TRANSACTION_CANCELLED: {"number"},
+	store.NOTIFICATION_LOW_BALANCE:           {"currency", "balance"},
+	store.NOTIFICATION_HIGH_BALANCE:          {"currency", "balance"},
+	store.NOTIFICATION_DEBT_DUE:              {"full_name", "amount", "currency"},
+	store.NOTIFICATION_LARGE_EXCHANGE:        {"amount", "currency"},
+	store.NOTIFICATION_NEW_DEVICE_LOGIN:      {"device"},
+}
+
+// Text returns the title and body of a notification kind in lang. Every
+// channel and the in-app inbox use the same wording.
+func Text(lang i18n.Lang, kind string, data map[string]string) (title, body string) {
+	title = i18n.T(lang, "notification."+kind+".title")
+
+	var args []any
+	for _, name := range textArgs[kind] {
+		args = append(args, data[name])
+	}
+	body = i18n.T(lang, "notification."+kind+".body", args...)
+
+	if kind == store.NOTIFICATION_TRANSACTION_COMPLETED && data["details"] != "" {
+		body = data["details"]
+	}
+	return title, body
+}
@@ -0,0 +1,362 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns the raw OpenAPI document served at /api/v1/openapi.json.
+func Spec() []byte {
+	return spec
+}
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type PathItem struct {
+	Get    *Operation `json:"get"`
+	Post   *Operation `json:"post"`
+	Put    *Operation `json:"put"`
+	Patch  *Operation `json:"patch"`
+	Delete *Operation `json:"delete"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []Parameter          `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Content map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema used by our document.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+}
+
+// Load parses the embedded document.
+func Load() (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	return &doc, nil
+}
+
+// NormalizePath makes chi route patterns and document paths comparable;
+// chi keeps the trailing slash of r.Get("/") inside a r.Route.
+func NormalizePath(path string) string {
+	if len(path) > 1 {
+		return strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+// Operation finds the operation for a method and chi route pattern.
+func (d *Document) Operation(method, pattern string) *Operation {
+	pattern = NormalizePath(pattern)
+	for path, item := range d.Paths {
+		if NormalizePath(path) != pattern {
+			continue
+		}
+		switch method {
+		case http.MethodGet:
+			return item.Get
+		case http.MethodPost:
+			return item.Post
+		case http.MethodPut:
+			return item.Put
+		case http.MethodPatch:
+			return item.Patch
+		case http.MethodDelete:
+			return item.Delete
+		}
+	}
+	return nil
+}
+
+// Errors maps a location such as "body.received_incomes[0].received_amount"
+// to what is wrong with it.
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+": "+e[k])
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ValidateRequest checks parameters and the JSON body. The body is read, up
+// to maxBytes, and put back so handlers can decode it as usual.
+func (d *Document) ValidateRequest(w http.ResponseWriter, r *http.Request, op *Operation, pathParam func(string) string, maxBytes int64) error {
+	errs := Errors{}
+
+	for _, p := range op.Parameters {
+		var value string
+		switch p.In {
+		case "path":
+			value = pathParam(p.Name)
+		case "query":
+			value = r.URL.Query().Get(p.Name)
+		default:
+			continue
+		}
+
+		location := p.In + "." + p.Name
+		if value == "" {
+			if p.Required {
+				errs[location] = "is required"
+			}
+			continue
+		}
+		d.validateParameter(p.Schema, value, location, errs)
+	}
+
+	if op.RequestBody != nil {
+		if media := op.RequestBody.Content["application/json"]; media != nil && media.Schema != nil {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+			if err != nil {
+				return err
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			if len(bytes.TrimSpace(body)) == 0 {
+				if op.RequestBody.Required {
+					errs["body"] = "is required"
+				}
+			} else if value, err := decode(body); err != nil {
+				errs["body"] = "is not valid JSON"
+			} else {
+				d.validate(media.Schema, value, "body", errs)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateResponse checks a JSON response body against the schema declared
+// for its status code, falling back to "default".
+func (d *Document) ValidateResponse(op *Operation, status int, body []byte) error {
+	response := op.Responses[strconv.Itoa(status)]
+	if response == nil {
+		response = op.Responses["default"]
+	}
+	if response == nil {
+		return Errors{"status": fmt.Sprintf("%d is not documented", status)}
+	}
+
+	media := response.Content["application/json"]
+	if media == nil || media.Schema == nil {
+		return nil
+	}
+
+	value, err := decode(body)
+	if err != nil {
+		return Errors{"response": "is not valid JSON"}
+	}
+
+	errs := Errors{}
+	d.validate(media.Schema, value, "response", errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (d *Document) resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+func (d *Document) validateParameter(s *Schema, raw, location string, errs Errors) {
+	s = d.resolve(s)
+	if s == nil {
+		return
+	}
+
+	var value any = raw
+	if s.Type == "integer" || s.Type == "number" {
+		value = json.Number(raw)
+	}
+	d.validate(s, value, location, errs)
+}
+
+func (d *Document) validate(s *Schema, value any, location string, errs Errors) {
+	s = d.resolve(s)
+	if s == nil {
+		return
+	}
+
+	if value == nil {
+		if s.Type != "" && !s.Nullable {
+			errs[location] = "must not be null"
+		}
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			errs[location] = "must be an object"
+			return
+		}
+		d.validateObject(s, object, location, errs)
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			errs[location] = "must be an array"
+			return
+		}
+		for i, item := range items {
+			d.validate(s.Items, item, fmt.Sprintf("%s[%d]", location, i), errs)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			errs[location] = "must be a string"
+			return
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			errs[location] = "must be an integer"
+			return
+		}
+		if _, err := number.Int64(); err != nil {
+			errs[location] = "must be an integer"
+			return
+		}
+	case "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			errs[location] = "must be a number"
+			return
+		}
+		if _, err := number.Float64(); err != nil {
+			errs[location] = "must be a number"
+			return
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs[location] = "must be a boolean"
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		errs[location] = "is not one of the allowed values"
+	}
+}
+
+func (d *Document) validateObject(s *Schema, object map[string]any, location string, errs Errors) {
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			errs[location+"."+name] = "is required"
+		}
+	}
+
+	var additional *Schema
+	closed := false
+	if len(s.AdditionalProperties) > 0 {
+		if string(s.AdditionalProperties) == "false" {
+			closed = true
+		} else if err := json.Unmarshal(s.AdditionalProperties, &additional); err != nil {
+			additional = nil
+		}
+	}
+
+	for name, value := range object {
+		if property, ok := s.Properties[name]; ok {
+			d.validate(property, value, location+"."+name, errs)
+			continue
+		}
+		if closed {
+			errs[location+"."+name] = "is not allowed"
+		} else if additional != nil {
+			d.validate(additional, value, location+"."+name, errs)
+		}
+	}
+}
+
+func inEnum(enum []any, value any) bool {
+	for _, allowed := range enum {
+		if allowed == nil {
+			continue
+		}
+		if number, ok := value.(json.Number); ok {
+			if f, ok := allowed.(float64); ok && number.String() == strconv.FormatFloat(f, 'f', -1, 64) {
+				return true
+			}
+			continue
+		}
+		if allowed == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,426 @@
+package receipt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Font is a parsed TrueType font. It is read once and shared by every
+// document; what a document uses of it is tracked by the document.
+type Font struct {
+	name       string
+	data       []byte
+	tables     map[string][]byte
+	unitsPerEm float64
+	ascent     float64
+	descent    float64
+	capHeight  float64
+	bbox       [4]float64
+	longLoca   bool
+	numGlyphs  int
+	advances   []uint16
+	glyphs     map[rune]uint16
+}
+
+// LoadFont reads the TrueType file at path. Only plain .ttf files are
+// supported, not collections or CFF-flavoured OpenType.
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	font, err := parseFont(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("font %s: %w", path, err)
+	}
+	return font, nil
+}
+
+var errBadFont = errors.New("not a supported TrueType font")
+
+func parseFont(name string, data []byte) (*Font, error) {
+	if len(data) < 12 || binary.BigEndian.Uint32(data) != 0x00010000 {
+		return nil, errBadFont
+	}
+
+	f := &Font{name: sanitizeName(name), data: data, tables: map[string][]byte{}}
+
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < count; i++ {
+		record := 12 + 16*i
+		if record+16 > len(data) {
+			return nil, errBadFont
+		}
+		tag := string(data[record : record+4])
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset+length > len(data) {
+			return nil, errBadFont
+		}
+		f.tables[tag] = data[offset : offset+length]
+	}
+
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "cmap", "loca", "glyf"} {
+		if f.tables[tag] == nil {
+			return nil, fmt.Errorf("missing %s table", tag)
+		}
+	}
+
+	head := f.tables["head"]
+	hhea := f.tables["hhea"]
+	if len(head) < 54 || len(hhea) < 36 || len(f.tables["maxp"]) < 6 {
+		return nil, errBadFont
+	}
+
+	f.unitsPerEm = float64(binary.BigEndian.Uint16(head[18:]))
+	for i := range f.bbox {
+		f.bbox[i] = f.scale(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	f.longLoca = binary.BigEndian.Uint16(head[50:]) != 0
+	f.numGlyphs = int(binary.BigEndian.Uint16(f.tables["maxp"][4:]))
+	f.ascent = f.scale(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = f.scale(int16(binary.BigEndian.Uint16(hhea[6:])))
+	f.capHeight = f.ascent
+	if os2 := f.tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		f.capHeight = f.scale(int16(binary.BigEndian.Uint16(os2[88:])))
+	}
+
+	metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := f.tables["hmtx"]
+	if metrics == 0 || len(hmtx) < 4*metrics {
+		return nil, errBadFont
+	}
+	f.advances = make([]uint16, metrics)
+	for i := range f.advances {
+		f.advances[i] = binary.BigEndian.Uint16(hmtx[4*i:])
+	}
+
+	glyphs, err := parseCmap(f.tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	f.glyphs = glyphs
+
+	return f, nil
+}
+
+// scale converts font units to thousandths of the font size, the unit PDF
+// uses for glyph metrics.
+func (f *Font) scale(v int16) float64 {
+	return float64(v) * 1000 / f.unitsPerEm
+}
+
+// glyph returns the glyph for r, or .notdef when the font lacks it.
+func (f *Font) glyph(r rune) uint16 {
+	return f.glyphs[r]
+}
+
+// advance is the width of glyph in thousandths of the font size.
+func (f *Font) advance(glyph uint16) float64 {
+	if int(glyph) >= len(f.advances) {
+		glyph = uint16(len(f.advances) - 1)
+	}
+	return float64(f.advances[glyph]) * 1000 / f.unitsPerEm
+}
+
+// Width is the width of text set at size points.
+func (f *Font) Width(text string, size float64) float64 {
+	var width float64
+	for _, r := range text {
+		width += f.advance(f.glyph(r))
+	}
+	return width * size / 1000
+}
+
+// parseCmap reads the Unicode mapping, preferring the full-range format 12
+// subtable over the BMP-only format 4 one.
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, errBadFont
+	}
+
+	var format4, format12 []byte
+	count := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < count; i++ {
+		record := 4 + 8*i
+		if record+8 > len(cmap) {
+			return nil, errBadFont
+		}
+		platform := binary.BigEndian.Uint16(cmap[record:])
+		encoding := binary.BigEndian.Uint16(cmap[record+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if offset+4 > len(cmap) {
+			return nil, errBadFont
+		}
+		if platform != 0 && !(platform == 3 && (encoding == 1 || encoding == 10)) {
+			continue
+		}
+
+		switch binary.BigEndian.Uint16(cmap[offset:]) {
+		case 4:
+			format4 = cmap[offset:]
+		case 12:
+			format12 = cmap[offset:]
+		}
+	}
+
+	glyphs := map[rune]uint16{}
+	switch {
+	case format12 != nil:
+		if len(format12) < 16 {
+			return nil, errBadFont
+		}
+		groups := int(binary.BigEndian.Uint32(format12[12:]))
+		if 16+12*groups > len(format12) {
+			return nil, errBadFont
+		}
+		for i := 0; i < groups; i++ {
+			group := format12[16+12*i:]
+			start := binary.BigEndian.Uint32(group)
+			end := binary.BigEndian.Uint32(group[4:])
+			first := binary.BigEndian.Uint32(group[8:])
+			for c := start; c <= end && c <= 0x10FFFF; c++ {
+				glyphs[rune(c)] = uint16(first + c - start)
+			}
+		}
+	case format4 != nil:
+		if len(format4) < 14 {
+			return nil, errBadFont
+		}
+		segments := int(binary.BigEndian.Uint16(format4[6:])) / 2
+		ends := 14
+		starts := ends + 2*segments + 2
+		deltas := starts + 2*segments
+		ranges := deltas + 2*segments
+		if ranges+2*segments > len(format4) {
+			return nil, errBadFont
+		}
+		for i := 0; i < segments; i++ {
+			end := binary.BigEndian.Uint16(format4[ends+2*i:])
+			start := binary.BigEndian.Uint16(format4[starts+2*i:])
+			delta := binary.BigEndian.Uint16(format4[deltas+2*i:])
+			rangeOffset := int(binary.BigEndian.Uint16(format4[ranges+2*i:]))
+			for c := uint32(start); c <= uint32(end) && c != 0xFFFF; c++ {
+				var glyph uint16
+				if rangeOffset == 0 {
+					glyph = uint16(c) + delta
+				} else {
+					at := ranges + 2*i + rangeOffset + 2*int(c-uint32(start))
+					if at+2 > len(format4) {
+						continue
+					}
+					if glyph = binary.BigEndian.Uint16(format4[at:]); glyph != 0 {
+						glyph += delta
+					}
+				}
+				if glyph != 0 {
+					glyphs[rune(c)] = glyph
+				}
+			}
+		}
+	default:
+		return nil, errors.New("no Unicode cmap")
+	}
+
+	return glyphs, nil
+}
+
+// glyphRange is where glyph lives in the glyf table.
+func (f *Font) glyphRange(glyph uint16) (int, int) {
+	loca := f.tables["loca"]
+	i := int(glyph)
+	if f.longLoca {
+		if 4*i+8 > len(loca) {
+			return 0, 0
+		}
+		return int(binary.BigEndian.Uint32(loca[4*i:])), int(binary.BigEndian.Uint32(loca[4*i+4:]))
+	}
+	if 2*i+4 > len(loca) {
+		return 0, 0
+	}
+	return 2 * int(binary.BigEndian.Uint16(loca[2*i:])), 2 * int(binary.BigEndian.Uint16(loca[2*i+2:]))
+}
+
+// Composite glyph flags.
+const (
+	argsAreWords   = 0x0001
+	haveScale      = 0x0008
+	moreComponents = 0x0020
+	haveXYScale    = 0x0040
+	haveTwoByTwo   = 0x0080
+)
+
+// components lists the glyphs a composite glyph is built from.
+func (f *Font) components(glyph uint16) []uint16 {
+	start, end := f.glyphRange(glyph)
+	glyf := f.tables["glyf"]
+	if end-start < 10 || end > len(glyf) || int16(binary.BigEndian.Uint16(glyf[start:])) >= 0 {
+		return nil
+	}
+
+	var parts []uint16
+	for at := start + 10; at+4 <= end; {
+		flags := binary.BigEndian.Uint16(glyf[at:])
+		parts = append(parts, binary.BigEndian.Uint16(glyf[at+2:]))
+
+		at += 4
+		if flags&argsAreWords != 0 {
+			at += 4
+		} else {
+			at += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			at += 2
+		case flags&haveXYScale != 0:
+			at += 4
+		case flags&haveTwoByTwo != 0:
+			at += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return parts
+}
+
+// subset returns a copy of the font where every glyph outside used is
+// empty. Glyph ids stay the same, so text can be written with the ids the
+// full font gives, and the file shrinks to the outlines actually drawn.
+func (f *Font) subset(used map[uint16]rune) []byte {
+	keep := map[uint16]bool{}
+	var visit func(uint16)
+	visit = func(glyph uint16) {
+		if keep[glyph] {
+			return
+		}
+		keep[glyph] = true
+		for _, part := range f.components(glyph) {
+			visit(part)
+		}
+	}
+	visit(0)
+	for glyph := range used {
+		visit(glyph)
+	}
+
+	glyf := f.tables["glyf"]
+	var outlines bytes.Buffer
+	loca := make([]byte, 4*(f.numGlyphs+1))
+	for glyph := 0; glyph < f.numGlyphs; glyph++ {
+		binary.BigEndian.PutUint32(loca[4*glyph:], uint32(outlines.Len()))
+		if !keep[uint16(glyph)] {
+			continue
+		}
+		start, end := f.glyphRange(uint16(glyph))
+		if start < end && end <= len(glyf) {
+			outlines.Write(glyf[start:end])
+			for outlines.Len()%4 != 0 {
+				outlines.WriteByte(0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*f.numGlyphs:], uint32(outlines.Len()))
+
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+
+	tables := map[string][]byte{
+		"head": head,
+		"hhea": f.tables["hhea"],
+		"maxp": f.tables["maxp"],
+		"hmtx": f.tables["hmtx"],
+		"loca": loca,
+		"glyf": outlines.Bytes(),
+	}
+	// Hinting programs are kept; some viewers render badly without them.
+	for _, tag := range []string{"cvt ", "fpgm", "prep"} {
+		if table := f.tables[tag]; table != nil {
+			tables[tag] = table
+		}
+	}
+
+	return writeFont(tables)
+}
+
+func writeFont(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	count := len(tags)
+	searchRange, selector := 1, 0
+	for searchRange*2 <= count {
+		searchRange *= 2
+		selector++
+	}
+
+	var out bytes.Buffer
+	header := make([]byte, 12+16*count)
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(count))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange*16))
+	binary.BigEndian.PutUint16(header[8:], uint16(selector))
+	binary.BigEndian.PutUint16(header[10:], uint16(count*16-searchRange*16))
+
+	offset := len(header)
+	for i, tag := range tags {
+		table := tables[tag]
+		record := header[12+16*i:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], checksum(table))
+		binary.BigEndian.PutUint32(record[8:], uint32(offset))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table)))
+		offset += (len(table) + 3) &^ 3
+	}
+
+	out.Write(header)
+	headAt := 0
+	for _, tag := range tags {
+		if tag == "head" {
+			headAt = out.Len()
+		}
+		out.Write(tables[tag])
+		for out.Len()%4 != 0 {
+			out.WriteByte(0)
+		}
+	}
+
+	data := out.Bytes()
+	binary.BigEndian.PutUint32(data[headAt+8:], 0xB1B0AFBA-checksum(data))
+	return data
+}
+
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+func sanitizeName(name string) string {
+	clean := strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return -1
+	}, name)
+	if clean == "" {
+		return "Font"
+	}
+	return clean
+}
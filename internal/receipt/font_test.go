@@ -0,0 +1,187 @@
+package receipt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+)
+
+// Glyphs of the test font: .notdef, A and B drawn, C built from A and B,
+// and D drawn.
+var testGlyphs = [][]byte{
+	simpleGlyph(0),
+	simpleGlyph(1),
+	simpleGlyph(2),
+	compositeGlyph(1, 2),
+	simpleGlyph(4),
+}
+
+// simpleGlyph is an outline whose bytes are marked with n, enough to tell
+// the glyphs apart in a subset.
+func simpleGlyph(n byte) []byte {
+	return []byte{0, 1, 0, 0, 0, 0, 0, 100, 0, 100, 0, 0, 0, 0, n, n}
+}
+
+// compositeGlyph places a with word offsets and b with a scale, so both
+// component layouts are walked.
+func compositeGlyph(a, b uint16) []byte {
+	glyph := []byte{0xFF, 0xFF, 0, 0, 0, 0, 0, 100, 0, 100}
+	glyph = binary.BigEndian.AppendUint16(glyph, argsAreWords|moreComponents)
+	glyph = binary.BigEndian.AppendUint16(glyph, a)
+	glyph = append(glyph, 0, 10, 0, 20)
+	glyph = binary.BigEndian.AppendUint16(glyph, haveScale)
+	glyph = binary.BigEndian.AppendUint16(glyph, b)
+	glyph = append(glyph, 5, 5, 0x40, 0)
+	return glyph
+}
+
+// testFontData is a TrueType file mapping A to D to glyphs 1 to 4, with
+// advances of 500 to 900 units of a 1000 unit em.
+func testFontData() []byte {
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint32(head[12:], 0x5F0F3CF5)
+	binary.BigEndian.PutUint16(head[18:], 1000)
+	binary.BigEndian.PutUint16(head[40:], 900)
+	binary.BigEndian.PutUint16(head[42:], 800)
+
+	hhea := make([]byte, 36)
+	binary.BigEndian.PutUint16(hhea[4:], 800)
+	binary.BigEndian.PutUint16(hhea[6:], uint16(0x10000-200))
+	binary.BigEndian.PutUint16(hhea[34:], uint16(len(testGlyphs)))
+
+	maxp := make([]byte, 6)
+	binary.BigEndian.PutUint32(maxp, 0x00005000)
+	binary.BigEndian.PutUint16(maxp[4:], uint16(len(testGlyphs)))
+
+	var hmtx, glyf, loca []byte
+	for i, glyph := range testGlyphs {
+		hmtx = binary.BigEndian.AppendUint16(hmtx, uint16(500+100*i))
+		hmtx = binary.BigEndian.AppendUint16(hmtx, 0)
+		loca = binary.BigEndian.AppendUint16(loca, uint16(len(glyf)/2))
+		glyf = append(glyf, glyph...)
+	}
+	loca = binary.BigEndian.AppendUint16(loca, uint16(len(glyf)/2))
+
+	// A format 4 subtable with the segment A-D and the closing 0xFFFF one.
+	var format4 []byte
+	for _, v := range []uint16{4, 32, 0, 4, 4, 1, 0} {
+		format4 = binary.BigEndian.AppendUint16(format4, v)
+	}
+	for _, v := range []uint16{'D', 0xFFFF, 0, 'A', 0xFFFF, 0x10000 + 1 - 'A', 1, 0, 0} {
+		format4 = binary.BigEndian.AppendUint16(format4, v)
+	}
+	cmap := []byte{0, 0, 0, 1, 0, 3, 0, 1, 0, 0, 0, 12}
+	cmap = append(cmap, format4...)
+
+	return writeFont(map[string][]byte{
+		"head": head, "hhea": hhea, "maxp": maxp, "hmtx": hmtx,
+		"cmap": cmap, "loca": loca, "glyf": glyf,
+	})
+}
+
+func testFont(t *testing.T) *Font {
+	font, err := parseFont("Test Sans", testFontData())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return font
+}
+
+func TestParseFont(t *testing.T) {
+	font := testFont(t)
+
+	if font.name != "TestSans" {
+		t.Errorf("name = %q, want TestSans", font.name)
+	}
+	for r, want := range map[rune]uint16{'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 0, 'Ж': 0} {
+		if got := font.glyph(r); got != want {
+			t.Errorf("glyph(%q) = %d, want %d", r, got, want)
+		}
+	}
+	if font.ascent != 800 || font.descent != -200 || font.bbox[2] != 900 {
+		t.Errorf("ascent %v, descent %v, bbox %v", font.ascent, font.descent, font.bbox)
+	}
+	if got := font.Width("AB", 10); got != 13 {
+		t.Errorf("Width(AB, 10) = %v, want 13", got)
+	}
+	if parts := font.components(3); len(parts) != 2 || parts[0] != 1 || parts[1] != 2 {
+		t.Errorf("components(C) = %v, want [1 2]", parts)
+	}
+	if parts := font.components(1); parts != nil {
+		t.Errorf("components(A) = %v, want none", parts)
+	}
+}
+
+func TestParseFontRejects(t *testing.T) {
+	data := testFontData()
+	cases := map[string][]byte{
+		"empty":     nil,
+		"OpenType":  append([]byte("OTTO"), data[4:]...),
+		"truncated": data[:len(data)/2],
+	}
+	for name, data := range cases {
+		if _, err := parseFont("Bad", data); err == nil {
+			t.Errorf("%s: parsed", name)
+		}
+	}
+}
+
+func TestSubset(t *testing.T) {
+	font := testFont(t)
+	file := font.subset(map[uint16]rune{3: 'C'})
+
+	if sum := checksum(file); sum != 0xB1B0AFBA {
+		t.Errorf("file checksum = %#x, want 0xB1B0AFBA", sum)
+	}
+
+	tables := map[string][]byte{}
+	count := int(binary.BigEndian.Uint16(file[4:]))
+	for i := 0; i < count; i++ {
+		record := file[12+16*i:]
+		tag := string(record[:4])
+		offset := binary.BigEndian.Uint32(record[8:])
+		table := file[offset : offset+binary.BigEndian.Uint32(record[12:])]
+		if tag != "head" && checksum(table) != binary.BigEndian.Uint32(record[4:]) {
+			t.Errorf("%s checksum does not match", tag)
+		}
+		tables[tag] = table
+	}
+	if _, ok := tables["cmap"]; ok {
+		t.Error("the subset keeps the cmap")
+	}
+
+	// C pulls in A and B; D is dropped. The subset has long offsets.
+	loca, glyf := tables["loca"], tables["glyf"]
+	for glyph, want := range testGlyphs {
+		start := binary.BigEndian.Uint32(loca[4*glyph:])
+		end := binary.BigEndian.Uint32(loca[4*glyph+4:])
+		got := glyf[start:end]
+		if glyph == 4 {
+			want = nil
+		}
+		if !bytes.Equal(bytes.TrimRight(got, "\x00"), bytes.TrimRight(want, "\x00")) {
+			t.Errorf("glyph %d = %x, want %x", glyph, got, want)
+		}
+	}
+}
+
+func TestLoadFontDejaVu(t *testing.T) {
+	const path = "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"
+	if _, err := os.Stat(path); err != nil {
+		t.Skip("DejaVu Sans is not installed")
+	}
+
+	font, err := LoadFont(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range "Aa1Жжʼ$" {
+		if font.glyph(r) == 0 {
+			t.Errorf("no glyph for %q", r)
+		}
+	}
+	if sum := checksum(font.subset(map[uint16]rune{font.glyph('Ж'): 'Ж'})); sum != 0xB1B0AFBA {
+		t.Errorf("subset checksum = %#x", sum)
+	}
+}
@@ -0,0 +1,240 @@
+package receipt
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+// Document is a PDF being built in memory. Pages draw with fonts shared
+// between documents; Document remembers which glyphs each font drew so only
+// those end up embedded.
+type Document struct {
+	pages []*Page
+	fonts []*Font
+	used  map[*Font]map[uint16]rune
+}
+
+func NewDocument() *Document {
+	return &Document{used: map[*Font]map[uint16]rune{}}
+}
+
+// Page is one page. Coordinates are in points from the bottom left corner,
+// as in PDF itself.
+type Page struct {
+	doc     *Document
+	width   float64
+	height  float64
+	content bytes.Buffer
+}
+
+func (d *Document) AddPage(width, height float64) *Page {
+	page := &Page{doc: d, width: width, height: height}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+func (d *Document) fontIndex(font *Font) int {
+	for i, f := range d.fonts {
+		if f == font {
+			return i
+		}
+	}
+	d.fonts = append(d.fonts, font)
+	d.used[font] = map[uint16]rune{}
+	return len(d.fonts) - 1
+}
+
+// Text draws text with its baseline starting at x, y.
+func (p *Page) Text(font *Font, size, x, y float64, text string) {
+	index := p.doc.fontIndex(font)
+	used := p.doc.used[font]
+
+	var glyphs bytes.Buffer
+	for _, r := range text {
+		glyph := font.glyph(r)
+		if _, ok := used[glyph]; !ok {
+			used[glyph] = r
+		}
+		fmt.Fprintf(&glyphs, "%04X", glyph)
+	}
+
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td <%s> Tj ET\n", index, num(size), num(x), num(y), glyphs.String())
+}
+
+// Line strokes a line width points thick.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// Rect fills a black rectangle with its bottom left corner at x, y.
+func (p *Page) Rect(x, y, width, height float64) {
+	fmt.Fprintf(&p.content, "%s %s %s %s re f\n", num(x), num(y), num(width), num(height))
+}
+
+// WriteTo writes the finished PDF.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	pdf := &pdfWriter{offsets: map[int]int{}}
+	pdf.buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	// Objects 1 and 2 are the catalog and the page tree; everything else is
+	// numbered as it is written.
+	pdf.next = 3
+
+	fontRefs := make([]int, len(d.fonts))
+	for i, font := range d.fonts {
+		fontRefs[i] = pdf.writeFont(font, d.used[font], i)
+	}
+
+	var resources bytes.Buffer
+	resources.WriteString("<< /Font <<")
+	for i, ref := range fontRefs {
+		fmt.Fprintf(&resources, " /F%d %d 0 R", i, ref)
+	}
+	resources.WriteString(" >> >>")
+
+	kids := make([]int, len(d.pages))
+	for i, page := range d.pages {
+		content := pdf.stream(page.content.Bytes(), "")
+		kids[i] = pdf.object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>",
+			num(page.width), num(page.height), resources.String(), content,
+		))
+	}
+
+	var pages bytes.Buffer
+	fmt.Fprintf(&pages, "<< /Type /Pages /Count %d /Kids [", len(kids))
+	for _, kid := range kids {
+		fmt.Fprintf(&pages, " %d 0 R", kid)
+	}
+	pages.WriteString(" ] >>")
+
+	pdf.objectAt(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	pdf.objectAt(2, pages.String())
+
+	pdf.finish()
+	n, err := w.Write(pdf.buf.Bytes())
+	return int64(n), err
+}
+
+type pdfWriter struct {
+	buf     bytes.Buffer
+	next    int
+	offsets map[int]int
+}
+
+func (pdf *pdfWriter) objectAt(id int, body string) {
+	pdf.offsets[id] = pdf.buf.Len()
+	fmt.Fprintf(&pdf.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (pdf *pdfWriter) object(body string) int {
+	id := pdf.next
+	pdf.next++
+	pdf.objectAt(id, body)
+	return id
+}
+
+// stream writes data zlib compressed. extra goes into the stream
+// dictionary as is.
+func (pdf *pdfWriter) stream(data []byte, extra string) int {
+	var compressed bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&compressed, zlib.BestCompression)
+	zw.Write(data)
+	zw.Close()
+
+	id := pdf.next
+	pdf.next++
+	pdf.offsets[id] = pdf.buf.Len()
+	fmt.Fprintf(&pdf.buf, "%d 0 obj\n<< /Length %d /Filter /FlateDecode%s >>\nstream\n", id, compressed.Len(), extra)
+	pdf.buf.Write(compressed.Bytes())
+	pdf.buf.WriteString("\nendstream\nendobj\n")
+	return id
+}
+
+// writeFont embeds font as a Type0 font with Identity-H encoding, so text
+// is written as glyph ids, and returns the font's object number.
+func (pdf *pdfWriter) writeFont(font *Font, used map[uint16]rune, index int) int {
+	glyphs := make([]int, 0, len(used))
+	for glyph := range used {
+		glyphs = append(glyphs, int(glyph))
+	}
+	sort.Ints(glyphs)
+
+	// Subset fonts are named with a six letter tag that differs per font.
+	name := fmt.Sprintf("RCPT%c%c+%s", 'A'+index/26%26, 'A'+index%26, font.name)
+
+	file := font.subset(used)
+	fileRef := pdf.stream(file, fmt.Sprintf(" /Length1 %d", len(file)))
+
+	descriptor := pdf.object(fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%s %s %s %s] /ItalicAngle 0 /Ascent %s /Descent %s /CapHeight %s /StemV 80 /FontFile2 %d 0 R >>",
+		name, num(font.bbox[0]), num(font.bbox[1]), num(font.bbox[2]), num(font.bbox[3]),
+		num(font.ascent), num(font.descent), num(font.capHeight), fileRef,
+	))
+
+	var widths bytes.Buffer
+	widths.WriteString("[")
+	for _, glyph := range glyphs {
+		fmt.Fprintf(&widths, " %d [%s]", glyph, num(font.advance(uint16(glyph))))
+	}
+	widths.WriteString(" ]")
+
+	cid := pdf.object(fmt.Sprintf(
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /W %s /CIDToGIDMap /Identity >>",
+		name, descriptor, widths.String(),
+	))
+
+	toUnicode := pdf.stream(toUnicodeCMap(glyphs, used), "")
+
+	return pdf.object(fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		name, cid, toUnicode,
+	))
+}
+
+// toUnicodeCMap lets viewers copy and search the text.
+func toUnicodeCMap(glyphs []int, used map[uint16]rune) []byte {
+	var cmap bytes.Buffer
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	cmap.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	cmap.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	cmap.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	for start := 0; start < len(glyphs); start += 100 {
+		end := min(start+100, len(glyphs))
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", end-start)
+		for _, glyph := range glyphs[start:end] {
+			fmt.Fprintf(&cmap, "<%04X> <", glyph)
+			for _, unit := range utf16.Encode([]rune{used[uint16(glyph)]}) {
+				fmt.Fprintf(&cmap, "%04X", unit)
+			}
+			cmap.WriteString(">\n")
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return cmap.Bytes()
+}
+
+func (pdf *pdfWriter) finish() {
+	xref := pdf.buf.Len()
+	size := pdf.next
+
+	fmt.Fprintf(&pdf.buf, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for id := 1; id < size; id++ {
+		fmt.Fprintf(&pdf.buf, "%010d 00000 n \n", pdf.offsets[id])
+	}
+	fmt.Fprintf(&pdf.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", size, xref)
+}
+
+// num formats a coordinate with at most two decimals.
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,98 @@
+package receipt
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDocumentXref(t *testing.T) {
+	font := testFont(t)
+	doc := NewDocument()
+	first := doc.AddPage(200, 100)
+	first.Text(font, 12, 10, 50, "AB")
+	first.Line(0, 0, 200, 0, 0.5)
+	doc.AddPage(200, 100).Text(font, 12.345, 10, 50, "CE")
+
+	var out bytes.Buffer
+	if _, err := doc.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	pdf := out.Bytes()
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("not framed as a PDF")
+	}
+
+	tail := regexp.MustCompile(`trailer\n<< /Size (\d+) /Root 1 0 R >>\nstartxref\n(\d+)\n%%EOF\n$`).FindSubmatch(pdf)
+	if tail == nil {
+		t.Fatalf("no trailer:\n%s", pdf[max(0, len(pdf)-200):])
+	}
+	size, _ := strconv.Atoi(string(tail[1]))
+	xref, _ := strconv.Atoi(string(tail[2]))
+
+	table := string(pdf[xref:])
+	header := fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", size)
+	if !strings.HasPrefix(table, header) {
+		t.Fatalf("startxref %d does not point at the table: %.40q", xref, table)
+	}
+	entries := strings.Split(strings.TrimPrefix(table, header), "\n")
+	for id := 1; id < size; id++ {
+		var offset int
+		if _, err := fmt.Sscanf(entries[id-1], "%010d 00000 n ", &offset); err != nil || len(entries[id-1]) != 19 {
+			t.Fatalf("entry %d is %q", id, entries[id-1])
+		}
+		if want := fmt.Sprintf("%d 0 obj\n", id); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+			t.Errorf("object %d: offset %d points at %.20q", id, offset, pdf[offset:])
+		}
+	}
+
+	streams := regexp.MustCompile(`(?s)<< /Length (\d+) /Filter /FlateDecode[^>]*>>\nstream\n`).FindAllSubmatchIndex(pdf, -1)
+	var contents []string
+	for _, match := range streams {
+		length, _ := strconv.Atoi(string(pdf[match[2]:match[3]]))
+		data := pdf[match[1]:]
+		if !bytes.HasPrefix(data[length:], []byte("\nendstream")) {
+			t.Fatalf("stream at %d is not %d bytes long", match[1], length)
+		}
+		zr, err := zlib.NewReader(bytes.NewReader(data[:length]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents = append(contents, string(content))
+	}
+
+	// One font file, one ToUnicode map and one content stream per page.
+	if len(contents) != 4 {
+		t.Fatalf("%d streams, want 4", len(contents))
+	}
+	all := strings.Join(contents, "\n")
+	for _, want := range []string{
+		"BT /F0 12 Tf 10 50 Td <00010002> Tj ET",
+		"0.5 w 0 0 m 200 0 l S",
+		"BT /F0 12.35 Tf 10 50 Td <00030000> Tj ET",
+		"<0001> <0041>",
+		"<0003> <0043>",
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("no %q in the streams", want)
+		}
+	}
+
+	// Widths are listed for the drawn glyphs only, in thousandths of the em.
+	if !bytes.Contains(pdf, []byte("/W [ 0 [500] 1 [600] 2 [700] 3 [800] ]")) {
+		t.Error("widths are not those of the drawn glyphs")
+	}
+	if count := bytes.Count(pdf, []byte("/Type /Page ")); count != 2 {
+		t.Errorf("%d pages, want 2", count)
+	}
+}
@@ -0,0 +1,460 @@
+package receipt
+
+import (
+	"errors"
+)
+
+// QR codes are encoded in byte mode at error correction level M, which
+// survives a smudged or creased receipt. Versions up to 10 hold 213 bytes,
+// plenty for a verification link.
+const QR_MAX_VERSION = 10
+
+var ErrQRTooLong = errors.New("text is too long for a QR code")
+
+// Error correction codewords per block and number of blocks at level M, by
+// version.
+var (
+	qrECCPerBlock = [QR_MAX_VERSION + 1]int{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26}
+	qrBlocks      = [QR_MAX_VERSION + 1]int{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5}
+)
+
+// QR is an encoded symbol. Dark modules are true; the quiet zone around the
+// symbol is not included.
+type QR struct {
+	Size    int
+	modules [][]bool
+	// function marks finder, timing, alignment and format modules, which
+	// data and masking leave alone.
+	function [][]bool
+}
+
+func (q *QR) Dark(x, y int) bool {
+	return q.modules[y][x]
+}
+
+// EncodeQR encodes text in the smallest version that fits it.
+func EncodeQR(text string) (*QR, error) {
+	data := []byte(text)
+
+	version := 1
+	for ; version <= QR_MAX_VERSION; version++ {
+		countBits := 8
+		if version >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= 8*qrDataCodewords(version) {
+			break
+		}
+	}
+	if version > QR_MAX_VERSION {
+		return nil, ErrQRTooLong
+	}
+
+	bits := &bitBuffer{}
+	bits.append(0b0100, 4)
+	if version >= 10 {
+		bits.append(len(data), 16)
+	} else {
+		bits.append(len(data), 8)
+	}
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	capacity := 8 * qrDataCodewords(version)
+	bits.append(0, min(4, capacity-len(*bits)))
+	bits.append(0, (8-len(*bits)%8)%8)
+	for pad := 0xEC; len(*bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	codewords := make([]byte, len(*bits)/8)
+	for i, bit := range *bits {
+		if bit {
+			codewords[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+
+	q := newQR(version)
+	q.drawFunctionPatterns(version)
+	q.drawCodewords(qrInterleave(version, codewords))
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		if penalty := q.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormatBits(best)
+
+	return q, nil
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, value>>i&1 != 0)
+	}
+}
+
+func newQR(version int) *QR {
+	size := 4*version + 17
+	q := &QR{Size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for i := range q.modules {
+		q.modules[i] = make([]bool, size)
+		q.function[i] = make([]bool, size)
+	}
+	return q
+}
+
+func (q *QR) setFunction(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+func (q *QR) drawFunctionPatterns(version int) {
+	for i := 0; i < q.Size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+
+	q.drawFinder(3, 3)
+	q.drawFinder(q.Size-4, 3)
+	q.drawFinder(3, q.Size-4)
+
+	positions := qrAlignmentPositions(version, q.Size)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas; the real bits are drawn per mask.
+	q.drawFormatBits(0)
+
+	if version >= 7 {
+		rem := version
+		for i := 0; i < 12; i++ {
+			rem = rem<<1 ^ (rem>>11)*0x1F25
+		}
+		bits := version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := bits>>i&1 != 0
+			a, b := q.Size-11+i%3, i/3
+			q.setFunction(a, b, dark)
+			q.setFunction(b, a, dark)
+		}
+	}
+}
+
+// drawFinder draws a finder pattern with its separator around x, y.
+func (q *QR) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= q.Size || yy < 0 || yy >= q.Size {
+				continue
+			}
+			distance := max(abs(dx), abs(dy))
+			q.setFunction(xx, yy, distance != 2 && distance != 4)
+		}
+	}
+}
+
+func qrAlignmentPositions(version, size int) []int {
+	if version == 1 {
+		return nil
+	}
+	count := version/7 + 2
+	step := (version*4 + count*2 + 1) / (count*2 - 2) * 2
+
+	positions := make([]int, count)
+	positions[0] = 6
+	for i, at := count-1, size-7; i >= 1; i, at = i-1, at-step {
+		positions[i] = at
+	}
+	return positions
+}
+
+// drawFormatBits writes the format information for mask. Level M is 00,
+// so the mask number is all of the data.
+func (q *QR) drawFormatBits(mask int) {
+	data := mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>i&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.setFunction(q.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.Size-15+i, bit(i))
+	}
+	q.setFunction(8, q.Size-8, true)
+}
+
+// qrRawModules is how many modules of a version carry data and error
+// correction, everything but the function patterns.
+func qrRawModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		count := version/7 + 2
+		result -= (25*count-10)*count - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+func qrDataCodewords(version int) int {
+	return qrRawModules(version)/8 - qrECCPerBlock[version]*qrBlocks[version]
+}
+
+// qrInterleave splits data into blocks, adds Reed-Solomon error correction
+// to each and interleaves them in the order they are placed.
+func qrInterleave(version int, data []byte) []byte {
+	blocks := qrBlocks[version]
+	eccLength := qrECCPerBlock[version]
+	raw := qrRawModules(version) / 8
+	shortBlocks := blocks - raw%blocks
+	shortLength := raw / blocks
+	divisor := reedSolomonDivisor(eccLength)
+
+	all := make([][]byte, blocks)
+	for i, at := 0, 0; i < blocks; i++ {
+		length := shortLength - eccLength
+		if i >= shortBlocks {
+			length++
+		}
+		block := append([]byte(nil), data[at:at+length]...)
+		at += length
+		ecc := reedSolomonRemainder(block, divisor)
+		if i < shortBlocks {
+			block = append(block, 0)
+		}
+		all[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, raw)
+	for i := range all[0] {
+		for j, block := range all {
+			if i != shortLength-eccLength || j >= shortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+// drawCodewords places data in the zigzag order, two columns at a time
+// from the bottom right, skipping the vertical timing pattern.
+func (q *QR) drawCodewords(data []byte) {
+	i := 0
+	for right := q.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vertical := 0; vertical < q.Size; vertical++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vertical
+				if (right+1)&2 == 0 {
+					y = q.Size - 1 - vertical
+				}
+				if !q.function[y][x] && i < len(data)*8 {
+					q.modules[y][x] = data[i>>3]>>(7-i&7)&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules selected by mask; applying it twice
+// undoes it.
+func (q *QR) applyMask(mask int) {
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !q.function[y][x] {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores how hard the symbol is to scan: long runs of one colour,
+// 2x2 blocks, finder-like patterns and an uneven dark to light balance.
+func (q *QR) penalty() int {
+	penalty := 0
+	line := make([]bool, q.Size)
+
+	for pass := 0; pass < 2; pass++ {
+		for a := 0; a < q.Size; a++ {
+			for b := 0; b < q.Size; b++ {
+				if pass == 0 {
+					line[b] = q.modules[a][b]
+				} else {
+					line[b] = q.modules[b][a]
+				}
+			}
+			penalty += runPenalty(line) + finderPenalty(line)
+		}
+	}
+
+	dark := 0
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < q.Size && y+1 < q.Size {
+				color := q.modules[y][x]
+				if color == q.modules[y][x+1] && color == q.modules[y+1][x] && color == q.modules[y+1][x+1] {
+					penalty += 3
+				}
+			}
+		}
+	}
+
+	// 10 points for every 5% the dark share is off 50%.
+	total := q.Size * q.Size
+	penalty += ((abs(dark*20-total*10)+total-1)/total - 1) * 10
+
+	return penalty
+}
+
+func runPenalty(line []bool) int {
+	penalty := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			penalty += run - 2
+		}
+		run = 1
+	}
+	return penalty
+}
+
+var qrFinderLike = []bool{true, false, true, true, true, false, true}
+
+func finderPenalty(line []bool) int {
+	penalty := 0
+	light := func(from, to int) bool {
+		for i := from; i < to; i++ {
+			if i >= 0 && i < len(line) && line[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for i := 0; i+len(qrFinderLike) <= len(line); i++ {
+		match := true
+		for j, dark := range qrFinderLike {
+			if line[i+j] != dark {
+				match = false
+				break
+			}
+		}
+		if match && (light(i-4, i) || light(i+7, i+11)) {
+			penalty += 40
+		}
+	}
+	return penalty
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
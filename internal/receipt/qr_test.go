@@ -0,0 +1,384 @@
+package receipt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// The tables below are copied from the QR code specification rather than
+// computed, so the decoder in this file checks the encoder instead of
+// repeating it.
+
+// Byte mode capacity at level M, by version.
+var qrCapacityM = [QR_MAX_VERSION + 1]int{0, 14, 26, 42, 62, 84, 106, 122, 152, 180, 213}
+
+// Format information for level M, by mask.
+var qrFormatM = [8]int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+
+// Version information, by version from 7.
+var qrVersionInfo = map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3}
+
+var qrAlignmentCentres = [QR_MAX_VERSION + 1][]int{
+	nil, nil, {6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34}, {6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50},
+}
+
+// Error correction codewords per block and the blocks as count and data
+// codewords each, at level M.
+var qrBlocksM = [QR_MAX_VERSION + 1]struct {
+	ecc    int
+	groups [][2]int
+}{
+	{},
+	{10, [][2]int{{1, 16}}},
+	{16, [][2]int{{1, 28}}},
+	{26, [][2]int{{1, 44}}},
+	{18, [][2]int{{2, 32}}},
+	{24, [][2]int{{2, 43}}},
+	{16, [][2]int{{4, 27}}},
+	{18, [][2]int{{4, 31}}},
+	{22, [][2]int{{2, 38}, {2, 39}}},
+	{22, [][2]int{{3, 36}, {2, 37}}},
+	{26, [][2]int{{4, 43}, {1, 44}}},
+}
+
+func qrText(n int) string {
+	const alphabet = "https://example.com/r/AbC-123_xyz/Ёж/"
+	var b strings.Builder
+	for b.Len() < n {
+		b.WriteString(alphabet)
+	}
+	return b.String()[:n]
+}
+
+func TestEncodeQRDecodes(t *testing.T) {
+	texts := []string{"", "a", "https://pay.example.uz/receipts/verify/abc.def"}
+	for version := 1; version <= QR_MAX_VERSION; version++ {
+		texts = append(texts, qrText(qrCapacityM[version]))
+	}
+
+	for _, text := range texts {
+		q, err := EncodeQR(text)
+		if err != nil {
+			t.Fatalf("EncodeQR(%d bytes): %v", len(text), err)
+		}
+		got, err := decodeQR(q)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", len(text), err)
+		}
+		if got != text {
+			t.Fatalf("decoded %q, want %q", got, text)
+		}
+	}
+}
+
+func TestEncodeQRVersions(t *testing.T) {
+	for version := 1; version <= QR_MAX_VERSION; version++ {
+		q, err := EncodeQR(qrText(qrCapacityM[version]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := (q.Size - 17) / 4; got != version {
+			t.Errorf("%d bytes: version %d, want %d", qrCapacityM[version], got, version)
+		}
+
+		if version == QR_MAX_VERSION {
+			continue
+		}
+		q, err = EncodeQR(qrText(qrCapacityM[version] + 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := (q.Size - 17) / 4; got != version+1 {
+			t.Errorf("%d bytes: version %d, want %d", qrCapacityM[version]+1, got, version+1)
+		}
+	}
+
+	if _, err := EncodeQR(qrText(qrCapacityM[QR_MAX_VERSION] + 1)); !errors.Is(err, ErrQRTooLong) {
+		t.Errorf("err = %v, want ErrQRTooLong", err)
+	}
+}
+
+// decodeQR reads q back: it checks the fixed patterns, the format and
+// version information and the error correction, and returns the text.
+func decodeQR(q *QR) (string, error) {
+	version := (q.Size - 17) / 4
+	if version < 1 || version > QR_MAX_VERSION || q.Size != 4*version+17 {
+		return "", errors.New("bad size")
+	}
+	size := q.Size
+
+	for _, corner := range [][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for dy := 0; dy < 7; dy++ {
+			for dx := 0; dx < 7; dx++ {
+				ring := max(abs(dx-3), abs(dy-3))
+				if q.Dark(corner[0]+dx, corner[1]+dy) != (ring != 2) {
+					return "", errors.New("bad finder pattern")
+				}
+			}
+		}
+	}
+	for i := 8; i < size-8; i++ {
+		if q.Dark(i, 6) != (i%2 == 0) || q.Dark(6, i) != (i%2 == 0) {
+			return "", errors.New("bad timing pattern")
+		}
+	}
+	if !q.Dark(8, size-8) {
+		return "", errors.New("dark module is light")
+	}
+
+	var first, second int
+	for i := 0; i < 15; i++ {
+		var x, y int
+		switch {
+		case i < 6:
+			x, y = 8, i
+		case i < 8:
+			x, y = 8, i+1
+		case i == 8:
+			x, y = 7, 8
+		default:
+			x, y = 14-i, 8
+		}
+		if q.Dark(x, y) {
+			first |= 1 << i
+		}
+		if i < 8 {
+			x, y = size-1-i, 8
+		} else {
+			x, y = 8, size-15+i
+		}
+		if q.Dark(x, y) {
+			second |= 1 << i
+		}
+	}
+	if first != second {
+		return "", errors.New("format copies differ")
+	}
+	mask := -1
+	for m, format := range qrFormatM {
+		if format == first {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		return "", errors.New("format is not level M")
+	}
+
+	if version >= 7 {
+		var top, left int
+		for i := 0; i < 18; i++ {
+			if q.Dark(size-11+i%3, i/3) {
+				top |= 1 << i
+			}
+			if q.Dark(i/3, size-11+i%3) {
+				left |= 1 << i
+			}
+		}
+		if top != qrVersionInfo[version] || left != qrVersionInfo[version] {
+			return "", errors.New("bad version information")
+		}
+	}
+
+	function := qrFunctionModules(version)
+	var bits []bool
+	up := true
+	for right := size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for k := 0; k < size; k++ {
+			y := k
+			if up {
+				y = size - 1 - k
+			}
+			for _, x := range []int{right, right - 1} {
+				if !function[y][x] {
+					bits = append(bits, q.Dark(x, y) != qrMasked(mask, y, x))
+				}
+			}
+		}
+		up = !up
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i := range codewords {
+		for _, bit := range bits[8*i : 8*i+8] {
+			codewords[i] <<= 1
+			if bit {
+				codewords[i] |= 1
+			}
+		}
+	}
+
+	spec := qrBlocksM[version]
+	var blocks [][]byte
+	var lengths []int
+	for _, group := range spec.groups {
+		for i := 0; i < group[0]; i++ {
+			blocks = append(blocks, nil)
+			lengths = append(lengths, group[1])
+		}
+	}
+	at := 0
+	for i := 0; i < lengths[len(lengths)-1]; i++ {
+		for b := range blocks {
+			if i < lengths[b] {
+				blocks[b] = append(blocks[b], codewords[at])
+				at++
+			}
+		}
+	}
+	for i := 0; i < spec.ecc; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[at])
+			at++
+		}
+	}
+	if at != len(codewords) {
+		return "", errors.New("codeword count does not match the version")
+	}
+
+	var data []byte
+	for b, block := range blocks {
+		if !qrCheckECC(block, spec.ecc) {
+			return "", errors.New("error correction does not check")
+		}
+		data = append(data, block[:lengths[b]]...)
+	}
+
+	read := qrBitReader{data: data}
+	if read.bits(4) != 0b0100 {
+		return "", errors.New("not byte mode")
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	text := make([]byte, read.bits(countBits))
+	for i := range text {
+		text[i] = byte(read.bits(8))
+	}
+	if read.at > 8*len(data) {
+		return "", errors.New("text runs past the data")
+	}
+
+	if terminator := min(4, 8*len(data)-read.at); read.bits(terminator) != 0 {
+		return "", errors.New("bad terminator")
+	}
+	read.at = (read.at + 7) / 8 * 8
+	for pad := 0xEC; read.at < 8*len(data); pad ^= 0xEC ^ 0x11 {
+		if read.bits(8) != pad {
+			return "", errors.New("bad padding")
+		}
+	}
+
+	return string(text), nil
+}
+
+func qrFunctionModules(version int) [][]bool {
+	size := 4*version + 17
+	function := make([][]bool, size)
+	for i := range function {
+		function[i] = make([]bool, size)
+	}
+	mark := func(x, y, width, height int) {
+		for dy := 0; dy < height; dy++ {
+			for dx := 0; dx < width; dx++ {
+				function[y+dy][x+dx] = true
+			}
+		}
+	}
+
+	// Finders with their separators and format information.
+	mark(0, 0, 9, 9)
+	mark(size-8, 0, 8, 9)
+	mark(0, size-8, 9, 8)
+	mark(6, 0, 1, size)
+	mark(0, 6, size, 1)
+
+	centres := qrAlignmentCentres[version]
+	for i, x := range centres {
+		for j, y := range centres {
+			last := len(centres) - 1
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			mark(x-2, y-2, 5, 5)
+		}
+	}
+
+	if version >= 7 {
+		mark(size-11, 0, 3, 6)
+		mark(0, size-11, 6, 3)
+	}
+	return function
+}
+
+// qrMasked is the mask condition of the specification for row i, column j.
+func qrMasked(mask, i, j int) bool {
+	switch mask {
+	case 0:
+		return (i+j)%2 == 0
+	case 1:
+		return i%2 == 0
+	case 2:
+		return j%3 == 0
+	case 3:
+		return (i+j)%3 == 0
+	case 4:
+		return (i/2+j/3)%2 == 0
+	case 5:
+		return (i*j)%2+(i*j)%3 == 0
+	case 6:
+		return ((i*j)%2+(i*j)%3)%2 == 0
+	default:
+		return ((i+j)%2+(i*j)%3)%2 == 0
+	}
+}
+
+// qrCheckECC reports whether block, data then error correction, is a
+// codeword: its polynomial is zero at the first ecc powers of 2 in GF(256).
+func qrCheckECC(block []byte, ecc int) bool {
+	var exp [512]byte
+	var log [256]int
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i], exp[i+255] = byte(x), byte(x)
+		log[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+
+	for power := 0; power < ecc; power++ {
+		var sum byte
+		for _, c := range block {
+			if sum != 0 {
+				sum = exp[log[sum]+power]
+			}
+			sum ^= c
+		}
+		if sum != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type qrBitReader struct {
+	data []byte
+	at   int
+}
+
+func (r *qrBitReader) bits(n int) int {
+	value := 0
+	for i := 0; i < n; i++ {
+		value <<= 1
+		if r.at < 8*len(r.data) && r.data[r.at/8]>>(7-r.at%8)&1 != 0 {
+			value |= 1
+		}
+		r.at++
+	}
+	return value
+}
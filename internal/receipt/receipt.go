@@ -0,0 +1,301 @@
+package receipt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Paper sizes. The thermal ones are rolls: the page is as long as the
+// receipt.
+const (
+	SIZE_58 = "58"
+	SIZE_80 = "80"
+	SIZE_A4 = "a4"
+)
+
+// MM is one millimetre in points.
+const MM = 72 / 25.4
+
+type paper struct {
+	width    float64
+	height   float64
+	margin   float64
+	fontSize float64
+	qrSize   float64
+	// left aligns the company header instead of centring it, as on a
+	// letterhead.
+	left bool
+}
+
+var papers = map[string]paper{
+	SIZE_58: {width: 58 * MM, margin: 3 * MM, fontSize: 7.5, qrSize: 30 * MM},
+	SIZE_80: {width: 80 * MM, margin: 4 * MM, fontSize: 9, qrSize: 36 * MM},
+	SIZE_A4: {width: 210 * MM, height: 297 * MM, margin: 20 * MM, fontSize: 11, qrSize: 40 * MM, left: true},
+}
+
+// ValidSize reports whether size is one of the paper sizes.
+func ValidSize(size string) bool {
+	_, ok := papers[size]
+	return ok
+}
+
+// Receipt is what goes on paper. Every string is already localized and
+// formatted; the renderer only lays it out.
+type Receipt struct {
+	Company        string
+	CompanyDetails string
+	Title          string
+	Rows           []Row
+	Sections       []Section
+	Notes          []Row
+	VerifyLabel    string
+	VerifyURL      string
+}
+
+// Row is a label with its value, printed on one line when it fits.
+type Row struct {
+	Label string
+	Value string
+}
+
+// Section is a list of amounts, one per currency, under a heading.
+type Section struct {
+	Title   string
+	Amounts []string
+}
+
+// Renderer draws receipts with a regular and a bold font. It is safe for
+// concurrent use.
+type Renderer struct {
+	regular *Font
+	bold    *Font
+}
+
+func NewRenderer(regularPath, boldPath string) (*Renderer, error) {
+	regular, err := LoadFont(regularPath)
+	if err != nil {
+		return nil, err
+	}
+	bold, err := LoadFont(boldPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Renderer{regular: regular, bold: bold}, nil
+}
+
+// Render writes receipt as a one page PDF for size.
+func (r *Renderer) Render(w io.Writer, receipt *Receipt, size string) error {
+	paper, ok := papers[size]
+	if !ok {
+		return fmt.Errorf("unknown receipt size %q", size)
+	}
+
+	l := &layout{renderer: r, paper: paper, width: paper.width - 2*paper.margin, y: paper.margin}
+	lead := paper.fontSize * 1.4
+
+	align := alignCenter
+	if paper.left {
+		align = alignLeft
+	}
+	l.paragraph(r.bold, paper.fontSize*1.4, receipt.Company, align)
+	l.paragraph(r.regular, paper.fontSize*0.9, receipt.CompanyDetails, align)
+	l.rule()
+
+	l.paragraph(r.bold, paper.fontSize*1.2, receipt.Title, alignCenter)
+	l.space(lead * 0.3)
+	for _, row := range receipt.Rows {
+		l.row(row)
+	}
+
+	for _, section := range receipt.Sections {
+		if len(section.Amounts) == 0 {
+			continue
+		}
+		l.rule()
+		l.paragraph(r.regular, paper.fontSize, section.Title, alignLeft)
+		for _, amount := range section.Amounts {
+			l.paragraph(r.bold, paper.fontSize*1.15, amount, alignRight)
+		}
+	}
+
+	if len(receipt.Notes) > 0 {
+		l.rule()
+		for _, row := range receipt.Notes {
+			l.row(row)
+		}
+	}
+
+	if receipt.VerifyURL != "" {
+		qr, err := EncodeQR(receipt.VerifyURL)
+		if err != nil {
+			return err
+		}
+		l.rule()
+		l.qr(qr, paper.qrSize)
+		l.paragraph(r.regular, paper.fontSize*0.85, receipt.VerifyLabel, alignCenter)
+	}
+
+	height := paper.height
+	if height == 0 {
+		height = l.y + paper.margin
+	}
+
+	doc := NewDocument()
+	page := doc.AddPage(paper.width, height)
+	for _, draw := range l.ops {
+		draw(page, height)
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+const (
+	alignLeft = iota
+	alignCenter
+	alignRight
+)
+
+// layout flows content down the page. y grows downwards from the top edge;
+// ops convert to PDF coordinates once the page height is known.
+type layout struct {
+	renderer *Renderer
+	paper    paper
+	width    float64
+	y        float64
+	ops      []func(page *Page, height float64)
+	// pages holds the ops of the pages before the current one.
+	pages [][]func(page *Page, height float64)
+}
+
+func (l *layout) space(height float64) {
+	l.y += height
+}
+
+func (l *layout) text(font *Font, size, x float64, text string) {
+	baseline := l.y + size*font.ascent/1000
+	l.ops = append(l.ops, func(page *Page, height float64) {
+		page.Text(font, size, x, height-baseline, text)
+	})
+}
+
+// paragraph prints text wrapped to the content width.
+func (l *layout) paragraph(font *Font, size float64, text string, align int) {
+	for _, line := range wrap(font, size, text, l.width) {
+		x := l.paper.margin
+		switch align {
+		case alignCenter:
+			x += (l.width - font.Width(line, size)) / 2
+		case alignRight:
+			x += l.width - font.Width(line, size)
+		}
+		l.text(font, size, x, line)
+		l.y += size * 1.3
+	}
+}
+
+// row prints the label on the left and the value on the right, or the
+// value under the label when both do not fit on one line.
+func (l *layout) row(row Row) {
+	if row.Value == "" {
+		return
+	}
+	size := l.paper.fontSize
+	regular, bold := l.renderer.regular, l.renderer.bold
+
+	label := row.Label + ":"
+	labelWidth := regular.Width(label, size)
+	valueWidth := bold.Width(row.Value, size)
+	if labelWidth+valueWidth+size <= l.width {
+		l.text(regular, size, l.paper.margin, label)
+		l.text(bold, size, l.paper.margin+l.width-valueWidth, row.Value)
+		l.y += size * 1.3
+		return
+	}
+
+	l.paragraph(regular, size, label, alignLeft)
+	l.paragraph(bold, size, row.Value, alignRight)
+}
+
+// rule draws a thin line across the content with some air around it.
+func (l *layout) rule() {
+	l.y += l.paper.fontSize * 0.4
+	y, left, right := l.y, l.paper.margin, l.paper.margin+l.width
+	l.ops = append(l.ops, func(page *Page, height float64) {
+		page.Line(left, height-y, right, height-y, 0.5)
+	})
+	l.y += l.paper.fontSize * 0.6
+}
+
+// qr draws the symbol centred, at most size points wide, with the four
+// module quiet zone scanners need.
+func (l *layout) qr(qr *QR, size float64) {
+	size = min(size, l.width)
+	module := size / float64(qr.Size+8)
+	left := l.paper.margin + (l.width-size)/2 + 4*module
+	top := l.y + 4*module
+
+	l.ops = append(l.ops, func(page *Page, height float64) {
+		for y := 0; y < qr.Size; y++ {
+			// Runs of dark modules are drawn as one rectangle, which keeps
+			// the page small and avoids hairline gaps between modules.
+			for x := 0; x < qr.Size; {
+				if !qr.Dark(x, y) {
+					x++
+					continue
+				}
+				start := x
+				for x < qr.Size && qr.Dark(x, y) {
+					x++
+				}
+				page.Rect(left+float64(start)*module, height-top-float64(y+1)*module, float64(x-start)*module, module)
+			}
+		}
+	})
+	l.y += size
+}
+
+// wrap breaks text into lines no wider than width, at spaces where it can
+// and inside words that are longer than a line.
+func wrap(font *Font, size float64, text string, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if font.Width(candidate, size) <= width {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			line = word
+			for font.Width(line, size) > width {
+				cut := fit(font, size, line, width)
+				lines = append(lines, line[:cut])
+				line = line[cut:]
+			}
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// fit is how many bytes of text fit in width, at least one rune.
+func fit(font *Font, size float64, text string, width float64) int {
+	var used float64
+	for i, r := range text {
+		used += font.advance(font.glyph(r)) * size / 1000
+		if used > width && i > 0 {
+			return i
+		}
+	}
+	return len(text)
+}
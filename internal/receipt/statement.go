@@ -0,0 +1,145 @@
+package receipt
+
+import (
+	"fmt"
+	"io"
+)
+
+// Statement is an A4 document headed like a receipt, with a table that runs
+// over as many pages as it needs. The column titles are repeated on every
+// page and each page is numbered.
+type Statement struct {
+	Company        string
+	CompanyDetails string
+	Title          string
+	Rows           []Row
+	Columns        []Column
+	Lines          []Line
+}
+
+// Column is a table column. Width is relative to the other columns.
+type Column struct {
+	Title string
+	Width float64
+	Right bool
+}
+
+// Line is a table row with one cell per column. Bold lines stand out, as
+// for opening and closing balances.
+type Line struct {
+	Cells []string
+	Bold  bool
+}
+
+// tableSize is the font size of the table, smaller than the body so that
+// amounts fit their columns.
+const tableSize = 8.5
+
+// RenderStatement writes statement as an A4 PDF.
+func (r *Renderer) RenderStatement(w io.Writer, statement *Statement) error {
+	paper := papers[SIZE_A4]
+	l := &layout{renderer: r, paper: paper, width: paper.width - 2*paper.margin, y: paper.margin}
+
+	l.paragraph(r.bold, paper.fontSize*1.4, statement.Company, alignLeft)
+	l.paragraph(r.regular, paper.fontSize*0.9, statement.CompanyDetails, alignLeft)
+	l.rule()
+	l.paragraph(r.bold, paper.fontSize*1.2, statement.Title, alignCenter)
+	l.space(paper.fontSize * 0.4)
+	for _, row := range statement.Rows {
+		l.row(row)
+	}
+	l.space(paper.fontSize)
+
+	var total float64
+	for _, column := range statement.Columns {
+		total += column.Width
+	}
+	if total <= 0 {
+		return fmt.Errorf("statement has no columns")
+	}
+	widths := make([]float64, len(statement.Columns))
+	titles := make([]string, len(statement.Columns))
+	for i, column := range statement.Columns {
+		widths[i] = l.width * column.Width / total
+		titles[i] = column.Title
+	}
+
+	bottom := paper.height - paper.margin - tableSize*2
+	l.tableLine(statement.Columns, widths, titles, r.bold)
+	for _, line := range statement.Lines {
+		font := r.regular
+		if line.Bold {
+			font = r.bold
+		}
+		if l.y+l.tableLineHeight(widths, line.Cells, font) > bottom {
+			l.newPage()
+			l.tableLine(statement.Columns, widths, titles, r.bold)
+		}
+		l.tableLine(statement.Columns, widths, line.Cells, font)
+	}
+	pages := append(l.pages, l.ops)
+
+	doc := NewDocument()
+	for i, ops := range pages {
+		page := doc.AddPage(paper.width, paper.height)
+		for _, draw := range ops {
+			draw(page, paper.height)
+		}
+
+		number := fmt.Sprintf("%d / %d", i+1, len(pages))
+		x := paper.margin + l.width - r.regular.Width(number, tableSize)
+		page.Text(r.regular, tableSize, x, paper.margin-tableSize, number)
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// newPage starts drawing on a new page of the same paper.
+func (l *layout) newPage() {
+	l.pages = append(l.pages, l.ops)
+	l.ops = nil
+	l.y = l.paper.margin
+}
+
+// tablePadding is the space between a cell's text and its neighbours.
+const tablePadding = 2.0
+
+func (l *layout) tableLineHeight(widths []float64, cells []string, font *Font) float64 {
+	lines := 1
+	for i, width := range widths {
+		if i < len(cells) {
+			lines = max(lines, len(wrap(font, tableSize, cells[i], width-2*tablePadding)))
+		}
+	}
+	return float64(lines)*tableSize*1.3 + 2*tablePadding
+}
+
+// tableLine draws one table row, wrapping cells that do not fit their
+// column, and underlines it.
+func (l *layout) tableLine(columns []Column, widths []float64, cells []string, font *Font) {
+	height := l.tableLineHeight(widths, cells, font)
+	top := l.y
+
+	x := l.paper.margin
+	for i, width := range widths {
+		if i < len(cells) {
+			l.y = top + tablePadding
+			for _, text := range wrap(font, tableSize, cells[i], width-2*tablePadding) {
+				left := x + tablePadding
+				if columns[i].Right {
+					left = x + width - tablePadding - font.Width(text, tableSize)
+				}
+				l.text(font, tableSize, left, text)
+				l.y += tableSize * 1.3
+			}
+		}
+		x += width
+	}
+
+	l.y = top + height
+	y, left, right := l.y, l.paper.margin, l.paper.margin+l.width
+	l.ops = append(l.ops, func(page *Page, height float64) {
+		page.Line(left, height-y, right, height-y, 0.25)
+	})
+}
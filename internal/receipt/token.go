@@ -0,0 +1,219 @@
+package receipt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+var ErrInvalidToken = errors.New("invalid receipt token")
+
+// Claims is what a verification token stands for: one entry as printed by
+// one company.
+type Claims struct {
+	Entity    string
+	ID        int64
+	CompanyID int64
+}
+
+// Entities are stored as one byte to keep tokens, and so QR codes, short.
+var entityCodes = map[string]byte{
+	store.ENTITY_TRANSACTION: 1,
+	store.ENTITY_EXCHANGE:    2,
+	store.ENTITY_DEBT:        3,
+}
+
+// statementCode marks statement links, so they can never pass as receipts
+// and the other way round.
+const statementCode = 0x10
+
+// StatementClaims is what a shared statement link stands for: a debtor's
+// statement for the days From to To, readable until Expires.
+type StatementClaims struct {
+	DebtorID  int64
+	CompanyID int64
+	From      time.Time
+	To        time.Time
+	Expires   time.Time
+}
+
+// Signer issues and checks verification tokens. A token is "<key id>.<data>"
+// where data is the claims sealed with AES-GCM: it cannot be forged or
+// altered without the key, and it does not reveal the ids inside it.
+//
+// Keys are rotated by putting a new key first: new tokens use it, and
+// receipts printed with the older keys still verify until those are removed.
+type Signer struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewSigner parses keys as "id:secret,id:secret". The first key signs.
+func NewSigner(keys string) (*Signer, error) {
+	s := &Signer{keys: map[string]cipher.AEAD{}}
+
+	for _, entry := range strings.Split(keys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || strings.ContainsAny(id, ".") || len(secret) < 16 {
+			return nil, fmt.Errorf("receipt key %q: want id:secret with a secret of at least 16 characters", id)
+		}
+		if _, exists := s.keys[id]; exists {
+			return nil, fmt.Errorf("receipt key %q is listed twice", id)
+		}
+
+		key := sha256.Sum256([]byte(secret))
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		s.keys[id] = aead
+		if s.current == "" {
+			s.current = id
+		}
+	}
+
+	if s.current == "" {
+		return nil, errors.New("no receipt keys")
+	}
+	return s, nil
+}
+
+func (s *Signer) Sign(claims Claims) (string, error) {
+	code, ok := entityCodes[claims.Entity]
+	if !ok {
+		return "", fmt.Errorf("unknown receipt entity %q", claims.Entity)
+	}
+
+	plain := []byte{code}
+	plain = binary.AppendUvarint(plain, uint64(claims.ID))
+	plain = binary.AppendUvarint(plain, uint64(claims.CompanyID))
+
+	return s.seal(plain)
+}
+
+// Verify returns the claims of a token made with any of the keys.
+func (s *Signer) Verify(token string) (Claims, error) {
+	plain, err := s.open(token)
+	if err != nil || len(plain) < 3 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var claims Claims
+	for entity, code := range entityCodes {
+		if code == plain[0] {
+			claims.Entity = entity
+		}
+	}
+	entityID, n := binary.Uvarint(plain[1:])
+	if n <= 0 {
+		return Claims{}, ErrInvalidToken
+	}
+	companyID, m := binary.Uvarint(plain[1+n:])
+	if m <= 0 || claims.Entity == "" {
+		return Claims{}, ErrInvalidToken
+	}
+
+	claims.ID = int64(entityID)
+	claims.CompanyID = int64(companyID)
+	return claims, nil
+}
+
+// SignStatement seals a statement link. Days are kept as days since the
+// Unix epoch and the expiry in seconds.
+func (s *Signer) SignStatement(claims StatementClaims) (string, error) {
+	plain := []byte{statementCode}
+	plain = binary.AppendUvarint(plain, uint64(claims.DebtorID))
+	plain = binary.AppendUvarint(plain, uint64(claims.CompanyID))
+	plain = binary.AppendUvarint(plain, uint64(epochDay(claims.From)))
+	plain = binary.AppendUvarint(plain, uint64(epochDay(claims.To)))
+	plain = binary.AppendUvarint(plain, uint64(claims.Expires.Unix()))
+
+	return s.seal(plain)
+}
+
+// VerifyStatement returns the claims of a statement link that has not
+// expired at now. From and To are returned as midnight UTC of their day.
+func (s *Signer) VerifyStatement(token string, now time.Time) (StatementClaims, error) {
+	plain, err := s.open(token)
+	if err != nil || len(plain) < 1 || plain[0] != statementCode {
+		return StatementClaims{}, ErrInvalidToken
+	}
+
+	var values [5]uint64
+	rest := plain[1:]
+	for i := range values {
+		v, n := binary.Uvarint(rest)
+		if n <= 0 {
+			return StatementClaims{}, ErrInvalidToken
+		}
+		values[i], rest = v, rest[n:]
+	}
+
+	claims := StatementClaims{
+		DebtorID:  int64(values[0]),
+		CompanyID: int64(values[1]),
+		From:      time.Unix(int64(values[2])*86400, 0).UTC(),
+		To:        time.Unix(int64(values[3])*86400, 0).UTC(),
+		Expires:   time.Unix(int64(values[4]), 0),
+	}
+	if !now.Before(claims.Expires) {
+		return StatementClaims{}, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func epochDay(t time.Time) int64 {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
+
+// seal encrypts plain with the current key, bound to its id.
+func (s *Signer) seal(plain []byte) (string, error) {
+	aead := s.keys[s.current]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plain, []byte(s.current))
+
+	return s.current + "." + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a token made with any of the keys.
+func (s *Signer) open(token string) ([]byte, error) {
+	id, data, ok := strings.Cut(token, ".")
+	aead, known := s.keys[id]
+	if !ok || !known {
+		return nil, ErrInvalidToken
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidToken
+	}
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plain, err := aead.Open(nil, nonce, sealed, []byte(id))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return plain, nil
+}
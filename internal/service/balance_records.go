@@ -36,23 +36,23 @@ func (s *BalanceRecordService) PerformBalanceRecord(ctx context.Context, balance
 		selledCurrencyBalance, err := balancesStorage.GetByUserIdAndCurrency(ctx, &balanceRecord.UserId, balanceRecord.SelledCurrency)
 		if err != nil {
 			tx.Rollback()
-			return fmt.Errorf(types.BALANCE_CURRENCY_NOT_FOUND)
+			return types.ErrBalanceCurrencyNotFound
 		}
 
 		fmt.Println("selledCurrencyBalance: ", selledCurrencyBalance)
 
 		if selledCurrencyBalance.Balance < balanceRecord.SelledMoney {
 			tx.Rollback()
-			return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+			return types.ErrBalanceNoEnoughMoney
 		}
 		selledCurrencyBalance.Balance -= balanceRecord.SelledMoney
 		selledCurrencyBalance.InOutLay += balanceRecord.SelledMoney
 
 		fmt.Println("selledCurrencyBalance: ", selledCurrencyBalance)
 
-		if err := balancesStorage.Update(ctx, selledCurrencyBalance); err != nil {
+		if err := updateBalance(ctx, tx, selledCurrencyBalance); err != nil {
 			tx.Rollback()
-			return fmt.Errorf("ERROR OCCURRED WHILE UPDATING selledCurrencyBalance %v", err)
+			return fmt.Errorf("ERROR OCCURRED WHILE UPDATING selledCurrencyBalance %w", err)
 		}
 
 		selledMoneyRecord := &store.BalanceRecord{
@@ -67,7 +67,7 @@ func (s *BalanceRecordService) PerformBalanceRecord(ctx context.Context, balance
 
 		if err := balanceRecordsStorage.Create(ctx, selledMoneyRecord); err != nil {
 			tx.Rollback()
-			return fmt.Errorf("ERROR OCCURRED WHILE CREATING BALANCE RECORD %v", err)
+			return fmt.Errorf("ERROR OCCURRED WHILE CREATING BALANCE RECORD %w", err)
 		}
 	}
 
@@ -76,7 +76,7 @@ func (s *BalanceRecordService) PerformBalanceRecord(ctx context.Context, balance
 		receivedCurrencyBalance, err := balancesStorage.GetByUserIdAndCurrency(ctx, &balanceRecord.UserId, balanceRecord.ReceivedCurrency)
 		if err != nil {
 			tx.Rollback()
-			return fmt.Errorf(types.BALANCE_CURRENCY_NOT_FOUND)
+			return types.ErrBalanceCurrencyNotFound
 		}
 
 		if receivedCurrencyBalance != nil {
@@ -84,9 +84,9 @@ func (s *BalanceRecordService) PerformBalanceRecord(ctx context.Context, balance
 			receivedCurrencyBalance.OutInLay += balanceRecord.ReceivedMoney
 		}
 
-		if err := balancesStorage.Update(ctx, receivedCurrencyBalance); err != nil {
+		if err := updateBalance(ctx, tx, receivedCurrencyBalance); err != nil {
 			tx.Rollback()
-			return fmt.Errorf("ERROR OCCURRED WHILE UPDATING receivedCurrencyBalance %v", err)
+			return fmt.Errorf("ERROR OCCURRED WHILE UPDATING receivedCurrencyBalance %w", err)
 		}
 
 		receivedMoneyRecord := &store.BalanceRecord{
@@ -101,12 +101,12 @@ func (s *BalanceRecordService) PerformBalanceRecord(ctx context.Context, balance
 
 		if err := balanceRecordsStorage.Create(ctx, receivedMoneyRecord); err != nil {
 			tx.Rollback()
-			return fmt.Errorf("ERROR OCCURRED WHILE CREATING BALANCE RECORD %v", err)
+			return fmt.Errorf("ERROR OCCURRED WHILE CREATING BALANCE RECORD %w", err)
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	log.Println("Transaction committed successfully")
 	return nil
@@ -117,21 +117,31 @@ func (s *BalanceRecordService) RollbackBalanceRecord(ctx context.Context, id int
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
+	if err := s.rollbackBalanceRecord(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// rollbackBalanceRecord reverts a balance record and removes it, within tx.
+func (s *BalanceRecordService) rollbackBalanceRecord(ctx context.Context, tx store.DBTX, id int64) error {
 	balancesStorage := store.NewBalanceStorage(tx)
 	balanceRecordsStorage := store.NewBalanceRecordStorage(tx)
 
-	records, err := balanceRecordsStorage.GetByField(ctx, "id", id, types.Pagination{Limit: 100, Offset: 0})
+	records, err := balanceRecordsStorage.GetByField(ctx, "id", id, &types.Pagination{Limit: 100, Offset: 0})
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
+	if len(records) == 0 {
+		return types.ErrBalanceRecordNotFound
+	}
 	record := records[0]
 
 	balance, err := balancesStorage.GetById(ctx, &record.BalanceID)
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE GETTING BALANCE WITH ID %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE GETTING BALANCE WITH ID %w", err)
 	}
 
 	switch record.Type {
@@ -143,25 +153,20 @@ func (s *BalanceRecordService) RollbackBalanceRecord(ctx context.Context, id int
 			balance.Balance -= record.Amount
 			balance.OutInLay -= record.Amount
 		} else {
-			tx.Rollback()
-			return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+			return types.ErrBalanceNoEnoughMoney
 		}
 	default:
-		tx.Rollback()
-		return fmt.Errorf("FOUND UNKOWN RECORD TYPE")
+		return types.ErrUnknownType
 	}
 
-	if err := balancesStorage.Update(ctx, balance); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE UPDATING BALANCE %v", err)
+	if err := updateBalance(ctx, tx, balance); err != nil {
+		return fmt.Errorf("ERROR OCCURRED WHILE UPDATING BALANCE %w", err)
 	}
 
 	if err := balanceRecordsStorage.Delete(ctx, record.ID); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE DELETING BALANCE RECORD %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE DELETING BALANCE RECORD %w", err)
 	}
 
-	tx.Commit()
 	return nil
 }
 
@@ -174,7 +179,7 @@ func (s *BalanceRecordService) UpdateRecord(ctx context.Context, balanceRecord s
 	balancesStorage := store.NewBalanceStorage(tx)
 	balanceRecordsStorage := store.NewBalanceRecordStorage(tx)
 
-	record, err := balanceRecordsStorage.GetByField(ctx, "id", &balanceRecord.ID, types.Pagination{Limit: 100, Offset: 0})
+	record, err := balanceRecordsStorage.GetByField(ctx, "id", &balanceRecord.ID, &types.Pagination{Limit: 100, Offset: 0})
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -198,7 +203,7 @@ func (s *BalanceRecordService) UpdateRecord(ctx context.Context, balanceRecord s
 
 		} else {
 			tx.Rollback()
-			return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+			return types.ErrBalanceNoEnoughMoney
 		}
 	}
 
@@ -209,16 +214,16 @@ func (s *BalanceRecordService) UpdateRecord(ctx context.Context, balanceRecord s
 			balance.InOutLay += balanceRecord.Amount
 		} else {
 			tx.Rollback()
-			return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+			return types.ErrBalanceNoEnoughMoney
 		}
 	case TYPE_BUY:
 		balance.Balance += balanceRecord.Amount
 		balance.OutInLay += balanceRecord.Amount
 	}
 
-	if err := balancesStorage.Update(ctx, balance); err != nil {
+	if err := updateBalance(ctx, tx, balance); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE UPDATING BALANCE %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE UPDATING BALANCE %w", err)
 	}
 
 	if err := balanceRecordsStorage.Update(ctx, &balanceRecord); err != nil {
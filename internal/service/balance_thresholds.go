@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+// updateBalance saves balance and, in the same transaction, queues an alert
+// for every threshold the new amount crosses. Services call it instead of
+// BalanceStorage.Update so no balance change skips the check.
+func updateBalance(ctx context.Context, tx store.DBTX, balance *store.Balance) error {
+	if err := store.NewBalanceStorage(tx).Update(ctx, balance); err != nil {
+		return err
+	}
+	return checkBalanceThresholds(ctx, tx, balance)
+}
+
+func checkBalanceThresholds(ctx context.Context, tx store.DBTX, balance *store.Balance) error {
+	thresholds := store.NewBalanceThresholdStorage(tx)
+	crossings, err := thresholds.Evaluate(ctx, balance)
+	if err != nil {
+		return err
+	}
+
+	for _, crossing := range crossings {
+		kind := store.NOTIFICATION_LOW_BALANCE
+		switch crossing.State {
+		case store.THRESHOLD_OK:
+			continue
+		case store.THRESHOLD_ABOVE:
+			kind = store.NOTIFICATION_HIGH_BALANCE
+		}
+
+		data := map[string]string{
+			"currency":     crossing.Currency,
+			"balance":      strconv.FormatInt(crossing.Amount, 10),
+			"threshold_id": strconv.FormatInt(crossing.ThresholdID, 10),
+		}
+		if crossing.Limit != nil {
+			data["limit"] = strconv.FormatInt(*crossing.Limit, 10)
+		}
+		if crossing.UserID != nil {
+			data["user_id"] = strconv.FormatInt(*crossing.UserID, 10)
+		}
+		if err := notifyCompany(ctx, tx, balance.CompanyId, crossing.UserID, kind, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
 )
 
 type BalanceService struct {
@@ -98,3 +101,111 @@ func GetUser(users []store.User, id int64) *store.User {
 	}
 	return nil
 }
+
+// Create opens a balance. An opening amount gets a record of its own, so
+// that the balance stays the sum of its records for reconciliation.
+func (s *BalanceService) Create(ctx context.Context, balance *store.Balance) error {
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := store.NewBalanceStorage(tx).Create(ctx, balance); err != nil {
+		return err
+	}
+	if err := recordAdjustment(ctx, tx, balance, balance.Balance, store.RECORD_OPENING); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return nil
+}
+
+// Update sets the amounts of balance.ID by hand. The rest of balance is
+// filled in from the stored row; the change of amount is recorded like in
+// Create, and thresholds are checked like after any other change.
+func (s *BalanceService) Update(ctx context.Context, balance *store.Balance) error {
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	stored, err := store.NewBalanceStorage(tx).GetForUpdate(ctx, balance.ID)
+	if err != nil {
+		return err
+	}
+	balance.UserId = stored.UserId
+	balance.CompanyId = stored.CompanyId
+	balance.Currency = stored.Currency
+	balance.CreatedAt = stored.CreatedAt
+
+	if err := recordAdjustment(ctx, tx, balance, balance.Balance-stored.Balance, store.RECORD_ADJUSTMENT); err != nil {
+		return err
+	}
+	if err := updateBalance(ctx, tx, balance); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return nil
+}
+
+// recordAdjustment writes the balance record for delta added to balance by
+// hand. Nothing is written for a zero delta.
+func recordAdjustment(ctx context.Context, tx store.DBTX, balance *store.Balance, delta int64, details string) error {
+	if delta == 0 {
+		return nil
+	}
+
+	record := &store.BalanceRecord{
+		Amount:    delta,
+		UserID:    balance.UserId,
+		BalanceID: balance.ID,
+		CompanyID: balance.CompanyId,
+		Details:   details,
+		Currency:  balance.Currency,
+		Type:      types.TYPE_BUY,
+	}
+	if delta < 0 {
+		record.Amount = -delta
+		record.Type = types.TYPE_SELL
+	}
+
+	if err := store.NewBalanceRecordStorage(tx).Create(ctx, record); err != nil {
+		return fmt.Errorf("failed to record balance adjustment: %w", err)
+	}
+	return nil
+}
+
+// Statement reads a balance statement for [from, to) in one transaction, so
+// the stored balance and its records are seen at the same moment.
+func (s *BalanceService) Statement(ctx context.Context, companyId, balanceId int64, from, to time.Time) (*store.BalanceStatement, error) {
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	return store.NewStatementStorage(tx).Balance(ctx, companyId, balanceId, from, to)
+}
+
+// Snapshot takes the end-of-day balances of every day from from to to, both
+// included, for companyId or for every company when it is nil. Days already
+// taken are kept, so it also fills in days a job missed.
+func (s *BalanceService) Snapshot(ctx context.Context, companyId *int64, from, to time.Time) (int64, error) {
+	var taken int64
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		n, err := s.store.BalanceSnapshots.Take(ctx, companyId, day)
+		if err != nil {
+			return taken, fmt.Errorf("failed to snapshot %s: %w", day.Format("2006-01-02"), err)
+		}
+		taken += n
+	}
+	return taken, nil
+}
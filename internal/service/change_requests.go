@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+type ChangeRequestService struct {
+	store          store.Storage
+	transactions   *TransactionService
+	exchanges      *ExchangeService
+	balanceRecords *BalanceRecordService
+	debts          *DebtsService
+}
+
+// Request records a proposed edit or delete together with a snapshot of the
+// entity as it is now. Nothing is applied until an owner approves it. Only
+// entities of the requester's own company can be asked for.
+func (s *ChangeRequestService) Request(ctx context.Context, cr *store.ChangeRequest) error {
+	entity, err := load(ctx, s.store, cr.EntityType, cr.EntityID)
+	if err != nil {
+		return err
+	}
+
+	if cr.Action == store.ACTION_UPDATE && cr.EntityType != store.ENTITY_TRANSACTION {
+		return types.ErrInvalidRequest.WithField("entity_type", "update of "+cr.EntityType+" is not supported")
+	}
+
+	requester, err := s.store.Users.GetById(ctx, &cr.RequestedBy)
+	if err != nil {
+		return fmt.Errorf("failed to get requester: %w", err)
+	}
+
+	if !ownedBy(entity, requester.CompanyId) {
+		return types.ErrForbidden.Wrap(fmt.Errorf("%s %d belongs to another company", cr.EntityType, cr.EntityID))
+	}
+
+	before, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+
+	cr.CompanyID = requester.CompanyId
+	cr.Before = before
+
+	return s.store.ChangeRequests.Create(ctx, cr)
+}
+
+// Approve claims a pending request and runs the existing service path for
+// it in the same transaction, so the request is only approved if the change
+// is applied.
+func (s *ChangeRequestService) Approve(ctx context.Context, id int64, reviewerId int64, note *string) (*store.ChangeRequest, error) {
+	cr, err := s.getForReviewer(ctx, id, reviewerId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := store.NewChangeRequestStorage(tx).Review(ctx, id, store.CHANGE_REQUEST_APPROVED, reviewerId, note); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, types.ErrChangeRequestReviewed
+		}
+		return nil, err
+	}
+
+	if err := s.apply(ctx, tx, cr); err != nil {
+		return nil, fmt.Errorf("failed to apply change request: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.store.ChangeRequests.GetById(ctx, id)
+}
+
+func (s *ChangeRequestService) Reject(ctx context.Context, id int64, reviewerId int64, note *string) (*store.ChangeRequest, error) {
+	if _, err := s.getForReviewer(ctx, id, reviewerId); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.ChangeRequests.Review(ctx, id, store.CHANGE_REQUEST_REJECTED, reviewerId, note); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, types.ErrChangeRequestReviewed
+		}
+		return nil, err
+	}
+
+	return s.store.ChangeRequests.GetById(ctx, id)
+}
+
+func (s *ChangeRequestService) getForReviewer(ctx context.Context, id int64, reviewerId int64) (*store.ChangeRequest, error) {
+	cr, err := s.store.ChangeRequests.GetById(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, types.ErrChangeRequestNotFound
+		}
+		return nil, err
+	}
+
+	reviewer, err := s.store.Users.GetById(ctx, &reviewerId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reviewer: %w", err)
+	}
+
+	if reviewer.Role != store.ROLE_OWNER || reviewer.CompanyId != cr.CompanyID {
+		return nil, types.ErrForbidden.Wrap(fmt.Errorf("only an owner of the company can review change requests"))
+	}
+
+	if cr.Status != store.CHANGE_REQUEST_PENDING {
+		return nil, types.ErrChangeRequestReviewed
+	}
+
+	return cr, nil
+}
+
+// apply makes the change through the existing service path. The entity is
+// read again in tx and must still belong to the request's company, as must
+// a proposed transaction, so an approval never touches another company.
+func (s *ChangeRequestService) apply(ctx context.Context, tx store.DBTX, cr *store.ChangeRequest) error {
+	id := cr.EntityID
+
+	entity, err := load(ctx, store.Storage{
+		Transactions:   store.NewTransactionStorage(tx),
+		Exchanges:      store.NewExchangeStorage(tx),
+		BalanceRecords: store.NewBalanceRecordStorage(tx),
+		Debts:          store.NewDebtsStorage(tx),
+	}, cr.EntityType, id)
+	if err != nil {
+		return err
+	}
+	if !ownedBy(entity, cr.CompanyID) {
+		return types.ErrForbidden.Wrap(fmt.Errorf("%s %d belongs to another company", cr.EntityType, id))
+	}
+
+	switch cr.EntityType + ":" + cr.Action {
+	case store.ENTITY_TRANSACTION + ":" + store.ACTION_UPDATE:
+		var transaction store.Transaction
+		if err := json.Unmarshal(cr.Proposed, &transaction); err != nil {
+			return fmt.Errorf("failed to decode proposed transaction: %w", err)
+		}
+		if !ownedBy(&transaction, cr.CompanyID) {
+			return types.ErrForbidden.Wrap(fmt.Errorf("the proposed transaction belongs to another company"))
+		}
+		transaction.ID = id
+		return s.transactions.update(ctx, tx, &transaction)
+	case store.ENTITY_TRANSACTION + ":" + store.ACTION_DELETE:
+		return s.transactions.delete(ctx, tx, &id)
+	case store.ENTITY_EXCHANGE + ":" + store.ACTION_DELETE:
+		return s.exchanges.delete(ctx, tx, id)
+	case store.ENTITY_BALANCE_RECORD + ":" + store.ACTION_DELETE:
+		return s.balanceRecords.rollbackBalanceRecord(ctx, tx, id)
+	case store.ENTITY_DEBT + ":" + store.ACTION_DELETE:
+		return s.debts.delete(ctx, tx, id)
+	default:
+		return fmt.Errorf("unsupported change request %s %s", cr.Action, cr.EntityType)
+	}
+}
+
+// load reads the entity a change request is about from st.
+func load(ctx context.Context, st store.Storage, entityType string, id int64) (any, error) {
+	var entity any
+	var err error
+
+	switch entityType {
+	case store.ENTITY_TRANSACTION:
+		entity, err = st.Transactions.GetById(ctx, id)
+	case store.ENTITY_EXCHANGE:
+		entity, err = st.Exchanges.GetById(ctx, id)
+	case store.ENTITY_BALANCE_RECORD:
+		var records []store.BalanceRecord
+		records, err = st.BalanceRecords.GetByField(ctx, "id", id, &types.Pagination{Limit: 1, Offset: 0})
+		if err == nil && len(records) == 0 {
+			err = sql.ErrNoRows
+		}
+		if err == nil {
+			entity = &records[0]
+		}
+	case store.ENTITY_DEBT:
+		entity, err = st.Debts.GetByID(ctx, id)
+	default:
+		return nil, types.ErrUnknownType.WithField("entity_type", entityType)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s %d: %w", entityType, id, err)
+	}
+	return entity, nil
+}
+
+// ownedBy reports whether entity belongs to the company. A transaction
+// belongs to both the company it was received from and the one it was
+// delivered to.
+func ownedBy(entity any, companyID int64) bool {
+	switch e := entity.(type) {
+	case *store.Transaction:
+		return e.ReceivedCompanyId == companyID || e.DeliveredCompanyId == companyID
+	case *store.Exchange:
+		return e.CompanyID == companyID
+	case *store.BalanceRecord:
+		return e.CompanyID == companyID
+	case *store.Debts:
+		return e.CompanyID == companyID
+	default:
+		return false
+	}
+}
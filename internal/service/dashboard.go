@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+type DashboardService struct {
+	store store.Storage
+}
+
+// Get builds the dashboard of companyId for [from, to) and adds the debtor
+// totals. A negative debtor balance is money the company gave, so it is
+// owed to the company.
+func (s *DashboardService) Get(ctx context.Context, companyId int64, from, to time.Time) (*store.Dashboard, error) {
+	dashboard, err := s.store.Dashboard.Get(ctx, companyId, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := s.store.Debtors.GetByBalanceInfo(ctx, companyId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum debtors: %w", err)
+	}
+
+	dashboard.Debts = make([]store.DashboardDebts, 0, len(infos))
+	for _, info := range infos {
+		currency, _ := info["currency"].(string)
+		positive, _ := info["positive_balance"].(int64)
+		negative, _ := info["negative_balance"].(int64)
+
+		dashboard.Debts = append(dashboard.Debts, store.DashboardDebts{
+			Currency:   currency,
+			Receivable: -negative,
+			Payable:    positive,
+		})
+	}
+
+	return dashboard, nil
+}
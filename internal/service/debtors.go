@@ -11,13 +11,27 @@ type DebtorsService struct {
 	store store.Storage
 }
 
-func (s *DebtorsService) GetByCompanyId(ctx context.Context, companyId int64, search *string, dateSearch *string, pagination types.Pagination) ([]map[string]interface{}, error) {
+func (s *DebtorsService) GetByCompanyId(ctx context.Context, companyId int64, search *string, dateSearch *string, pagination *types.Pagination) ([]map[string]interface{}, error) {
 
 	debtors, err := s.store.Debtors.GetByCompanyId(ctx, companyId, search, dateSearch, pagination)
 	if err != nil {
 		return nil, err
 	}
 
+	return s.describe(ctx, debtors)
+}
+
+func (s *DebtorsService) Filter(ctx context.Context, companyId int64, filter *types.Filter, pagination *types.Pagination) ([]map[string]interface{}, error) {
+	debtors, err := s.store.Debtors.Filter(ctx, companyId, filter, pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.describe(ctx, debtors)
+}
+
+// describe adds the username of whoever created each debtor.
+func (s *DebtorsService) describe(ctx context.Context, debtors []store.Debtors) ([]map[string]interface{}, error) {
 	users, err := s.store.Users.GetAll(ctx)
 	if err != nil {
 		return nil, err
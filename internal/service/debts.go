@@ -5,18 +5,22 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"strconv"
+	"time"
 
+	"github.com/mubashshir3767/currencyExchange/internal/events"
 	"github.com/mubashshir3767/currencyExchange/internal/store"
 	"github.com/mubashshir3767/currencyExchange/internal/types"
 )
 
 type DebtsService struct {
 	store store.Storage
+	bus   events.Bus
 }
 
 func (s *DebtsService) Create(ctx context.Context, debt *store.Debts) error {
 	if len(debt.ReceivedIncomes) == 0 {
-		return fmt.Errorf("received incomes cannot be empty")
+		return types.ErrEmptyIncomes
 	}
 
 	tx, err := s.store.BeginTx(ctx)
@@ -58,7 +62,7 @@ func (s *DebtsService) Create(ctx context.Context, debt *store.Debts) error {
 	case types.TYPE_BUY:
 		signedDebtedAmount = debt.DebtedAmount
 	default:
-		return fmt.Errorf("invalid debt type: %d", debt.Type)
+		return types.ErrUnknownType.WithField("type", strconv.Itoa(debt.Type))
 	}
 
 	debt.DebtedAmount = signedDebtedAmount
@@ -86,7 +90,7 @@ func (s *DebtsService) Create(ctx context.Context, debt *store.Debts) error {
 		switch debt.Type {
 		case types.TYPE_SELL:
 			if balance.Balance < originalPositiveReceived {
-				return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+				return types.ErrBalanceNoEnoughMoney
 			}
 			balance.Balance -= originalPositiveReceived
 			balance.InOutLay += originalPositiveReceived
@@ -112,7 +116,7 @@ func (s *DebtsService) Create(ctx context.Context, debt *store.Debts) error {
 			return fmt.Errorf("failed to create balance record: %w", err)
 		}
 
-		if err := balancesStorage.Update(ctx, balance); err != nil {
+		if err := updateBalance(ctx, tx, balance); err != nil {
 			return fmt.Errorf("failed to update balance: %w", err)
 		}
 	}
@@ -131,12 +135,14 @@ func (s *DebtsService) Create(ctx context.Context, debt *store.Debts) error {
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit tx: %w", err)
 	}
+
+	events.Publish(ctx, s.bus, events.DEBT_POSTED, []int64{debt.CompanyID}, debt)
 	return nil
 }
 
 func (s *DebtsService) Transaction(ctx context.Context, debt *store.Debts) error {
 	if len(debt.ReceivedIncomes) == 0 {
-		return fmt.Errorf("received incomes cannot be empty")
+		return types.ErrEmptyIncomes
 	}
 
 	tx, err := s.store.BeginTx(ctx)
@@ -156,7 +162,7 @@ func (s *DebtsService) Transaction(ctx context.Context, debt *store.Debts) error
 	}
 
 	if debtor.Currency != debt.DebtedCurrency {
-		return fmt.Errorf("debted currencies do not match: %s != %s", debtor.Currency, debt.DebtedCurrency)
+		return types.ErrCurrencyMismatch.WithField("debted_currency", debt.DebtedCurrency)
 	}
 
 	originalPositiveDebted := debt.DebtedAmount // Positive input
@@ -167,7 +173,7 @@ func (s *DebtsService) Transaction(ctx context.Context, debt *store.Debts) error
 	case types.TYPE_BUY:
 		signedDebtedAmount = debt.DebtedAmount
 	default:
-		return fmt.Errorf("invalid debt type: %d", debt.Type)
+		return types.ErrUnknownType.WithField("type", strconv.Itoa(debt.Type))
 	}
 
 	debt.DebtedAmount = signedDebtedAmount
@@ -194,7 +200,7 @@ func (s *DebtsService) Transaction(ctx context.Context, debt *store.Debts) error
 		switch debt.Type {
 		case types.TYPE_SELL:
 			if balance.Balance < originalPositiveReceived {
-				return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+				return types.ErrBalanceNoEnoughMoney
 			}
 			balance.Balance -= originalPositiveReceived
 			balance.InOutLay += originalPositiveReceived
@@ -220,7 +226,7 @@ func (s *DebtsService) Transaction(ctx context.Context, debt *store.Debts) error
 			return fmt.Errorf("failed to create balance record: %w", err)
 		}
 
-		if err := balancesStorage.Update(ctx, balance); err != nil {
+		if err := updateBalance(ctx, tx, balance); err != nil {
 			return fmt.Errorf("failed to update balance: %w", err)
 		}
 	}
@@ -239,6 +245,8 @@ func (s *DebtsService) Transaction(ctx context.Context, debt *store.Debts) error
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit tx: %w", err)
 	}
+
+	events.Publish(ctx, s.bus, events.DEBT_POSTED, []int64{debt.CompanyID}, debt)
 	return nil
 }
 
@@ -309,12 +317,12 @@ func (s *DebtsService) Update(ctx context.Context, debt *store.Debts) error {
 	case types.TYPE_BUY:
 		signedDebtedAmount = debt.DebtedAmount
 	default:
-		return fmt.Errorf("invalid new debt type: %d", debt.Type)
+		return types.ErrUnknownType.WithField("type", strconv.Itoa(debt.Type))
 	}
 	debt.DebtedAmount = signedDebtedAmount
 
 	if len(debt.ReceivedIncomes) == 0 {
-		return fmt.Errorf("new received incomes cannot be empty")
+		return types.ErrEmptyIncomes
 	}
 
 	for _, tr := range debt.ReceivedIncomes {
@@ -327,7 +335,7 @@ func (s *DebtsService) Update(ctx context.Context, debt *store.Debts) error {
 		switch debt.Type {
 		case types.TYPE_SELL:
 			if balance.Balance < originalPositiveReceived {
-				return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+				return types.ErrBalanceNoEnoughMoney
 			}
 			balance.Balance -= originalPositiveReceived
 			balance.InOutLay += originalPositiveReceived
@@ -365,7 +373,7 @@ func (s *DebtsService) Update(ctx context.Context, debt *store.Debts) error {
 		return fmt.Errorf("failed to update debt: %w", err)
 	}
 
-	if err := balanceStorage.Update(ctx, balance); err != nil {
+	if err := updateBalance(ctx, tx, balance); err != nil {
 		return fmt.Errorf("failed to update balance: %w", err)
 	}
 
@@ -386,6 +394,15 @@ func (s *DebtsService) Delete(ctx context.Context, debtId int64) error {
 	}
 	defer tx.Rollback()
 
+	if err := s.delete(ctx, tx, debtId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// delete reverts a debt on the balance and the debtor and removes it,
+// within tx.
+func (s *DebtsService) delete(ctx context.Context, tx store.DBTX, debtId int64) error {
 	balanceRecordsStorage := store.NewBalanceRecordStorage(tx)
 	balancesStorage := store.NewBalanceStorage(tx)
 	debtorsStorage := store.NewDebtorsStorage(tx)
@@ -428,7 +445,7 @@ func (s *DebtsService) Delete(ctx context.Context, debtId int64) error {
 		debtor.Balance -= int64(originalPositive) // Undo - positive
 	}
 
-	if err := balancesStorage.Update(ctx, balance); err != nil {
+	if err := updateBalance(ctx, tx, balance); err != nil {
 		return fmt.Errorf("failed to update balance: %w", err)
 	}
 
@@ -440,8 +457,53 @@ func (s *DebtsService) Delete(ctx context.Context, debtId int64) error {
 		return fmt.Errorf("failed to delete debt: %w", err)
 	}
 
+	return nil
+}
+
+// DEBT_DUE_BATCH is how many due debts NotifyDue claims per transaction.
+const DEBT_DUE_BATCH = 100
+
+// NotifyDue queues a debt_due notification, to the company's owners and the
+// user who recorded it, for every debt that fell due while its debtor still
+// owes. Each debt is notified once per due date. It returns how many debts
+// were notified.
+func (s *DebtsService) NotifyDue(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := s.notifyDueBatch(ctx)
+		total += n
+		if err != nil || n < DEBT_DUE_BATCH {
+			return total, err
+		}
+	}
+}
+
+func (s *DebtsService) notifyDueBatch(ctx context.Context) (int, error) {
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	debts, err := store.NewDebtsStorage(tx).ClaimDue(ctx, time.Now(), DEBT_DUE_BATCH)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, debt := range debts {
+		data := map[string]string{
+			"full_name": debt.FullName,
+			"amount":    strconv.FormatInt(debt.Amount, 10),
+			"currency":  debt.Currency,
+			"debt_id":   strconv.FormatInt(debt.ID, 10),
+		}
+		if err := notifyCompany(ctx, tx, debt.CompanyID, debt.UserID, store.NOTIFICATION_DEBT_DUE, data); err != nil {
+			return 0, err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit tx: %w", err)
+		return 0, fmt.Errorf("failed to commit tx: %w", err)
 	}
-	return nil
+	return len(debts), nil
 }
@@ -2,8 +2,8 @@ package service
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"strconv"
 
 	"github.com/mubashshir3767/currencyExchange/internal/store"
 	"github.com/mubashshir3767/currencyExchange/internal/types"
@@ -11,6 +11,9 @@ import (
 
 type ExchangeService struct {
 	store store.Storage
+	// largeExchange is the amount per currency from which owners are told
+	// about an exchange. Currencies without one are never large.
+	largeExchange map[string]int64
 }
 
 func (s *ExchangeService) Create(ctx context.Context, exchange *store.Exchange) error {
@@ -33,13 +36,13 @@ func (s *ExchangeService) Create(ctx context.Context, exchange *store.Exchange)
 
 	if err := exchangeStore.Create(ctx, exchange); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE CREATING EXCHANGE  %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE CREATING EXCHANGE  %w", err)
 	}
 
 	receivedCurrencyBalance, err := balancesStorage.GetByUserIdAndCurrency(ctx, &exchange.UserId, exchange.ReceivedCurrency)
 	if err != nil {
 		tx.Rollback()
-		return fmt.Errorf(types.BALANCE_CURRENCY_NOT_FOUND)
+		return types.ErrBalanceCurrencyNotFound
 	}
 
 	// RECEIVED MONEY PERFORM
@@ -48,9 +51,9 @@ func (s *ExchangeService) Create(ctx context.Context, exchange *store.Exchange)
 		receivedCurrencyBalance.OutInLay += exchange.ReceivedMoney
 	}
 
-	if err := balancesStorage.Update(ctx, receivedCurrencyBalance); err != nil {
+	if err := updateBalance(ctx, tx, receivedCurrencyBalance); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE UPDATING receivedCurrencyBalance %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE UPDATING receivedCurrencyBalance %w", err)
 	}
 
 	receivedMoneyRecord := &store.BalanceRecord{
@@ -66,13 +69,13 @@ func (s *ExchangeService) Create(ctx context.Context, exchange *store.Exchange)
 
 	if err := balanceRecordsStorage.Create(ctx, receivedMoneyRecord); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE CREATING BALANCE RECORD %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE CREATING BALANCE RECORD %w", err)
 	}
 
 	selledCurrencyBalance, err := balancesStorage.GetByUserIdAndCurrency(ctx, &exchange.UserId, exchange.SelledCurrency)
 	if err != nil {
 		tx.Rollback()
-		return fmt.Errorf(types.BALANCE_CURRENCY_NOT_FOUND)
+		return types.ErrBalanceCurrencyNotFound
 	}
 
 	/// SELLED MONEY PERFORM
@@ -81,12 +84,12 @@ func (s *ExchangeService) Create(ctx context.Context, exchange *store.Exchange)
 		selledCurrencyBalance.InOutLay += exchange.SelledMoney
 	} else {
 		tx.Rollback()
-		return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+		return types.ErrBalanceNoEnoughMoney
 	}
 
-	if err := balancesStorage.Update(ctx, selledCurrencyBalance); err != nil {
+	if err := updateBalance(ctx, tx, selledCurrencyBalance); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE UPDATING selledCurrencyBalance %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE UPDATING selledCurrencyBalance %w", err)
 	}
 
 	selledMoneyRecord := &store.BalanceRecord{
@@ -102,13 +105,38 @@ func (s *ExchangeService) Create(ctx context.Context, exchange *store.Exchange)
 
 	if err := balanceRecordsStorage.Create(ctx, selledMoneyRecord); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE CREATING BALANCE RECORD %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE CREATING BALANCE RECORD %w", err)
+	}
+
+	if currency, amount, ok := s.isLarge(exchange); ok {
+		data := map[string]string{
+			"amount":      strconv.FormatInt(amount, 10),
+			"currency":    currency,
+			"exchange_id": strconv.FormatInt(exchange.ID, 10),
+			"user_id":     strconv.FormatInt(exchange.UserId, 10),
+		}
+		if err := notifyCompany(ctx, tx, user.CompanyId, nil, store.NOTIFICATION_LARGE_EXCHANGE, data); err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
 
 	tx.Commit()
 	return nil
 }
 
+// isLarge returns the side of exchange that reaches the large exchange
+// amount of its currency, the sold side first.
+func (s *ExchangeService) isLarge(exchange *store.Exchange) (string, int64, bool) {
+	if limit, ok := s.largeExchange[exchange.SelledCurrency]; ok && exchange.SelledMoney >= limit {
+		return exchange.SelledCurrency, exchange.SelledMoney, true
+	}
+	if limit, ok := s.largeExchange[exchange.ReceivedCurrency]; ok && exchange.ReceivedMoney >= limit {
+		return exchange.ReceivedCurrency, exchange.ReceivedMoney, true
+	}
+	return "", 0, false
+}
+
 func (s *ExchangeService) Update(ctx context.Context, exchange *store.Exchange) error {
 	tx, err := s.store.BeginTx(ctx)
 	if err != nil {
@@ -126,7 +154,7 @@ func (s *ExchangeService) Update(ctx context.Context, exchange *store.Exchange)
 	}
 	exchange.CompanyID = old.CompanyID
 
-	records, err := balanceRecordsStorage.GetByField(ctx, "exchange_id", exchange.ID, types.Pagination{Limit: 100, Offset: 0})
+	records, err := balanceRecordsStorage.GetByField(ctx, "exchange_id", exchange.ID, &types.Pagination{Limit: 100, Offset: 0})
 	if err != nil {
 		return err
 	}
@@ -143,7 +171,7 @@ func (s *ExchangeService) Update(ctx context.Context, exchange *store.Exchange)
 				balance.Balance -= record.Amount
 				balance.OutInLay -= record.Amount
 			} else {
-				return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+				return types.ErrBalanceNoEnoughMoney
 			}
 		case TYPE_SELL:
 			balance.Balance += record.Amount
@@ -166,8 +194,8 @@ func (s *ExchangeService) Update(ctx context.Context, exchange *store.Exchange)
 			balance.InOutLay += record.Amount
 		}
 
-		if err := balancesStorage.Update(ctx, balance); err != nil {
-			return fmt.Errorf("ERROR OCCURRED WHILE UPDATING BALANCE %v", err)
+		if err := updateBalance(ctx, tx, balance); err != nil {
+			return fmt.Errorf("ERROR OCCURRED WHILE UPDATING BALANCE %w", err)
 		}
 
 		if err := balanceRecordsStorage.Update(ctx, &record); err != nil {
@@ -188,26 +216,34 @@ func (s *ExchangeService) Delete(ctx context.Context, id int64) error {
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
+	if err := s.delete(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// delete reverts an exchange on the user's balances and removes it,
+// within tx.
+func (s *ExchangeService) delete(ctx context.Context, tx store.DBTX, id int64) error {
 	exchangeStorage := store.NewExchangeStorage(tx)
 	balancesStorage := store.NewBalanceStorage(tx)
 	balanceRecordsStorage := store.NewBalanceRecordStorage(tx)
 
-	exchanges, err := exchangeStorage.GetByField(ctx, "id", id, types.Pagination{Limit: 100, Offset: 0})
+	exchanges, err := exchangeStorage.GetByField(ctx, "id", id, &types.Pagination{Limit: 100, Offset: 0})
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 	if len(exchanges) == 0 {
-		return sql.ErrNoRows
+		return types.ErrExchangeNotFound
 	}
 
 	exchange := exchanges[0]
 
 	balances, err := balancesStorage.GetByUserId(ctx, &exchange.UserId)
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE GETTING BALANCE WITH ID %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE GETTING BALANCE WITH ID %w", err)
 	}
 
 	for _, balance := range balances {
@@ -222,26 +258,22 @@ func (s *ExchangeService) Delete(ctx context.Context, id int64) error {
 				balance.Balance -= exchange.ReceivedMoney
 				balance.OutInLay -= exchange.ReceivedMoney
 			} else {
-				return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+				return types.ErrBalanceNoEnoughMoney
 			}
 		}
 
-		if err := balancesStorage.Update(ctx, &balance); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("ERROR OCCURRED WHILE UPDATING BALANCE %v", err)
+		if err := updateBalance(ctx, tx, &balance); err != nil {
+			return fmt.Errorf("ERROR OCCURRED WHILE UPDATING BALANCE %w", err)
 		}
 	}
 
 	if err := balanceRecordsStorage.DeleteByExchangeId(ctx, exchange.ID); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE DELETING BALANCE RECORD %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE DELETING BALANCE RECORD %w", err)
 	}
 
 	if err := exchangeStorage.Delete(ctx, id); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE DELETING BALANCE RECORD %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE DELETING BALANCE RECORD %w", err)
 	}
 
-	tx.Commit()
 	return nil
 }
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+// notifyCompany queues a notification of kind to the company's owners and,
+// when userID is set, that user, in the caller's transaction.
+func notifyCompany(ctx context.Context, tx store.DBTX, companyID int64, userID *int64, kind string, data map[string]string) error {
+	recipients, err := store.NewBalanceThresholdStorage(tx).Recipients(ctx, companyID, userID)
+	if err != nil {
+		return fmt.Errorf("ERROR OCCURRED WHILE thresholds.Recipients %w", err)
+	}
+
+	payload, _ := json.Marshal(data)
+	outbox := store.NewNotificationOutboxStorage(tx)
+	for _, recipient := range recipients {
+		if err := outbox.Create(ctx, &store.NotificationOutbox{
+			UserID:    recipient,
+			CompanyID: companyID,
+			Kind:      kind,
+			Payload:   payload,
+		}); err != nil {
+			return fmt.Errorf("ERROR OCCURRED WHILE notifications.Create %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+type ReconciliationService struct {
+	store store.Storage
+}
+
+// Run checks every balance of run.CompanyID against its records and saves
+// what it finds. Nothing is repaired: each discrepancy is for an owner to
+// look at and Repair by hand. It reports false when a scheduled run's slot
+// was already taken.
+func (s *ReconciliationService) Run(ctx context.Context, run *store.ReconciliationRun) (bool, error) {
+	started, err := s.store.Reconciliation.StartRun(ctx, run)
+	if err != nil || !started {
+		return started, err
+	}
+
+	err = s.check(ctx, run)
+	if err != nil {
+		message := err.Error()
+		run.Error = &message
+	}
+
+	if finishErr := s.store.Reconciliation.FinishRun(ctx, run); finishErr != nil {
+		if err != nil {
+			return true, fmt.Errorf("%v (finish: %v)", err, finishErr)
+		}
+		return true, finishErr
+	}
+	return true, err
+}
+
+func (s *ReconciliationService) check(ctx context.Context, run *store.ReconciliationRun) error {
+	checked, discrepancies, err := s.store.Reconciliation.Check(ctx, run.CompanyID)
+	if err != nil {
+		return err
+	}
+	run.BalancesChecked = checked
+	run.Discrepancies = len(discrepancies)
+
+	for i := range discrepancies {
+		d := &discrepancies[i]
+		if err := s.store.Reconciliation.AddDiscrepancy(ctx, run.ID, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Repair sets the balance of a discrepancy to the sum of its records as
+// they are now, and records the change in the audit log in the same
+// transaction. audit carries who asked and from where; the entity, action
+// and before/after values are filled in here.
+func (s *ReconciliationService) Repair(ctx context.Context, companyId, id int64, audit store.AuditLog) (*store.Discrepancy, error) {
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	reconciliation := store.NewReconciliationStorage(tx)
+	d, err := reconciliation.GetDiscrepancy(ctx, companyId, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.RepairedAt != nil || !d.Mismatched() {
+		return nil, types.ErrDiscrepancyResolved
+	}
+
+	balance, computed, err := reconciliation.Recompute(ctx, companyId, d.BalanceID)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Balance == computed {
+		return nil, types.ErrDiscrepancyResolved
+	}
+
+	before, err := json.Marshal(balance)
+	if err != nil {
+		return nil, err
+	}
+	balance.Balance = computed
+	if err := updateBalance(ctx, tx, balance); err != nil {
+		return nil, err
+	}
+	after, err := json.Marshal(balance)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := reconciliation.MarkRepaired(ctx, d, audit.ActorID); err != nil {
+		return nil, err
+	}
+
+	audit.CompanyID = &companyId
+	audit.EntityType = store.ENTITY_BALANCE
+	audit.EntityID = &balance.ID
+	audit.Action = store.ACTION_REPAIR
+	audit.Before = before
+	audit.After = after
+	if err := store.NewAuditLogStorage(tx).Create(ctx, &audit); err != nil {
+		return nil, fmt.Errorf("failed to audit repair: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit tx: %w", err)
+	}
+	return d, nil
+}
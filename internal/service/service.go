@@ -4,8 +4,9 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"time"
 
-	"github.com/mubashshir3767/currencyExchange/internal/notify"
+	"github.com/mubashshir3767/currencyExchange/internal/events"
 	"github.com/mubashshir3767/currencyExchange/internal/store"
 	"github.com/mubashshir3767/currencyExchange/internal/types"
 )
@@ -20,6 +21,10 @@ type Service struct {
 	Balances interface {
 		GetByCompanyId(context.Context, int64) ([]map[string]interface{}, error)
 		GetAll(context.Context) ([]map[string]interface{}, error)
+		Statement(context.Context, int64, int64, time.Time, time.Time) (*store.BalanceStatement, error)
+		Snapshot(context.Context, *int64, time.Time, time.Time) (int64, error)
+		Create(context.Context, *store.Balance) error
+		Update(context.Context, *store.Balance) error
 	}
 
 	Debts interface {
@@ -27,10 +32,12 @@ type Service struct {
 		Transaction(context.Context, *store.Debts) error
 		Update(context.Context, *store.Debts) error
 		Delete(context.Context, int64) error
+		NotifyDue(context.Context) (int, error)
 	}
 
 	Debtors interface {
-		GetByCompanyId(context.Context, int64, *string, *string, types.Pagination) ([]map[string]interface{}, error)
+		GetByCompanyId(context.Context, int64, *string, *string, *types.Pagination) ([]map[string]interface{}, error)
+		Filter(context.Context, int64, *types.Filter, *types.Pagination) ([]map[string]interface{}, error)
 	}
 
 	BalanceRecords interface {
@@ -40,25 +47,68 @@ type Service struct {
 	}
 
 	Transactions interface {
-		GetByField(context.Context, *string, string, any, types.Pagination) ([]map[string]interface{}, error)
+		GetByField(context.Context, *string, string, any, *types.Pagination) ([]map[string]interface{}, error)
+		Filter(context.Context, int64, *types.Filter, *types.Pagination) ([]map[string]interface{}, error)
 		PerformTransaction(context.Context, *store.Transaction) error
 		CompleteTransaction(context.Context, types.TransactionComplete) error
-		GetByCompanyId(context.Context, int64, types.Pagination) ([]map[string]interface{}, error)
+		GetByCompanyId(context.Context, int64, *types.Pagination) ([]map[string]interface{}, error)
 		GetInfos(ctx context.Context, date string) ([]store.CompanyAmount, error)
-		Archived(context.Context, types.Pagination) ([]map[string]interface{}, error)
+		Archived(context.Context, *types.Pagination) ([]map[string]interface{}, error)
 		Update(context.Context, *store.Transaction) error
 		Delete(context.Context, *int64) error
+		RebuildCompanyAmounts(context.Context) error
+	}
+
+	ChangeRequests interface {
+		Request(context.Context, *store.ChangeRequest) error
+		Approve(context.Context, int64, int64, *string) (*store.ChangeRequest, error)
+		Reject(context.Context, int64, int64, *string) (*store.ChangeRequest, error)
+	}
+
+	Dashboard interface {
+		Get(context.Context, int64, time.Time, time.Time) (*store.Dashboard, error)
+	}
+
+	Users interface {
+		Register(context.Context, *store.User) error
+	}
+
+	UserSessions interface {
+		Upsert(context.Context, *store.UserSession) error
+	}
+
+	Reconciliation interface {
+		Run(context.Context, *store.ReconciliationRun) (bool, error)
+		Repair(context.Context, int64, int64, store.AuditLog) (*store.Discrepancy, error)
 	}
 }
 
-func NewService(store store.Storage, delivered notify.DeliveredUser) Service {
+// NewService wires the services. largeExchange is the amount per currency
+// from which an exchange is reported to the company's owners.
+func NewService(store store.Storage, bus events.Bus, largeExchange map[string]int64) Service {
+	exchanges := &ExchangeService{store: store, largeExchange: largeExchange}
+	balanceRecords := &BalanceRecordService{store: store}
+	transactions := NewTransactionService(store, bus)
+	debts := &DebtsService{store: store, bus: bus}
+
 	return Service{
 		Debtors:        &DebtorsService{store: store},
 		Balances:       &BalanceService{store: store},
-		Exchanges:      &ExchangeService{store: store},
-		BalanceRecords: &BalanceRecordService{store: store},
-		Transactions:   NewTransactionService(store, delivered),
-		Debts:          &DebtsService{store: store},
+		Exchanges:      exchanges,
+		BalanceRecords: balanceRecords,
+		Transactions:   transactions,
+		Debts:          debts,
+		ChangeRequests: &ChangeRequestService{
+			store:          store,
+			transactions:   transactions,
+			exchanges:      exchanges,
+			balanceRecords: balanceRecords,
+			debts:          debts,
+		},
+		Users:          &UserService{store: store},
+		UserSessions:   &UserSessionService{store: store},
+		Reconciliation: &ReconciliationService{store: store},
+		Dashboard:      &DashboardService{store: store},
 	}
 }
 
@@ -3,10 +3,12 @@ package service
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"time"
+	"strconv"
 
-	"github.com/mubashshir3767/currencyExchange/internal/notify"
+	"github.com/mubashshir3767/currencyExchange/internal/events"
+	"github.com/mubashshir3767/currencyExchange/internal/sms"
 	"github.com/mubashshir3767/currencyExchange/internal/store"
 	"github.com/mubashshir3767/currencyExchange/internal/types"
 )
@@ -19,15 +21,12 @@ const (
 )
 
 type TransactionService struct {
-	store  store.Storage
-	notify notify.DeliveredUser
+	store store.Storage
+	bus   events.Bus
 }
 
-func NewTransactionService(store store.Storage, delivered notify.DeliveredUser) *TransactionService {
-	if delivered == nil {
-		delivered = notify.NoopDeliveredUser{}
-	}
-	return &TransactionService{store: store, notify: delivered}
+func NewTransactionService(store store.Storage, bus events.Bus) *TransactionService {
+	return &TransactionService{store: store, bus: bus}
 }
 
 func (s *TransactionService) PerformTransaction(ctx context.Context, transaction *store.Transaction) error {
@@ -41,7 +40,7 @@ func (s *TransactionService) PerformTransaction(ctx context.Context, transaction
 	transactionsStorage := store.NewTransactionStorage(tx)
 	if err := transactionsStorage.Create(ctx, transaction); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE Transactions.Create %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE Transactions.Create %w", err)
 	}
 
 	for _, tr := range transaction.ReceivedIncomes {
@@ -49,9 +48,9 @@ func (s *TransactionService) PerformTransaction(ctx context.Context, transaction
 		if err != nil {
 			tx.Rollback()
 			if err == sql.ErrNoRows {
-				return fmt.Errorf("user %d does not have a balance for currency %s", transaction.ReceivedUserId, tr.ReceivedCurrency)
+				return types.ErrBalanceCurrencyNotFound.WithField("currency", tr.ReceivedCurrency)
 			} else {
-				return fmt.Errorf("ERROR OCCURRED WHILE balancesStorage.GetByUserIdAndCurrency %v", err)
+				return fmt.Errorf("ERROR OCCURRED WHILE balancesStorage.GetByUserIdAndCurrency %w", err)
 			}
 		}
 
@@ -62,14 +61,14 @@ func (s *TransactionService) PerformTransaction(ctx context.Context, transaction
 				balance.InOutLay += tr.ReceivedAmount
 			} else {
 				tx.Rollback()
-				return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+				return types.ErrBalanceNoEnoughMoney
 			}
 		case TYPE_BUY:
 			balance.Balance += tr.ReceivedAmount
 			balance.OutInLay += tr.ReceivedAmount
 		default:
 			tx.Rollback()
-			return fmt.Errorf("FOUND UNKNOWN TYPE")
+			return types.ErrUnknownType
 		}
 
 		balanceRecord := &store.BalanceRecord{
@@ -84,30 +83,47 @@ func (s *TransactionService) PerformTransaction(ctx context.Context, transaction
 
 		if err := balanceRecordsStorage.Create(ctx, balanceRecord); err != nil {
 			tx.Rollback()
-			return fmt.Errorf("ERROR OCCURRED WHILE BalanceRecords.Create %v", err)
+			return fmt.Errorf("ERROR OCCURRED WHILE BalanceRecords.Create %w", err)
 		}
 
-		if err := balancesStorage.Update(ctx, balance); err != nil {
+		if err := updateBalance(ctx, tx, balance); err != nil {
 			tx.Rollback()
-			return fmt.Errorf("ERROR OCCURRED WHILE balancesStorage.Update %v", err)
+			return fmt.Errorf("ERROR OCCURRED WHILE balancesStorage.Update %w", err)
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return err
+	outboxStorage := store.NewOutboxStorage(tx)
+	if err := outboxStorage.Add(ctx, events.TRANSACTION_CREATED, []int64{transaction.ReceivedCompanyId, transaction.DeliveredCompanyId}, transaction); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("ERROR OCCURRED WHILE outboxStorage.Add %w", err)
 	}
 
 	if transaction.DeliveredUserId != nil {
-		uid := *transaction.DeliveredUserId
-		tid := transaction.ID
-		phone := transaction.Phone
-		details := transaction.Details
-		go func() {
-			ctxN, cancel := context.WithTimeout(context.Background(), 25*time.Second)
-			defer cancel()
-			s.notify.NotifyPendingDelivery(ctxN, &uid, tid, phone, details)
-		}()
+		notification := deliveredNotification(store.NOTIFICATION_TRANSACTION_PENDING, *transaction.DeliveredUserId, transaction.DeliveredCompanyId, transaction.ID, transaction.Phone, transaction.Details)
+		if err := store.NewNotificationOutboxStorage(tx).Create(ctx, notification); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("ERROR OCCURRED WHILE notifications.Create %w", err)
+		}
+	}
+
+	if transaction.Phone != "" {
+		code, err := sms.PickupCode()
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		message := recipientSMS(store.SMS_TRANSACTION_CREATED, transaction.ReceivedCompanyId, transaction.ID, transaction.Phone, &code)
+		if err := store.NewSMSStorage(tx).Create(ctx, message); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("ERROR OCCURRED WHILE sms.Create %w", err)
+		}
 	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	events.Publish(ctx, s.bus, events.TRANSACTION_CREATED, []int64{transaction.ReceivedCompanyId, transaction.DeliveredCompanyId}, transaction)
 	return nil
 }
 
@@ -123,8 +139,20 @@ func (s *TransactionService) CompleteTransaction(ctx context.Context, transactio
 
 	tran, err := transactionsStorage.GetById(ctx, transaction.TransactionID)
 	if err != nil {
+		if err != sql.ErrNoRows {
+			tx.Rollback()
+			return fmt.Errorf("ERROR OCCURRED WHILE transactionsStorage.GetById %w", err)
+		}
+		// GetById only finds pending transactions.
+		exists, err := transactionsStorage.Exists(ctx, transaction.TransactionID)
 		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE transactionsStorage.GetById %v", err)
+		if err != nil {
+			return fmt.Errorf("ERROR OCCURRED WHILE transactionsStorage.Exists %w", err)
+		}
+		if !exists {
+			return types.ErrTransactionNotFound
+		}
+		return types.ErrTransactionAlreadyCompleted
 	}
 
 	for _, tr := range tran.DeliveredOutcomes {
@@ -132,9 +160,9 @@ func (s *TransactionService) CompleteTransaction(ctx context.Context, transactio
 		if err != nil {
 			tx.Rollback()
 			if err == sql.ErrNoRows {
-				return fmt.Errorf("user %d does not have a balance for currency %s", transaction.DeliveredUserId, tr.DeliveredCurrency)
+				return types.ErrBalanceCurrencyNotFound.WithField("currency", tr.DeliveredCurrency)
 			}
-			return fmt.Errorf("ERROR OCCURRED WHILE balancesStorage.GetByUserIdAndCurrency %v", err)
+			return fmt.Errorf("ERROR OCCURRED WHILE balancesStorage.GetByUserIdAndCurrency %w", err)
 		}
 
 		var recordType int64
@@ -149,7 +177,7 @@ func (s *TransactionService) CompleteTransaction(ctx context.Context, transactio
 				balance.InOutLay += tr.DeliveredAmount
 			} else {
 				tx.Rollback()
-				return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+				return types.ErrBalanceNoEnoughMoney
 			}
 		}
 
@@ -165,12 +193,12 @@ func (s *TransactionService) CompleteTransaction(ctx context.Context, transactio
 
 		if err := balanceRecordsStorage.Create(ctx, balanceRecord); err != nil {
 			tx.Rollback()
-			return fmt.Errorf("ERROR OCCURRED WHILE balanceRecordsStorage.Create %v", err)
+			return fmt.Errorf("ERROR OCCURRED WHILE balanceRecordsStorage.Create %w", err)
 		}
 
-		if err := balancesStorage.Update(ctx, balance); err != nil {
+		if err := updateBalance(ctx, tx, balance); err != nil {
 			tx.Rollback()
-			return fmt.Errorf("ERROR OCCURRED WHILE balancesStorage.Update %v", err)
+			return fmt.Errorf("ERROR OCCURRED WHILE balancesStorage.Update %w", err)
 		}
 	}
 
@@ -179,46 +207,124 @@ func (s *TransactionService) CompleteTransaction(ctx context.Context, transactio
 
 	if err := transactionsStorage.Update(ctx, tran); err != nil {
 		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE transactionsStorage.Update %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE transactionsStorage.Update %w", err)
+	}
+
+	outboxStorage := store.NewOutboxStorage(tx)
+	if err := outboxStorage.Add(ctx, events.TRANSACTION_COMPLETED, []int64{tran.ReceivedCompanyId, tran.DeliveredCompanyId}, tran); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("ERROR OCCURRED WHILE outboxStorage.Add %w", err)
+	}
+
+	notification := deliveredNotification(store.NOTIFICATION_TRANSACTION_COMPLETED, transaction.DeliveredUserId, tran.DeliveredCompanyId, tran.ID, "", tran.Details)
+	if err := store.NewNotificationOutboxStorage(tx).Create(ctx, notification); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("ERROR OCCURRED WHILE notifications.Create %w", err)
+	}
+
+	if tran.Phone != "" {
+		message := recipientSMS(store.SMS_TRANSACTION_COMPLETED, tran.DeliveredCompanyId, tran.ID, tran.Phone, nil)
+		if err := store.NewSMSStorage(tx).Create(ctx, message); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("ERROR OCCURRED WHILE sms.Create %w", err)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
 		return err
 	}
 
-	deliveredID := transaction.DeliveredUserId
-	tid := tran.ID
-	details := tran.Details
-	go func() {
-		ctxN, cancel := context.WithTimeout(context.Background(), 25*time.Second)
-		defer cancel()
-		s.notify.NotifyDeliveryCompleted(ctxN, deliveredID, tid, details)
-	}()
+	events.Publish(ctx, s.bus, events.TRANSACTION_COMPLETED, []int64{tran.ReceivedCompanyId, tran.DeliveredCompanyId}, tran)
 	return nil
 }
 
+// deliveredNotification builds the outbox row for a notification to the
+// delivered user of a transaction.
+func deliveredNotification(kind string, userID, companyID, transactionID int64, phone, details string) *store.NotificationOutbox {
+	payload, _ := json.Marshal(map[string]string{"phone": phone, "details": details})
+	return &store.NotificationOutbox{
+		UserID:        userID,
+		CompanyID:     companyID,
+		Kind:          kind,
+		TransactionID: &transactionID,
+		Payload:       payload,
+	}
+}
+
+// cancelledNotifications builds the outbox rows telling both users of a
+// deleted transaction that it was cancelled.
+func cancelledNotifications(tran *store.Transaction) []*store.NotificationOutbox {
+	payload, _ := json.Marshal(map[string]string{"number": strconv.FormatInt(tran.Number, 10)})
+
+	var notifications []*store.NotificationOutbox
+	if tran.ReceivedUserId != 0 {
+		notifications = append(notifications, &store.NotificationOutbox{
+			UserID:        tran.ReceivedUserId,
+			CompanyID:     tran.ReceivedCompanyId,
+			Kind:          store.NOTIFICATION_TRANSACTION_CANCELLED,
+			TransactionID: &tran.ID,
+			Payload:       payload,
+		})
+	}
+	if tran.DeliveredUserId != nil && *tran.DeliveredUserId != tran.ReceivedUserId {
+		notifications = append(notifications, &store.NotificationOutbox{
+			UserID:        *tran.DeliveredUserId,
+			CompanyID:     tran.DeliveredCompanyId,
+			Kind:          store.NOTIFICATION_TRANSACTION_CANCELLED,
+			TransactionID: &tran.ID,
+			Payload:       payload,
+		})
+	}
+	return notifications
+}
+
+// recipientSMS builds the text to the phone the remittance is for. The pickup
+// code is only sent when the transaction is created.
+func recipientSMS(kind string, companyID, transactionID int64, phone string, pickupCode *string) *store.SMSMessage {
+	var code string
+	if pickupCode != nil {
+		code = *pickupCode
+	}
+	return &store.SMSMessage{
+		CompanyID:     companyID,
+		TransactionID: &transactionID,
+		Phone:         phone,
+		Kind:          kind,
+		Body:          sms.Text(kind, transactionID, code),
+		PickupCode:    pickupCode,
+	}
+}
+
 func (s *TransactionService) Update(ctx context.Context, transaction *store.Transaction) error {
 	tx, err := s.store.BeginTx(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	if err := s.update(ctx, tx, transaction); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
+// update reverts the balance records of a transaction and books it again
+// as given, within tx.
+func (s *TransactionService) update(ctx context.Context, tx store.DBTX, transaction *store.Transaction) error {
 	balancesStorage := store.NewBalanceStorage(tx)
 	balanceRecordsStorage := store.NewBalanceRecordStorage(tx)
 	transactionsStorage := store.NewTransactionStorage(tx)
 
-	records, err := balanceRecordsStorage.GetByField(ctx, "transaction_id", transaction.ID, types.Pagination{Limit: 1000, Offset: 0})
+	records, err := balanceRecordsStorage.GetByField(ctx, "transaction_id", transaction.ID, &types.Pagination{Limit: 1000, Offset: 0})
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
 	for _, record := range records {
 		balance, err := balancesStorage.GetById(ctx, &record.BalanceID)
 		if err != nil {
-			tx.Rollback()
 			if err == sql.ErrNoRows {
-				return fmt.Errorf("balance %d not found", record.BalanceID)
+				return types.ErrBalanceNotFound
 			}
 			return err
 		}
@@ -233,12 +339,10 @@ func (s *TransactionService) Update(ctx context.Context, transaction *store.Tran
 		}
 
 		if err := balanceRecordsStorage.Delete(ctx, record.ID); err != nil {
-			tx.Rollback()
 			return err
 		}
 
-		if err := balancesStorage.Update(ctx, balance); err != nil {
-			tx.Rollback()
+		if err := updateBalance(ctx, tx, balance); err != nil {
 			return err
 		}
 	}
@@ -247,9 +351,8 @@ func (s *TransactionService) Update(ctx context.Context, transaction *store.Tran
 		for _, tr := range transaction.ReceivedIncomes {
 			balance, err := balancesStorage.GetByUserIdAndCurrency(ctx, &transaction.ReceivedUserId, tr.ReceivedCurrency)
 			if err != nil {
-				tx.Rollback()
 				if err == sql.ErrNoRows {
-					return fmt.Errorf("user %d does not have a balance for currency %s", transaction.ReceivedUserId, tr.ReceivedCurrency)
+					return types.ErrBalanceCurrencyNotFound.WithField("currency", tr.ReceivedCurrency)
 				}
 				return err
 			}
@@ -268,8 +371,7 @@ func (s *TransactionService) Update(ctx context.Context, transaction *store.Tran
 			switch transaction.Type {
 			case TYPE_SELL:
 				if balance.Balance < tr.ReceivedAmount {
-					tx.Rollback()
-					return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+					return types.ErrBalanceNoEnoughMoney
 				}
 				balance.Balance -= tr.ReceivedAmount
 				balance.InOutLay += tr.ReceivedAmount
@@ -277,17 +379,14 @@ func (s *TransactionService) Update(ctx context.Context, transaction *store.Tran
 				balance.Balance += tr.ReceivedAmount
 				balance.OutInLay += tr.ReceivedAmount
 			default:
-				tx.Rollback()
-				return fmt.Errorf("FOUND UNKNOWN TYPE")
+				return types.ErrUnknownType
 			}
 
-			if err := balancesStorage.Update(ctx, balance); err != nil {
-				tx.Rollback()
+			if err := updateBalance(ctx, tx, balance); err != nil {
 				return err
 			}
 
 			if err := balanceRecordsStorage.Create(ctx, record); err != nil {
-				tx.Rollback()
 				return err
 			}
 		}
@@ -297,9 +396,8 @@ func (s *TransactionService) Update(ctx context.Context, transaction *store.Tran
 		for _, tr := range transaction.DeliveredOutcomes {
 			balance, err := balancesStorage.GetByUserIdAndCurrency(ctx, transaction.DeliveredUserId, tr.DeliveredCurrency)
 			if err != nil {
-				tx.Rollback()
 				if err == sql.ErrNoRows {
-					return fmt.Errorf("user %d does not have a balance for currency %s", *transaction.DeliveredUserId, tr.DeliveredCurrency)
+					return types.ErrBalanceCurrencyNotFound.WithField("currency", tr.DeliveredCurrency)
 				}
 				return err
 			}
@@ -312,8 +410,7 @@ func (s *TransactionService) Update(ctx context.Context, transaction *store.Tran
 			} else {
 				recordType = TYPE_SELL
 				if balance.Balance < tr.DeliveredAmount {
-					tx.Rollback()
-					return fmt.Errorf(types.BALANCE_NO_ENOUGH_MONEY)
+					return types.ErrBalanceNoEnoughMoney
 				}
 				balance.Balance -= tr.DeliveredAmount
 				balance.InOutLay += tr.DeliveredAmount
@@ -330,24 +427,20 @@ func (s *TransactionService) Update(ctx context.Context, transaction *store.Tran
 				Type:          recordType,
 			}
 
-			if err := balancesStorage.Update(ctx, balance); err != nil {
-				tx.Rollback()
+			if err := updateBalance(ctx, tx, balance); err != nil {
 				return err
 			}
 
 			if err := balanceRecordsStorage.Create(ctx, record); err != nil {
-				tx.Rollback()
 				return err
 			}
 		}
 	}
 
 	if err := transactionsStorage.Update(ctx, transaction); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("ERROR OCCURRED WHILE UPDATING TRANSACTION %v", err)
+		return fmt.Errorf("ERROR OCCURRED WHILE UPDATING TRANSACTION %w", err)
 	}
 
-	tx.Commit()
 	return nil
 }
 
@@ -356,29 +449,36 @@ func (s *TransactionService) Delete(ctx context.Context, id *int64) error {
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
+	if err := s.delete(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// delete reverts the balance records of a transaction and removes it,
+// within tx.
+func (s *TransactionService) delete(ctx context.Context, tx store.DBTX, id *int64) error {
 	balancesStorage := store.NewBalanceStorage(tx)
 	balanceRecordsStorage := store.NewBalanceRecordStorage(tx)
 	transactionsStorage := store.NewTransactionStorage(tx)
 
 	tran, err := transactionsStorage.GetById(ctx, *id)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
-	records, err := balanceRecordsStorage.GetByField(ctx, "transaction_id", tran.ID, types.Pagination{Limit: 1000, Offset: 0})
+	records, err := balanceRecordsStorage.GetByField(ctx, "transaction_id", tran.ID, &types.Pagination{Limit: 1000, Offset: 0})
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
 	for _, record := range records {
 		balance, err := balancesStorage.GetById(ctx, &record.BalanceID)
 		if err != nil {
-			tx.Rollback()
 			if err == sql.ErrNoRows {
-				return fmt.Errorf("balance %d not found", record.BalanceID)
+				return types.ErrBalanceNotFound
 			}
 			return err
 		}
@@ -391,26 +491,28 @@ func (s *TransactionService) Delete(ctx context.Context, id *int64) error {
 			balance.Balance -= record.Amount
 			balance.OutInLay -= record.Amount
 		}
-		if err := balancesStorage.Update(ctx, balance); err != nil {
-			tx.Rollback()
+		if err := updateBalance(ctx, tx, balance); err != nil {
 			return err
 		}
 		if err := balanceRecordsStorage.Delete(ctx, record.ID); err != nil {
-			tx.Rollback()
 			return err
 		}
 	}
 
 	if err := transactionsStorage.Delete(ctx, &tran.ID); err != nil {
-		tx.Rollback()
 		return err
 	}
 
-	tx.Commit()
+	for _, notification := range cancelledNotifications(tran) {
+		if err := store.NewNotificationOutboxStorage(tx).Create(ctx, notification); err != nil {
+			return fmt.Errorf("ERROR OCCURRED WHILE notifications.Create %w", err)
+		}
+	}
+
 	return nil
 }
 
-func (s *TransactionService) GetByCompanyId(ctx context.Context, companyId int64, pagination types.Pagination) ([]map[string]interface{}, error) {
+func (s *TransactionService) GetByCompanyId(ctx context.Context, companyId int64, pagination *types.Pagination) ([]map[string]interface{}, error) {
 	trans, err := s.store.Transactions.GetByField(ctx, nil, "delivered_company_id", companyId, pagination)
 	if err != nil {
 		return nil, err
@@ -482,12 +584,26 @@ func (s *TransactionService) GetByCompanyId(ctx context.Context, companyId int64
 	return response, nil
 }
 
-func (s *TransactionService) GetByField(ctx context.Context, search *string, fieldName string, value any, pagination types.Pagination) ([]map[string]interface{}, error) {
+func (s *TransactionService) GetByField(ctx context.Context, search *string, fieldName string, value any, pagination *types.Pagination) ([]map[string]interface{}, error) {
 	trans, err := s.store.Transactions.GetByField(ctx, search, fieldName, value, pagination)
 	if err != nil {
 		return nil, err
 	}
 
+	return s.describe(ctx, trans)
+}
+
+func (s *TransactionService) Filter(ctx context.Context, companyId int64, filter *types.Filter, pagination *types.Pagination) ([]map[string]interface{}, error) {
+	trans, err := s.store.Transactions.Filter(ctx, companyId, filter, pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.describe(ctx, trans)
+}
+
+// describe adds company and user names to each transaction.
+func (s *TransactionService) describe(ctx context.Context, trans []store.Transaction) ([]map[string]interface{}, error) {
 	companies, err := s.store.Companies.GetAll(ctx)
 	if err != nil {
 		return nil, err
@@ -550,7 +666,7 @@ func (s *TransactionService) GetByField(ctx context.Context, search *string, fie
 	return response, nil
 }
 
-func (s *TransactionService) Archived(ctx context.Context, pagination types.Pagination) ([]map[string]interface{}, error) {
+func (s *TransactionService) Archived(ctx context.Context, pagination *types.Pagination) ([]map[string]interface{}, error) {
 	trans, err := s.store.Transactions.Archived(ctx, pagination)
 	if err != nil {
 		return nil, err
@@ -619,12 +735,27 @@ func (s *TransactionService) Archived(ctx context.Context, pagination types.Pagi
 func (s *TransactionService) GetInfos(ctx context.Context, date string) ([]store.CompanyAmount, error) {
 	trans, err := s.store.Transactions.GetCompanyFinalAmounts(ctx, []int64{1, 2}, date)
 	if err != nil {
-		return nil, fmt.Errorf("ERROR OCCURRED WHILE Transactions.GetByField %v", err)
+		return nil, fmt.Errorf("ERROR OCCURRED WHILE Transactions.GetByField %w", err)
 	}
 
 	return trans, nil
 }
 
+// RebuildCompanyAmounts recounts the company amounts GetInfos reads from
+// every transaction, in case they have drifted.
+func (s *TransactionService) RebuildCompanyAmounts(ctx context.Context) error {
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := store.NewTransactionStorage(tx).RebuildCompanyAmounts(ctx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func GetOne(ids map[string]int64, id string) int64 {
 	return ids[id]
 }
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+type UserSessionService struct {
+	store store.Storage
+}
+
+// Upsert saves the session of a device. The first time a device signs in to
+// an account that already has another one, the user is told about it.
+func (s *UserSessionService) Upsert(ctx context.Context, session *store.UserSession) error {
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sessionsStorage := store.NewUserSessionStorage(tx)
+
+	created, err := sessionsStorage.Upsert(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		sessions, err := sessionsStorage.ListByUserID(ctx, session.UserID)
+		if err != nil {
+			return err
+		}
+
+		if len(sessions) > 1 {
+			user, err := store.NewUserStorage(tx).GetById(ctx, &session.UserID)
+			if err != nil {
+				return fmt.Errorf("failed to get user: %w", err)
+			}
+
+			if err := store.NewNotificationOutboxStorage(tx).Create(ctx, newDeviceNotification(user, session)); err != nil {
+				return fmt.Errorf("ERROR OCCURRED WHILE notifications.Create %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// newDeviceNotification names the device by what the app reported about it,
+// falling back to its id.
+func newDeviceNotification(user *store.User, session *store.UserSession) *store.NotificationOutbox {
+	device := session.DeviceID
+	switch {
+	case session.UserAgent != nil && *session.UserAgent != "":
+		device = *session.UserAgent
+	case session.Platform != nil && *session.Platform != "":
+		device = *session.Platform
+	}
+
+	payload, _ := json.Marshal(map[string]string{"device": device, "device_id": session.DeviceID})
+	return &store.NotificationOutbox{
+		UserID:    user.ID,
+		CompanyID: user.CompanyId,
+		Kind:      store.NOTIFICATION_NEW_DEVICE_LOGIN,
+		Payload:   payload,
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+type UserService struct {
+	store store.Storage
+}
+
+// Register creates a user who signed up themselves, with the role
+// UserStorage.Register picks. The company stays locked while it looks at
+// the company's users, so only one first user becomes the owner.
+func (s *UserService) Register(ctx context.Context, user *store.User) error {
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := store.NewCompanyStorage(tx).Lock(ctx, user.CompanyId); err != nil {
+		return err
+	}
+
+	if err := store.NewUserStorage(tx).Register(ctx, user); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,123 @@
+package sms
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/i18n"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+const (
+	// MAX_ATTEMPTS is how many times a message is tried before it is marked
+	// failed.
+	MAX_ATTEMPTS = 6
+	BATCH_SIZE   = 50
+	POLL_EVERY   = 3 * time.Second
+	SEND_TIMEOUT = 15 * time.Second
+	BASE_BACKOFF = 15 * time.Second
+	MAX_BACKOFF  = 15 * time.Minute
+)
+
+// Store is the part of store.Storage the dispatcher uses.
+type Store interface {
+	Claim(context.Context, int, time.Duration) ([]store.SMSMessage, error)
+	MarkSent(context.Context, int64, string) error
+	MarkFailed(context.Context, int64, string, *time.Time) error
+}
+
+// Dispatcher sends the queued messages through the provider.
+type Dispatcher struct {
+	store    Store
+	provider Provider
+}
+
+func NewDispatcher(st Store, provider Provider) *Dispatcher {
+	return &Dispatcher{store: st, provider: provider}
+}
+
+// Run polls until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(POLL_EVERY)
+	defer ticker.Stop()
+
+	for {
+		d.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) sendDue(ctx context.Context) {
+	messages, err := d.store.Claim(ctx, BATCH_SIZE, 2*SEND_TIMEOUT)
+	if err != nil {
+		log.Printf("sms: %v", err)
+		return
+	}
+
+	for _, m := range messages {
+		sendCtx, cancel := context.WithTimeout(ctx, SEND_TIMEOUT)
+		providerID, err := d.provider.SendSMS(sendCtx, m.Phone, m.Body)
+		cancel()
+
+		if err == nil {
+			err = d.store.MarkSent(ctx, m.ID, providerID)
+		} else {
+			var retryAt *time.Time
+			if m.Attempts < MAX_ATTEMPTS {
+				next := time.Now().Add(Backoff(m.Attempts))
+				retryAt = &next
+			}
+			err = d.store.MarkFailed(ctx, m.ID, err.Error(), retryAt)
+		}
+		if err != nil {
+			log.Printf("sms: message %d: %v", m.ID, err)
+		}
+	}
+}
+
+// Backoff is the wait after the given failed attempt: 15s, 30s, 1m, ...
+// capped at a quarter of an hour.
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	wait := BASE_BACKOFF
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if wait >= MAX_BACKOFF {
+			return MAX_BACKOFF
+		}
+	}
+	return wait
+}
+
+// PickupCode returns a random six digit code the recipient shows to collect
+// the money.
+func PickupCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// Text renders the message for a recipient. Recipients have no account, so
+// it is written in the default language.
+func Text(kind string, transactionID int64, pickupCode string) string {
+	id := strconv.FormatInt(transactionID, 10)
+	switch kind {
+	case store.SMS_TRANSACTION_CREATED:
+		return i18n.T(i18n.DEFAULT, "sms.transaction_created", id, pickupCode)
+	}
+	return i18n.T(i18n.DEFAULT, "sms."+kind, id)
+}
@@ -0,0 +1,96 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Provider hands a text message to an SMS gateway and returns the gateway's
+// id for it, if it has one.
+type Provider interface {
+	SendSMS(ctx context.Context, phone, text string) (string, error)
+}
+
+// FileProvider appends every message to a file as a JSON line instead of
+// sending it. It is the stub used for local runs and tests.
+type FileProvider struct {
+	mu   sync.Mutex
+	Path string
+}
+
+func (p *FileProvider) SendSMS(_ context.Context, phone, text string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(p.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	id := strconv.FormatInt(time.Now().UnixNano(), 10)
+	line, err := json.Marshal(map[string]string{"id": id, "phone": phone, "text": text})
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// HTTPProvider posts {"phone", "text"} as JSON to a gateway URL and reads
+// {"id"} back. Pointing it at a local server gives an HTTP stub.
+type HTTPProvider struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+func NewHTTPProvider(url, token string) *HTTPProvider {
+	return &HTTPProvider{URL: url, Token: token, Client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (p *HTTPProvider) SendSMS(ctx context.Context, phone, text string) (string, error) {
+	body, err := json.Marshal(map[string]string{"phone": phone, "text": text})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("sms gateway answered %s", resp.Status)
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return result.ID, nil
+}
+
+var (
+	_ Provider = (*FileProvider)(nil)
+	_ Provider = (*HTTPProvider)(nil)
+)
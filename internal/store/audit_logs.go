@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+type AuditLog struct {
+	ID                 int64           `json:"id"`
+	ActorID            *int64          `json:"actor_id"`
+	CompanyID          *int64          `json:"company_id"`
+	DeviceID           *string         `json:"device_id"`
+	IP                 string          `json:"ip"`
+	Method             string          `json:"method"`
+	Route              string          `json:"route"`
+	EntityType         string          `json:"entity_type"`
+	EntityID           *int64          `json:"entity_id"`
+	Action             string          `json:"action"`
+	Before             json.RawMessage `json:"before"`
+	After              json.RawMessage `json:"after"`
+	CreatedAt          time.Time       `json:"-"`
+	CreatedAtFormatted string          `json:"created_at"`
+}
+
+type AuditLogStorage struct {
+	db DBTX
+}
+
+func NewAuditLogStorage(db DBTX) *AuditLogStorage {
+	return &AuditLogStorage{db: db}
+}
+
+func (s *AuditLogStorage) Create(ctx context.Context, log *AuditLog) error {
+	query := `
+		INSERT INTO audit_logs (actor_id, company_id, device_id, ip, method, route, entity_type, entity_id, action, before, after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, created_at
+	`
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		log.ActorID,
+		log.CompanyID,
+		log.DeviceID,
+		log.IP,
+		log.Method,
+		log.Route,
+		log.EntityType,
+		log.EntityID,
+		log.Action,
+		nullableJSON(log.Before),
+		nullableJSON(log.After),
+	).Scan(
+		&log.ID,
+		&log.CreatedAt,
+	)
+}
+
+func (s *AuditLogStorage) GetByEntity(ctx context.Context, companyId int64, entityType string, entityId int64, pagination *types.Pagination) ([]AuditLog, error) {
+	query := `
+		SELECT id, actor_id, company_id, device_id, ip, method, route, entity_type, entity_id, action, before, after, created_at
+		FROM audit_logs WHERE company_id = $1 AND entity_type = $2 AND entity_id = $3
+	`
+
+	rows, err := queryPage(ctx, s.db, query, []any{companyId, entityType, entityId}, AuditLogSorts, pagination)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return s.fetchPage(rows, pagination)
+}
+
+func (s *AuditLogStorage) GetByActor(ctx context.Context, companyId int64, actorId int64, pagination *types.Pagination) ([]AuditLog, error) {
+	query := `
+		SELECT id, actor_id, company_id, device_id, ip, method, route, entity_type, entity_id, action, before, after, created_at
+		FROM audit_logs WHERE company_id = $1 AND actor_id = $2
+	`
+
+	rows, err := queryPage(ctx, s.db, query, []any{companyId, actorId}, AuditLogSorts, pagination)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return s.fetchPage(rows, pagination)
+}
+
+func (s *AuditLogStorage) fetchPage(rows *sql.Rows, pagination *types.Pagination) ([]AuditLog, error) {
+	logs, err := s.scanAuditLogs(rows)
+	if err != nil {
+		return nil, err
+	}
+	return trimPage(pagination, logs, AuditLog.pageKey), nil
+}
+
+// DeleteOlderThan removes entries created before the cutoff and returns how
+// many were deleted.
+func (s *AuditLogStorage) DeleteOlderThan(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM audit_logs WHERE created_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (s *AuditLogStorage) scanAuditLogs(rows *sql.Rows) ([]AuditLog, error) {
+	var logs []AuditLog
+	for rows.Next() {
+		var l AuditLog
+		var actorID, companyID, entityID sql.NullInt64
+		var deviceID, ip, method, route sql.NullString
+		var before, after []byte
+
+		if err := rows.Scan(
+			&l.ID,
+			&actorID,
+			&companyID,
+			&deviceID,
+			&ip,
+			&method,
+			&route,
+			&l.EntityType,
+			&entityID,
+			&l.Action,
+			&before,
+			&after,
+			&l.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if actorID.Valid {
+			l.ActorID = &actorID.Int64
+		}
+		if companyID.Valid {
+			l.CompanyID = &companyID.Int64
+		}
+		if entityID.Valid {
+			l.EntityID = &entityID.Int64
+		}
+		if deviceID.Valid {
+			l.DeviceID = &deviceID.String
+		}
+		l.IP = ip.String
+		l.Method = method.String
+		l.Route = route.String
+		if len(before) > 0 {
+			l.Before = json.RawMessage(before)
+		}
+		if len(after) > 0 {
+			l.After = json.RawMessage(after)
+		}
+		l.CreatedAtFormatted = formatTashkent(l.CreatedAt)
+
+		logs = append(logs, l)
+	}
+
+	return logs, rows.Err()
+}
@@ -35,6 +35,20 @@ func NewBalanceRecordStorage(db DBTX) *BalanceRecordStorage {
 	return &BalanceRecordStorage{db: db}
 }
 
+// Details of the records written for balances set by hand, which have no
+// transaction, debt or exchange behind them.
+const (
+	RECORD_OPENING    = "opening balance"
+	RECORD_ADJUSTMENT = "balance adjustment"
+)
+
+// recordDelta is the SQL for what a balance record, aliased alias, added to
+// its balance. The direction is in type; debt records also sign amount, so
+// only its size is used.
+func recordDelta(alias string) string {
+	return fmt.Sprintf("(CASE WHEN %[1]s.type = %[2]d THEN -ABS(%[1]s.amount) ELSE ABS(%[1]s.amount) END)", alias, types.TYPE_SELL)
+}
+
 func (s *BalanceRecordStorage) Archive(ctx context.Context, companyId int64) error {
 	query := `UPDATE balance_records SET status = $1 WHERE company_id = $2`
 	rows, err := s.db.ExecContext(ctx, query, STATUS_ARCHIVED, companyId)
@@ -48,7 +62,7 @@ func (s *BalanceRecordStorage) Archive(ctx context.Context, companyId int64) err
 	}
 
 	if res == 0 {
-		return fmt.Errorf("ERROR NOT FOUND")
+		return types.ErrBalanceRecordNotFound
 	}
 
 	return nil
@@ -118,67 +132,63 @@ func (s *BalanceRecordStorage) Update(ctx context.Context, balanceRecord *Balanc
 	return err
 }
 
-func (s *BalanceRecordStorage) GetByFieldAndDate(ctx context.Context, fieldName string, from, to *string, fieldValue any, pagination types.Pagination) ([]BalanceRecord, error) {
-	query := `
-				SELECT id, amount, user_id, balance_id, company_id, transaction_id, debt_id, exchange_id, details, currency, type, created_at
-				FROM balance_records WHERE ` + fieldName + ` = $1 AND status != $2  AND amount != 0 AND created_at BETWEEN $3 AND $4 	ORDER BY created_at DESC
-	` + fmt.Sprintf(" OFFSET %v LIMIT %v", pagination.Offset, pagination.Limit)
+func (s *BalanceRecordStorage) GetByFieldAndDate(ctx context.Context, fieldName string, from, to *string, fieldValue any, pagination *types.Pagination) ([]BalanceRecord, error) {
+	filter := types.Where(fieldName, fieldValue)
+	if from != nil && to != nil {
+		filter.And("created_at", types.FILTER_BETWEEN, []any{*from, *to})
+	}
 
-	rows, err := s.db.QueryContext(
-		ctx,
-		query,
-		fieldValue,
-		STATUS_ARCHIVED,
-		from,
-		to,
-	)
+	return s.find(ctx, "status != $1", []any{STATUS_ARCHIVED}, filter, pagination)
+}
 
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+func (s *BalanceRecordStorage) GetByField(ctx context.Context, fieldName string, fieldValue any, pagination *types.Pagination) ([]BalanceRecord, error) {
+	return s.find(ctx, "status != $1", []any{STATUS_ARCHIVED}, types.Where(fieldName, fieldValue), pagination)
+}
 
-	return s.FetchDataFromQuery(rows)
+func (s *BalanceRecordStorage) Filter(ctx context.Context, companyId int64, filter *types.Filter, pagination *types.Pagination) ([]BalanceRecord, error) {
+	return s.find(ctx, "company_id = $1 AND status != $2", []any{companyId, STATUS_ARCHIVED}, filter, pagination)
 }
 
-func (s *BalanceRecordStorage) GetByField(ctx context.Context, fieldName string, fieldValue any, pagination types.Pagination) ([]BalanceRecord, error) {
+func (s *BalanceRecordStorage) find(ctx context.Context, where string, args []any, filter *types.Filter, pagination *types.Pagination) ([]BalanceRecord, error) {
 	query := `
 				SELECT id, amount, user_id, balance_id, company_id, transaction_id, debt_id, exchange_id, details, currency, type, created_at
-				FROM balance_records WHERE ` + fieldName + ` = $1 AND status != $2 AND amount != 0   	ORDER BY created_at DESC
-	` + fmt.Sprintf(" OFFSET %v LIMIT %v", pagination.Offset, pagination.Limit)
+				FROM balance_records WHERE amount != 0 AND ` + where
 
-	rows, err := s.db.QueryContext(
-		ctx,
-		query,
-		fieldValue,
-		STATUS_ARCHIVED,
-	)
+	query, args, err := BalanceRecordFilters.Apply(query, args, filter)
+	if err != nil {
+		return nil, err
+	}
 
+	rows, err := queryPage(ctx, s.db, query, args, BalanceRecordSorts, pagination)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return s.FetchDataFromQuery(rows)
+	return s.fetchPage(rows, pagination)
 }
 
-func (s *BalanceRecordStorage) Archived(ctx context.Context, pagination types.Pagination) ([]BalanceRecord, error) {
+func (s *BalanceRecordStorage) Archived(ctx context.Context, pagination *types.Pagination) ([]BalanceRecord, error) {
 	query := `
 				SELECT id, amount, user_id, balance_id, company_id, transaction_id, debt_id, exchange_id, details, currency, type, created_at
-				FROM balance_records WHERE status = $1 AND amount != 0  	ORDER BY created_at DESC
-	` + fmt.Sprintf(" OFFSET %v LIMIT %v", pagination.Offset, pagination.Limit)
-	rows, err := s.db.QueryContext(
-		ctx,
-		query,
-		STATUS_ARCHIVED,
-	)
+				FROM balance_records WHERE status = $1 AND amount != 0
+	`
 
+	rows, err := queryPage(ctx, s.db, query, []any{STATUS_ARCHIVED}, BalanceRecordSorts, pagination)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return s.FetchDataFromQuery(rows)
+	return s.fetchPage(rows, pagination)
+}
+
+func (s *BalanceRecordStorage) fetchPage(rows *sql.Rows, pagination *types.Pagination) ([]BalanceRecord, error) {
+	records, err := s.FetchDataFromQuery(rows)
+	if err != nil {
+		return nil, err
+	}
+	return trimPage(pagination, records, BalanceRecord.pageKey), nil
 }
 
 func (s *BalanceRecordStorage) FetchDataFromQuery(rows *sql.Rows) ([]BalanceRecord, error) {
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BalancePoint is the end-of-day total of a currency.
+type BalancePoint struct {
+	Day     string `json:"day"`
+	Balance int64  `json:"balance"`
+}
+
+type BalanceSeries struct {
+	Currency string         `json:"currency"`
+	Points   []BalancePoint `json:"points"`
+}
+
+// BalanceHistory is how balances evolved over a period, one series per
+// currency. Missing lists the days without a snapshot, to be backfilled.
+type BalanceHistory struct {
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Series  []BalanceSeries `json:"series"`
+	Missing []string        `json:"missing"`
+}
+
+type BalanceSnapshotStorage struct {
+	db DBTX
+}
+
+func NewBalanceSnapshotStorage(db DBTX) *BalanceSnapshotStorage {
+	return &BalanceSnapshotStorage{db: db}
+}
+
+// Take stores every balance as it was at the end of day, a midnight in
+// Tashkent, for companyId or for every company when it is nil. Like a
+// balance statement it counts back from the stored balance, so a day can
+// be taken at any time later. Days already taken are left as they are; it
+// returns how many snapshots were added.
+func (s *BalanceSnapshotStorage) Take(ctx context.Context, companyId *int64, day time.Time) (int64, error) {
+	end := day.AddDate(0, 0, 1)
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO balance_snapshots (day, balance_id, company_id, user_id, currency, balance)
+		SELECT $1::date, b.id, b.company_id, b.user_id, COALESCE(b.currency, ''),
+			COALESCE(b.balance, 0) - COALESCE((
+				SELECT SUM(`+recordDelta("r")+`) FROM balance_records r
+				WHERE r.balance_id = b.id AND r.created_at >= $2
+			), 0)
+		FROM balances b
+		WHERE b.created_at < $2 AND ($3::bigint IS NULL OR b.company_id = $3)
+		ON CONFLICT (balance_id, day) DO NOTHING
+	`, day.Format("2006-01-02"), end, companyId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to take balance snapshots: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// History sums the snapshots of companyId per currency and day from from
+// to to, both included. An empty currency means every currency and a nil
+// userId every user.
+func (s *BalanceSnapshotStorage) History(ctx context.Context, companyId int64, currency string, userId *int64, from, to time.Time) (*BalanceHistory, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT currency, day, SUM(balance)
+		FROM balance_snapshots
+		WHERE company_id = $1 AND day >= $2::date AND day <= $3::date
+			AND ($4 = '' OR currency = $4)
+			AND ($5::bigint IS NULL OR user_id = $5)
+		GROUP BY currency, day
+		ORDER BY currency, day
+	`, companyId, from.Format("2006-01-02"), to.Format("2006-01-02"), currency, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balance snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	history := &BalanceHistory{Series: []BalanceSeries{}, Missing: []string{}}
+	taken := map[string]bool{}
+	for rows.Next() {
+		var code string
+		var day time.Time
+		var point BalancePoint
+		if err := rows.Scan(&code, &day, &point.Balance); err != nil {
+			return nil, fmt.Errorf("failed to scan balance snapshot: %w", err)
+		}
+		point.Day = day.Format("2006-01-02")
+		taken[point.Day] = true
+
+		if n := len(history.Series); n == 0 || history.Series[n-1].Currency != code {
+			history.Series = append(history.Series, BalanceSeries{Currency: code})
+		}
+		series := &history.Series[len(history.Series)-1]
+		series.Points = append(series.Points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if key := day.Format("2006-01-02"); !taken[key] {
+			history.Missing = append(history.Missing, key)
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,232 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// Threshold states. A threshold alerts once when it leaves THRESHOLD_OK and
+// stays quiet until the balance is back within its limits.
+const (
+	THRESHOLD_OK    = 0
+	THRESHOLD_BELOW = 1
+	THRESHOLD_ABOVE = 2
+)
+
+// BalanceThreshold bounds one user's balance in a currency, or the sum of
+// the company's balances in it when UserID is nil.
+type BalanceThreshold struct {
+	ID                 int64      `json:"id"`
+	CompanyID          int64      `json:"company_id"`
+	UserID             *int64     `json:"user_id"`
+	Currency           string     `json:"currency"`
+	MinBalance         *int64     `json:"min_balance"`
+	MaxBalance         *int64     `json:"max_balance"`
+	State              int64      `json:"state"`
+	AlertedAt          *time.Time `json:"-"`
+	AlertedAtFormatted *string    `json:"alerted_at"`
+	CreatedAt          time.Time  `json:"-"`
+	CreatedAtFormatted string     `json:"created_at"`
+}
+
+// ThresholdCrossing is a threshold whose state changed with a balance
+// update. Amount is the balance it was checked against.
+type ThresholdCrossing struct {
+	ThresholdID int64
+	UserID      *int64
+	Currency    string
+	Limit       *int64
+	State       int64
+	Amount      int64
+}
+
+type BalanceThresholdStorage struct {
+	db DBTX
+}
+
+func NewBalanceThresholdStorage(db DBTX) *BalanceThresholdStorage {
+	return &BalanceThresholdStorage{db: db}
+}
+
+const balanceThresholdColumns = `
+	id, company_id, user_id, currency, min_balance, max_balance, state, alerted_at, created_at
+`
+
+// Set creates or replaces the threshold of its scope. The state is reset,
+// so the next balance update checks the new limits afresh.
+func (s *BalanceThresholdStorage) Set(ctx context.Context, t *BalanceThreshold) error {
+	query := `
+		INSERT INTO balance_thresholds (company_id, user_id, currency, min_balance, max_balance)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (company_id, COALESCE(user_id, 0), currency) DO UPDATE
+		SET min_balance = EXCLUDED.min_balance, max_balance = EXCLUDED.max_balance,
+			state = 0, alerted_at = NULL, updated_at = now()
+		RETURNING id, state, created_at
+	`
+
+	err := s.db.QueryRowContext(ctx, query, t.CompanyID, t.UserID, t.Currency, t.MinBalance, t.MaxBalance).Scan(
+		&t.ID,
+		&t.State,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set balance threshold: %w", err)
+	}
+
+	t.AlertedAt = nil
+	t.AlertedAtFormatted = nil
+	t.CreatedAtFormatted = formatTashkent(t.CreatedAt)
+	return nil
+}
+
+func (s *BalanceThresholdStorage) GetByCompanyId(ctx context.Context, companyId int64) ([]BalanceThreshold, error) {
+	query := `SELECT ` + balanceThresholdColumns + ` FROM balance_thresholds
+		WHERE company_id = $1 ORDER BY currency, user_id NULLS FIRST`
+
+	rows, err := s.db.QueryContext(ctx, query, companyId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var thresholds []BalanceThreshold
+	for rows.Next() {
+		var t BalanceThreshold
+		var userID, minBalance, maxBalance sql.NullInt64
+		var alertedAt sql.NullTime
+
+		if err := rows.Scan(
+			&t.ID,
+			&t.CompanyID,
+			&userID,
+			&t.Currency,
+			&minBalance,
+			&maxBalance,
+			&t.State,
+			&alertedAt,
+			&t.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if userID.Valid {
+			t.UserID = &userID.Int64
+		}
+		if minBalance.Valid {
+			t.MinBalance = &minBalance.Int64
+		}
+		if maxBalance.Valid {
+			t.MaxBalance = &maxBalance.Int64
+		}
+		if alertedAt.Valid {
+			formatted := formatTashkent(alertedAt.Time)
+			t.AlertedAt = &alertedAt.Time
+			t.AlertedAtFormatted = &formatted
+		}
+		t.CreatedAtFormatted = formatTashkent(t.CreatedAt)
+
+		thresholds = append(thresholds, t)
+	}
+
+	return thresholds, rows.Err()
+}
+
+func (s *BalanceThresholdStorage) Delete(ctx context.Context, companyId, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM balance_thresholds WHERE id = $1 AND company_id = $2`, id, companyId)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return types.ErrBalanceThresholdNotFound
+	}
+	return nil
+}
+
+// Evaluate checks the thresholds that balance falls under, its own and the
+// company-wide one, and saves their new states. It returns only the ones
+// whose state changed, so a balance that stays low alerts once. Run it in
+// the transaction that saved balance so the company sum includes it.
+func (s *BalanceThresholdStorage) Evaluate(ctx context.Context, balance *Balance) ([]ThresholdCrossing, error) {
+	query := `
+		WITH checked AS (
+			SELECT t.id, t.min_balance, t.max_balance,
+				CASE WHEN t.user_id IS NULL
+					THEN (SELECT COALESCE(SUM(b.balance), 0) FROM balances b WHERE b.company_id = t.company_id AND b.currency = t.currency)
+					ELSE $3
+				END::bigint AS amount
+			FROM balance_thresholds t
+			WHERE t.company_id = $1 AND t.currency = $2 AND (t.user_id IS NULL OR t.user_id = $4)
+		), next AS (
+			SELECT id, amount,
+				CASE
+					WHEN min_balance IS NOT NULL AND amount < min_balance THEN 1
+					WHEN max_balance IS NOT NULL AND amount > max_balance THEN 2
+					ELSE 0
+				END AS state
+			FROM checked
+		)
+		UPDATE balance_thresholds t
+		SET state = next.state, alerted_at = CASE WHEN next.state = 0 THEN t.alerted_at ELSE now() END
+		FROM next
+		WHERE t.id = next.id AND t.state != next.state
+		RETURNING t.id, t.user_id, t.currency, CASE WHEN next.state = 1 THEN t.min_balance ELSE t.max_balance END, next.state, next.amount
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, balance.CompanyId, balance.Currency, balance.Balance, balance.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate balance thresholds: %w", err)
+	}
+	defer rows.Close()
+
+	var crossings []ThresholdCrossing
+	for rows.Next() {
+		var c ThresholdCrossing
+		var userID, limit sql.NullInt64
+
+		if err := rows.Scan(&c.ThresholdID, &userID, &c.Currency, &limit, &c.State, &c.Amount); err != nil {
+			return nil, err
+		}
+		if userID.Valid {
+			c.UserID = &userID.Int64
+		}
+		if limit.Valid {
+			c.Limit = &limit.Int64
+		}
+
+		crossings = append(crossings, c)
+	}
+
+	return crossings, rows.Err()
+}
+
+// Recipients returns who is told about a crossing, a due debt or a large
+// exchange: the company's owners and, when userId is set, that user.
+func (s *BalanceThresholdStorage) Recipients(ctx context.Context, companyId int64, userId *int64) ([]int64, error) {
+	query := `SELECT id FROM users WHERE company_id = $1 AND (role = $2 OR id = $3) ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, companyId, ROLE_OWNER, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
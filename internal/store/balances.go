@@ -2,8 +2,9 @@ package store
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"database/sql"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
 )
 
 type Balance struct {
@@ -37,7 +38,7 @@ func (s *BalanceStorage) Create(ctx context.Context, balance *Balance) error {
 		return err
 	}
 	if res != 0 {
-		return fmt.Errorf("ALREADY EXIST WITH CURRENCY %v", balance.Currency)
+		return types.ErrBalanceAlreadyExists.WithField("currency", balance.Currency)
 	}
 
 	query = `INSERT INTO balances(balance, user_id, in_out_lay, out_in_lay, company_id, currency)
@@ -207,6 +208,31 @@ func (s *BalanceStorage) GetById(ctx context.Context, id *int64) (*Balance, erro
 	return balance, nil
 }
 
+// GetForUpdate reads a balance and locks it until the transaction ends.
+func (s *BalanceStorage) GetForUpdate(ctx context.Context, id int64) (*Balance, error) {
+	query := `SELECT id, balance, user_id, in_out_lay, out_in_lay, company_id, currency, created_at FROM balances WHERE id = $1 FOR UPDATE`
+	balance := &Balance{}
+
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&balance.ID,
+		&balance.Balance,
+		&balance.UserId,
+		&balance.InOutLay,
+		&balance.OutInLay,
+		&balance.CompanyId,
+		&balance.Currency,
+		&balance.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, types.ErrBalanceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return balance, nil
+}
+
 func (s *BalanceStorage) Update(ctx context.Context, balance *Balance) error {
 	query := `UPDATE balances SET balance = $1, in_out_lay = $2, out_in_lay = $3
 		WHERE id = $4`
@@ -222,9 +248,13 @@ func (s *BalanceStorage) Update(ctx context.Context, balance *Balance) error {
 	}
 
 	if res == 0 {
-		return errors.New("BALANCE THAT WILL BE UPDATE IS NOT FOUND")
+		return types.ErrBalanceNotFound
 	}
 
+	saved := *balance
+	hooks := s.db.Hooks()
+	s.db.AfterCommit(func() { hooks.runBalanceChanged(ctx, saved) })
+
 	return nil
 }
 
@@ -241,7 +271,7 @@ func (s *BalanceStorage) Delete(ctx context.Context, id int64) error {
 	}
 
 	if res == 0 {
-		return errors.New("BALANCE NOT FOUND")
+		return types.ErrBalanceNotFound
 	}
 
 	return nil
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+// DashboardExpTime bounds how long a dashboard is served from the cache
+// when no write invalidates it.
+const DashboardExpTime = 5 * time.Minute
+
+// DashboardStore caches dashboards under a version per company. Invalidate
+// moves the version on, so a dashboard built before a write and saved after
+// it lands under the old version and is never read.
+type DashboardStore struct {
+	rdb *redis.Client
+}
+
+// Get returns the cached dashboard of companyId for period, or nil, and the
+// version to save a freshly built one under.
+func (s *DashboardStore) Get(ctx context.Context, companyId int64, period string) (*store.Dashboard, int64, error) {
+	version, err := s.rdb.Get(ctx, fmt.Sprintf("dashboard-version-%v", companyId)).Int64()
+	if err != nil && err != redis.Nil {
+		return nil, 0, err
+	}
+
+	data, err := s.rdb.HGet(ctx, fmt.Sprintf("dashboard-%v-%v", companyId, version), period).Result()
+	if err == redis.Nil {
+		return nil, version, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	var dashboard store.Dashboard
+	if err := json.Unmarshal([]byte(data), &dashboard); err != nil {
+		return nil, 0, err
+	}
+	return &dashboard, version, nil
+}
+
+func (s *DashboardStore) Set(ctx context.Context, companyId, version int64, period string, dashboard *store.Dashboard) error {
+	data, err := json.Marshal(dashboard)
+	if err != nil {
+		return err
+	}
+
+	cacheKey := fmt.Sprintf("dashboard-%v-%v", companyId, version)
+	pipe := s.rdb.TxPipeline()
+	pipe.HSet(ctx, cacheKey, period, data)
+	pipe.Expire(ctx, cacheKey, DashboardExpTime)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Invalidate drops every cached dashboard of companyId.
+func (s *DashboardStore) Invalidate(ctx context.Context, companyId int64) error {
+	return s.rdb.Incr(ctx, fmt.Sprintf("dashboard-version-%v", companyId)).Err()
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RateLimitStore counts requests per key in fixed windows shared by every
+// instance of the API.
+type RateLimitStore struct {
+	rdb *redis.Client
+}
+
+// Allow counts one request for key and reports whether it is within limit
+// for the current window, and if not, how long until the window ends.
+func (s *RateLimitStore) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	now := time.Now()
+	windowStart := now.Truncate(window)
+	cacheKey := fmt.Sprintf("ratelimit-%s-%d", key, windowStart.Unix())
+
+	pipe := s.rdb.TxPipeline()
+	count := pipe.Incr(ctx, cacheKey)
+	pipe.Expire(ctx, cacheKey, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, 0, err
+	}
+
+	if count.Val() > int64(limit) {
+		return false, windowStart.Add(window).Sub(now), nil
+	}
+	return true, 0, nil
+}
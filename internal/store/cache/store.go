@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/mubashshir3767/currencyExchange/internal/store"
@@ -12,10 +13,22 @@ type Storage struct {
 		Get(context.Context, int64) (*store.User, error)
 		Set(context.Context, *store.User) error
 	}
+
+	RateLimits interface {
+		Allow(context.Context, string, int, time.Duration) (bool, time.Duration, error)
+	}
+
+	Dashboards interface {
+		Get(context.Context, int64, string) (*store.Dashboard, int64, error)
+		Set(context.Context, int64, int64, string, *store.Dashboard) error
+		Invalidate(context.Context, int64) error
+	}
 }
 
 func NewRedisStorage(redis *redis.Client) Storage {
 	return Storage{
-		Users: &UsersStore{rdb: redis},
+		Users:      &UsersStore{rdb: redis},
+		RateLimits: &RateLimitStore{rdb: redis},
+		Dashboards: &DashboardStore{rdb: redis},
 	}
 }
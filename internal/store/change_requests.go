@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+const (
+	CHANGE_REQUEST_PENDING  = 1
+	CHANGE_REQUEST_APPROVED = 2
+	CHANGE_REQUEST_REJECTED = 3
+)
+
+// ChangeRequest is an edit or delete of a financial record proposed by a
+// cashier and waiting for an owner to approve or reject it.
+type ChangeRequest struct {
+	ID                  int64           `json:"id"`
+	CompanyID           int64           `json:"company_id"`
+	EntityType          string          `json:"entity_type"`
+	EntityID            int64           `json:"entity_id"`
+	Action              string          `json:"action"`
+	Before              json.RawMessage `json:"before"`
+	Proposed            json.RawMessage `json:"proposed"`
+	Status              int64           `json:"status"`
+	RequestedBy         int64           `json:"requested_by"`
+	ReviewedBy          *int64          `json:"reviewed_by"`
+	ReviewNote          *string         `json:"review_note"`
+	ReviewedAt          *time.Time      `json:"-"`
+	ReviewedAtFormatted *string         `json:"reviewed_at"`
+	CreatedAt           time.Time       `json:"-"`
+	CreatedAtFormatted  string          `json:"created_at"`
+}
+
+type ChangeRequestStorage struct {
+	db DBTX
+}
+
+func NewChangeRequestStorage(db DBTX) *ChangeRequestStorage {
+	return &ChangeRequestStorage{db: db}
+}
+
+func (s *ChangeRequestStorage) Create(ctx context.Context, cr *ChangeRequest) error {
+	query := `
+		INSERT INTO change_requests (company_id, entity_type, entity_id, action, before, proposed, status, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, status, created_at
+	`
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		cr.CompanyID,
+		cr.EntityType,
+		cr.EntityID,
+		cr.Action,
+		nullableJSON(cr.Before),
+		nullableJSON(cr.Proposed),
+		CHANGE_REQUEST_PENDING,
+		cr.RequestedBy,
+	).Scan(
+		&cr.ID,
+		&cr.Status,
+		&cr.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create change request: %w", err)
+	}
+
+	cr.CreatedAtFormatted = formatTashkent(cr.CreatedAt)
+	return nil
+}
+
+func (s *ChangeRequestStorage) GetById(ctx context.Context, id int64) (*ChangeRequest, error) {
+	query := `
+		SELECT id, company_id, entity_type, entity_id, action, before, proposed, status,
+		requested_by, reviewed_by, review_note, reviewed_at, created_at
+		FROM change_requests WHERE id = $1
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests, err := s.scanChangeRequests(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &requests[0], nil
+}
+
+func (s *ChangeRequestStorage) GetByCompanyId(ctx context.Context, companyId int64, status *int64, pagination *types.Pagination) ([]ChangeRequest, error) {
+	query := `
+		SELECT id, company_id, entity_type, entity_id, action, before, proposed, status,
+		requested_by, reviewed_by, review_note, reviewed_at, created_at
+		FROM change_requests WHERE company_id = $1 AND ($2::bigint IS NULL OR status = $2)
+	`
+
+	rows, err := queryPage(ctx, s.db, query, []any{companyId, status}, ChangeRequestSorts, pagination)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests, err := s.scanChangeRequests(rows)
+	if err != nil {
+		return nil, err
+	}
+	return trimPage(pagination, requests, ChangeRequest.pageKey), nil
+}
+
+// Review moves a pending request to the given status. It only touches rows
+// that are still pending, so two owners cannot review the same request twice.
+func (s *ChangeRequestStorage) Review(ctx context.Context, id int64, status int64, reviewerId int64, note *string) error {
+	query := `
+		UPDATE change_requests SET status = $1, reviewed_by = $2, review_note = $3, reviewed_at = now()
+		WHERE id = $4 AND status = $5
+	`
+
+	res, err := s.db.ExecContext(ctx, query, status, reviewerId, note, id, CHANGE_REQUEST_PENDING)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s *ChangeRequestStorage) scanChangeRequests(rows *sql.Rows) ([]ChangeRequest, error) {
+	var requests []ChangeRequest
+	for rows.Next() {
+		var cr ChangeRequest
+		var before, proposed []byte
+		var reviewedBy sql.NullInt64
+		var reviewNote sql.NullString
+		var reviewedAt sql.NullTime
+
+		if err := rows.Scan(
+			&cr.ID,
+			&cr.CompanyID,
+			&cr.EntityType,
+			&cr.EntityID,
+			&cr.Action,
+			&before,
+			&proposed,
+			&cr.Status,
+			&cr.RequestedBy,
+			&reviewedBy,
+			&reviewNote,
+			&reviewedAt,
+			&cr.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if len(before) > 0 {
+			cr.Before = json.RawMessage(before)
+		}
+		if len(proposed) > 0 {
+			cr.Proposed = json.RawMessage(proposed)
+		}
+		if reviewedBy.Valid {
+			cr.ReviewedBy = &reviewedBy.Int64
+		}
+		if reviewNote.Valid {
+			cr.ReviewNote = &reviewNote.String
+		}
+		if reviewedAt.Valid {
+			formatted := formatTashkent(reviewedAt.Time)
+			cr.ReviewedAt = &reviewedAt.Time
+			cr.ReviewedAtFormatted = &formatted
+		}
+		cr.CreatedAtFormatted = formatTashkent(cr.CreatedAt)
+
+		requests = append(requests, cr)
+	}
+
+	return requests, rows.Err()
+}
+
+func nullableJSON(data json.RawMessage) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return []byte(data)
+}
+
+func formatTashkent(t time.Time) string {
+	loc, err := time.LoadLocation("Asia/Tashkent")
+	if err != nil {
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return t.In(loc).Format("2006-01-02 15:04:05")
+}
@@ -2,7 +2,9 @@ package store
 
 import (
 	"context"
-	"errors"
+	"database/sql"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
 )
 
 type Company struct {
@@ -50,7 +52,7 @@ func (s *CompanyStorage) Update(ctx context.Context, company *Company) error {
 	}
 
 	if res == 0 {
-		return errors.New("NOT FOUND")
+		return types.ErrCompanyNotFound
 	}
 
 	return nil
@@ -91,7 +93,7 @@ func (s *CompanyStorage) GetById(ctx context.Context, id *int64) (*Company, erro
 
 	company := &Company{}
 
-	err := s.db.QueryRowContext(ctx, query).Scan(
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&company.ID,
 		&company.Name,
 		&company.Details,
@@ -99,6 +101,9 @@ func (s *CompanyStorage) GetById(ctx context.Context, id *int64) (*Company, erro
 		&company.CreatedAt,
 	)
 
+	if err == sql.ErrNoRows {
+		return nil, types.ErrCompanyNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -106,6 +111,17 @@ func (s *CompanyStorage) GetById(ctx context.Context, id *int64) (*Company, erro
 	return company, nil
 }
 
+// Lock locks the company row until the transaction ends, so writes that
+// depend on the company's other rows, such as its first user, take turns.
+func (s *CompanyStorage) Lock(ctx context.Context, id int64) error {
+	var locked int64
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM companies WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return types.ErrCompanyNotFound
+	}
+	return err
+}
+
 func (s *CompanyStorage) Delete(ctx context.Context, id *int64) error {
 	query := `DELETE FROM companies WHERE id = $1`
 
@@ -121,7 +137,7 @@ func (s *CompanyStorage) Delete(ctx context.Context, id *int64) error {
 	}
 
 	if res == 0 {
-		return errors.New("NOT FOUND")
+		return types.ErrCompanyNotFound
 	}
 
 	return nil
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// amountSource is the part of a transaction the company amounts are made
+// of. Company ids are nil once the company is deleted.
+type amountSource struct {
+	Type               *int64
+	ReceivedCompanyID  *int64
+	DeliveredCompanyID *int64
+	ReceivedIncomes    []types.ReceivedIncomes
+	DeliveredOutcomes  []types.DeliveredOutcomes
+	CreatedAt          time.Time
+}
+
+func amountSourceOf(tr *Transaction) amountSource {
+	return amountSource{
+		Type:               &tr.Type,
+		ReceivedCompanyID:  &tr.ReceivedCompanyId,
+		DeliveredCompanyID: &tr.DeliveredCompanyId,
+		ReceivedIncomes:    tr.ReceivedIncomes,
+		DeliveredOutcomes:  tr.DeliveredOutcomes,
+		CreatedAt:          tr.CreatedAt,
+	}
+}
+
+// scanAmountSource reads type, received_company_id, delivered_company_id,
+// received_incomes, delivered_outcomes and created_at, in that order.
+func scanAmountSource(row interface{ Scan(...any) error }) (amountSource, error) {
+	var src amountSource
+	var receivedIncomesJSON, deliveredOutcomesJSON []byte
+	if err := row.Scan(
+		&src.Type,
+		&src.ReceivedCompanyID,
+		&src.DeliveredCompanyID,
+		&receivedIncomesJSON,
+		&deliveredOutcomesJSON,
+		&src.CreatedAt,
+	); err != nil {
+		return src, err
+	}
+
+	if len(receivedIncomesJSON) > 0 {
+		if err := json.Unmarshal(receivedIncomesJSON, &src.ReceivedIncomes); err != nil {
+			return src, err
+		}
+	}
+	if len(deliveredOutcomesJSON) > 0 {
+		if err := json.Unmarshal(deliveredOutcomesJSON, &src.DeliveredOutcomes); err != nil {
+			return src, err
+		}
+	}
+	return src, nil
+}
+
+type companyAmountKey struct {
+	companyID int64
+	currency  string
+	day       string
+}
+
+type companyAmountDelta struct {
+	olingan  int64
+	berilgan int64
+}
+
+// add counts src into deltas sign times. On a sell (type 1) the company
+// paying out has taken in what it delivers and the company taking the money
+// has given it; a buy is the other way round.
+func (src amountSource) add(deltas map[companyAmountKey]companyAmountDelta, sign int64) {
+	var typ int64
+	if src.Type != nil {
+		typ = *src.Type
+	}
+	day := src.CreatedAt.In(tashkentLocation()).Format("2006-01-02")
+
+	if src.DeliveredCompanyID != nil {
+		for _, outcome := range src.DeliveredOutcomes {
+			key := companyAmountKey{*src.DeliveredCompanyID, outcome.DeliveredCurrency, day}
+			delta := deltas[key]
+			switch typ {
+			case types.TYPE_SELL:
+				delta.olingan += sign * outcome.DeliveredAmount
+			case types.TYPE_BUY:
+				delta.berilgan += sign * outcome.DeliveredAmount
+			}
+			deltas[key] = delta
+		}
+	}
+
+	if src.ReceivedCompanyID != nil {
+		for _, income := range src.ReceivedIncomes {
+			key := companyAmountKey{*src.ReceivedCompanyID, income.ReceivedCurrency, day}
+			delta := deltas[key]
+			switch typ {
+			case types.TYPE_SELL:
+				delta.berilgan += sign * income.ReceivedAmount
+			case types.TYPE_BUY:
+				delta.olingan += sign * income.ReceivedAmount
+			}
+			deltas[key] = delta
+		}
+	}
+}
+
+// applyCompanyAmounts adds deltas to the daily and total company amounts.
+// Rows are written in key order so that concurrent writers lock them in
+// the same order.
+func applyCompanyAmounts(ctx context.Context, db DBTX, deltas map[companyAmountKey]companyAmountDelta) error {
+	keys := make([]companyAmountKey, 0, len(deltas))
+	for key := range deltas {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].companyID != keys[j].companyID {
+			return keys[i].companyID < keys[j].companyID
+		}
+		if keys[i].currency != keys[j].currency {
+			return keys[i].currency < keys[j].currency
+		}
+		return keys[i].day < keys[j].day
+	})
+
+	for _, key := range keys {
+		delta := deltas[key]
+		_, err := db.ExecContext(ctx, `
+			WITH daily AS (
+				INSERT INTO company_daily_amounts (company_id, currency, day, olingan_amount, berilgan_amount)
+				VALUES ($1, $2, $3::date, $4, $5)
+				ON CONFLICT (company_id, currency, day) DO UPDATE SET
+					olingan_amount = company_daily_amounts.olingan_amount + EXCLUDED.olingan_amount,
+					berilgan_amount = company_daily_amounts.berilgan_amount + EXCLUDED.berilgan_amount
+			)
+			INSERT INTO company_amount_totals (company_id, currency, olingan_amount, berilgan_amount)
+			VALUES ($1, $2, $4, $5)
+			ON CONFLICT (company_id, currency) DO UPDATE SET
+				olingan_amount = company_amount_totals.olingan_amount + EXCLUDED.olingan_amount,
+				berilgan_amount = company_amount_totals.berilgan_amount + EXCLUDED.berilgan_amount
+		`, key.companyID, key.currency, key.day, delta.olingan, delta.berilgan)
+		if err != nil {
+			return fmt.Errorf("failed to update company amounts: %w", err)
+		}
+	}
+	return nil
+}
+
+// RebuildCompanyAmounts recounts the company amounts from every
+// transaction, for repairs. Transactions are share locked until the caller's
+// transaction ends, so no write is missed or counted twice.
+func (s *TransactionStorage) RebuildCompanyAmounts(ctx context.Context) error {
+	for _, query := range []string{
+		`LOCK TABLE transactions IN SHARE MODE`,
+		`DELETE FROM company_amount_totals`,
+		`DELETE FROM company_daily_amounts`,
+	} {
+		if _, err := s.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to clear company amounts: %w", err)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT type, received_company_id, delivered_company_id, `+transactionLineColumns("transactions")+`, created_at
+		FROM transactions
+		ORDER BY id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to read transactions: %w", err)
+	}
+	defer rows.Close()
+
+	deltas := map[companyAmountKey]companyAmountDelta{}
+	for rows.Next() {
+		src, err := scanAmountSource(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		src.add(deltas, 1)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+	rows.Close()
+
+	return applyCompanyAmounts(ctx, s.db, deltas)
+}
+
+func tashkentLocation() *time.Location {
+	loc, err := time.LoadLocation("Asia/Tashkent")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,266 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DASHBOARD_TOP_CASHIERS is how many cashiers a dashboard ranks.
+const DASHBOARD_TOP_CASHIERS = 5
+
+type CurrencyAmount struct {
+	Currency string `json:"currency"`
+	Amount   int64  `json:"amount"`
+}
+
+// DashboardTrade is what the company bought and sold of a currency in
+// exchanges.
+type DashboardTrade struct {
+	Currency string `json:"currency"`
+	Bought   int64  `json:"bought"`
+	Sold     int64  `json:"sold"`
+}
+
+// DashboardRemittances are transfers not yet paid out, with the amounts the
+// recipients are to be paid.
+type DashboardRemittances struct {
+	Count   int              `json:"count"`
+	Amounts []CurrencyAmount `json:"amounts"`
+}
+
+// DashboardDebts are what debtors owe the company (Receivable) and what the
+// company owes them (Payable) in a currency, both positive.
+type DashboardDebts struct {
+	Currency   string `json:"currency"`
+	Receivable int64  `json:"receivable"`
+	Payable    int64  `json:"payable"`
+}
+
+type DashboardCashier struct {
+	UserID     int64  `json:"user_id"`
+	Username   string `json:"username"`
+	Operations int    `json:"operations"`
+}
+
+// Dashboard sums up a company for a period. Holdings are as of the end of
+// the period; pending remittances and debts are as of now.
+//
+// Service fees are free text without a currency; FeeIncome adds up the
+// numeric ones of transactions the company took in, and FeeTransactions
+// counts them.
+type Dashboard struct {
+	From            string               `json:"from"`
+	To              string               `json:"to"`
+	Holdings        []CurrencyAmount     `json:"holdings"`
+	Exchanges       int                  `json:"exchanges"`
+	Trades          []DashboardTrade     `json:"trades"`
+	PendingIncoming DashboardRemittances `json:"pending_incoming"`
+	PendingOutgoing DashboardRemittances `json:"pending_outgoing"`
+	Debts           []DashboardDebts     `json:"debts"`
+	FeeIncome       float64              `json:"fee_income"`
+	FeeTransactions int                  `json:"fee_transactions"`
+	TopCashiers     []DashboardCashier   `json:"top_cashiers"`
+}
+
+type DashboardStorage struct {
+	db DBTX
+}
+
+func NewDashboardStorage(db DBTX) *DashboardStorage {
+	return &DashboardStorage{db: db}
+}
+
+// Get builds the dashboard of companyId for [from, to), except for debts,
+// which come from the debtors.
+func (s *DashboardStorage) Get(ctx context.Context, companyId int64, from, to time.Time) (*Dashboard, error) {
+	d := &Dashboard{}
+
+	var err error
+	if d.Holdings, err = s.holdings(ctx, companyId, to); err != nil {
+		return nil, err
+	}
+	if d.Exchanges, d.Trades, err = s.trades(ctx, companyId, from, to); err != nil {
+		return nil, err
+	}
+	if d.PendingIncoming, err = s.pending(ctx, "delivered_company_id", companyId); err != nil {
+		return nil, err
+	}
+	if d.PendingOutgoing, err = s.pending(ctx, "received_company_id", companyId); err != nil {
+		return nil, err
+	}
+	if d.FeeIncome, d.FeeTransactions, err = s.fees(ctx, companyId, from, to); err != nil {
+		return nil, err
+	}
+	if d.TopCashiers, err = s.topCashiers(ctx, companyId, from, to); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// holdings counts back from the stored balances the records made at or
+// after at, like a balance statement does.
+func (s *DashboardStorage) holdings(ctx context.Context, companyId int64, at time.Time) ([]CurrencyAmount, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT b.currency, SUM(b.balance - COALESCE(a.amount, 0))
+		FROM balances b
+		LEFT JOIN (
+			SELECT r.balance_id, SUM(`+recordDelta("r")+`) AS amount
+			FROM balance_records r
+			JOIN balances rb ON rb.id = r.balance_id
+			WHERE rb.company_id = $1 AND r.created_at >= $2
+			GROUP BY r.balance_id
+		) a ON a.balance_id = b.id
+		WHERE b.company_id = $1
+		GROUP BY b.currency
+		ORDER BY b.currency
+	`, companyId, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum balances: %w", err)
+	}
+	defer rows.Close()
+
+	holdings := []CurrencyAmount{}
+	for rows.Next() {
+		var amount CurrencyAmount
+		if err := rows.Scan(&amount.Currency, &amount.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		holdings = append(holdings, amount)
+	}
+	return holdings, rows.Err()
+}
+
+func (s *DashboardStorage) trades(ctx context.Context, companyId int64, from, to time.Time) (int, []DashboardTrade, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM exchanges
+		WHERE company_id = $1 AND created_at >= $2 AND created_at < $3
+	`, companyId, from, to).Scan(&count)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to count exchanges: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT currency, SUM(bought), SUM(sold)
+		FROM (
+			SELECT received_currency AS currency, received_money AS bought, 0 AS sold
+			FROM exchanges WHERE company_id = $1 AND created_at >= $2 AND created_at < $3
+			UNION ALL
+			SELECT selled_currency, 0, selled_money
+			FROM exchanges WHERE company_id = $1 AND created_at >= $2 AND created_at < $3
+		) e
+		GROUP BY currency
+		ORDER BY currency
+	`, companyId, from, to)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to sum exchanges: %w", err)
+	}
+	defer rows.Close()
+
+	trades := []DashboardTrade{}
+	for rows.Next() {
+		var trade DashboardTrade
+		if err := rows.Scan(&trade.Currency, &trade.Bought, &trade.Sold); err != nil {
+			return 0, nil, fmt.Errorf("failed to scan exchanges: %w", err)
+		}
+		trades = append(trades, trade)
+	}
+	return count, trades, rows.Err()
+}
+
+// pending sums the transactions not yet completed where column is the
+// company: delivered_company_id for those it is to pay out, and
+// received_company_id for those it took in.
+func (s *DashboardStorage) pending(ctx context.Context, column string, companyId int64) (DashboardRemittances, error) {
+	remittances := DashboardRemittances{Amounts: []CurrencyAmount{}}
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM transactions WHERE `+column+` = $1 AND status = $2
+	`, companyId, STATUS_CREATED).Scan(&remittances.Count)
+	if err != nil {
+		return remittances, fmt.Errorf("failed to count pending transactions: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT l.currency, SUM(l.amount)
+		FROM transactions t
+		JOIN transaction_lines l ON l.transaction_id = t.id AND l.direction = $3
+		WHERE t.`+column+` = $1 AND t.status = $2
+		GROUP BY l.currency
+		ORDER BY l.currency
+	`, companyId, STATUS_CREATED, LINE_OUT)
+	if err != nil {
+		return remittances, fmt.Errorf("failed to sum pending transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var amount CurrencyAmount
+		if err := rows.Scan(&amount.Currency, &amount.Amount); err != nil {
+			return remittances, fmt.Errorf("failed to scan pending transactions: %w", err)
+		}
+		remittances.Amounts = append(remittances.Amounts, amount)
+	}
+	return remittances, rows.Err()
+}
+
+func (s *DashboardStorage) fees(ctx context.Context, companyId int64, from, to time.Time) (float64, int, error) {
+	var income float64
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(fee), 0), COUNT(fee)
+		FROM (
+			SELECT CASE
+				WHEN btrim(service_fee) ~ '^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$' THEN btrim(service_fee)::numeric
+			END AS fee
+			FROM transactions
+			WHERE received_company_id = $1 AND created_at >= $2 AND created_at < $3
+		) t
+		WHERE fee <> 0
+	`, companyId, from, to).Scan(&income, &count)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to sum service fees: %w", err)
+	}
+	return income, count, nil
+}
+
+// topCashiers ranks users by the exchanges, transactions and debts they
+// entered in the period.
+func (s *DashboardStorage) topCashiers(ctx context.Context, companyId int64, from, to time.Time) ([]DashboardCashier, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.user_id, u.username, COUNT(*)
+		FROM (
+			SELECT user_id FROM exchanges
+			WHERE company_id = $1 AND created_at >= $2 AND created_at < $3
+			UNION ALL
+			SELECT received_user_id FROM transactions
+			WHERE received_company_id = $1 AND created_at >= $2 AND created_at < $3
+			UNION ALL
+			SELECT delivered_user_id FROM transactions
+			WHERE delivered_company_id = $1 AND delivered_user_id IS NOT NULL AND created_at >= $2 AND created_at < $3
+			UNION ALL
+			SELECT user_id FROM debts
+			WHERE company_id = $1 AND created_at >= $2 AND created_at < $3
+		) o
+		JOIN users u ON u.id = o.user_id
+		GROUP BY o.user_id, u.username
+		ORDER BY COUNT(*) DESC, o.user_id
+		LIMIT $4
+	`, companyId, from, to, DASHBOARD_TOP_CASHIERS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank cashiers: %w", err)
+	}
+	defer rows.Close()
+
+	cashiers := []DashboardCashier{}
+	for rows.Next() {
+		var cashier DashboardCashier
+		if err := rows.Scan(&cashier.UserID, &cashier.Username, &cashier.Operations); err != nil {
+			return nil, fmt.Errorf("failed to scan cashier: %w", err)
+		}
+		cashiers = append(cashiers, cashier)
+	}
+	return cashiers, rows.Err()
+}
@@ -6,7 +6,8 @@ import (
 )
 
 type DBWrapper struct {
-	db *sql.DB
+	db    *sql.DB
+	hooks *Hooks
 }
 
 func (d *DBWrapper) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
@@ -28,3 +29,13 @@ func (d *DBWrapper) Commit() error {
 func (d *DBWrapper) Rollback() error {
 	return nil // no-op for DB
 }
+
+// AfterCommit runs fn straight away; outside a transaction every write is
+// already committed.
+func (d *DBWrapper) AfterCommit(fn func()) {
+	fn()
+}
+
+func (d *DBWrapper) Hooks() *Hooks {
+	return d.hooks
+}
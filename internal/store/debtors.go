@@ -70,37 +70,22 @@ func (s *DebtorsStorage) Create(ctx context.Context, credits *Debtors) error {
 	return nil
 }
 
-// allowedOrderBy whitelists safe ORDER BY values to prevent SQL injection.
-var allowedOrderBy = map[string]string{
-	"id ASC":            "d.id ASC",
-	"id DESC":           "d.id DESC",
-	"d.id ASC":          "d.id ASC",
-	"d.id DESC":         "d.id DESC",
-	"created_at ASC":    "d.created_at ASC",
-	"created_at DESC":   "d.created_at DESC",
-	"d.created_at ASC":  "d.created_at ASC",
-	"d.created_at DESC": "d.created_at DESC",
-	"full_name ASC":     "d.full_name ASC",
-	"full_name DESC":    "d.full_name DESC",
-	"balance ASC":       "d.balance ASC",
-	"balance DESC":      "d.balance DESC",
-}
-
-func sanitizeOrderBy(raw string) string {
-	if safe, ok := allowedOrderBy[raw]; ok {
-		return safe
-	}
-	return "d.created_at DESC" // safe default
-}
-
 func (s *DebtorsStorage) GetByCompanyId(
 	ctx context.Context,
 	companyId int64,
 	search *string,
 	dateFilter *string, // "2025-02-18" or nil/empty = all
-	pagination types.Pagination,
+	pagination *types.Pagination,
 ) ([]Debtors, error) {
+	filter := &types.Filter{Search: search}
+	if dateFilter != nil && *dateFilter != "" {
+		filter.And("created_at", types.FILTER_DATE, *dateFilter)
+	}
+
+	return s.Filter(ctx, companyId, filter, pagination)
+}
 
+func (s *DebtorsStorage) Filter(ctx context.Context, companyId int64, filter *types.Filter, pagination *types.Pagination) ([]Debtors, error) {
 	query := `
         SELECT DISTINCT
             d.id,
@@ -112,82 +97,14 @@ func (s *DebtorsStorage) GetByCompanyId(
             d.created_at,
             d.full_name
         FROM debtors d
-        WHERE d.company_id = $1
-    `
-
-	args := []interface{}{companyId}
-	argIndex := 2
-
-	// ------------------------
-	// SEARCH FILTER
-	// ------------------------
-	if search != nil && *search != "" {
-		searchLike := "%" + *search + "%"
-		query += fmt.Sprintf(`
-            AND (
-                CAST(d.balance AS TEXT) ILIKE $%d OR
-                d.currency ILIKE $%d OR
-                d.phone ILIKE $%d OR
-                d.full_name ILIKE $%d
-            )
-        `, argIndex, argIndex, argIndex, argIndex)
-
-		args = append(args, searchLike)
-		argIndex++
-	}
-
-	// ------------------------
-	// DATE FILTER (FIXED)
-	// ------------------------
-	// created_at Tashkent time da saqlangan
-	// request ham Tashkent time
-	// timezone conversion kerak emas
-	// index-friendly range filter ishlatyapmiz
-	if dateFilter != nil && *dateFilter != "" {
-
-		loc, err := time.LoadLocation("Asia/Tashkent")
-		if err != nil {
-			return nil, fmt.Errorf("timezone load error: %w", err)
-		}
-
-		// Parse as Tashkent time
-		localDate, err := time.ParseInLocation("2006-01-02", *dateFilter, loc)
-		if err != nil {
-			return nil, fmt.Errorf("invalid date format (YYYY-MM-DD): %w", err)
-		}
-
-		startOfDay := time.Date(
-			localDate.Year(),
-			localDate.Month(),
-			localDate.Day(),
-			0, 0, 0, 0,
-			loc,
-		)
+        WHERE d.company_id = $1`
 
-		endOfDay := startOfDay.Add(24 * time.Hour)
-
-		query += fmt.Sprintf(`
-        AND d.created_at >= $%d
-        AND d.created_at < $%d
-    `, argIndex, argIndex+1)
-
-		args = append(args, startOfDay, endOfDay)
-		argIndex += 2
+	query, args, err := DebtorFilters.Apply(query, []any{companyId}, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	// ------------------------
-	// ORDER BY + PAGINATION
-	// ------------------------
-	orderBy := sanitizeOrderBy(pagination.OrderBy)
-
-	query += fmt.Sprintf(`
-        ORDER BY %s
-        OFFSET $%d LIMIT $%d
-    `, orderBy, argIndex, argIndex+1)
-
-	args = append(args, pagination.Offset, pagination.Limit)
-
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := queryPage(ctx, s.db, query, args, DebtorSorts, pagination)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
@@ -226,7 +143,7 @@ func (s *DebtorsStorage) GetByCompanyId(
 		return nil, fmt.Errorf("rows error: %w", err)
 	}
 
-	return debtors, nil
+	return trimPage(pagination, debtors, Debtors.pageKey), nil
 }
 
 func (s *DebtorsStorage) GetByBalanceInfo(ctx context.Context, companyId int64) ([]map[string]interface{}, error) {
@@ -274,18 +191,14 @@ func (s *DebtorsStorage) GetByBalanceInfo(ctx context.Context, companyId int64)
 	return result, nil
 }
 
-func (s *DebtorsStorage) GetByUserId(ctx context.Context, userId int64, pagination types.Pagination) ([]Debtors, error) {
+func (s *DebtorsStorage) GetByUserId(ctx context.Context, userId int64, pagination *types.Pagination) ([]Debtors, error) {
 	query := `
 				SELECT id, balance, currency, user_id, phone, company_id, created_at, full_name
-				FROM debtors WHERE user_id = $1 ORDER BY balance DESC
-	` + fmt.Sprintf(" OFFSET %v LIMIT %v", pagination.Offset, pagination.Limit)
+				FROM debtors d WHERE user_id = $1
+	`
 
 	var credits []Debtors
-	rows, err := s.db.QueryContext(
-		ctx,
-		query,
-		userId,
-	)
+	rows, err := queryPage(ctx, s.db, query, []any{userId}, DebtorSorts, pagination)
 
 	if err != nil {
 		return nil, err
@@ -317,7 +230,7 @@ func (s *DebtorsStorage) GetByUserId(ctx context.Context, userId int64, paginati
 
 	}
 
-	return credits, nil
+	return trimPage(pagination, credits, Debtors.pageKey), nil
 }
 
 func (s *DebtorsStorage) GetById(ctx context.Context, id int64) (*Debtors, error) {
@@ -383,7 +296,7 @@ func (s *DebtorsStorage) Update(ctx context.Context, credit *Debtors) error {
 	}
 
 	if res == 0 {
-		return fmt.Errorf("DEBTORS NOT FOUND")
+		return types.ErrDebtorNotFound
 	}
 
 	return nil
@@ -408,7 +321,7 @@ func (s *DebtorsStorage) Delete(ctx context.Context, id int64) error {
 	}
 
 	if res == 0 {
-		return fmt.Errorf("DEBTORS NOT FOUND")
+		return types.ErrDebtorNotFound
 	}
 
 	return nil
@@ -25,6 +25,7 @@ type Debts struct {
 	Phone              string                  `json:"phone"`
 	IsBalanceEffect    int                     `json:"is_balance_effect"`
 	Type               int                     `json:"type"`
+	DueAt              *time.Time              `json:"due_at"`
 	CreatedAt          time.Time               `json:"-"`
 	CreatedAtFormatted string                  `json:"created_at"`
 }
@@ -39,21 +40,14 @@ func NewDebtsStorage(db DBTX) *DebtsStorage {
 
 func (s *DebtsStorage) Create(ctx context.Context, debts *Debts) error {
 	query := `
-		INSERT INTO debts (received_incomes, debted_amount, debted_currency, user_id, 
-		details, phone, is_balance_effect, type, company_id, debtor_id, state)
+		INSERT INTO debts (debted_amount, debted_currency, user_id, 
+		details, phone, is_balance_effect, type, company_id, debtor_id, state, due_at)
 		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, created_at
 	`
 
-	// Convert ReceivedIncomes to JSON for storage
-	incomesJSON, err := json.Marshal(debts.ReceivedIncomes)
-	if err != nil {
-		return fmt.Errorf("failed to marshal received_incomes: %w", err)
-	}
-
-	err = s.db.QueryRowContext(
+	err := s.db.QueryRowContext(
 		ctx,
 		query,
-		incomesJSON,
 		debts.DebtedAmount,
 		debts.DebtedCurrency,
 		debts.UserID,
@@ -64,6 +58,7 @@ func (s *DebtsStorage) Create(ctx context.Context, debts *Debts) error {
 		debts.CompanyID,
 		debts.DebtorID,
 		debts.State,
+		debts.DueAt,
 	).Scan(
 		&debts.ID,
 		&debts.CreatedAt,
@@ -73,13 +68,13 @@ func (s *DebtsStorage) Create(ctx context.Context, debts *Debts) error {
 		return fmt.Errorf("failed to create debt: %w", err)
 	}
 
-	return nil
+	return setDebtLines(ctx, s.db, debts.ID, debts.ReceivedIncomes)
 }
 
 func (s *DebtsStorage) GetByCompanyID(ctx context.Context, companyID int64) ([]Debts, error) {
 	query := `
-		SELECT id, received_incomes, debted_amount, debted_currency, user_id, 
-		details, phone, is_balance_effect, type, created_at, company_id, debtor_id, state
+		SELECT id, ` + debtLineColumns("debts") + `, debted_amount, debted_currency, user_id,
+		details, phone, is_balance_effect, type, created_at, company_id, debtor_id, state, due_at
 		FROM debts WHERE company_id = $1 ORDER BY created_at DESC
 	`
 
@@ -92,50 +87,67 @@ func (s *DebtsStorage) GetByCompanyID(ctx context.Context, companyID int64) ([]D
 	return s.scanDebts(rows)
 }
 
-func (s *DebtsStorage) GetByDebtorID(ctx context.Context, debtorID int64, pagination types.Pagination) ([]Debts, error) {
+func (s *DebtsStorage) GetByDebtorID(ctx context.Context, debtorID int64, pagination *types.Pagination) ([]Debts, error) {
+	return s.find(ctx, "d.debtor_id = $1", []any{debtorID}, nil, pagination)
+}
+
+func (s *DebtsStorage) Filter(ctx context.Context, companyId int64, filter *types.Filter, pagination *types.Pagination) ([]Debts, error) {
+	return s.find(ctx, "d.company_id = $1", []any{companyId}, filter, pagination)
+}
+
+func (s *DebtsStorage) find(ctx context.Context, where string, args []any, filter *types.Filter, pagination *types.Pagination) ([]Debts, error) {
 	query := `
 		SELECT 
 			u.username,
-			d.id, d.received_incomes, d.debted_amount, d.debted_currency,
+			d.id, ` + debtLineColumns("d") + `, d.debted_amount, d.debted_currency,
 			d.user_id, d.details, d.phone, d.is_balance_effect,
-			d.type, d.created_at, d.company_id, d.debtor_id, d.state
+			d.type, d.created_at, d.company_id, d.debtor_id, d.state, d.due_at
 		FROM debts d
 		LEFT JOIN users u ON d.user_id = u.id
-		WHERE d.debtor_id = $1
-		ORDER BY d.created_at DESC
-		LIMIT $2 OFFSET $3
-	`
+		WHERE ` + where
 
-	rows, err := s.db.QueryContext(ctx, query, debtorID, pagination.Limit, pagination.Offset)
+	query, args, err := DebtFilters.Apply(query, args, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := queryPage(ctx, s.db, query, args, DebtSorts, pagination)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query debts: %w", err)
 	}
 	defer rows.Close()
 
-	return s.scanDebtsWithUsername(rows)
+	debts, err := s.scanDebtsWithUsername(rows)
+	if err != nil {
+		return nil, err
+	}
+	return trimPage(pagination, debts, Debts.pageKey), nil
 }
 
-func (s *DebtsStorage) GetByUserID(ctx context.Context, userID int64, pagination types.Pagination) ([]Debts, error) {
+func (s *DebtsStorage) GetByUserID(ctx context.Context, userID int64, pagination *types.Pagination) ([]Debts, error) {
 	query := `
-		SELECT id, received_incomes, debted_amount, debted_currency, user_id, 
-		details, phone, is_balance_effect, type, created_at, company_id, debtor_id, state
-		FROM debts WHERE user_id = $1 ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
+		SELECT id, ` + debtLineColumns("d") + `, debted_amount, debted_currency, user_id,
+		details, phone, is_balance_effect, type, created_at, company_id, debtor_id, state, due_at
+		FROM debts d WHERE user_id = $1
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, userID, pagination.Limit, pagination.Offset)
+	rows, err := queryPage(ctx, s.db, query, []any{userID}, DebtSorts, pagination)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query debts: %w", err)
 	}
 	defer rows.Close()
 
-	return s.scanDebts(rows)
+	debts, err := s.scanDebts(rows)
+	if err != nil {
+		return nil, err
+	}
+	return trimPage(pagination, debts, Debts.pageKey), nil
 }
 
 func (s *DebtsStorage) GetByID(ctx context.Context, id int64) (*Debts, error) {
 	query := `
-		SELECT id, received_incomes, debted_amount, debted_currency, user_id, 
-		details, phone, is_balance_effect, type, created_at, company_id, debtor_id, state
+		SELECT id, ` + debtLineColumns("debts") + `, debted_amount, debted_currency, user_id,
+		details, phone, is_balance_effect, type, created_at, company_id, debtor_id, state, due_at
 		FROM debts WHERE id = $1
 	`
 
@@ -156,10 +168,11 @@ func (s *DebtsStorage) GetByID(ctx context.Context, id int64) (*Debts, error) {
 		&debt.CompanyID,
 		&debt.DebtorID,
 		&debt.State,
+		&debt.DueAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("debt not found")
+		return nil, types.ErrDebtNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get debt: %w", err)
@@ -179,21 +192,16 @@ func (s *DebtsStorage) GetByID(ctx context.Context, id int64) (*Debts, error) {
 
 func (s *DebtsStorage) Update(ctx context.Context, debt *Debts) error {
 	query := `
-		UPDATE debts SET received_incomes = $1, debted_amount = $2, debted_currency = $3, 
-		user_id = $4, details = $5, phone = $6, is_balance_effect = $7, type = $8, 
-		company_id = $9, debtor_id = $10, state = $11  WHERE id = $12
+		UPDATE debts SET debted_amount = $1, debted_currency = $2, 
+		user_id = $3, details = $4, phone = $5, is_balance_effect = $6, type = $7, 
+		company_id = $8, debtor_id = $9, state = $10,
+		due_notified_at = CASE WHEN due_at IS DISTINCT FROM $11 THEN NULL ELSE due_notified_at END,
+		due_at = $11 WHERE id = $12
 	`
 
-	// Convert ReceivedIncomes to JSON
-	incomesJSON, err := json.Marshal(debt.ReceivedIncomes)
-	if err != nil {
-		return fmt.Errorf("failed to marshal received_incomes: %w", err)
-	}
-
 	result, err := s.db.ExecContext(
 		ctx,
 		query,
-		incomesJSON,
 		debt.DebtedAmount,
 		debt.DebtedCurrency,
 		debt.UserID,
@@ -204,6 +212,7 @@ func (s *DebtsStorage) Update(ctx context.Context, debt *Debts) error {
 		debt.CompanyID,
 		debt.DebtorID,
 		debt.State,
+		debt.DueAt,
 		debt.ID,
 	)
 
@@ -217,10 +226,10 @@ func (s *DebtsStorage) Update(ctx context.Context, debt *Debts) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("debt not found")
+		return types.ErrDebtNotFound
 	}
 
-	return nil
+	return setDebtLines(ctx, s.db, debt.ID, debt.ReceivedIncomes)
 }
 
 func (s *DebtsStorage) Delete(ctx context.Context, id int64) error {
@@ -237,12 +246,68 @@ func (s *DebtsStorage) Delete(ctx context.Context, id int64) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("debt not found")
+		return types.ErrDebtNotFound
 	}
 
 	return nil
 }
 
+// DueDebt is a debt past its due date whose debtor still owes Amount.
+type DueDebt struct {
+	ID        int64
+	UserID    *int64
+	CompanyID int64
+	FullName  string
+	Amount    int64
+	Currency  string
+}
+
+// ClaimDue marks up to limit debts that fell due by now, and whose debtor
+// still has a balance, as notified and returns them. Rows locked by another
+// run are skipped; the caller queues the notifications in the same
+// transaction.
+func (s *DebtsStorage) ClaimDue(ctx context.Context, now time.Time, limit int) ([]DueDebt, error) {
+	query := `
+		UPDATE debts d SET due_notified_at = now()
+		FROM debtors dr
+		WHERE dr.id = d.debtor_id AND d.id IN (
+			SELECT due.id FROM debts due
+			JOIN debtors owing ON owing.id = due.debtor_id
+			WHERE due.due_at <= $1 AND due.due_notified_at IS NULL
+			AND due.company_id IS NOT NULL AND COALESCE(owing.balance, 0) <> 0
+			ORDER BY due.due_at
+			LIMIT $2
+			FOR UPDATE OF due SKIP LOCKED
+		)
+		RETURNING d.id, d.user_id, d.company_id, COALESCE(dr.full_name, ''),
+			ABS(COALESCE(dr.balance, 0)), COALESCE(dr.currency, d.debted_currency)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due debts: %w", err)
+	}
+	defer rows.Close()
+
+	var debts []DueDebt
+	for rows.Next() {
+		var debt DueDebt
+		if err := rows.Scan(
+			&debt.ID,
+			&debt.UserID,
+			&debt.CompanyID,
+			&debt.FullName,
+			&debt.Amount,
+			&debt.Currency,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan due debt: %w", err)
+		}
+		debts = append(debts, debt)
+	}
+
+	return debts, rows.Err()
+}
+
 // Helper function to scan multiple debts from rows
 func (s *DebtsStorage) scanDebts(rows *sql.Rows) ([]Debts, error) {
 	var debts []Debts
@@ -265,6 +330,7 @@ func (s *DebtsStorage) scanDebts(rows *sql.Rows) ([]Debts, error) {
 			&debt.CompanyID,
 			&debt.DebtorID,
 			&debt.State,
+			&debt.DueAt,
 		)
 
 		if err != nil {
@@ -321,6 +387,7 @@ func (s *DebtsStorage) scanDebtsWithUsername(rows *sql.Rows) ([]Debts, error) {
 			&debt.CompanyID,
 			&debt.DebtorID,
 			&debt.State,
+			&debt.DueAt,
 		)
 		if err != nil {
 			return nil, err
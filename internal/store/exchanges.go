@@ -2,7 +2,6 @@ package store
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/mubashshir3767/currencyExchange/internal/types"
@@ -43,7 +42,7 @@ func (s *ExchangeStorage) Archive(ctx context.Context, companyId int64) error {
 	}
 
 	if res == 0 {
-		return fmt.Errorf("ERROR NOT FOUND")
+		return types.ErrExchangeNotFound
 	}
 
 	return nil
@@ -99,24 +98,20 @@ func (s *ExchangeStorage) Update(ctx context.Context, exchange *Exchange) error
 	}
 
 	if res == 0 {
-		return fmt.Errorf("NOT FOUND")
+		return types.ErrExchangeNotFound
 	}
 
 	return err
 }
 
-func (s *ExchangeStorage) Archived(ctx context.Context, pagination types.Pagination) ([]Exchange, error) {
+func (s *ExchangeStorage) Archived(ctx context.Context, pagination *types.Pagination) ([]Exchange, error) {
 	query := `
 				SELECT id, received_money, received_currency, selled_money,
 				selled_currency, user_id, company_id, details, created_at 
-				FROM exchanges WHERE status = $1  	ORDER BY created_at DESC
-	` + fmt.Sprintf(" OFFSET %v LIMIT %v", pagination.Offset, pagination.Limit)
+				FROM exchanges WHERE status = $1
+	`
 
-	rows, err := s.db.QueryContext(
-		ctx,
-		query,
-		STATUS_ARCHIVED,
-	)
+	rows, err := queryPage(ctx, s.db, query, []any{STATUS_ARCHIVED}, ExchangeSorts, pagination)
 	if err != nil {
 		return nil, err
 	}
@@ -147,20 +142,29 @@ func (s *ExchangeStorage) Archived(ctx context.Context, pagination types.Paginat
 		exchanges = append(exchanges, *exchage)
 	}
 
-	return exchanges, nil
+	return trimPage(pagination, exchanges, Exchange.pageKey), nil
+}
+
+func (s *ExchangeStorage) GetByField(ctx context.Context, fieldName string, fieldValue any, pagination *types.Pagination) ([]Exchange, error) {
+	return s.find(ctx, "status != $1", []any{STATUS_ARCHIVED}, types.Where(fieldName, fieldValue), pagination)
+}
+
+func (s *ExchangeStorage) Filter(ctx context.Context, companyId int64, filter *types.Filter, pagination *types.Pagination) ([]Exchange, error) {
+	return s.find(ctx, "company_id = $1 AND status != $2", []any{companyId, STATUS_ARCHIVED}, filter, pagination)
 }
 
-func (s *ExchangeStorage) GetByField(ctx context.Context, fieldName string, fieldValue any, pagination types.Pagination) ([]Exchange, error) {
+func (s *ExchangeStorage) find(ctx context.Context, where string, args []any, filter *types.Filter, pagination *types.Pagination) ([]Exchange, error) {
 	query := `
 				SELECT id, received_money, received_currency, selled_money,
 				selled_currency, user_id, company_id, details, created_at 
-				FROM exchanges WHERE status != $1 AND ` + fmt.Sprintf(" %v = %v ORDER BY created_at DESC OFFSET %v LIMIT %v", fieldName, fieldValue, pagination.Offset, pagination.Limit)
+				FROM exchanges WHERE ` + where
 
-	rows, err := s.db.QueryContext(
-		ctx,
-		query,
-		STATUS_ARCHIVED,
-	)
+	query, args, err := ExchangeFilters.Apply(query, args, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := queryPage(ctx, s.db, query, args, ExchangeSorts, pagination)
 	if err != nil {
 		return nil, err
 	}
@@ -191,7 +195,7 @@ func (s *ExchangeStorage) GetByField(ctx context.Context, fieldName string, fiel
 		exchanges = append(exchanges, *exchage)
 	}
 
-	return exchanges, nil
+	return trimPage(pagination, exchanges, Exchange.pageKey), nil
 }
 
 func (s *ExchangeStorage) GetById(ctx context.Context, id int64) (*Exchange, error) {
@@ -247,7 +251,7 @@ func (s *ExchangeStorage) Delete(ctx context.Context, id int64) error {
 	}
 
 	if res == 0 {
-		return fmt.Errorf("NOT FOUND")
+		return types.ErrExchangeNotFound
 	}
 
 	return nil
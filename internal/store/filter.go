@@ -0,0 +1,299 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// Column kinds decide how filter values are converted before they are bound.
+const (
+	COLUMN_INT = iota
+	COLUMN_TEXT
+	COLUMN_TIME
+)
+
+// FilterFields whitelists the columns a resource can be filtered on and the
+// expressions its text search runs over. Only names from the whitelist ever
+// reach the SQL; every value is bound as a parameter.
+type FilterFields struct {
+	columns map[string]filterColumn
+	search  []string
+}
+
+type filterColumn struct {
+	sql  string
+	kind int
+}
+
+// NewFilterFields builds the whitelist for a table. alias is the table alias
+// used in the list query, or "" when there is none. search holds SQL
+// expressions, not user input.
+func NewFilterFields(alias string, columns map[string]int, search ...string) FilterFields {
+	prefix := ""
+	if alias != "" {
+		prefix = alias + "."
+	}
+
+	fields := FilterFields{columns: map[string]filterColumn{}, search: search}
+	for name, kind := range columns {
+		fields.columns[name] = filterColumn{sql: prefix + name, kind: kind}
+	}
+	return fields
+}
+
+var (
+	TransactionFilters = NewFilterFields("", map[string]int{
+		"id":                   COLUMN_INT,
+		"number":               COLUMN_INT,
+		"type":                 COLUMN_INT,
+		"status":               COLUMN_INT,
+		"received_company_id":  COLUMN_INT,
+		"delivered_company_id": COLUMN_INT,
+		"received_user_id":     COLUMN_INT,
+		"delivered_user_id":    COLUMN_INT,
+		"phone":                COLUMN_TEXT,
+		"details":              COLUMN_TEXT,
+		"created_at":           COLUMN_TIME,
+	}, "details", "phone", "CAST(number AS TEXT)", "CAST(service_fee AS TEXT)")
+
+	ExchangeFilters = NewFilterFields("", map[string]int{
+		"id":                COLUMN_INT,
+		"user_id":           COLUMN_INT,
+		"company_id":        COLUMN_INT,
+		"received_money":    COLUMN_INT,
+		"selled_money":      COLUMN_INT,
+		"received_currency": COLUMN_TEXT,
+		"selled_currency":   COLUMN_TEXT,
+		"details":           COLUMN_TEXT,
+		"created_at":        COLUMN_TIME,
+	}, "details", "received_currency", "selled_currency")
+
+	BalanceRecordFilters = NewFilterFields("", map[string]int{
+		"id":             COLUMN_INT,
+		"user_id":        COLUMN_INT,
+		"balance_id":     COLUMN_INT,
+		"company_id":     COLUMN_INT,
+		"transaction_id": COLUMN_INT,
+		"debt_id":        COLUMN_INT,
+		"exchange_id":    COLUMN_INT,
+		"amount":         COLUMN_INT,
+		"type":           COLUMN_INT,
+		"currency":       COLUMN_TEXT,
+		"details":        COLUMN_TEXT,
+		"created_at":     COLUMN_TIME,
+	}, "details", "currency")
+
+	DebtFilters = NewFilterFields("d", map[string]int{
+		"id":                COLUMN_INT,
+		"debtor_id":         COLUMN_INT,
+		"user_id":           COLUMN_INT,
+		"company_id":        COLUMN_INT,
+		"debted_amount":     COLUMN_INT,
+		"type":              COLUMN_INT,
+		"state":             COLUMN_INT,
+		"is_balance_effect": COLUMN_INT,
+		"debted_currency":   COLUMN_TEXT,
+		"phone":             COLUMN_TEXT,
+		"details":           COLUMN_TEXT,
+		"created_at":        COLUMN_TIME,
+	}, "d.details", "d.phone", "d.debted_currency")
+
+	DebtorFilters = NewFilterFields("d", map[string]int{
+		"id":         COLUMN_INT,
+		"user_id":    COLUMN_INT,
+		"company_id": COLUMN_INT,
+		"balance":    COLUMN_INT,
+		"currency":   COLUMN_TEXT,
+		"phone":      COLUMN_TEXT,
+		"full_name":  COLUMN_TEXT,
+		"created_at": COLUMN_TIME,
+	}, "CAST(d.balance AS TEXT)", "d.currency", "d.phone", "d.full_name")
+)
+
+var comparisons = map[string]string{
+	types.FILTER_EQ:  "=",
+	types.FILTER_NE:  "!=",
+	types.FILTER_GT:  ">",
+	types.FILTER_GTE: ">=",
+	types.FILTER_LT:  "<",
+	types.FILTER_LTE: "<=",
+}
+
+// Apply appends the conditions of filter to query, which must end inside its
+// WHERE clause, and returns the query with its arguments.
+func (f FilterFields) Apply(query string, args []any, filter *types.Filter) (string, []any, error) {
+	if filter == nil {
+		return query, args, nil
+	}
+
+	var where strings.Builder
+	where.WriteString(query)
+
+	bind := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	for _, cond := range filter.Where {
+		column, ok := f.columns[cond.Field]
+		if !ok {
+			return "", nil, types.ErrInvalidField.WithField("field", cond.Field)
+		}
+		invalid := types.ErrInvalidFilter.WithField(cond.Field, cond.Op)
+
+		switch cond.Op {
+		case types.FILTER_EQ, types.FILTER_NE, types.FILTER_GT, types.FILTER_GTE, types.FILTER_LT, types.FILTER_LTE:
+			value, _, err := column.convert(cond.Value)
+			if err != nil {
+				return "", nil, invalid.Wrap(err)
+			}
+			fmt.Fprintf(&where, " AND %s %s %s", column.sql, comparisons[cond.Op], bind(value))
+
+		case types.FILTER_IN:
+			values, ok := cond.Value.([]any)
+			if !ok || len(values) == 0 {
+				return "", nil, invalid
+			}
+			params := make([]string, 0, len(values))
+			for _, raw := range values {
+				value, _, err := column.convert(raw)
+				if err != nil {
+					return "", nil, invalid.Wrap(err)
+				}
+				params = append(params, bind(value))
+			}
+			fmt.Fprintf(&where, " AND %s IN (%s)", column.sql, strings.Join(params, ", "))
+
+		case types.FILTER_BETWEEN:
+			bounds, ok := cond.Value.([]any)
+			if !ok || len(bounds) != 2 {
+				return "", nil, invalid
+			}
+			from, _, err := column.convert(bounds[0])
+			if err != nil {
+				return "", nil, invalid.Wrap(err)
+			}
+			to, dateOnly, err := column.convert(bounds[1])
+			if err != nil {
+				return "", nil, invalid.Wrap(err)
+			}
+			// A bare date as the upper bound includes that whole day.
+			if dateOnly {
+				fmt.Fprintf(&where, " AND %s >= %s AND %s < %s", column.sql, bind(from), column.sql, bind(to.(time.Time).Add(24*time.Hour)))
+			} else {
+				fmt.Fprintf(&where, " AND %s BETWEEN %s AND %s", column.sql, bind(from), bind(to))
+			}
+
+		case types.FILTER_DATE:
+			if column.kind != COLUMN_TIME {
+				return "", nil, invalid
+			}
+			day, _, err := column.convert(cond.Value)
+			if err != nil {
+				return "", nil, invalid.Wrap(err)
+			}
+			start := day.(time.Time)
+			start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+			fmt.Fprintf(&where, " AND %s >= %s AND %s < %s", column.sql, bind(start), column.sql, bind(start.Add(24*time.Hour)))
+
+		case types.FILTER_CONTAINS:
+			text, ok := cond.Value.(string)
+			if !ok || column.kind != COLUMN_TEXT {
+				return "", nil, invalid
+			}
+			fmt.Fprintf(&where, " AND %s ILIKE %s", column.sql, bind("%"+escapeLike(text)+"%"))
+
+		default:
+			return "", nil, invalid
+		}
+	}
+
+	if filter.Search != nil && *filter.Search != "" && len(f.search) > 0 {
+		param := bind("%" + escapeLike(*filter.Search) + "%")
+		matches := make([]string, 0, len(f.search))
+		for _, expr := range f.search {
+			matches = append(matches, expr+" ILIKE "+param)
+		}
+		fmt.Fprintf(&where, " AND (%s)", strings.Join(matches, " OR "))
+	}
+
+	return where.String(), args, nil
+}
+
+// convert turns a decoded JSON value into what the column expects. dateOnly
+// reports a time given as a bare YYYY-MM-DD.
+func (c filterColumn) convert(raw any) (value any, dateOnly bool, err error) {
+	switch c.kind {
+	case COLUMN_INT:
+		value, err = toInt64(raw)
+		return value, false, err
+	case COLUMN_TEXT:
+		text, ok := raw.(string)
+		if !ok {
+			return nil, false, fmt.Errorf("%v is not a string", raw)
+		}
+		return text, false, nil
+	case COLUMN_TIME:
+		t, dateOnly, err := toTime(raw)
+		return t, dateOnly, err
+	}
+	return nil, false, fmt.Errorf("unknown column kind %d", c.kind)
+}
+
+func toInt64(raw any) (int64, error) {
+	switch v := raw.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case *int64:
+		if v != nil {
+			return *v, nil
+		}
+	case float64:
+		if v == math.Trunc(v) {
+			return int64(v), nil
+		}
+	case json.Number:
+		return v.Int64()
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("%v is not an integer", raw)
+}
+
+func toTime(raw any) (time.Time, bool, error) {
+	text, ok := raw.(string)
+	if !ok {
+		if t, ok := raw.(time.Time); ok {
+			return t, false, nil
+		}
+		return time.Time{}, false, fmt.Errorf("%v is not a date", raw)
+	}
+
+	loc, err := time.LoadLocation("Asia/Tashkent")
+	if err != nil {
+		loc = time.UTC
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", text, loc); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", text, loc); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%q is not a date", text)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
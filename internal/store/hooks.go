@@ -0,0 +1,37 @@
+package store
+
+import (
+	"context"
+	"sync"
+)
+
+// Hooks run after a write is committed, so listeners never see a change that
+// was rolled back. One Hooks is shared by a Storage and by every storage
+// built inside its transactions.
+type Hooks struct {
+	mu             sync.RWMutex
+	balanceChanged []func(context.Context, Balance)
+}
+
+// OnBalanceChanged registers fn for every balance saved by BalanceStorage.Update.
+func (h *Hooks) OnBalanceChanged(fn func(context.Context, Balance)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.balanceChanged = append(h.balanceChanged, fn)
+}
+
+func (h *Hooks) runBalanceChanged(ctx context.Context, balance Balance) {
+	if h == nil {
+		return
+	}
+
+	h.mu.RLock()
+	fns := h.balanceChanged
+	h.mu.RUnlock()
+
+	// The request that made the change may already be finished.
+	ctx = context.WithoutCancel(ctx)
+	for _, fn := range fns {
+		fn(ctx, balance)
+	}
+}
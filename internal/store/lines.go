@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// Line directions: received incomes come in, delivered outcomes go out.
+const (
+	LINE_IN  = "in"
+	LINE_OUT = "out"
+)
+
+// transactionLineColumns selects the lines of the transactions row table,
+// a table name or alias, as the received_incomes and delivered_outcomes JSON
+// arrays the scanners read.
+func transactionLineColumns(table string) string {
+	return linesJSON("transaction_lines", "transaction_id", table, LINE_IN, "received") + ` AS received_incomes, ` +
+		linesJSON("transaction_lines", "transaction_id", table, LINE_OUT, "delivered") + ` AS delivered_outcomes`
+}
+
+// debtLineColumns is transactionLineColumns for debts, which only have
+// received incomes.
+func debtLineColumns(table string) string {
+	return linesJSON("debt_lines", "debt_id", table, LINE_IN, "received") + ` AS received_incomes`
+}
+
+// linesJSON aggregates lines in the shape of types.ReceivedIncomes or
+// types.DeliveredOutcomes, whose keys start with prefix.
+func linesJSON(lines, key, table, direction, prefix string) string {
+	return fmt.Sprintf(`(
+		SELECT COALESCE(jsonb_agg(jsonb_build_object('%[5]s_amount', l.amount, '%[5]s_currency', l.currency) ORDER BY l.position), '[]'::jsonb)
+		FROM %[1]s l WHERE l.%[2]s = %[3]s.id AND l.direction = '%[4]s'
+	)`, lines, key, table, direction, prefix)
+}
+
+// setTransactionLines replaces the lines of a transaction.
+func setTransactionLines(ctx context.Context, db DBTX, id int64, incomes []types.ReceivedIncomes, outcomes []types.DeliveredOutcomes) error {
+	var currencies []string
+	var amounts []int64
+	for _, income := range incomes {
+		currencies = append(currencies, income.ReceivedCurrency)
+		amounts = append(amounts, income.ReceivedAmount)
+	}
+	if err := setLines(ctx, db, "transaction_lines", "transaction_id", id, LINE_IN, currencies, amounts); err != nil {
+		return err
+	}
+
+	currencies, amounts = nil, nil
+	for _, outcome := range outcomes {
+		currencies = append(currencies, outcome.DeliveredCurrency)
+		amounts = append(amounts, outcome.DeliveredAmount)
+	}
+	return setLines(ctx, db, "transaction_lines", "transaction_id", id, LINE_OUT, currencies, amounts)
+}
+
+// setDebtLines replaces the lines of a debt.
+func setDebtLines(ctx context.Context, db DBTX, id int64, incomes []types.ReceivedIncomes) error {
+	var currencies []string
+	var amounts []int64
+	for _, income := range incomes {
+		currencies = append(currencies, income.ReceivedCurrency)
+		amounts = append(amounts, income.ReceivedAmount)
+	}
+	return setLines(ctx, db, "debt_lines", "debt_id", id, LINE_IN, currencies, amounts)
+}
+
+func setLines(ctx context.Context, db DBTX, lines, key string, id int64, direction string, currencies []string, amounts []int64) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM `+lines+` WHERE `+key+` = $1 AND direction = $2`, id, direction)
+	if err != nil {
+		return fmt.Errorf("failed to clear %s: %w", lines, err)
+	}
+	if len(currencies) == 0 {
+		return nil
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO `+lines+` (`+key+`, direction, position, currency, amount)
+		SELECT $1::bigint, $2::varchar, l.position, l.currency, l.amount
+		FROM unnest($3::text[], $4::bigint[]) WITH ORDINALITY AS l(currency, amount, position)
+	`, id, direction, pq.Array(currencies), pq.Array(amounts))
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", lines, err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+const (
+	NOTIFICATION_PENDING = 1
+	NOTIFICATION_SENT    = 2
+	NOTIFICATION_FAILED  = 3
+)
+
+const (
+	NOTIFICATION_TRANSACTION_PENDING   = "transaction_pending"
+	NOTIFICATION_TRANSACTION_COMPLETED = "transaction_completed"
+	NOTIFICATION_TRANSACTION_CANCELLED = "transaction_cancelled"
+	NOTIFICATION_LOW_BALANCE           = "low_balance"
+	NOTIFICATION_HIGH_BALANCE          = "high_balance"
+	NOTIFICATION_DEBT_DUE              = "debt_due"
+	NOTIFICATION_LARGE_EXCHANGE        = "large_exchange"
+	NOTIFICATION_NEW_DEVICE_LOGIN      = "new_device_login"
+)
+
+// NotificationOutbox is a notification written in the same transaction as
+// the change it is about and sent later by the notification dispatcher.
+// Payload is a flat JSON object of strings that the message texts use.
+type NotificationOutbox struct {
+	ID                   int64           `json:"id"`
+	UserID               int64           `json:"user_id"`
+	CompanyID            int64           `json:"company_id"`
+	Kind                 string          `json:"kind"`
+	TransactionID        *int64          `json:"transaction_id"`
+	Payload              json.RawMessage `json:"payload"`
+	Status               int64           `json:"status"`
+	Attempts             int             `json:"attempts"`
+	LastError            *string         `json:"last_error"`
+	NextAttemptAt        time.Time       `json:"-"`
+	NextAttemptFormatted string          `json:"next_attempt_at"`
+	SentAt               *time.Time      `json:"-"`
+	SentAtFormatted      *string         `json:"sent_at"`
+	CreatedAt            time.Time       `json:"-"`
+	CreatedAtFormatted   string          `json:"created_at"`
+}
+
+type NotificationOutboxStorage struct {
+	db DBTX
+}
+
+func NewNotificationOutboxStorage(db DBTX) *NotificationOutboxStorage {
+	return &NotificationOutboxStorage{db: db}
+}
+
+const notificationOutboxColumns = `
+	id, user_id, company_id, kind, transaction_id, payload, status, attempts,
+	last_error, next_attempt_at, sent_at, created_at
+`
+
+func (s *NotificationOutboxStorage) Create(ctx context.Context, n *NotificationOutbox) error {
+	query := `
+		INSERT INTO notification_outbox (user_id, company_id, kind, transaction_id, payload)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, status, next_attempt_at, created_at
+	`
+
+	payload := n.Payload
+	if len(payload) == 0 {
+		payload = json.RawMessage(`{}`)
+	}
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		n.UserID,
+		n.CompanyID,
+		n.Kind,
+		n.TransactionID,
+		[]byte(payload),
+	).Scan(
+		&n.ID,
+		&n.Status,
+		&n.NextAttemptAt,
+		&n.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	n.Payload = payload
+	n.NextAttemptFormatted = formatTashkent(n.NextAttemptAt)
+	n.CreatedAtFormatted = formatTashkent(n.CreatedAt)
+	return nil
+}
+
+func (s *NotificationOutboxStorage) GetById(ctx context.Context, id int64) (*NotificationOutbox, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+notificationOutboxColumns+` FROM notification_outbox WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications, err := s.scanNotifications(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(notifications) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &notifications[0], nil
+}
+
+// GetByCompanyId lists the notifications of a company. userId, status and
+// transactionId narrow the list when they are set.
+func (s *NotificationOutboxStorage) GetByCompanyId(ctx context.Context, companyId int64, userId, status, transactionId *int64, pagination *types.Pagination) ([]NotificationOutbox, error) {
+	query := `SELECT ` + notificationOutboxColumns + ` FROM notification_outbox
+		WHERE company_id = $1 AND ($2::bigint IS NULL OR user_id = $2)
+		AND ($3::bigint IS NULL OR status = $3) AND ($4::bigint IS NULL OR transaction_id = $4)
+	`
+
+	rows, err := queryPage(ctx, s.db, query, []any{companyId, userId, status, transactionId}, NotificationOutboxSorts, pagination)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications, err := s.scanNotifications(rows)
+	if err != nil {
+		return nil, err
+	}
+	return trimPage(pagination, notifications, NotificationOutbox.pageKey), nil
+}
+
+// Claim picks up to limit due notifications, counts the attempt and moves
+// next_attempt_at forward by lease so other dispatchers skip them meanwhile.
+func (s *NotificationOutboxStorage) Claim(ctx context.Context, limit int, lease time.Duration) ([]NotificationOutbox, error) {
+	query := `
+		UPDATE notification_outbox SET attempts = attempts + 1, next_attempt_at = now() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM notification_outbox WHERE status = $3 AND next_attempt_at <= now()
+			ORDER BY next_attempt_at LIMIT $1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + notificationOutboxColumns
+
+	rows, err := s.db.QueryContext(ctx, query, limit, lease.Seconds(), NOTIFICATION_PENDING)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanNotifications(rows)
+}
+
+func (s *NotificationOutboxStorage) MarkSent(ctx context.Context, id int64) error {
+	query := `UPDATE notification_outbox SET status = $1, last_error = NULL, sent_at = now() WHERE id = $2`
+
+	_, err := s.db.ExecContext(ctx, query, NOTIFICATION_SENT, id)
+	return err
+}
+
+// MarkFailed records a failed attempt. A nil retryAt gives up on the
+// notification.
+func (s *NotificationOutboxStorage) MarkFailed(ctx context.Context, id int64, lastError string, retryAt *time.Time) error {
+	status := int64(NOTIFICATION_PENDING)
+	next := time.Now()
+	if retryAt == nil {
+		status = NOTIFICATION_FAILED
+	} else {
+		next = *retryAt
+	}
+
+	query := `UPDATE notification_outbox SET status = $1, last_error = $2, next_attempt_at = $3 WHERE id = $4`
+
+	_, err := s.db.ExecContext(ctx, query, status, lastError, next, id)
+	return err
+}
+
+func (s *NotificationOutboxStorage) scanNotifications(rows *sql.Rows) ([]NotificationOutbox, error) {
+	var notifications []NotificationOutbox
+	for rows.Next() {
+		var n NotificationOutbox
+		var transactionID sql.NullInt64
+		var payload []byte
+		var lastError sql.NullString
+		var sentAt sql.NullTime
+
+		if err := rows.Scan(
+			&n.ID,
+			&n.UserID,
+			&n.CompanyID,
+			&n.Kind,
+			&transactionID,
+			&payload,
+			&n.Status,
+			&n.Attempts,
+			&lastError,
+			&n.NextAttemptAt,
+			&sentAt,
+			&n.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if transactionID.Valid {
+			n.TransactionID = &transactionID.Int64
+		}
+		n.Payload = json.RawMessage(payload)
+		if lastError.Valid {
+			n.LastError = &lastError.String
+		}
+		if sentAt.Valid {
+			formatted := formatTashkent(sentAt.Time)
+			n.SentAt = &sentAt.Time
+			n.SentAtFormatted = &formatted
+		}
+		n.NextAttemptFormatted = formatTashkent(n.NextAttemptAt)
+		n.CreatedAtFormatted = formatTashkent(n.CreatedAt)
+
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// NotificationPreferenceStorage keeps which channels a user wants for each
+// notification kind. Kinds without a row use the notifier's defaults.
+type NotificationPreferenceStorage struct {
+	db DBTX
+}
+
+func NewNotificationPreferenceStorage(db DBTX) *NotificationPreferenceStorage {
+	return &NotificationPreferenceStorage{db: db}
+}
+
+// GetByUserId returns the channels the user chose, keyed by kind.
+func (s *NotificationPreferenceStorage) GetByUserId(ctx context.Context, userId int64) (map[string][]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT kind, channels FROM notification_preferences WHERE user_id = $1`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	preferences := map[string][]string{}
+	for rows.Next() {
+		var kind string
+		var channels []string
+		if err := rows.Scan(&kind, pq.Array(&channels)); err != nil {
+			return nil, err
+		}
+		preferences[kind] = channels
+	}
+
+	return preferences, rows.Err()
+}
+
+// Set replaces the channels of one kind. An empty list turns the kind off
+// everywhere but the in-app inbox.
+func (s *NotificationPreferenceStorage) Set(ctx context.Context, userId int64, kind string, channels []string) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, kind, channels) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, kind) DO UPDATE SET channels = EXCLUDED.channels, updated_at = now()
+	`
+
+	if channels == nil {
+		channels = []string{}
+	}
+
+	if _, err := s.db.ExecContext(ctx, query, userId, kind, pq.Array(channels)); err != nil {
+		return fmt.Errorf("failed to save notification preference: %w", err)
+	}
+	return nil
+}
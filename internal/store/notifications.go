@@ -0,0 +1,151 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// Notification is an entry of a user's in-app inbox. It is kept whether or
+// not the push reached a device. Title and Body are rendered in the reader's
+// language when the inbox is read.
+type Notification struct {
+	ID                 int64           `json:"id"`
+	UserID             int64           `json:"user_id"`
+	CompanyID          int64           `json:"company_id"`
+	OutboxID           *int64          `json:"-"`
+	Kind               string          `json:"kind"`
+	TransactionID      *int64          `json:"transaction_id"`
+	Data               json.RawMessage `json:"data"`
+	Title              string          `json:"title"`
+	Body               string          `json:"body"`
+	Read               bool            `json:"read"`
+	ReadAt             *time.Time      `json:"-"`
+	ReadAtFormatted    *string         `json:"read_at"`
+	CreatedAt          time.Time       `json:"-"`
+	CreatedAtFormatted string          `json:"created_at"`
+}
+
+type NotificationStorage struct {
+	db DBTX
+}
+
+func NewNotificationStorage(db DBTX) *NotificationStorage {
+	return &NotificationStorage{db: db}
+}
+
+// Create adds the notification to the inbox. A notification coming from the
+// outbox is only stored once however often its push is retried.
+func (s *NotificationStorage) Create(ctx context.Context, n *Notification) error {
+	query := `
+		INSERT INTO notifications (user_id, company_id, outbox_id, kind, transaction_id, data)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (outbox_id) DO NOTHING
+	`
+
+	data := n.Data
+	if len(data) == 0 {
+		data = json.RawMessage(`{}`)
+	}
+
+	_, err := s.db.ExecContext(ctx, query, n.UserID, n.CompanyID, n.OutboxID, n.Kind, n.TransactionID, []byte(data))
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
+
+func (s *NotificationStorage) GetByUserId(ctx context.Context, userId int64, unreadOnly bool, pagination *types.Pagination) ([]Notification, error) {
+	query := `
+		SELECT id, user_id, company_id, kind, transaction_id, data, read_at, created_at
+		FROM notifications WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+	`
+
+	rows, err := queryPage(ctx, s.db, query, []any{userId, unreadOnly}, NotificationSorts, pagination)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []Notification
+	for rows.Next() {
+		var n Notification
+		var transactionID sql.NullInt64
+		var data []byte
+		var readAt sql.NullTime
+
+		if err := rows.Scan(
+			&n.ID,
+			&n.UserID,
+			&n.CompanyID,
+			&n.Kind,
+			&transactionID,
+			&data,
+			&readAt,
+			&n.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if transactionID.Valid {
+			n.TransactionID = &transactionID.Int64
+		}
+		n.Data = json.RawMessage(data)
+		if readAt.Valid {
+			formatted := formatTashkent(readAt.Time)
+			n.Read = true
+			n.ReadAt = &readAt.Time
+			n.ReadAtFormatted = &formatted
+		}
+		n.CreatedAtFormatted = formatTashkent(n.CreatedAt)
+
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return trimPage(pagination, notifications, Notification.pageKey), nil
+}
+
+func (s *NotificationStorage) UnreadCount(ctx context.Context, userId int64) (int64, error) {
+	var count int64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userId).Scan(&count)
+	return count, err
+}
+
+// MarkRead marks one notification of the user as read. Reading it again is
+// not an error.
+func (s *NotificationStorage) MarkRead(ctx context.Context, userId, id int64) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, now()) WHERE id = $1 AND user_id = $2`
+
+	res, err := s.db.ExecContext(ctx, query, id, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user as read and
+// returns how many there were.
+func (s *NotificationStorage) MarkAllRead(ctx context.Context, userId int64) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE notifications SET read_at = now() WHERE user_id = $1 AND read_at IS NULL`, userId)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// SortFields whitelists the order_by values a list accepts and maps them to
+// safe ORDER BY clauses. Every list gets id as a tie breaker so pages are
+// stable.
+type SortFields struct {
+	alias    string
+	fields   map[string]string
+	fallback string
+}
+
+// NewSortFields builds the whitelist for a table. alias is the table alias
+// used in the list query, or "" when there is none. fallback is the order_by
+// used when the client sends none.
+func NewSortFields(alias, fallback string, fields ...string) SortFields {
+	prefix := ""
+	if alias != "" {
+		prefix = alias + "."
+	}
+
+	allowed := map[string]string{}
+	for _, field := range append([]string{"id", "created_at"}, fields...) {
+		allowed[field+" ASC"] = prefix + field + " ASC, " + prefix + "id ASC"
+		allowed[field+" DESC"] = prefix + field + " DESC, " + prefix + "id DESC"
+	}
+
+	return SortFields{alias: prefix, fields: allowed, fallback: fallback}
+}
+
+// Validate reports whether order_by is allowed, so handlers can reject it
+// instead of silently falling back.
+func (s SortFields) Validate(orderBy string) error {
+	if orderBy == "" {
+		return nil
+	}
+	if _, ok := s.fields[orderBy]; !ok {
+		return types.ErrInvalidSort.WithField("order_by", orderBy)
+	}
+	return nil
+}
+
+func (s SortFields) resolve(orderBy string) (clause string, keyset bool) {
+	if _, ok := s.fields[orderBy]; !ok {
+		orderBy = s.fallback
+	}
+	return s.fields[orderBy], orderBy == "created_at DESC"
+}
+
+var (
+	TransactionSorts        = NewSortFields("", "created_at DESC", "number", "type", "status")
+	ExchangeSorts           = NewSortFields("", "created_at DESC", "received_money", "selled_money")
+	BalanceRecordSorts      = NewSortFields("", "created_at DESC", "amount", "type")
+	DebtorSorts             = NewSortFields("d", "balance DESC", "full_name", "balance")
+	DebtSorts               = NewSortFields("d", "created_at DESC", "debted_amount", "type")
+	ChangeRequestSorts      = NewSortFields("", "created_at DESC", "status")
+	AuditLogSorts           = NewSortFields("", "created_at DESC")
+	WebhookDeliverySorts    = NewSortFields("d", "created_at DESC", "status", "attempts")
+	NotificationOutboxSorts = NewSortFields("", "created_at DESC", "status", "attempts")
+	NotificationSorts       = NewSortFields("", "created_at DESC")
+)
+
+// queryPage runs query as one page of a list. query must end inside its
+// WHERE clause so the keyset condition can be appended. One extra row is read
+// to know whether another page follows; trimPage drops it again. Cursors
+// only work with the keyset order, so any other order_by with a cursor is
+// refused.
+func queryPage(ctx context.Context, db DBTX, query string, args []any, sorts SortFields, pagination *types.Pagination) (*sql.Rows, error) {
+	if pagination.Limit < 1 {
+		pagination.Limit = types.DEFAULT_LIMIT
+	}
+
+	orderBy, keyset := sorts.resolve(pagination.OrderBy)
+	if !keyset && pagination.Cursor != nil {
+		return nil, types.ErrInvalidRequest.WithField("cursor", "only works when ordered by created_at DESC")
+	}
+	pagination.Keyset = keyset
+
+	if pagination.CountTotal {
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+query+") AS counted", args...).Scan(&pagination.Total); err != nil {
+			return nil, fmt.Errorf("failed to count rows: %w", err)
+		}
+	}
+
+	if keyset && pagination.Cursor != nil {
+		query += fmt.Sprintf(" AND (%[1]screated_at, %[1]sid) < ($%[2]d, $%[3]d)", sorts.alias, len(args)+1, len(args)+2)
+		args = append(args, pagination.Cursor.CreatedAt, pagination.Cursor.ID)
+	} else if pagination.Offset > 0 {
+		query += fmt.Sprintf(" ORDER BY %s OFFSET $%d LIMIT $%d", orderBy, len(args)+1, len(args)+2)
+		args = append(args, pagination.Offset, pagination.Limit+1)
+		return db.QueryContext(ctx, query, args...)
+	}
+
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d", orderBy, len(args)+1)
+	args = append(args, pagination.Limit+1)
+	return db.QueryContext(ctx, query, args...)
+}
+
+// trimPage drops the extra row read by queryPage and sets the cursor for the
+// next page.
+func trimPage[T any](pagination *types.Pagination, items []T, key func(T) (time.Time, int64)) []T {
+	pagination.HasMore = len(items) > pagination.Limit
+	pagination.NextCursor = nil
+	if !pagination.HasMore {
+		return items
+	}
+
+	items = items[:pagination.Limit]
+	if pagination.Keyset {
+		createdAt, id := key(items[len(items)-1])
+		next := types.Cursor{CreatedAt: createdAt, ID: id}.Encode()
+		pagination.NextCursor = &next
+	}
+	return items
+}
+
+func (e Exchange) pageKey() (time.Time, int64)           { return e.CreatedAt, e.ID }
+func (d Debtors) pageKey() (time.Time, int64)            { return d.CreatedAt, d.ID }
+func (d Debts) pageKey() (time.Time, int64)              { return d.CreatedAt, d.ID }
+func (b BalanceRecord) pageKey() (time.Time, int64)      { return b.CreatedAt, b.ID }
+func (t Transaction) pageKey() (time.Time, int64)        { return t.CreatedAt, t.ID }
+func (c ChangeRequest) pageKey() (time.Time, int64)      { return c.CreatedAt, c.ID }
+func (a AuditLog) pageKey() (time.Time, int64)           { return a.CreatedAt, a.ID }
+func (d WebhookDelivery) pageKey() (time.Time, int64)    { return d.CreatedAt, d.ID }
+func (n NotificationOutbox) pageKey() (time.Time, int64) { return n.CreatedAt, n.ID }
+func (n Notification) pageKey() (time.Time, int64)       { return n.CreatedAt, n.ID }
@@ -0,0 +1,219 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+const (
+	RECEIPT_PENDING   = "pending"
+	RECEIPT_COMPLETED = "completed"
+	RECEIPT_ARCHIVED  = "archived"
+)
+
+type ReceiptAmount struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// ReceiptStatus is what the public verification endpoint tells about a
+// printed receipt. It carries no internal ids; Number is only set for
+// transactions, whose number is meant to be shown to customers.
+type ReceiptStatus struct {
+	Type               string          `json:"type"`
+	Number             *int64          `json:"number"`
+	Company            string          `json:"company"`
+	Received           []ReceiptAmount `json:"received"`
+	Delivered          []ReceiptAmount `json:"delivered"`
+	Status             string          `json:"status"`
+	CreatedAt          time.Time       `json:"-"`
+	CreatedAtFormatted string          `json:"date"`
+}
+
+type ReceiptStorage struct {
+	db DBTX
+}
+
+func NewReceiptStorage(db DBTX) *ReceiptStorage {
+	return &ReceiptStorage{db: db}
+}
+
+// GetStatus looks up the entry a receipt was printed for, as long as it
+// still exists and belongs to companyId.
+func (s *ReceiptStorage) GetStatus(ctx context.Context, entity string, id, companyId int64) (*ReceiptStatus, error) {
+	var (
+		status *ReceiptStatus
+		err    error
+	)
+
+	switch entity {
+	case ENTITY_TRANSACTION:
+		status, err = s.transaction(ctx, id, companyId)
+	case ENTITY_EXCHANGE:
+		status, err = s.exchange(ctx, id, companyId)
+	case ENTITY_DEBT:
+		status, err = s.debt(ctx, id, companyId)
+	default:
+		return nil, types.ErrReceiptNotFound
+	}
+
+	if err == sql.ErrNoRows {
+		return nil, types.ErrReceiptNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt status: %w", err)
+	}
+
+	status.Type = entity
+	status.CreatedAtFormatted = formatTashkent(status.CreatedAt)
+	return status, nil
+}
+
+func (s *ReceiptStorage) transaction(ctx context.Context, id, companyId int64) (*ReceiptStatus, error) {
+	query := `
+		SELECT t.number, ` + transactionLineColumns("t") + `, t.status, t.created_at, c.name
+		FROM transactions t JOIN companies c ON c.id = $2
+		WHERE t.id = $1 AND $2 IN (t.received_company_id, t.delivered_company_id)
+	`
+
+	status := &ReceiptStatus{}
+	var number, state int64
+	var receivedJSON, deliveredJSON []byte
+
+	err := s.db.QueryRowContext(ctx, query, id, companyId).Scan(
+		&number,
+		&receivedJSON,
+		&deliveredJSON,
+		&state,
+		&status.CreatedAt,
+		&status.Company,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var received []types.ReceivedIncomes
+	var delivered []types.DeliveredOutcomes
+	if err := json.Unmarshal(receivedJSON, &received); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(deliveredJSON, &delivered); err != nil {
+		return nil, err
+	}
+
+	status.Number = &number
+	status.Received = make([]ReceiptAmount, 0, len(received))
+	for _, income := range received {
+		status.Received = append(status.Received, ReceiptAmount{Amount: income.ReceivedAmount, Currency: income.ReceivedCurrency})
+	}
+	status.Delivered = make([]ReceiptAmount, 0, len(delivered))
+	for _, outcome := range delivered {
+		status.Delivered = append(status.Delivered, ReceiptAmount{Amount: outcome.DeliveredAmount, Currency: outcome.DeliveredCurrency})
+	}
+
+	switch state {
+	case STATUS_COMPLETED:
+		status.Status = RECEIPT_COMPLETED
+	case STATUS_ARCHIVED:
+		status.Status = RECEIPT_ARCHIVED
+	default:
+		status.Status = RECEIPT_PENDING
+	}
+
+	return status, nil
+}
+
+func (s *ReceiptStorage) exchange(ctx context.Context, id, companyId int64) (*ReceiptStatus, error) {
+	query := `
+		SELECT e.received_money, e.received_currency, e.selled_money, e.selled_currency, e.status, e.created_at, c.name
+		FROM exchanges e JOIN companies c ON c.id = e.company_id
+		WHERE e.id = $1 AND e.company_id = $2
+	`
+
+	status := &ReceiptStatus{Status: RECEIPT_COMPLETED}
+	var received, delivered ReceiptAmount
+	var state int64
+
+	err := s.db.QueryRowContext(ctx, query, id, companyId).Scan(
+		&received.Amount,
+		&received.Currency,
+		&delivered.Amount,
+		&delivered.Currency,
+		&state,
+		&status.CreatedAt,
+		&status.Company,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Received = []ReceiptAmount{received}
+	status.Delivered = []ReceiptAmount{delivered}
+	if state == STATUS_ARCHIVED {
+		status.Status = RECEIPT_ARCHIVED
+	}
+
+	return status, nil
+}
+
+// debt reports the money that changed hands: paid out when the company
+// lent it, taken in when it was paid back.
+func (s *ReceiptStorage) debt(ctx context.Context, id, companyId int64) (*ReceiptStatus, error) {
+	query := `
+		SELECT ` + debtLineColumns("d") + `, d.debted_amount, d.debted_currency, d.type, d.created_at, c.name
+		FROM debts d JOIN companies c ON c.id = d.company_id
+		WHERE d.id = $1 AND d.company_id = $2
+	`
+
+	status := &ReceiptStatus{Status: RECEIPT_COMPLETED}
+	var incomesJSON []byte
+	var debted ReceiptAmount
+	var debtType int
+
+	err := s.db.QueryRowContext(ctx, query, id, companyId).Scan(
+		&incomesJSON,
+		&debted.Amount,
+		&debted.Currency,
+		&debtType,
+		&status.CreatedAt,
+		&status.Company,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var incomes []types.ReceivedIncomes
+	if len(incomesJSON) > 0 {
+		if err := json.Unmarshal(incomesJSON, &incomes); err != nil {
+			return nil, err
+		}
+	}
+
+	// Amounts are stored signed by direction.
+	amounts := make([]ReceiptAmount, 0, len(incomes))
+	for _, income := range incomes {
+		amounts = append(amounts, ReceiptAmount{Amount: abs(income.ReceivedAmount), Currency: income.ReceivedCurrency})
+	}
+	if len(amounts) == 0 {
+		amounts = append(amounts, ReceiptAmount{Amount: abs(debted.Amount), Currency: debted.Currency})
+	}
+
+	status.Received, status.Delivered = amounts, []ReceiptAmount{}
+	if debtType == types.TYPE_SELL {
+		status.Received, status.Delivered = []ReceiptAmount{}, amounts
+	}
+
+	return status, nil
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
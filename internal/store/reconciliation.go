@@ -0,0 +1,418 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+const (
+	RECONCILIATION_RUNNING  = 1
+	RECONCILIATION_FINISHED = 2
+	RECONCILIATION_FAILED   = 3
+)
+
+// Reasons a balance record is reported as one of the rows behind a
+// discrepancy.
+const (
+	RECORD_CURRENCY_MISMATCH = "currency_mismatch"
+	RECORD_COMPANY_MISMATCH  = "company_mismatch"
+	RECORD_DUPLICATE         = "duplicate"
+)
+
+type ReconciliationRun struct {
+	ID                  int64      `json:"id"`
+	CompanyID           int64      `json:"company_id"`
+	Slot                *time.Time `json:"-"`
+	StartedBy           *int64     `json:"started_by"`
+	Status              int        `json:"status"`
+	BalancesChecked     int        `json:"balances_checked"`
+	Discrepancies       int        `json:"discrepancies"`
+	Error               *string    `json:"error"`
+	FinishedAt          *time.Time `json:"-"`
+	FinishedAtFormatted *string    `json:"finished_at"`
+	CreatedAt           time.Time  `json:"-"`
+	CreatedAtFormatted  string     `json:"created_at"`
+}
+
+// OffendingRecord is a balance record that does not fit the balance it is
+// counted in. Amount is what it added to the balance.
+type OffendingRecord struct {
+	RecordID  int64  `json:"record_id"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+	Reason    string `json:"reason"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Discrepancy is a balance whose stored value is not the sum of its
+// records, or whose records include rows that do not belong to it.
+type Discrepancy struct {
+	ID                  int64             `json:"id"`
+	RunID               int64             `json:"run_id"`
+	CompanyID           int64             `json:"company_id"`
+	BalanceID           int64             `json:"balance_id"`
+	UserID              *int64            `json:"user_id"`
+	Currency            string            `json:"currency"`
+	StoredBalance       int64             `json:"stored_balance"`
+	ComputedBalance     int64             `json:"computed_balance"`
+	Records             []OffendingRecord `json:"records"`
+	RepairedAt          *time.Time        `json:"-"`
+	RepairedAtFormatted *string           `json:"repaired_at"`
+	RepairedBy          *int64            `json:"repaired_by"`
+	CreatedAt           time.Time         `json:"-"`
+	CreatedAtFormatted  string            `json:"created_at"`
+}
+
+// Mismatched reports whether the stored balance differs from its records.
+func (d Discrepancy) Mismatched() bool {
+	return d.StoredBalance != d.ComputedBalance
+}
+
+func (r ReconciliationRun) pageKey() (time.Time, int64) { return r.CreatedAt, r.ID }
+func (d Discrepancy) pageKey() (time.Time, int64)       { return d.CreatedAt, d.ID }
+
+var (
+	ReconciliationRunSorts = NewSortFields("", "created_at DESC", "discrepancies")
+	DiscrepancySorts       = NewSortFields("", "created_at DESC", "balance_id")
+)
+
+type ReconciliationStorage struct {
+	db DBTX
+}
+
+func NewReconciliationStorage(db DBTX) *ReconciliationStorage {
+	return &ReconciliationStorage{db: db}
+}
+
+// StartRun records a run as started. A scheduled run reports false when
+// another instance already took its slot.
+func (s *ReconciliationStorage) StartRun(ctx context.Context, run *ReconciliationRun) (bool, error) {
+	run.Status = RECONCILIATION_RUNNING
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO reconciliation_runs (company_id, slot, started_by, status)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (company_id, slot) DO NOTHING
+		RETURNING id, created_at
+	`, run.CompanyID, run.Slot, run.StartedBy, run.Status).Scan(&run.ID, &run.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to start reconciliation: %w", err)
+	}
+	run.CreatedAtFormatted = formatTashkent(run.CreatedAt)
+	return true, nil
+}
+
+// Check recomputes every balance of companyId from its records. It returns
+// how many balances were checked and those that are off, not yet saved.
+func (s *ReconciliationStorage) Check(ctx context.Context, companyId int64) (int, []Discrepancy, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT b.id, b.user_id, COALESCE(b.currency, ''), COALESCE(b.balance, 0), COALESCE(SUM(`+recordDelta("r")+`), 0)
+		FROM balances b
+		LEFT JOIN balance_records r ON r.balance_id = b.id
+		WHERE b.company_id = $1
+		GROUP BY b.id
+		ORDER BY b.id
+	`, companyId)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to sum balance records: %w", err)
+	}
+
+	checked := 0
+	var discrepancies []Discrepancy
+	byBalance := map[int64]int{}
+	for rows.Next() {
+		d := Discrepancy{CompanyID: companyId, Records: []OffendingRecord{}}
+		if err := rows.Scan(&d.BalanceID, &d.UserID, &d.Currency, &d.StoredBalance, &d.ComputedBalance); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		checked++
+		byBalance[d.BalanceID] = len(discrepancies)
+		discrepancies = append(discrepancies, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	// Records in another currency or company than their balance, and
+	// exchanges counted twice in the same balance. Each exchange moves one
+	// currency in and another out, so it has one record per balance.
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT r.id, r.balance_id, COALESCE(`+recordDelta("r")+`, 0), r.currency, r.created_at,
+			CASE
+				WHEN r.currency IS DISTINCT FROM b.currency THEN $2
+				WHEN r.company_id IS DISTINCT FROM b.company_id THEN $3
+				ELSE $4
+			END
+		FROM balance_records r
+		JOIN balances b ON b.id = r.balance_id
+		WHERE b.company_id = $1 AND (
+			r.currency IS DISTINCT FROM b.currency
+			OR r.company_id IS DISTINCT FROM b.company_id
+			OR (r.exchange_id IS NOT NULL AND EXISTS (
+				SELECT 1 FROM balance_records o
+				WHERE o.exchange_id = r.exchange_id AND o.balance_id = r.balance_id AND o.id < r.id
+			))
+		)
+		ORDER BY r.created_at, r.id
+	`, companyId, RECORD_CURRENCY_MISMATCH, RECORD_COMPANY_MISMATCH, RECORD_DUPLICATE)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to check balance records: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record OffendingRecord
+		var balanceID int64
+		var currency sql.NullString
+		var createdAt time.Time
+		if err := rows.Scan(&record.RecordID, &balanceID, &record.Amount, &currency, &createdAt, &record.Reason); err != nil {
+			return 0, nil, fmt.Errorf("failed to scan balance record: %w", err)
+		}
+		record.Currency = currency.String
+		record.CreatedAt = formatTashkent(createdAt)
+
+		if i, ok := byBalance[balanceID]; ok {
+			discrepancies[i].Records = append(discrepancies[i].Records, record)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	off := discrepancies[:0]
+	for _, d := range discrepancies {
+		if d.Mismatched() || len(d.Records) > 0 {
+			off = append(off, d)
+		}
+	}
+	return checked, off, nil
+}
+
+// AddDiscrepancy saves a discrepancy found by run.
+func (s *ReconciliationStorage) AddDiscrepancy(ctx context.Context, runId int64, d *Discrepancy) error {
+	records, err := json.Marshal(d.Records)
+	if err != nil {
+		return err
+	}
+
+	d.RunID = runId
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO reconciliation_discrepancies (run_id, company_id, balance_id, user_id, currency, stored_balance, computed_balance, records)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`, d.RunID, d.CompanyID, d.BalanceID, d.UserID, d.Currency, d.StoredBalance, d.ComputedBalance, records).Scan(&d.ID, &d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save discrepancy: %w", err)
+	}
+	d.CreatedAtFormatted = formatTashkent(d.CreatedAt)
+	return nil
+}
+
+// FinishRun saves the outcome of run. A run with an error is failed.
+func (s *ReconciliationStorage) FinishRun(ctx context.Context, run *ReconciliationRun) error {
+	run.Status = RECONCILIATION_FINISHED
+	if run.Error != nil {
+		run.Status = RECONCILIATION_FAILED
+	}
+
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE reconciliation_runs
+		SET status = $2, balances_checked = $3, discrepancies = $4, error = $5, finished_at = now()
+		WHERE id = $1
+		RETURNING finished_at
+	`, run.ID, run.Status, run.BalancesChecked, run.Discrepancies, run.Error).Scan(&run.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to finish reconciliation: %w", err)
+	}
+	finished := formatTashkent(*run.FinishedAt)
+	run.FinishedAtFormatted = &finished
+	return nil
+}
+
+func (s *ReconciliationStorage) GetRuns(ctx context.Context, companyId int64, pagination *types.Pagination) ([]ReconciliationRun, error) {
+	query := `
+		SELECT id, company_id, slot, started_by, status, balances_checked, discrepancies, error, finished_at, created_at
+		FROM reconciliation_runs WHERE company_id = $1
+	`
+
+	rows, err := queryPage(ctx, s.db, query, []any{companyId}, ReconciliationRunSorts, pagination)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []ReconciliationRun
+	for rows.Next() {
+		var run ReconciliationRun
+		if err := rows.Scan(
+			&run.ID,
+			&run.CompanyID,
+			&run.Slot,
+			&run.StartedBy,
+			&run.Status,
+			&run.BalancesChecked,
+			&run.Discrepancies,
+			&run.Error,
+			&run.FinishedAt,
+			&run.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if run.FinishedAt != nil {
+			finished := formatTashkent(*run.FinishedAt)
+			run.FinishedAtFormatted = &finished
+		}
+		run.CreatedAtFormatted = formatTashkent(run.CreatedAt)
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return trimPage(pagination, runs, ReconciliationRun.pageKey), nil
+}
+
+// GetDiscrepancies lists what run found. A runId of 0 means the latest
+// finished run of the company.
+func (s *ReconciliationStorage) GetDiscrepancies(ctx context.Context, companyId, runId int64, pagination *types.Pagination) ([]Discrepancy, error) {
+	query := `
+		SELECT id, run_id, company_id, balance_id, user_id, currency, stored_balance, computed_balance, records, repaired_at, repaired_by, created_at
+		FROM reconciliation_discrepancies
+		WHERE company_id = $1 AND run_id = COALESCE(NULLIF($2::bigint, 0), (
+			SELECT MAX(id) FROM reconciliation_runs WHERE company_id = $1 AND status = $3
+		))
+	`
+
+	rows, err := queryPage(ctx, s.db, query, []any{companyId, runId, RECONCILIATION_FINISHED}, DiscrepancySorts, pagination)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var discrepancies []Discrepancy
+	for rows.Next() {
+		d, err := scanDiscrepancy(rows)
+		if err != nil {
+			return nil, err
+		}
+		discrepancies = append(discrepancies, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return trimPage(pagination, discrepancies, Discrepancy.pageKey), nil
+}
+
+// GetDiscrepancy returns a discrepancy of companyId, locked for repair when
+// read inside a transaction.
+func (s *ReconciliationStorage) GetDiscrepancy(ctx context.Context, companyId, id int64) (*Discrepancy, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, run_id, company_id, balance_id, user_id, currency, stored_balance, computed_balance, records, repaired_at, repaired_by, created_at
+		FROM reconciliation_discrepancies WHERE id = $1 AND company_id = $2
+		FOR UPDATE
+	`, id, companyId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, types.ErrDiscrepancyNotFound
+	}
+	return scanDiscrepancy(rows)
+}
+
+// Recompute locks a balance of companyId for update and sums its records
+// as they are now. Call it inside a transaction.
+func (s *ReconciliationStorage) Recompute(ctx context.Context, companyId, balanceId int64) (*Balance, int64, error) {
+	balance := &Balance{}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, balance, user_id, in_out_lay, out_in_lay, company_id, currency, created_at
+		FROM balances WHERE id = $1 AND company_id = $2
+		FOR UPDATE
+	`, balanceId, companyId).Scan(
+		&balance.ID,
+		&balance.Balance,
+		&balance.UserId,
+		&balance.InOutLay,
+		&balance.OutInLay,
+		&balance.CompanyId,
+		&balance.Currency,
+		&balance.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, 0, types.ErrBalanceNotFound
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	var computed int64
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(`+recordDelta("r")+`), 0) FROM balance_records r WHERE r.balance_id = $1
+	`, balanceId).Scan(&computed)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to sum balance records: %w", err)
+	}
+	return balance, computed, nil
+}
+
+// MarkRepaired records that the balance of discrepancy id was set to its
+// computed value.
+func (s *ReconciliationStorage) MarkRepaired(ctx context.Context, d *Discrepancy, repairedBy *int64) error {
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE reconciliation_discrepancies SET repaired_at = now(), repaired_by = $2
+		WHERE id = $1
+		RETURNING repaired_at
+	`, d.ID, repairedBy).Scan(&d.RepairedAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark discrepancy repaired: %w", err)
+	}
+	repaired := formatTashkent(*d.RepairedAt)
+	d.RepairedAtFormatted = &repaired
+	d.RepairedBy = repairedBy
+	return nil
+}
+
+func scanDiscrepancy(rows *sql.Rows) (*Discrepancy, error) {
+	var d Discrepancy
+	var records []byte
+	if err := rows.Scan(
+		&d.ID,
+		&d.RunID,
+		&d.CompanyID,
+		&d.BalanceID,
+		&d.UserID,
+		&d.Currency,
+		&d.StoredBalance,
+		&d.ComputedBalance,
+		&records,
+		&d.RepairedAt,
+		&d.RepairedBy,
+		&d.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(records, &d.Records); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal records: %w", err)
+	}
+	if d.RepairedAt != nil {
+		repaired := formatTashkent(*d.RepairedAt)
+		d.RepairedAtFormatted = &repaired
+	}
+	d.CreatedAtFormatted = formatTashkent(d.CreatedAt)
+	return &d, nil
+}
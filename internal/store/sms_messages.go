@@ -0,0 +1,222 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	SMS_PENDING      = 1
+	SMS_SENT         = 2
+	SMS_FAILED       = 3
+	SMS_RATE_LIMITED = 4
+)
+
+const (
+	SMS_TRANSACTION_CREATED   = "transaction_created"
+	SMS_TRANSACTION_COMPLETED = "transaction_completed"
+)
+
+// SMS_RATE_LIMIT is how many messages one number may get per
+// SMS_RATE_WINDOW. Messages over the limit are kept as rate limited and
+// never sent.
+const (
+	SMS_RATE_LIMIT  = 5
+	SMS_RATE_WINDOW = time.Hour
+)
+
+// SMSMessage is a text to a remittance recipient, written in the same
+// transaction as the change it is about and sent by the SMS dispatcher.
+type SMSMessage struct {
+	ID                   int64      `json:"id"`
+	CompanyID            int64      `json:"company_id"`
+	TransactionID        *int64     `json:"transaction_id"`
+	Phone                string     `json:"phone"`
+	Kind                 string     `json:"kind"`
+	Body                 string     `json:"body"`
+	PickupCode           *string    `json:"pickup_code"`
+	Status               int64      `json:"status"`
+	Attempts             int        `json:"attempts"`
+	ProviderMessageID    *string    `json:"provider_message_id"`
+	LastError            *string    `json:"last_error"`
+	NextAttemptAt        time.Time  `json:"-"`
+	NextAttemptFormatted string     `json:"next_attempt_at"`
+	SentAt               *time.Time `json:"-"`
+	SentAtFormatted      *string    `json:"sent_at"`
+	CreatedAt            time.Time  `json:"-"`
+	CreatedAtFormatted   string     `json:"created_at"`
+}
+
+type SMSStorage struct {
+	db DBTX
+}
+
+func NewSMSStorage(db DBTX) *SMSStorage {
+	return &SMSStorage{db: db}
+}
+
+const smsColumns = `
+	id, company_id, transaction_id, phone, kind, body, pickup_code, status, attempts,
+	provider_message_id, last_error, next_attempt_at, sent_at, created_at
+`
+
+// Create queues the message, or stores it as rate limited when the number
+// already got SMS_RATE_LIMIT messages within SMS_RATE_WINDOW.
+func (s *SMSStorage) Create(ctx context.Context, m *SMSMessage) error {
+	query := `
+		INSERT INTO sms_messages (company_id, transaction_id, phone, kind, body, pickup_code, status)
+		SELECT $1, $2, $3, $4, $5, $6, CASE WHEN COUNT(*) >= $7 THEN $8 ELSE $9 END::bigint
+		FROM sms_messages
+		WHERE phone = $3 AND status != $8 AND created_at > now() - make_interval(secs => $10)
+		RETURNING id, status, next_attempt_at, created_at
+	`
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		m.CompanyID,
+		m.TransactionID,
+		m.Phone,
+		m.Kind,
+		m.Body,
+		m.PickupCode,
+		SMS_RATE_LIMIT,
+		SMS_RATE_LIMITED,
+		SMS_PENDING,
+		SMS_RATE_WINDOW.Seconds(),
+	).Scan(
+		&m.ID,
+		&m.Status,
+		&m.NextAttemptAt,
+		&m.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create sms: %w", err)
+	}
+
+	m.NextAttemptFormatted = formatTashkent(m.NextAttemptAt)
+	m.CreatedAtFormatted = formatTashkent(m.CreatedAt)
+	return nil
+}
+
+// GetByTransactionId lists the texts sent about a transaction, as long as
+// companyId is one of its two sides.
+func (s *SMSStorage) GetByTransactionId(ctx context.Context, companyId, transactionId int64) ([]SMSMessage, error) {
+	query := `SELECT ` + smsColumns + ` FROM sms_messages
+		WHERE transaction_id = $1 AND EXISTS (
+			SELECT 1 FROM transactions t
+			WHERE t.id = $1 AND $2 IN (t.received_company_id, t.delivered_company_id)
+		)
+		ORDER BY id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, transactionId, companyId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return s.scanMessages(rows)
+}
+
+// Claim picks up to limit due messages, counts the attempt and moves
+// next_attempt_at forward by lease so other dispatchers skip them meanwhile.
+func (s *SMSStorage) Claim(ctx context.Context, limit int, lease time.Duration) ([]SMSMessage, error) {
+	query := `
+		UPDATE sms_messages SET attempts = attempts + 1, next_attempt_at = now() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM sms_messages WHERE status = $3 AND next_attempt_at <= now()
+			ORDER BY next_attempt_at LIMIT $1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + smsColumns
+
+	rows, err := s.db.QueryContext(ctx, query, limit, lease.Seconds(), SMS_PENDING)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim sms: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanMessages(rows)
+}
+
+func (s *SMSStorage) MarkSent(ctx context.Context, id int64, providerMessageId string) error {
+	query := `
+		UPDATE sms_messages SET status = $1, provider_message_id = NULLIF($2, ''), last_error = NULL, sent_at = now()
+		WHERE id = $3
+	`
+
+	_, err := s.db.ExecContext(ctx, query, SMS_SENT, providerMessageId, id)
+	return err
+}
+
+// MarkFailed records a failed attempt. A nil retryAt gives up on the
+// message.
+func (s *SMSStorage) MarkFailed(ctx context.Context, id int64, lastError string, retryAt *time.Time) error {
+	status := int64(SMS_PENDING)
+	next := time.Now()
+	if retryAt == nil {
+		status = SMS_FAILED
+	} else {
+		next = *retryAt
+	}
+
+	query := `UPDATE sms_messages SET status = $1, last_error = $2, next_attempt_at = $3 WHERE id = $4`
+
+	_, err := s.db.ExecContext(ctx, query, status, lastError, next, id)
+	return err
+}
+
+func (s *SMSStorage) scanMessages(rows *sql.Rows) ([]SMSMessage, error) {
+	var messages []SMSMessage
+	for rows.Next() {
+		var m SMSMessage
+		var transactionID sql.NullInt64
+		var pickupCode, providerMessageID, lastError sql.NullString
+		var sentAt sql.NullTime
+
+		if err := rows.Scan(
+			&m.ID,
+			&m.CompanyID,
+			&transactionID,
+			&m.Phone,
+			&m.Kind,
+			&m.Body,
+			&pickupCode,
+			&m.Status,
+			&m.Attempts,
+			&providerMessageID,
+			&lastError,
+			&m.NextAttemptAt,
+			&sentAt,
+			&m.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if transactionID.Valid {
+			m.TransactionID = &transactionID.Int64
+		}
+		if pickupCode.Valid {
+			m.PickupCode = &pickupCode.String
+		}
+		if providerMessageID.Valid {
+			m.ProviderMessageID = &providerMessageID.String
+		}
+		if lastError.Valid {
+			m.LastError = &lastError.String
+		}
+		if sentAt.Valid {
+			formatted := formatTashkent(sentAt.Time)
+			m.SentAt = &sentAt.Time
+			m.SentAtFormatted = &formatted
+		}
+		m.NextAttemptFormatted = formatTashkent(m.NextAttemptAt)
+		m.CreatedAtFormatted = formatTashkent(m.CreatedAt)
+
+		messages = append(messages, m)
+	}
+
+	return messages, rows.Err()
+}
@@ -0,0 +1,279 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// StatementBalance is a balance in one currency at the edge of a statement
+// period.
+type StatementBalance struct {
+	Currency string `json:"currency"`
+	Balance  int64  `json:"balance"`
+}
+
+// DebtorStatementEntry is one debt or repayment with the debtor's balance in
+// its currency right after it. Amount is signed like debts.debted_amount:
+// negative when the company gave money, positive when it received money.
+type DebtorStatementEntry struct {
+	DebtID             int64     `json:"debt_id"`
+	Type               int       `json:"type"`
+	Amount             int64     `json:"amount"`
+	Currency           string    `json:"currency"`
+	Balance            int64     `json:"balance"`
+	Details            string    `json:"details"`
+	CreatedAt          time.Time `json:"-"`
+	CreatedAtFormatted string    `json:"created_at"`
+}
+
+// DebtorStatement covers the debts of one debtor over a period. Closing is
+// Opening plus every entry, per currency. From and To are the first and last
+// day of the period, as the caller names them.
+type DebtorStatement struct {
+	Debtor  *Debtors               `json:"debtor"`
+	From    string                 `json:"from"`
+	To      string                 `json:"to"`
+	Opening []StatementBalance     `json:"opening"`
+	Entries []DebtorStatementEntry `json:"entries"`
+	Closing []StatementBalance     `json:"closing"`
+}
+
+type StatementStorage struct {
+	db DBTX
+}
+
+func NewStatementStorage(db DBTX) *StatementStorage {
+	return &StatementStorage{db: db}
+}
+
+// Debtor builds the statement of a debtor of companyId for [from, to).
+func (s *StatementStorage) Debtor(ctx context.Context, companyId, debtorId int64, from, to time.Time) (*DebtorStatement, error) {
+	debtor := &Debtors{}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, balance, currency, user_id, phone, company_id, created_at, full_name
+		FROM debtors WHERE id = $1 AND company_id = $2
+	`, debtorId, companyId).Scan(
+		&debtor.ID,
+		&debtor.Balance,
+		&debtor.Currency,
+		&debtor.UserID,
+		&debtor.Phone,
+		&debtor.CompanyID,
+		&debtor.CreatedAt,
+		&debtor.FullName,
+	)
+	if err == sql.ErrNoRows {
+		return nil, types.ErrDebtorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get debtor: %w", err)
+	}
+	debtor.CreatedAtFormatted = formatTashkent(debtor.CreatedAt)
+
+	statement := &DebtorStatement{Debtor: debtor, Entries: []DebtorStatementEntry{}}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT debted_currency, SUM(debted_amount)
+		FROM debts WHERE debtor_id = $1 AND created_at < $2
+		GROUP BY debted_currency
+	`, debtorId, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get opening balance: %w", err)
+	}
+	running := map[string]int64{}
+	for rows.Next() {
+		var currency string
+		var balance int64
+		if err := rows.Scan(&currency, &balance); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan opening balance: %w", err)
+		}
+		running[currency] = balance
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get opening balance: %w", err)
+	}
+	opening := map[string]int64{}
+	for currency, balance := range running {
+		opening[currency] = balance
+	}
+
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT id, type, debted_amount, debted_currency, details, created_at
+		FROM debts WHERE debtor_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at, id
+	`, debtorId, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query debts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry DebtorStatementEntry
+		if err := rows.Scan(&entry.DebtID, &entry.Type, &entry.Amount, &entry.Currency, &entry.Details, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan debt: %w", err)
+		}
+		running[entry.Currency] += entry.Amount
+		entry.Balance = running[entry.Currency]
+		entry.CreatedAtFormatted = formatTashkent(entry.CreatedAt)
+		statement.Entries = append(statement.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	// A currency first used inside the period opens at zero.
+	for currency := range running {
+		opening[currency] += 0
+	}
+	statement.Opening = balancesOf(opening)
+	statement.Closing = balancesOf(running)
+
+	return statement, nil
+}
+
+// balancesOf lists balances by currency code.
+func balancesOf(balances map[string]int64) []StatementBalance {
+	list := make([]StatementBalance, 0, len(balances))
+	for currency, balance := range balances {
+		list = append(list, StatementBalance{Currency: currency, Balance: balance})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Currency < list[j].Currency })
+	return list
+}
+
+// BalanceStatementEntry is one balance record with the balance right after
+// it. Amount is signed: negative when money left the balance. Source names
+// what the money moved for; SourceID is its id, or nil for a record entered
+// by hand. Number is the transaction number customers see.
+type BalanceStatementEntry struct {
+	RecordID           int64     `json:"record_id"`
+	Source             string    `json:"source"`
+	SourceID           *int64    `json:"source_id"`
+	Number             *int64    `json:"number"`
+	Type               int64     `json:"type"`
+	Amount             int64     `json:"amount"`
+	Balance            int64     `json:"balance"`
+	Details            string    `json:"details"`
+	CreatedAt          time.Time `json:"-"`
+	CreatedAtFormatted string    `json:"created_at"`
+}
+
+// BalanceStatement covers the records of one balance over a period. Closing
+// is Opening plus every entry.
+type BalanceStatement struct {
+	Balance *Balance                `json:"balance"`
+	From    string                  `json:"from"`
+	To      string                  `json:"to"`
+	Opening int64                   `json:"opening"`
+	Entries []BalanceStatementEntry `json:"entries"`
+	Closing int64                   `json:"closing"`
+}
+
+// Balance builds the statement of a balance of companyId for [from, to).
+//
+// Balances set by hand get an opening or adjustment record, but the
+// statement still counts back from balances.balance rather than up from the
+// first record: a statement running to the present always closes on the
+// stored balance, even when reconciliation has found it off.
+// The balance row is share locked so that no write lands between reading
+// it and reading its records; call it inside a transaction.
+func (s *StatementStorage) Balance(ctx context.Context, companyId, balanceId int64, from, to time.Time) (*BalanceStatement, error) {
+	balance := &Balance{}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, balance, user_id, in_out_lay, out_in_lay, company_id, currency, created_at
+		FROM balances WHERE id = $1 AND company_id = $2
+		FOR SHARE
+	`, balanceId, companyId).Scan(
+		&balance.ID,
+		&balance.Balance,
+		&balance.UserId,
+		&balance.InOutLay,
+		&balance.OutInLay,
+		&balance.CompanyId,
+		&balance.Currency,
+		&balance.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, types.ErrBalanceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	var during, after int64
+	err = s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT
+			COALESCE(SUM(%[1]s) FILTER (WHERE r.created_at >= $2 AND r.created_at < $3), 0),
+			COALESCE(SUM(%[1]s) FILTER (WHERE r.created_at >= $3), 0)
+		FROM balance_records r WHERE r.balance_id = $1
+	`, recordDelta("r")), balanceId, from, to).Scan(&during, &after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum balance records: %w", err)
+	}
+
+	statement := &BalanceStatement{
+		Balance: balance,
+		Opening: balance.Balance - after - during,
+		Closing: balance.Balance - after,
+		Entries: []BalanceStatementEntry{},
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.id, `+recordDelta("r")+`, r.type, r.transaction_id, t.number, r.debt_id, r.exchange_id, r.details, r.created_at
+		FROM balance_records r
+		LEFT JOIN transactions t ON t.id = r.transaction_id
+		WHERE r.balance_id = $1 AND r.created_at >= $2 AND r.created_at < $3
+		ORDER BY r.created_at, r.id
+	`, balanceId, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balance records: %w", err)
+	}
+	defer rows.Close()
+
+	running := statement.Opening
+	for rows.Next() {
+		var entry BalanceStatementEntry
+		var transactionID, debtID, exchangeID *int64
+		if err := rows.Scan(
+			&entry.RecordID,
+			&entry.Amount,
+			&entry.Type,
+			&transactionID,
+			&entry.Number,
+			&debtID,
+			&exchangeID,
+			&entry.Details,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan balance record: %w", err)
+		}
+
+		switch {
+		case transactionID != nil:
+			entry.Source, entry.SourceID = ENTITY_TRANSACTION, transactionID
+		case exchangeID != nil:
+			entry.Source, entry.SourceID = ENTITY_EXCHANGE, exchangeID
+		case debtID != nil:
+			entry.Source, entry.SourceID = ENTITY_DEBT, debtID
+		default:
+			entry.Source = ENTITY_BALANCE_RECORD
+		}
+
+		running += entry.Amount
+		entry.Balance = running
+		entry.CreatedAtFormatted = formatTashkent(entry.CreatedAt)
+		statement.Entries = append(statement.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return statement, nil
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/mubashshir3767/currencyExchange/internal/types"
 )
@@ -13,33 +14,67 @@ const (
 	STATUS_ARCHIVED  = 3
 )
 
+const (
+	ENTITY_TRANSACTION    = "transaction"
+	ENTITY_EXCHANGE       = "exchange"
+	ENTITY_BALANCE        = "balance"
+	ENTITY_BALANCE_RECORD = "balance_record"
+	ENTITY_DEBT           = "debt"
+	ENTITY_DEBTOR         = "debtor"
+	ENTITY_COMPANY        = "company"
+	ENTITY_USER           = "user"
+	ENTITY_USER_SESSION   = "user_session"
+	ENTITY_CHANGE_REQUEST = "change_request"
+	ENTITY_WEBHOOK        = "webhook"
+	ENTITY_THRESHOLD      = "balance_threshold"
+	ENTITY_RECONCILIATION = "reconciliation"
+	ENTITY_SNAPSHOT       = "balance_snapshot"
+)
+
+const (
+	ACTION_CREATE   = "create"
+	ACTION_UPDATE   = "update"
+	ACTION_DELETE   = "delete"
+	ACTION_ARCHIVE  = "archive"
+	ACTION_COMPLETE = "complete"
+	ACTION_APPROVE  = "approve"
+	ACTION_REJECT   = "reject"
+	ACTION_SHARE    = "share"
+	ACTION_REPAIR   = "repair"
+)
+
 type DBTX interface {
 	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 	Commit() error
 	Rollback() error
+	AfterCommit(func())
+	Hooks() *Hooks
 }
 
 type Storage struct {
-	DB *sql.DB
+	DB    *sql.DB
+	Hooks *Hooks
 
 	Exchanges interface {
 		Create(context.Context, *Exchange) error
 		Update(context.Context, *Exchange) error
 		GetById(context.Context, int64) (*Exchange, error)
-		GetByField(context.Context, string, any, types.Pagination) ([]Exchange, error)
+		GetByField(context.Context, string, any, *types.Pagination) ([]Exchange, error)
+		Filter(context.Context, int64, *types.Filter, *types.Pagination) ([]Exchange, error)
 		Delete(context.Context, int64) error
 		Archive(context.Context, int64) error
-		Archived(context.Context, types.Pagination) ([]Exchange, error)
+		Archived(context.Context, *types.Pagination) ([]Exchange, error)
 	}
 
 	Debtors interface {
 		Create(context.Context, *Debtors) error
 		Update(context.Context, *Debtors) error
 		GetById(context.Context, int64) (*Debtors, error)
-		GetByUserId(context.Context, int64, types.Pagination) ([]Debtors, error)
-		GetByCompanyId(context.Context, int64, *string, *string, types.Pagination) ([]Debtors, error)
+		GetByUserId(context.Context, int64, *types.Pagination) ([]Debtors, error)
+		GetByCompanyId(context.Context, int64, *string, *string, *types.Pagination) ([]Debtors, error)
+		Filter(context.Context, int64, *types.Filter, *types.Pagination) ([]Debtors, error)
 		GetByBalanceInfo(context.Context, int64) ([]map[string]interface{}, error)
 		Delete(context.Context, int64) error
 	}
@@ -48,9 +83,11 @@ type Storage struct {
 		Create(context.Context, *Debts) error
 		Update(context.Context, *Debts) error
 		GetByID(context.Context, int64) (*Debts, error)
-		GetByUserID(context.Context, int64, types.Pagination) ([]Debts, error)
-		GetByDebtorID(context.Context, int64, types.Pagination) ([]Debts, error)
+		GetByUserID(context.Context, int64, *types.Pagination) ([]Debts, error)
+		GetByDebtorID(context.Context, int64, *types.Pagination) ([]Debts, error)
+		Filter(context.Context, int64, *types.Filter, *types.Pagination) ([]Debts, error)
 		Delete(context.Context, int64) error
+		ClaimDue(context.Context, time.Time, int) ([]DueDebt, error)
 	}
 
 	Users interface {
@@ -75,24 +112,27 @@ type Storage struct {
 
 	BalanceRecords interface {
 		Create(context.Context, *BalanceRecord) error
-		GetByField(context.Context, string, any, types.Pagination) ([]BalanceRecord, error)
-		GetByFieldAndDate(context.Context, string, *string, *string, any, types.Pagination) ([]BalanceRecord, error)
+		GetByField(context.Context, string, any, *types.Pagination) ([]BalanceRecord, error)
+		GetByFieldAndDate(context.Context, string, *string, *string, any, *types.Pagination) ([]BalanceRecord, error)
+		Filter(context.Context, int64, *types.Filter, *types.Pagination) ([]BalanceRecord, error)
 		Update(context.Context, *BalanceRecord) error
 		Delete(context.Context, int64) error
 		Archive(context.Context, int64) error
-		Archived(context.Context, types.Pagination) ([]BalanceRecord, error)
+		Archived(context.Context, *types.Pagination) ([]BalanceRecord, error)
 	}
 
 	Transactions interface {
 		Create(context.Context, *Transaction) error
 		Update(context.Context, *Transaction) error
 		Delete(context.Context, *int64) error
-		GetByField(context.Context, *string, string, any, types.Pagination) ([]Transaction, error)
+		GetById(context.Context, int64) (*Transaction, error)
+		GetByField(context.Context, *string, string, any, *types.Pagination) ([]Transaction, error)
 		GetInfos(ctx context.Context, companyId int64) ([]Transaction, error)
 		GetCompanyFinalAmounts(ctx context.Context, companyIDs []int64, date string) ([]CompanyAmount, error)
-		GetByFieldAndDate(context.Context, string, string, string, any, types.Pagination) ([]Transaction, error)
+		GetByFieldAndDate(context.Context, string, string, string, any, *types.Pagination) ([]Transaction, error)
+		Filter(context.Context, int64, *types.Filter, *types.Pagination) ([]Transaction, error)
 		Archive(context.Context, int64) error
-		Archived(context.Context, types.Pagination) ([]Transaction, error)
+		Archived(context.Context, *types.Pagination) ([]Transaction, error)
 	}
 
 	Companies interface {
@@ -104,7 +144,7 @@ type Storage struct {
 	}
 
 	UserSessions interface {
-		Upsert(context.Context, *UserSession) error
+		Upsert(context.Context, *UserSession) (bool, error)
 		ListByUserID(context.Context, int64) ([]UserSession, error)
 		GetByIDForUser(context.Context, int64, int64) (*UserSession, error)
 		UpdateFCM(context.Context, int64, int64, string, *string) error
@@ -112,22 +152,138 @@ type Storage struct {
 		FCMTokensByUserID(context.Context, int64) ([]string, error)
 		DeleteByFCMToken(context.Context, string) error
 	}
+
+	ChangeRequests interface {
+		Create(context.Context, *ChangeRequest) error
+		GetById(context.Context, int64) (*ChangeRequest, error)
+		GetByCompanyId(context.Context, int64, *int64, *types.Pagination) ([]ChangeRequest, error)
+		Review(context.Context, int64, int64, int64, *string) error
+	}
+
+	AuditLogs interface {
+		Create(context.Context, *AuditLog) error
+		GetByEntity(context.Context, int64, string, int64, *types.Pagination) ([]AuditLog, error)
+		GetByActor(context.Context, int64, int64, *types.Pagination) ([]AuditLog, error)
+		DeleteOlderThan(context.Context, time.Time) (int64, error)
+	}
+
+	Webhooks interface {
+		Create(context.Context, *WebhookEndpoint) error
+		GetByCompanyId(context.Context, int64) ([]WebhookEndpoint, error)
+		Delete(context.Context, int64, int64) error
+		Fanout(context.Context, int) (int64, error)
+		ClaimDeliveries(context.Context, int, time.Duration) ([]PendingDelivery, error)
+		MarkDelivered(context.Context, int64, int) error
+		MarkFailed(context.Context, int64, *int, string, *time.Time) error
+		GetDeliveries(context.Context, int64, *int64, *types.Pagination) ([]WebhookDelivery, error)
+		Redeliver(context.Context, int64, int64) error
+	}
+
+	NotificationOutbox interface {
+		Create(context.Context, *NotificationOutbox) error
+		GetById(context.Context, int64) (*NotificationOutbox, error)
+		GetByCompanyId(context.Context, int64, *int64, *int64, *int64, *types.Pagination) ([]NotificationOutbox, error)
+		Claim(context.Context, int, time.Duration) ([]NotificationOutbox, error)
+		MarkSent(context.Context, int64) error
+		MarkFailed(context.Context, int64, string, *time.Time) error
+	}
+
+	Notifications interface {
+		Create(context.Context, *Notification) error
+		GetByUserId(context.Context, int64, bool, *types.Pagination) ([]Notification, error)
+		UnreadCount(context.Context, int64) (int64, error)
+		MarkRead(context.Context, int64, int64) error
+		MarkAllRead(context.Context, int64) (int64, error)
+	}
+
+	SMS interface {
+		Create(context.Context, *SMSMessage) error
+		GetByTransactionId(context.Context, int64, int64) ([]SMSMessage, error)
+		Claim(context.Context, int, time.Duration) ([]SMSMessage, error)
+		MarkSent(context.Context, int64, string) error
+		MarkFailed(context.Context, int64, string, *time.Time) error
+	}
+
+	NotificationPreferences interface {
+		GetByUserId(context.Context, int64) (map[string][]string, error)
+		Set(context.Context, int64, string, []string) error
+	}
+
+	BalanceThresholds interface {
+		Set(context.Context, *BalanceThreshold) error
+		GetByCompanyId(context.Context, int64) ([]BalanceThreshold, error)
+		Delete(context.Context, int64, int64) error
+	}
+
+	Telegram interface {
+		CreateLinkCode(context.Context, int64, string, time.Duration) (*TelegramLinkCode, error)
+		Link(context.Context, string, int64) (int64, error)
+		Unlink(context.Context, int64) error
+		UnlinkUser(context.Context, int64) error
+		GetOwnerByChatId(context.Context, int64) (*User, error)
+		GetLinkedOwners(context.Context) ([]User, error)
+		ClaimDigest(context.Context, int64, string) (bool, error)
+		ReleaseDigest(context.Context, int64, string) error
+	}
+
+	Receipts interface {
+		GetStatus(context.Context, string, int64, int64) (*ReceiptStatus, error)
+	}
+
+	Statements interface {
+		Debtor(context.Context, int64, int64, time.Time, time.Time) (*DebtorStatement, error)
+		Balance(context.Context, int64, int64, time.Time, time.Time) (*BalanceStatement, error)
+	}
+
+	BalanceSnapshots interface {
+		Take(context.Context, *int64, time.Time) (int64, error)
+		History(context.Context, int64, string, *int64, time.Time, time.Time) (*BalanceHistory, error)
+	}
+
+	Dashboard interface {
+		Get(context.Context, int64, time.Time, time.Time) (*Dashboard, error)
+	}
+
+	Reconciliation interface {
+		StartRun(context.Context, *ReconciliationRun) (bool, error)
+		Check(context.Context, int64) (int, []Discrepancy, error)
+		AddDiscrepancy(context.Context, int64, *Discrepancy) error
+		FinishRun(context.Context, *ReconciliationRun) error
+		GetRuns(context.Context, int64, *types.Pagination) ([]ReconciliationRun, error)
+		GetDiscrepancies(context.Context, int64, int64, *types.Pagination) ([]Discrepancy, error)
+	}
 }
 
 func NewStorage(db *sql.DB) Storage {
-	dbwrapper := &DBWrapper{db: db}
+	hooks := &Hooks{}
+	dbwrapper := &DBWrapper{db: db, hooks: hooks}
 
 	return Storage{
-		DB:             db,
-		Debts:          &DebtsStorage{db: dbwrapper},
-		Exchanges:      &ExchangeStorage{db: dbwrapper},
-		Debtors:        &DebtorsStorage{db: dbwrapper},
-		Users:          &UserStorage{db: dbwrapper},
-		Transactions:   &TransactionStorage{db: dbwrapper},
-		Balances:       &BalanceStorage{db: dbwrapper},
-		Companies:      &CompanyStorage{db: dbwrapper},
-		BalanceRecords: &BalanceRecordStorage{db: dbwrapper},
-		UserSessions:   &UserSessionStorage{db: dbwrapper},
+		DB:                      db,
+		Hooks:                   hooks,
+		Debts:                   &DebtsStorage{db: dbwrapper},
+		Exchanges:               &ExchangeStorage{db: dbwrapper},
+		Debtors:                 &DebtorsStorage{db: dbwrapper},
+		Users:                   &UserStorage{db: dbwrapper},
+		Transactions:            &TransactionStorage{db: dbwrapper},
+		Balances:                &BalanceStorage{db: dbwrapper},
+		Companies:               &CompanyStorage{db: dbwrapper},
+		BalanceRecords:          &BalanceRecordStorage{db: dbwrapper},
+		UserSessions:            &UserSessionStorage{db: dbwrapper},
+		ChangeRequests:          &ChangeRequestStorage{db: dbwrapper},
+		AuditLogs:               &AuditLogStorage{db: dbwrapper},
+		Webhooks:                &WebhookStorage{db: dbwrapper},
+		NotificationOutbox:      &NotificationOutboxStorage{db: dbwrapper},
+		Notifications:           &NotificationStorage{db: dbwrapper},
+		NotificationPreferences: &NotificationPreferenceStorage{db: dbwrapper},
+		SMS:                     &SMSStorage{db: dbwrapper},
+		Telegram:                &TelegramStorage{db: dbwrapper},
+		BalanceThresholds:       &BalanceThresholdStorage{db: dbwrapper},
+		Receipts:                &ReceiptStorage{db: dbwrapper},
+		Statements:              &StatementStorage{db: dbwrapper},
+		Reconciliation:          &ReconciliationStorage{db: dbwrapper},
+		Dashboard:               &DashboardStorage{db: dbwrapper},
+		BalanceSnapshots:        &BalanceSnapshotStorage{db: dbwrapper},
 	}
 }
 
@@ -136,5 +292,5 @@ func (s *Storage) BeginTx(ctx context.Context) (DBTX, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &TxWrapper{tx: tx}, nil
+	return &TxWrapper{tx: tx, hooks: s.Hooks}, nil
 }
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TelegramLinkCode is a one-time code the owner sends to the bot as
+// "/start CODE". URL opens the bot with the code filled in when the bot's
+// username is known.
+type TelegramLinkCode struct {
+	Code               string    `json:"code"`
+	URL                *string   `json:"url"`
+	ExpiresAt          time.Time `json:"-"`
+	ExpiresAtFormatted string    `json:"expires_at"`
+}
+
+// TelegramStorage keeps what the Telegram bot needs besides users: the
+// one-time codes that link a chat to a user and the days a digest went out.
+type TelegramStorage struct {
+	db DBTX
+}
+
+func NewTelegramStorage(db DBTX) *TelegramStorage {
+	return &TelegramStorage{db: db}
+}
+
+// CreateLinkCode stores code for userId until ttl passes. Codes the user
+// asked for earlier stop working.
+func (s *TelegramStorage) CreateLinkCode(ctx context.Context, userId int64, code string, ttl time.Duration) (*TelegramLinkCode, error) {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM telegram_link_codes WHERE user_id = $1 OR expires_at <= now()`, userId); err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO telegram_link_codes (code, user_id, expires_at)
+		VALUES ($1, $2, now() + make_interval(secs => $3)) RETURNING expires_at
+	`
+
+	link := &TelegramLinkCode{Code: code}
+	if err := s.db.QueryRowContext(ctx, query, code, userId, ttl.Seconds()).Scan(&link.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	link.ExpiresAtFormatted = formatTashkent(link.ExpiresAt)
+	return link, nil
+}
+
+// Link uses up code and sets chatId on the user it belongs to. It returns
+// sql.ErrNoRows when the code is unknown or expired.
+func (s *TelegramStorage) Link(ctx context.Context, code string, chatId int64) (int64, error) {
+	query := `
+		WITH used AS (
+			DELETE FROM telegram_link_codes WHERE code = $1 AND expires_at > now() RETURNING user_id
+		)
+		UPDATE users SET telegram_chat_id = $2 FROM used WHERE users.id = used.user_id
+		RETURNING users.id
+	`
+
+	var userId int64
+	err := s.db.QueryRowContext(ctx, query, code, chatId).Scan(&userId)
+	return userId, err
+}
+
+// Unlink clears the chat of every user linked to chatId.
+func (s *TelegramStorage) Unlink(ctx context.Context, chatId int64) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE users SET telegram_chat_id = NULL WHERE telegram_chat_id = $1`, chatId)
+	return err
+}
+
+// UnlinkUser clears the chat of one user.
+func (s *TelegramStorage) UnlinkUser(ctx context.Context, userId int64) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE users SET telegram_chat_id = NULL WHERE id = $1`, userId)
+	return err
+}
+
+// GetOwnerByChatId returns the owner linked to chatId.
+func (s *TelegramStorage) GetOwnerByChatId(ctx context.Context, chatId int64) (*User, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM users WHERE telegram_chat_id = $1 AND role = $2 ORDER BY id LIMIT 1`, chatId, ROLE_OWNER)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users, err := s.scanUsers(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &users[0], nil
+}
+
+// GetLinkedOwners lists the owners that have linked a chat.
+func (s *TelegramStorage) GetLinkedOwners(ctx context.Context) ([]User, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM users WHERE telegram_chat_id IS NOT NULL AND role = $1 ORDER BY id`, ROLE_OWNER)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return s.scanUsers(rows)
+}
+
+// ClaimDigest records that userId gets the digest of day. It reports false
+// when the digest was already claimed, so only one instance sends it.
+func (s *TelegramStorage) ClaimDigest(ctx context.Context, userId int64, day string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO telegram_digests (user_id, day) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userId, day)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ReleaseDigest forgets a claim so the digest is tried again.
+func (s *TelegramStorage) ReleaseDigest(ctx context.Context, userId int64, day string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM telegram_digests WHERE user_id = $1 AND day = $2`, userId, day)
+	return err
+}
+
+func (s *TelegramStorage) scanUsers(rows *sql.Rows) ([]User, error) {
+	var users []User
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(
+			&user.ID,
+			&user.Phone,
+			&user.Role,
+			&user.Avatar,
+			&user.Username,
+			&user.Password,
+			&user.CompanyId,
+			&user.CreatedAt,
+			&user.Language,
+			&user.Email,
+			&user.TelegramChatID,
+		); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
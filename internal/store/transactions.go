@@ -4,10 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"errors"
-	"fmt"
-	"log"
-	"strconv"
 	"time"
 
 	"github.com/lib/pq"
@@ -53,38 +49,26 @@ func (s *TransactionStorage) Archive(ctx context.Context, companyId int64) error
 	}
 
 	if res == 0 {
-		return fmt.Errorf("TRANSACTION NOT FOUND")
+		return types.ErrTransactionNotFound
 	}
 
 	return nil
 }
 
 func (s *TransactionStorage) Create(ctx context.Context, tr *Transaction) error {
-	receivedIncomesJSON, err := json.Marshal(tr.ReceivedIncomes)
-	if err != nil {
-		return err
-	}
-
-	deliveredOutcomesJSON, err := json.Marshal(tr.DeliveredOutcomes)
-	if err != nil {
-		return err
-	}
-
 	loc, _ := time.LoadLocation("Asia/Tashkent")
 	nowUz := time.Now().In(loc)
 
 	query := `
 			INSERT INTO transactions(
-				service_fee, received_incomes, delivered_outcomes,
+				service_fee,
 	 			received_company_id, delivered_company_id, received_user_id, delivered_user_id, phone, details, status, type, created_at) 
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id, created_at`
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at`
 
-	err = s.db.QueryRowContext(
+	err := s.db.QueryRowContext(
 		ctx,
 		query,
 		tr.ServiceFee,
-		receivedIncomesJSON,
-		deliveredOutcomesJSON,
 		tr.ReceivedCompanyId,
 		tr.DeliveredCompanyId,
 		tr.ReceivedUserId,
@@ -103,41 +87,38 @@ func (s *TransactionStorage) Create(ctx context.Context, tr *Transaction) error
 		return err
 	}
 
-	return nil
-}
-func (s *TransactionStorage) Update(ctx context.Context, tr *Transaction) error {
-	receivedIncomesJSON, err := json.Marshal(tr.ReceivedIncomes)
-	if err != nil {
+	if err := setTransactionLines(ctx, s.db, tr.ID, tr.ReceivedIncomes, tr.DeliveredOutcomes); err != nil {
 		return err
 	}
 
-	deliveredOutcomesJSON, err := json.Marshal(tr.DeliveredOutcomes)
-	if err != nil {
-		return err
-	}
+	deltas := map[companyAmountKey]companyAmountDelta{}
+	amountSourceOf(tr).add(deltas, 1)
+	return applyCompanyAmounts(ctx, s.db, deltas)
+}
 
-	query := `	
-		UPDATE transactions SET
+// Update replaces a transaction not yet completed. The company amounts move
+// from what it was to what it is, on the day it was created.
+func (s *TransactionStorage) Update(ctx context.Context, tr *Transaction) error {
+	query := `
+		UPDATE transactions t SET
 			service_fee = $1,
-			received_incomes = $2,
-			delivered_outcomes = $3,
-			received_company_id = $4,
-			delivered_company_id = $5,
-			received_user_id = $6,
-			delivered_user_id = $7,
-			phone = $8,
-			details = $9,
-			status = $10,
-			type = $11
-		WHERE id = $12 AND status = $13
+			received_company_id = $2,
+			delivered_company_id = $3,
+			received_user_id = $4,
+			delivered_user_id = $5,
+			phone = $6,
+			details = $7,
+			status = $8,
+			type = $9
+		FROM (SELECT * FROM transactions WHERE id = $10 FOR UPDATE) old
+		WHERE t.id = old.id AND t.status = $11
+		RETURNING old.type, old.received_company_id, old.delivered_company_id, ` + transactionLineColumns("old") + `, old.created_at
 	`
 
-	result, err := s.db.ExecContext(
+	old, err := scanAmountSource(s.db.QueryRowContext(
 		ctx,
 		query,
 		tr.ServiceFee,
-		receivedIncomesJSON,
-		deliveredOutcomesJSON,
 		tr.ReceivedCompanyId,
 		tr.DeliveredCompanyId,
 		tr.ReceivedUserId,
@@ -148,27 +129,38 @@ func (s *TransactionStorage) Update(ctx context.Context, tr *Transaction) error
 		tr.Type,
 		tr.ID,
 		STATUS_CREATED,
-	)
+	))
 
+	if err == sql.ErrNoRows {
+		return types.ErrTransactionNotFound
+	}
 	if err != nil {
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
+	if err := setTransactionLines(ctx, s.db, tr.ID, tr.ReceivedIncomes, tr.DeliveredOutcomes); err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return errors.New("transaction to update not found")
-	}
+	current := amountSourceOf(tr)
+	current.CreatedAt = old.CreatedAt
 
-	return nil
+	deltas := map[companyAmountKey]companyAmountDelta{}
+	old.add(deltas, -1)
+	current.add(deltas, 1)
+	return applyCompanyAmounts(ctx, s.db, deltas)
+}
+
+// Exists reports whether the transaction exists, whatever its status.
+func (s *TransactionStorage) Exists(ctx context.Context, id int64) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM transactions WHERE id = $1)`, id).Scan(&exists)
+	return exists, err
 }
 
 func (s *TransactionStorage) GetById(ctx context.Context, id int64) (*Transaction, error) {
 	query := `
-				SELECT id, number, service_fee, received_incomes, delivered_outcomes,
+				SELECT id, number, service_fee, ` + transactionLineColumns("transactions") + `,
 	 			received_company_id, delivered_company_id, received_user_id, delivered_user_id, phone, details, status, type, created_at
 				FROM transactions WHERE id = $1 AND status = $2 ORDER BY created_at DESC
 			`
@@ -216,19 +208,15 @@ func (s *TransactionStorage) GetById(ctx context.Context, id int64) (*Transactio
 	return tr, nil
 }
 
-func (s *TransactionStorage) Archived(ctx context.Context, pagination types.Pagination) ([]Transaction, error) {
+func (s *TransactionStorage) Archived(ctx context.Context, pagination *types.Pagination) ([]Transaction, error) {
 	query := `
-				SELECT id, number, service_fee, received_incomes, delivered_outcomes,
+				SELECT id, number, service_fee, ` + transactionLineColumns("transactions") + `,
 	 			received_company_id, delivered_company_id, received_user_id, delivered_user_id, phone, details, status, type, created_at
-				FROM transactions WHERE status = $1   ORDER BY created_at DESC ` + fmt.Sprintf("OFFSET %v LIMIT %v", pagination.Offset, pagination.Limit)
-
-	rows, err := s.db.QueryContext(
-		ctx,
-		query,
-		STATUS_ARCHIVED,
-	)
+				FROM transactions WHERE status = $1
+	`
 
-	return s.ConvertRowsToObject(rows, err)
+	rows, err := queryPage(ctx, s.db, query, []any{STATUS_ARCHIVED}, TransactionSorts, pagination)
+	return s.convertPage(rows, err, pagination)
 }
 
 func (s *TransactionStorage) GetByField(
@@ -236,76 +224,39 @@ func (s *TransactionStorage) GetByField(
 	search *string,
 	fieldName string,
 	fieldValue any,
-	pagination types.Pagination,
+	pagination *types.Pagination,
 ) ([]Transaction, error) {
+	filter := types.Where(fieldName, fieldValue)
+	filter.Search = search
 
-	if search != nil {
-		log.Printf("COME search param %v", *search)
-	} else {
-		log.Println("DO NOT COME search param")
-	}
-
-	allowedFields := map[string]bool{
-		"id":                   true,
-		"received_user_id":     true,
-		"delivered_user_id":    true,
-		"received_company_id":  true,
-		"delivered_company_id": true,
-	}
-
-	if !allowedFields[fieldName] {
-		return nil, fmt.Errorf("invalid field name")
-	}
+	return s.find(ctx, "status != $1", []any{STATUS_ARCHIVED}, filter, pagination)
+}
 
-	args := []any{fieldValue, STATUS_ARCHIVED}
-	argIndex := 3 // ✅ TO‘G‘RI
+// Filter lists the transactions a company sent or received.
+func (s *TransactionStorage) Filter(ctx context.Context, companyId int64, filter *types.Filter, pagination *types.Pagination) ([]Transaction, error) {
+	return s.find(ctx, "(received_company_id = $1 OR delivered_company_id = $1) AND status != $2", []any{companyId, STATUS_ARCHIVED}, filter, pagination)
+}
 
+func (s *TransactionStorage) find(ctx context.Context, where string, args []any, filter *types.Filter, pagination *types.Pagination) ([]Transaction, error) {
 	query := `
-		SELECT id, number, service_fee, received_incomes, delivered_outcomes,
+		SELECT id, number, service_fee, ` + transactionLineColumns("transactions") + `,
 		received_company_id, delivered_company_id, received_user_id, delivered_user_id,
 		phone, details, status, type, created_at
 		FROM transactions
-		WHERE ` + fieldName + ` = $1 AND status != $2
-	`
+		WHERE ` + where
 
-	if search != nil && *search != "" {
-		query += fmt.Sprintf(`
-			AND (
-				details ILIKE $%d 
-				OR phone ILIKE $%d
-				OR CAST(number AS TEXT) ILIKE $%d
-				OR CAST(service_fee AS TEXT) ILIKE $%d
-			)
-		`, argIndex, argIndex+1, argIndex+2, argIndex+3)
-
-		searchValue := "%" + *search + "%"
-
-		// ✅ 4 TA PARAM
-		args = append(args, searchValue, searchValue, searchValue, searchValue)
-		argIndex += 4
-
-		// 🔥 BONUS: exact number search
-		if num, err := strconv.ParseInt(*search, 10, 64); err == nil {
-			query += fmt.Sprintf(` OR number = $%d`, argIndex)
-			args = append(args, num)
-			argIndex++
-		}
+	query, args, err := TransactionFilters.Apply(query, args, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	query += fmt.Sprintf(`
-		ORDER BY created_at DESC
-		OFFSET $%d LIMIT $%d
-	`, argIndex, argIndex+1)
-
-	args = append(args, pagination.Offset, pagination.Limit)
-
-	rows, err := s.db.QueryContext(ctx, query, args...)
-	return s.ConvertRowsToObject(rows, err)
+	rows, err := queryPage(ctx, s.db, query, args, TransactionSorts, pagination)
+	return s.convertPage(rows, err, pagination)
 }
 
 func (s *TransactionStorage) GetInfos(ctx context.Context, companyId int64) ([]Transaction, error) {
 	query := `
-				SELECT id, number, service_fee, received_incomes, delivered_outcomes,
+				SELECT id, number, service_fee, ` + transactionLineColumns("transactions") + `,
 	 			received_company_id, delivered_company_id, received_user_id, delivered_user_id, phone, details, status, type, created_at
 				FROM transactions WHERE delivered_company_id = $1 AND status = $2
 			`
@@ -319,47 +270,42 @@ func (s *TransactionStorage) GetInfos(ctx context.Context, companyId int64) ([]T
 	return s.ConvertRowsToObject(rows, err)
 }
 
-func (s *TransactionStorage) GetByFieldAndDate(ctx context.Context, fieldName, from, to string, fieldValue any, pagination types.Pagination) ([]Transaction, error) {
-	query := `
-				SELECT id, number, service_fee, received_incomes, delivered_outcomes,
-	 			received_company_id, delivered_company_id, received_user_id, delivered_user_id, phone, details, status, type, created_at
-				FROM transactions WHERE ` + fmt.Sprintf("%v", fieldName) + ` = $1 AND created_at BETWEEN $2 AND $3 AND status != $4  ` + fmt.Sprintf("ORDER BY created_at DESC OFFSET %v LIMIT %v", pagination.Offset, pagination.Limit)
-
-	rows, err := s.db.QueryContext(
-		ctx,
-		query,
-		fieldValue,
-		from,
-		to,
-		STATUS_ARCHIVED,
-	)
+func (s *TransactionStorage) GetByFieldAndDate(ctx context.Context, fieldName, from, to string, fieldValue any, pagination *types.Pagination) ([]Transaction, error) {
+	filter := types.Where(fieldName, fieldValue).And("created_at", types.FILTER_BETWEEN, []any{from, to})
 
-	return s.ConvertRowsToObject(rows, err)
+	return s.find(ctx, "status != $1", []any{STATUS_ARCHIVED}, filter, pagination)
 }
 
 func (s *TransactionStorage) Delete(ctx context.Context, id *int64) error {
-	query := `DELETE FROM transactions WHERE id = $1`
+	query := `
+		DELETE FROM transactions WHERE id = $1
+		RETURNING type, received_company_id, delivered_company_id, ` + transactionLineColumns("transactions") + `, created_at
+	`
 
-	rows, err := s.db.ExecContext(
+	old, err := scanAmountSource(s.db.QueryRowContext(
 		ctx,
 		query,
 		id,
-	)
+	))
 
-	if err != nil {
-		return err
+	if err == sql.ErrNoRows {
+		return types.ErrTransactionNotFound
 	}
-
-	res, err := rows.RowsAffected()
 	if err != nil {
 		return err
 	}
 
-	if res == 0 {
-		return errors.New("TRANSACTION NOT FOUND")
-	}
+	deltas := map[companyAmountKey]companyAmountDelta{}
+	old.add(deltas, -1)
+	return applyCompanyAmounts(ctx, s.db, deltas)
+}
 
-	return nil
+func (s *TransactionStorage) convertPage(rows *sql.Rows, err error, pagination *types.Pagination) ([]Transaction, error) {
+	transactions, err := s.ConvertRowsToObject(rows, err)
+	if err != nil {
+		return nil, err
+	}
+	return trimPage(pagination, transactions, Transaction.pageKey), nil
 }
 
 func (s *TransactionStorage) ConvertRowsToObject(rows *sql.Rows, err error) ([]Transaction, error) {
@@ -419,87 +365,22 @@ type CompanyAmount struct {
 	Remain         float64
 }
 
+// GetCompanyFinalAmounts returns, per currency, what each of companyIDs
+// took in and gave out on date, a day in Tashkent, and what remains of all
+// its transactions. It reads the aggregates kept by the write paths.
 func (s *TransactionStorage) GetCompanyFinalAmounts(ctx context.Context, companyIDs []int64, date string) ([]CompanyAmount, error) {
 	query := `
-with all_outcomes as (
-    -- delivered_outcomes
-    select 
-        t.delivered_company_id as company_id,
-        t.type,
-        elem->>'delivered_currency' as currency,
-        (elem->>'delivered_amount')::numeric as delivered_amount,
-        0::numeric as received_amount,
-        t.created_at
-    from transactions t
-    cross join jsonb_array_elements(t.delivered_outcomes) as elem
-    where t.delivered_company_id = ANY($1) or t.received_company_id = ANY($1)
-
-    union all
-
-    -- received_incomes
-    select 
-        t.received_company_id as company_id,
-        t.type,
-        elem->>'received_currency' as currency,
-        0::numeric as delivered_amount,
-        (elem->>'received_amount')::numeric as received_amount,
-        t.created_at
-    from transactions t
-    cross join jsonb_array_elements(t.received_incomes) as elem
-    where t.delivered_company_id = ANY($1) or t.received_company_id = ANY($1)
-)
-select 
-    c.name as company_name,
-    a.currency,
-    
-    -- Olingan summalar (faqat date filter)
-    coalesce(sum(
-        case 
-            when a.created_at::date = $2 then
-                case 
-                    when a.type = 1 then a.delivered_amount
-                    when a.type = 2 then a.received_amount
-                    else 0
-                end
-            else 0
-        end
-    ),0) as olingan_amount,
-
-    -- Berilgan summalar (faqat date filter)
-    coalesce(sum(
-        case 
-            when a.created_at::date = $2 then
-                case 
-                    when a.type = 1 then a.received_amount
-                    when a.type = 2 then a.delivered_amount
-                    else 0
-                end
-            else 0
-        end
-    ),0) as berilgan_amount,
-
-    -- Qolgan summasi (barcha transactionlar)
-    coalesce(sum(
-        case 
-            when a.type = 1 then a.delivered_amount
-            when a.type = 2 then a.received_amount
-            else 0
-        end
-    ),0)
-    -
-    coalesce(sum(
-        case 
-            when a.type = 1 then a.received_amount
-            when a.type = 2 then a.delivered_amount
-            else 0
-        end
-    ),0) as remain
-
-from all_outcomes a
-join companies c on c.id = a.company_id
-group by a.company_id, c.name, a.currency
-order by a.company_id, a.currency;
-`
+		SELECT c.name, t.currency,
+			COALESCE(d.olingan_amount, 0),
+			COALESCE(d.berilgan_amount, 0),
+			t.olingan_amount - t.berilgan_amount
+		FROM company_amount_totals t
+		JOIN companies c ON c.id = t.company_id
+		LEFT JOIN company_daily_amounts d
+			ON d.company_id = t.company_id AND d.currency = t.currency AND d.day = $2::date
+		WHERE t.company_id = ANY($1)
+		ORDER BY t.company_id, t.currency
+	`
 
 	rows, err := s.db.QueryContext(ctx, query, pq.Array(companyIDs), date)
 	if err != nil {
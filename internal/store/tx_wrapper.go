@@ -6,7 +6,9 @@ import (
 )
 
 type TxWrapper struct {
-	tx *sql.Tx
+	tx          *sql.Tx
+	hooks       *Hooks
+	afterCommit []func()
 }
 
 func (t *TxWrapper) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
@@ -22,9 +24,27 @@ func (t *TxWrapper) ExecContext(ctx context.Context, query string, args ...any)
 }
 
 func (t *TxWrapper) Commit() error {
-	return t.tx.Commit()
+	if err := t.tx.Commit(); err != nil {
+		return err
+	}
+
+	fns := t.afterCommit
+	t.afterCommit = nil
+	for _, fn := range fns {
+		fn()
+	}
+	return nil
 }
 
 func (t *TxWrapper) Rollback() error {
+	t.afterCommit = nil
 	return t.tx.Rollback()
 }
+
+func (t *TxWrapper) AfterCommit(fn func()) {
+	t.afterCommit = append(t.afterCommit, fn)
+}
+
+func (t *TxWrapper) Hooks() *Hooks {
+	return t.hooks
+}
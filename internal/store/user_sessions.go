@@ -62,7 +62,9 @@ func scanUserSession(row *sql.Row) (*UserSession, error) {
 	return &s, nil
 }
 
-func (s *UserSessionStorage) Upsert(ctx context.Context, row *UserSession) error {
+// Upsert saves the session of a device and reports whether the device is
+// new to the user.
+func (s *UserSessionStorage) Upsert(ctx context.Context, row *UserSession) (bool, error) {
 	q := `
 		INSERT INTO user_sessions (user_id, device_id, fcm_token, refresh_token, platform, app_version, user_agent)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
@@ -74,7 +76,7 @@ func (s *UserSessionStorage) Upsert(ctx context.Context, row *UserSession) error
 			user_agent = COALESCE(EXCLUDED.user_agent, user_sessions.user_agent),
 			last_seen_at = now(),
 			updated_at = now()
-		RETURNING id, last_seen_at, created_at, updated_at`
+		RETURNING id, last_seen_at, created_at, updated_at, (xmax = 0) AS created`
 
 	var refresh, platform, appVer, ua interface{}
 	if row.RefreshToken != nil {
@@ -90,7 +92,8 @@ func (s *UserSessionStorage) Upsert(ctx context.Context, row *UserSession) error
 		ua = *row.UserAgent
 	}
 
-	return s.db.QueryRowContext(ctx, q,
+	var created bool
+	err := s.db.QueryRowContext(ctx, q,
 		row.UserID,
 		row.DeviceID,
 		row.FCMToken,
@@ -98,7 +101,8 @@ func (s *UserSessionStorage) Upsert(ctx context.Context, row *UserSession) error
 		platform,
 		appVer,
 		ua,
-	).Scan(&row.ID, &row.LastSeenAt, &row.CreatedAt, &row.UpdatedAt)
+	).Scan(&row.ID, &row.LastSeenAt, &row.CreatedAt, &row.UpdatedAt, &created)
+	return created, err
 }
 
 func (s *UserSessionStorage) ListByUserID(ctx context.Context, userID int64) ([]UserSession, error) {
@@ -2,7 +2,15 @@ package store
 
 import (
 	"context"
-	"errors"
+
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+// Roles of users.role, also documented on the column. Owners review change
+// requests and manage the company; cashiers enter operations.
+const (
+	ROLE_OWNER   = 1
+	ROLE_CASHIER = 2
 )
 
 type User struct {
@@ -14,6 +22,10 @@ type User struct {
 	Avatar    *string `json:"avatar"`
 	CompanyId int64   `json:"company_id"`
 	CreatedAt string  `json:"created_at"`
+	Language  *string `json:"language"`
+	Email     *string `json:"email"`
+	// TelegramChatID is set once the user links the Telegram bot.
+	TelegramChatID *int64 `json:"telegram_chat_id"`
 }
 
 type UserStorage struct {
@@ -25,8 +37,8 @@ func NewUserStorage(db DBTX) *UserStorage {
 }
 
 func (s *UserStorage) Create(ctx context.Context, user *User) error {
-	query := `INSERT INTO users(username, phone, password, role, company_id)
-				VALUES($1, $2, $3, $4, $5) RETURNING id, created_at`
+	query := `INSERT INTO users(username, phone, password, role, company_id, language, email)
+				VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`
 
 	err := s.db.QueryRowContext(
 		ctx,
@@ -35,7 +47,9 @@ func (s *UserStorage) Create(ctx context.Context, user *User) error {
 		user.Phone,
 		user.Password,
 		user.Role,
-		user.CompanyId).Scan(
+		user.CompanyId,
+		user.Language,
+		user.Email).Scan(
 		&user.ID,
 		&user.CreatedAt,
 	)
@@ -47,6 +61,34 @@ func (s *UserStorage) Create(ctx context.Context, user *User) error {
 	return nil
 }
 
+// Register creates a user who signed up themselves. The role is not theirs
+// to choose: the first user of a company becomes its owner and everyone
+// after a cashier. Run it after CompanyStorage.Lock in the same transaction,
+// or two first users could both become owners.
+func (s *UserStorage) Register(ctx context.Context, user *User) error {
+	query := `INSERT INTO users(username, phone, password, role, company_id, language, email)
+				SELECT $1, $2, $3,
+					CASE WHEN EXISTS (SELECT 1 FROM users WHERE company_id = $4) THEN $7::bigint ELSE $8::bigint END,
+					$4, $5, $6
+				RETURNING id, role, created_at`
+
+	return s.db.QueryRowContext(
+		ctx,
+		query,
+		user.Username,
+		user.Phone,
+		user.Password,
+		user.CompanyId,
+		user.Language,
+		user.Email,
+		ROLE_CASHIER,
+		ROLE_OWNER).Scan(
+		&user.ID,
+		&user.Role,
+		&user.CreatedAt,
+	)
+}
+
 func (s *UserStorage) Login(ctx context.Context, user *User) error {
 	query := `SELECT * FROM users WHERE phone = $1 AND password = $2`
 
@@ -63,6 +105,9 @@ func (s *UserStorage) Login(ctx context.Context, user *User) error {
 		&user.Password,
 		&user.CompanyId,
 		&user.CreatedAt,
+		&user.Language,
+		&user.Email,
+		&user.TelegramChatID,
 	)
 
 	if err != nil {
@@ -86,6 +131,9 @@ func (s *UserStorage) GetById(ctx context.Context, id *int64) (*User, error) {
 		&user.Password,
 		&user.CompanyId,
 		&user.CreatedAt,
+		&user.Language,
+		&user.Email,
+		&user.TelegramChatID,
 	)
 
 	if err != nil {
@@ -119,6 +167,9 @@ func (s *UserStorage) GetAll(ctx context.Context) ([]User, error) {
 			&user.Password,
 			&user.CompanyId,
 			&user.CreatedAt,
+			&user.Language,
+			&user.Email,
+			&user.TelegramChatID,
 		)
 
 		users = append(users, *user)
@@ -128,7 +179,7 @@ func (s *UserStorage) GetAll(ctx context.Context) ([]User, error) {
 }
 
 func (s *UserStorage) Update(ctx context.Context, user *User) error {
-	query := `UPDATE users SET username = $1, password = $2, role = $3, avatar = $4, company_id = $5 WHERE id = $6`
+	query := `UPDATE users SET username = $1, password = $2, role = $3, avatar = $4, company_id = $5, language = $6, email = $7 WHERE id = $8`
 
 	result, err := s.db.ExecContext(
 		ctx,
@@ -138,6 +189,8 @@ func (s *UserStorage) Update(ctx context.Context, user *User) error {
 		user.Role,
 		user.Avatar,
 		user.CompanyId,
+		user.Language,
+		user.Email,
 		user.ID)
 
 	if err != nil {
@@ -150,7 +203,7 @@ func (s *UserStorage) Update(ctx context.Context, user *User) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("user not found")
+		return types.ErrUserNotFound
 	}
 
 	return nil
@@ -176,7 +229,7 @@ func (s *UserStorage) Delete(ctx context.Context, id *int64) error {
 	}
 
 	if result == 0 {
-		return errors.New("NOT FOUND")
+		return types.ErrUserNotFound
 	}
 
 	return nil
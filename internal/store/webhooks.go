@@ -0,0 +1,368 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mubashshir3767/currencyExchange/internal/types"
+)
+
+const (
+	DELIVERY_PENDING   = 1
+	DELIVERY_DELIVERED = 2
+	DELIVERY_DEAD      = 3
+)
+
+// WebhookEndpoint is a partner URL that receives the events of a company.
+// The secret is only returned when the endpoint is created.
+type WebhookEndpoint struct {
+	ID                 int64     `json:"id"`
+	CompanyID          int64     `json:"company_id"`
+	URL                string    `json:"url"`
+	Secret             string    `json:"secret,omitempty"`
+	EventTypes         []string  `json:"event_types"`
+	IsActive           bool      `json:"is_active"`
+	CreatedAt          time.Time `json:"-"`
+	CreatedAtFormatted string    `json:"created_at"`
+}
+
+// WebhookDelivery is one attempt to hand an outbox event to one endpoint.
+type WebhookDelivery struct {
+	ID                   int64      `json:"id"`
+	EndpointID           int64      `json:"endpoint_id"`
+	OutboxEventID        int64      `json:"outbox_event_id"`
+	EventType            string     `json:"event_type"`
+	Status               int64      `json:"status"`
+	Attempts             int        `json:"attempts"`
+	ResponseStatus       *int       `json:"response_status"`
+	LastError            *string    `json:"last_error"`
+	NextAttemptAt        time.Time  `json:"-"`
+	NextAttemptFormatted string     `json:"next_attempt_at"`
+	DeliveredAt          *time.Time `json:"-"`
+	DeliveredAtFormatted *string    `json:"delivered_at"`
+	CreatedAt            time.Time  `json:"-"`
+	CreatedAtFormatted   string     `json:"created_at"`
+}
+
+// PendingDelivery is a claimed delivery with everything needed to send it.
+type PendingDelivery struct {
+	ID             int64
+	OutboxEventID  int64
+	Attempts       int
+	URL            string
+	Secret         string
+	CompanyID      int64
+	EventType      string
+	Payload        json.RawMessage
+	EventCreatedAt time.Time
+}
+
+type WebhookStorage struct {
+	db DBTX
+}
+
+func NewWebhookStorage(db DBTX) *WebhookStorage {
+	return &WebhookStorage{db: db}
+}
+
+func (s *WebhookStorage) Create(ctx context.Context, endpoint *WebhookEndpoint) error {
+	query := `
+		INSERT INTO webhook_endpoints (company_id, url, secret, event_types)
+		VALUES ($1, $2, $3, $4) RETURNING id, is_active, created_at
+	`
+
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		endpoint.CompanyID,
+		endpoint.URL,
+		endpoint.Secret,
+		pq.Array(endpoint.EventTypes),
+	).Scan(
+		&endpoint.ID,
+		&endpoint.IsActive,
+		&endpoint.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+
+	endpoint.CreatedAtFormatted = formatTashkent(endpoint.CreatedAt)
+	return nil
+}
+
+func (s *WebhookStorage) GetByCompanyId(ctx context.Context, companyId int64) ([]WebhookEndpoint, error) {
+	query := `
+		SELECT id, company_id, url, event_types, is_active, created_at
+		FROM webhook_endpoints WHERE company_id = $1 ORDER BY id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, companyId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []WebhookEndpoint
+	for rows.Next() {
+		var e WebhookEndpoint
+		if err := rows.Scan(
+			&e.ID,
+			&e.CompanyID,
+			&e.URL,
+			pq.Array(&e.EventTypes),
+			&e.IsActive,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		e.CreatedAtFormatted = formatTashkent(e.CreatedAt)
+		endpoints = append(endpoints, e)
+	}
+
+	return endpoints, rows.Err()
+}
+
+func (s *WebhookStorage) Delete(ctx context.Context, companyId, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1 AND company_id = $2`, id, companyId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// Fanout marks up to limit unprocessed outbox events as processed and queues
+// a delivery for every active endpoint subscribed to them, all in one
+// statement. Concurrent dispatchers skip each other's rows.
+func (s *WebhookStorage) Fanout(ctx context.Context, limit int) (int64, error) {
+	query := `
+		WITH batch AS (
+			SELECT id FROM outbox_events WHERE processed_at IS NULL
+			ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED
+		), processed AS (
+			UPDATE outbox_events o SET processed_at = now() FROM batch
+			WHERE o.id = batch.id RETURNING o.id, o.company_id, o.event_type
+		)
+		INSERT INTO webhook_deliveries (endpoint_id, outbox_event_id)
+		SELECT w.id, p.id FROM processed p
+		JOIN webhook_endpoints w ON w.company_id = p.company_id AND w.is_active AND p.event_type = ANY(w.event_types)
+		ON CONFLICT DO NOTHING
+	`
+
+	res, err := s.db.ExecContext(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fan out outbox events: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// ClaimDeliveries picks up to limit due deliveries, counts the attempt and
+// pushes next_attempt_at forward by lease so another dispatcher does not
+// send them while this one is still waiting for the partner.
+func (s *WebhookStorage) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]PendingDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = now() + make_interval(secs => $2)
+		FROM webhook_endpoints w, outbox_events o
+		WHERE d.id IN (
+			SELECT id FROM webhook_deliveries WHERE status = $3 AND next_attempt_at <= now()
+			ORDER BY next_attempt_at LIMIT $1 FOR UPDATE SKIP LOCKED
+		) AND w.id = d.endpoint_id AND o.id = d.outbox_event_id
+		RETURNING d.id, d.outbox_event_id, d.attempts, w.url, w.secret, o.company_id, o.event_type, o.payload, o.created_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, limit, lease.Seconds(), DELIVERY_PENDING)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []PendingDelivery
+	for rows.Next() {
+		var d PendingDelivery
+		var payload []byte
+		if err := rows.Scan(
+			&d.ID,
+			&d.OutboxEventID,
+			&d.Attempts,
+			&d.URL,
+			&d.Secret,
+			&d.CompanyID,
+			&d.EventType,
+			&payload,
+			&d.EventCreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		d.Payload = json.RawMessage(payload)
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (s *WebhookStorage) MarkDelivered(ctx context.Context, id int64, responseStatus int) error {
+	query := `
+		UPDATE webhook_deliveries SET status = $1, response_status = $2, last_error = NULL, delivered_at = now()
+		WHERE id = $3
+	`
+
+	_, err := s.db.ExecContext(ctx, query, DELIVERY_DELIVERED, responseStatus, id)
+	return err
+}
+
+// MarkFailed records a failed attempt. A nil retryAt moves the delivery to
+// the dead-letter list.
+func (s *WebhookStorage) MarkFailed(ctx context.Context, id int64, responseStatus *int, lastError string, retryAt *time.Time) error {
+	status := int64(DELIVERY_PENDING)
+	next := time.Now()
+	if retryAt == nil {
+		status = DELIVERY_DEAD
+	} else {
+		next = *retryAt
+	}
+
+	query := `
+		UPDATE webhook_deliveries SET status = $1, response_status = $2, last_error = $3, next_attempt_at = $4
+		WHERE id = $5
+	`
+
+	_, err := s.db.ExecContext(ctx, query, status, responseStatus, lastError, next, id)
+	return err
+}
+
+func (s *WebhookStorage) GetDeliveries(ctx context.Context, companyId int64, status *int64, pagination *types.Pagination) ([]WebhookDelivery, error) {
+	query := `
+		SELECT d.id, d.endpoint_id, d.outbox_event_id, o.event_type, d.status, d.attempts, d.response_status,
+		d.last_error, d.next_attempt_at, d.delivered_at, d.created_at
+		FROM webhook_deliveries d
+		JOIN webhook_endpoints w ON w.id = d.endpoint_id
+		JOIN outbox_events o ON o.id = d.outbox_event_id
+		WHERE w.company_id = $1 AND ($2::bigint IS NULL OR d.status = $2)
+	`
+
+	rows, err := queryPage(ctx, s.db, query, []any{companyId, status}, WebhookDeliverySorts, pagination)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		var responseStatus sql.NullInt64
+		var lastError sql.NullString
+		var deliveredAt sql.NullTime
+
+		if err := rows.Scan(
+			&d.ID,
+			&d.EndpointID,
+			&d.OutboxEventID,
+			&d.EventType,
+			&d.Status,
+			&d.Attempts,
+			&responseStatus,
+			&lastError,
+			&d.NextAttemptAt,
+			&deliveredAt,
+			&d.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if responseStatus.Valid {
+			code := int(responseStatus.Int64)
+			d.ResponseStatus = &code
+		}
+		if lastError.Valid {
+			d.LastError = &lastError.String
+		}
+		if deliveredAt.Valid {
+			formatted := formatTashkent(deliveredAt.Time)
+			d.DeliveredAt = &deliveredAt.Time
+			d.DeliveredAtFormatted = &formatted
+		}
+		d.NextAttemptFormatted = formatTashkent(d.NextAttemptAt)
+		d.CreatedAtFormatted = formatTashkent(d.CreatedAt)
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return trimPage(pagination, deliveries, WebhookDelivery.pageKey), nil
+}
+
+// Redeliver queues a dead or delivered delivery again with a fresh attempt
+// count. Pending deliveries are left alone since they are already queued.
+func (s *WebhookStorage) Redeliver(ctx context.Context, companyId, id int64) error {
+	query := `
+		UPDATE webhook_deliveries d SET status = $1, attempts = 0, next_attempt_at = now(), last_error = NULL
+		FROM webhook_endpoints w
+		WHERE d.id = $2 AND w.id = d.endpoint_id AND w.company_id = $3 AND d.status != $1
+	`
+
+	res, err := s.db.ExecContext(ctx, query, DELIVERY_PENDING, id, companyId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+type OutboxStorage struct {
+	db DBTX
+}
+
+func NewOutboxStorage(db DBTX) *OutboxStorage {
+	return &OutboxStorage{db: db}
+}
+
+// Add writes one outbox event per distinct company. It is meant to run on the
+// transaction that makes the change, so the event exists if and only if the
+// change was committed.
+func (s *OutboxStorage) Add(ctx context.Context, eventType string, companyIDs []int64, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox payload: %w", err)
+	}
+
+	seen := map[int64]bool{}
+	for _, id := range companyIDs {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		query := `INSERT INTO outbox_events (company_id, event_type, payload) VALUES ($1, $2, $3)`
+		if _, err := s.db.ExecContext(ctx, query, id, eventType, payload); err != nil {
+			return fmt.Errorf("failed to write outbox event: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,324 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/i18n"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+const (
+	POLL_TIMEOUT = 25 * time.Second
+	RETRY_AFTER  = 5 * time.Second
+	// LINK_CODE_TTL is how long a code from the app can be sent to the bot.
+	LINK_CODE_TTL    = 10 * time.Minute
+	LINK_CODE_LENGTH = 8
+	DIGEST_EVERY     = time.Minute
+)
+
+// API is the part of the Bot API the bot uses. *Client implements it.
+type API interface {
+	GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error)
+	SendMessage(ctx context.Context, chatID int64, text string) error
+}
+
+var _ API = (*Client)(nil)
+
+// Bot answers owners' commands and sends them a daily digest. Only one
+// instance should poll a bot token; Telegram refuses a second poller.
+type Bot struct {
+	api          API
+	store        store.Storage
+	digestHour   int
+	lastUpdateID int64
+}
+
+// NewBot sends the digest every day once digestHour (Tashkent time) has
+// passed.
+func NewBot(api API, st store.Storage, digestHour int) *Bot {
+	return &Bot{api: api, store: st, digestHour: digestHour}
+}
+
+// Run polls for commands and sends digests until ctx is cancelled.
+func (b *Bot) Run(ctx context.Context) {
+	go b.runDigests(ctx)
+
+	for ctx.Err() == nil {
+		updates, err := b.api.GetUpdates(ctx, b.lastUpdateID+1, POLL_TIMEOUT)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("telegram: %v", err)
+				sleep(ctx, RETRY_AFTER)
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			b.lastUpdateID = update.UpdateID
+			if update.Message == nil || update.Message.Text == "" {
+				continue
+			}
+			if err := b.Handle(ctx, *update.Message); err != nil {
+				log.Printf("telegram: chat %d: %v", update.Message.Chat.ID, err)
+			}
+		}
+	}
+}
+
+// Handle answers one message.
+func (b *Bot) Handle(ctx context.Context, msg Message) error {
+	command, arg := parseCommand(msg.Text)
+	chatID := msg.Chat.ID
+
+	lang := i18n.DEFAULT
+	if msg.From != nil {
+		if l, ok := i18n.Normalize(msg.From.LanguageCode); ok {
+			lang = l
+		}
+	}
+
+	switch command {
+	case "/start", "/link":
+		if arg == "" {
+			return b.reply(ctx, chatID, i18n.T(lang, "telegram.help"))
+		}
+		return b.link(ctx, chatID, lang, arg)
+	case "/help":
+		return b.reply(ctx, chatID, i18n.T(lang, "telegram.help"))
+	}
+
+	owner, err := b.store.Telegram.GetOwnerByChatId(ctx, chatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return b.reply(ctx, chatID, i18n.T(lang, "telegram.not_linked"))
+	}
+	if err != nil {
+		return err
+	}
+	lang = i18n.FromPreference(owner.Language)
+
+	switch command {
+	case "/balance":
+		text, err := b.Balances(ctx, lang, owner.CompanyId)
+		if err != nil {
+			return err
+		}
+		return b.reply(ctx, chatID, text)
+	case "/debtors":
+		text, err := b.Debtors(ctx, lang, owner.CompanyId)
+		if err != nil {
+			return err
+		}
+		return b.reply(ctx, chatID, text)
+	case "/digest":
+		text, err := b.Digest(ctx, lang, owner.CompanyId, today())
+		if err != nil {
+			return err
+		}
+		return b.reply(ctx, chatID, text)
+	case "/unlink":
+		if err := b.store.Telegram.Unlink(ctx, chatID); err != nil {
+			return err
+		}
+		return b.reply(ctx, chatID, i18n.T(lang, "telegram.unlinked"))
+	}
+
+	return b.reply(ctx, chatID, i18n.T(lang, "telegram.help"))
+}
+
+func (b *Bot) link(ctx context.Context, chatID int64, lang i18n.Lang, code string) error {
+	userID, err := b.store.Telegram.Link(ctx, strings.ToUpper(code), chatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return b.reply(ctx, chatID, i18n.T(lang, "telegram.link_failed"))
+	}
+	if err != nil {
+		return err
+	}
+
+	if user, err := b.store.Users.GetById(ctx, &userID); err == nil {
+		lang = i18n.FromPreference(user.Language)
+	}
+	return b.reply(ctx, chatID, i18n.T(lang, "telegram.linked"))
+}
+
+// Balances lists the cash balances of the company.
+func (b *Bot) Balances(ctx context.Context, lang i18n.Lang, companyID int64) (string, error) {
+	balances, err := b.store.Balances.GetByCompanyId(ctx, &companyID)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	text.WriteString(i18n.T(lang, "telegram.balances"))
+	if len(balances) == 0 {
+		text.WriteString("\n" + i18n.T(lang, "telegram.empty"))
+	}
+	for _, balance := range balances {
+		fmt.Fprintf(&text, "\n%s: %d", balance.Currency, balance.Balance)
+	}
+	return text.String(), nil
+}
+
+// Debtors sums what debtors owe and are owed, per currency.
+func (b *Bot) Debtors(ctx context.Context, lang i18n.Lang, companyID int64) (string, error) {
+	infos, err := b.store.Debtors.GetByBalanceInfo(ctx, companyID)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	text.WriteString(i18n.T(lang, "telegram.debtors"))
+	writeDebtors(&text, lang, infos)
+	return text.String(), nil
+}
+
+// Digest is the daily summary: the company's received, paid and remaining
+// amounts for day and its debtor totals.
+func (b *Bot) Digest(ctx context.Context, lang i18n.Lang, companyID int64, day string) (string, error) {
+	company, err := b.store.Companies.GetById(ctx, &companyID)
+	if err != nil {
+		return "", err
+	}
+	amounts, err := b.store.Transactions.GetCompanyFinalAmounts(ctx, []int64{companyID}, day)
+	if err != nil {
+		return "", err
+	}
+	infos, err := b.store.Debtors.GetByBalanceInfo(ctx, companyID)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	text.WriteString(i18n.T(lang, "telegram.digest", company.Name, day))
+
+	for _, amount := range amounts {
+		text.WriteString("\n" + i18n.T(lang, "telegram.digest_line", amount.Currency, formatAmount(amount.OlinganAmount), formatAmount(amount.BerilganAmount), formatAmount(amount.Remain)))
+	}
+	if len(amounts) == 0 {
+		text.WriteString("\n" + i18n.T(lang, "telegram.empty"))
+	}
+
+	text.WriteString("\n\n" + i18n.T(lang, "telegram.debtors"))
+	writeDebtors(&text, lang, infos)
+	return text.String(), nil
+}
+
+func (b *Bot) runDigests(ctx context.Context) {
+	ticker := time.NewTicker(DIGEST_EVERY)
+	defer ticker.Stop()
+
+	for {
+		if tashkentNow().Hour() >= b.digestHour {
+			b.SendDigests(ctx, today())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDigests sends the digest of day to every linked owner that has not
+// had it yet. A digest that could not be sent is tried again next round.
+func (b *Bot) SendDigests(ctx context.Context, day string) {
+	owners, err := b.store.Telegram.GetLinkedOwners(ctx)
+	if err != nil {
+		log.Printf("telegram digest: %v", err)
+		return
+	}
+
+	for _, owner := range owners {
+		claimed, err := b.store.Telegram.ClaimDigest(ctx, owner.ID, day)
+		if err != nil {
+			log.Printf("telegram digest: user %d: %v", owner.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		text, err := b.Digest(ctx, i18n.FromPreference(owner.Language), owner.CompanyId, day)
+		if err == nil {
+			err = b.api.SendMessage(ctx, *owner.TelegramChatID, text)
+		}
+		if err != nil {
+			log.Printf("telegram digest: user %d: %v", owner.ID, err)
+			if err := b.store.Telegram.ReleaseDigest(ctx, owner.ID, day); err != nil {
+				log.Printf("telegram digest: user %d: %v", owner.ID, err)
+			}
+		}
+	}
+}
+
+func (b *Bot) reply(ctx context.Context, chatID int64, text string) error {
+	return b.api.SendMessage(ctx, chatID, text)
+}
+
+// NewLinkCode returns a one-time code for linking a chat. It avoids
+// characters that are easy to mix up when typed.
+func NewLinkCode() (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+	buf := make([]byte, LINK_CODE_LENGTH)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i := range buf {
+		buf[i] = alphabet[int(buf[i])%len(alphabet)]
+	}
+	return string(buf), nil
+}
+
+// parseCommand splits "/cmd@bot arg" into "/cmd" and "arg".
+func parseCommand(text string) (string, string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	command, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+	if len(fields) > 1 {
+		return command, fields[1]
+	}
+	return command, ""
+}
+
+func writeDebtors(text *strings.Builder, lang i18n.Lang, infos []map[string]interface{}) {
+	if len(infos) == 0 {
+		text.WriteString("\n" + i18n.T(lang, "telegram.empty"))
+	}
+	for _, info := range infos {
+		text.WriteString("\n" + i18n.T(lang, "telegram.debtor_line", info["currency"], info["positive_balance"], info["negative_balance"]))
+	}
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func tashkentNow() time.Time {
+	loc, err := time.LoadLocation("Asia/Tashkent")
+	if err != nil {
+		return time.Now()
+	}
+	return time.Now().In(loc)
+}
+
+func today() string {
+	return tashkentNow().Format("2006-01-02")
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
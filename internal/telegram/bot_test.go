@@ -0,0 +1,375 @@
+package telegram
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/i18n"
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+const testToken = "123:test"
+
+type sentMessage struct {
+	ChatID int64  `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+// fakeAPI is a Bot API server. It hands out updates once and records every
+// message sent; messages to a blocked chat fail the way Telegram fails them.
+type fakeAPI struct {
+	mu      sync.Mutex
+	updates []Update
+	sent    []sentMessage
+	blocked map[int64]bool
+	notify  chan struct{}
+}
+
+func newFakeAPI(t *testing.T) (*fakeAPI, *Client) {
+	api := &fakeAPI{blocked: map[int64]bool{}, notify: make(chan struct{}, 16)}
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	return api, NewClientWithURL(testToken, srv.URL)
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method, ok := strings.CutPrefix(r.URL.Path, "/bot"+testToken+"/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch method {
+	case "getUpdates":
+		var params struct {
+			Offset int64 `json:"offset"`
+		}
+		json.NewDecoder(r.Body).Decode(&params)
+
+		var updates []Update
+		for _, update := range f.updates {
+			if update.UpdateID >= params.Offset {
+				updates = append(updates, update)
+			}
+		}
+		if len(updates) == 0 {
+			// Stand in for the long poll instead of spinning.
+			f.mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			f.mu.Lock()
+		}
+		writeResult(w, updates)
+	case "sendMessage":
+		var msg sentMessage
+		json.NewDecoder(r.Body).Decode(&msg)
+
+		if f.blocked[msg.ChatID] {
+			json.NewEncoder(w).Encode(map[string]any{"ok": false, "description": "Forbidden: bot was blocked by the user"})
+			return
+		}
+		f.sent = append(f.sent, msg)
+		select {
+		case f.notify <- struct{}{}:
+		default:
+		}
+		writeResult(w, map[string]any{"message_id": len(f.sent)})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeResult(w http.ResponseWriter, result any) {
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+func (f *fakeAPI) messages() []sentMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentMessage(nil), f.sent...)
+}
+
+// The fakes embed the real storages, without a database, and override what
+// the bot calls.
+
+type fakeTelegram struct {
+	*store.TelegramStorage
+	mu      sync.Mutex
+	codes   map[string]int64
+	owners  []store.User
+	claimed map[digestKey]bool
+}
+
+type digestKey struct {
+	userID int64
+	day    string
+}
+
+func (f *fakeTelegram) Link(_ context.Context, code string, chatID int64) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	userID, ok := f.codes[code]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	delete(f.codes, code)
+	for i := range f.owners {
+		if f.owners[i].ID == userID {
+			f.owners[i].TelegramChatID = &chatID
+		}
+	}
+	return userID, nil
+}
+
+func (f *fakeTelegram) GetOwnerByChatId(_ context.Context, chatID int64) (*store.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, owner := range f.owners {
+		if owner.TelegramChatID != nil && *owner.TelegramChatID == chatID {
+			return &owner, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeTelegram) GetLinkedOwners(context.Context) ([]store.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var owners []store.User
+	for _, owner := range f.owners {
+		if owner.TelegramChatID != nil {
+			owners = append(owners, owner)
+		}
+	}
+	return owners, nil
+}
+
+func (f *fakeTelegram) ClaimDigest(_ context.Context, userID int64, day string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := digestKey{userID, day}
+	if f.claimed[key] {
+		return false, nil
+	}
+	f.claimed[key] = true
+	return true, nil
+}
+
+func (f *fakeTelegram) ReleaseDigest(_ context.Context, userID int64, day string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.claimed, digestKey{userID, day})
+	return nil
+}
+
+func (f *fakeTelegram) isClaimed(userID int64, day string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.claimed[digestKey{userID, day}]
+}
+
+type fakeUsers struct {
+	*store.UserStorage
+	telegram *fakeTelegram
+}
+
+func (f fakeUsers) GetById(_ context.Context, id *int64) (*store.User, error) {
+	for _, owner := range f.telegram.owners {
+		if owner.ID == *id {
+			return &owner, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+type fakeBalances struct {
+	*store.BalanceStorage
+}
+
+func (fakeBalances) GetByCompanyId(_ context.Context, companyID *int64) ([]store.Balance, error) {
+	return []store.Balance{
+		{ID: 1, CompanyId: *companyID, Currency: "USD", Balance: 1500},
+		{ID: 2, CompanyId: *companyID, Currency: "SUM", Balance: 2000000},
+	}, nil
+}
+
+type fakeCompanies struct {
+	*store.CompanyStorage
+}
+
+func (fakeCompanies) GetById(_ context.Context, id *int64) (*store.Company, error) {
+	return &store.Company{ID: *id, Name: "Main"}, nil
+}
+
+type fakeTransactions struct {
+	*store.TransactionStorage
+}
+
+func (fakeTransactions) GetCompanyFinalAmounts(context.Context, []int64, string) ([]store.CompanyAmount, error) {
+	return []store.CompanyAmount{{Currency: "USD", OlinganAmount: 300, BerilganAmount: 100, Remain: 200}}, nil
+}
+
+type fakeDebtors struct {
+	*store.DebtorsStorage
+}
+
+func (fakeDebtors) GetByBalanceInfo(context.Context, int64) ([]map[string]interface{}, error) {
+	return nil, nil
+}
+
+func newTestBot(t *testing.T) (*Bot, *fakeAPI, *fakeTelegram) {
+	api, client := newFakeAPI(t)
+
+	english := string(i18n.EN)
+	telegram := &fakeTelegram{
+		codes: map[string]int64{"ABCD2345": 1},
+		owners: []store.User{
+			{ID: 1, CompanyId: 10, Role: store.ROLE_OWNER, Language: &english},
+			{ID: 2, CompanyId: 20, Role: store.ROLE_OWNER, Language: &english},
+		},
+		claimed: map[digestKey]bool{},
+	}
+
+	st := store.Storage{
+		Telegram:     telegram,
+		Users:        fakeUsers{telegram: telegram},
+		Balances:     fakeBalances{},
+		Companies:    fakeCompanies{},
+		Transactions: fakeTransactions{},
+		Debtors:      fakeDebtors{},
+	}
+	// A digest hour that never comes keeps Run from sending digests.
+	return NewBot(client, st, 24), api, telegram
+}
+
+func TestStartLinksChat(t *testing.T) {
+	bot, api, telegram := newTestBot(t)
+	api.updates = []Update{
+		{UpdateID: 7, Message: &Message{MessageID: 1, Chat: Chat{ID: 555, Type: "private"}, Text: "/start abcd2345"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		bot.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-api.notify:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reply to /start")
+	}
+	cancel()
+	<-done
+
+	sent := api.messages()
+	if len(sent) != 1 || sent[0].ChatID != 555 || sent[0].Text != i18n.T(i18n.EN, "telegram.linked") {
+		t.Fatalf("sent = %+v, want the linked message to chat 555", sent)
+	}
+
+	owner, err := telegram.GetOwnerByChatId(context.Background(), 555)
+	if err != nil || owner.ID != 1 {
+		t.Fatalf("chat 555 is linked to %+v (%v), want user 1", owner, err)
+	}
+	if bot.lastUpdateID != 7 {
+		t.Errorf("lastUpdateID = %d, want 7", bot.lastUpdateID)
+	}
+}
+
+func TestStartWithWrongCode(t *testing.T) {
+	bot, api, _ := newTestBot(t)
+
+	msg := Message{Chat: Chat{ID: 555}, From: &User{LanguageCode: "en"}, Text: "/start WRONG123"}
+	if err := bot.Handle(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := api.messages()
+	if len(sent) != 1 || sent[0].Text != i18n.T(i18n.EN, "telegram.link_failed") {
+		t.Fatalf("sent = %+v, want the link failed message", sent)
+	}
+}
+
+func TestBalance(t *testing.T) {
+	bot, api, _ := newTestBot(t)
+	ctx := context.Background()
+
+	if err := bot.Handle(ctx, Message{Chat: Chat{ID: 555}, Text: "/balance"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Handle(ctx, Message{Chat: Chat{ID: 555}, Text: "/start ABCD2345"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.Handle(ctx, Message{Chat: Chat{ID: 555}, Text: "/balance@exchange_bot"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := api.messages()
+	if len(sent) != 3 {
+		t.Fatalf("sent %d messages, want 3: %+v", len(sent), sent)
+	}
+	if sent[0].Text != i18n.T(i18n.DEFAULT, "telegram.not_linked") {
+		t.Errorf("before linking got %q, want the not linked message", sent[0].Text)
+	}
+
+	want := i18n.T(i18n.EN, "telegram.balances") + "\nUSD: 1500\nSUM: 2000000"
+	if sent[2].Text != want {
+		t.Errorf("balance = %q, want %q", sent[2].Text, want)
+	}
+}
+
+func TestSendDigestsClaimsAndReleases(t *testing.T) {
+	bot, api, telegram := newTestBot(t)
+	ctx := context.Background()
+	day := "2026-10-19"
+
+	first, second := int64(555), int64(666)
+	telegram.owners[0].TelegramChatID = &first
+	telegram.owners[1].TelegramChatID = &second
+	api.blocked[second] = true
+
+	bot.SendDigests(ctx, day)
+
+	sent := api.messages()
+	if len(sent) != 1 || sent[0].ChatID != first {
+		t.Fatalf("sent = %+v, want one digest to chat %d", sent, first)
+	}
+	if !strings.HasPrefix(sent[0].Text, i18n.T(i18n.EN, "telegram.digest", "Main", day)) {
+		t.Errorf("digest = %q", sent[0].Text)
+	}
+	if !telegram.isClaimed(1, day) {
+		t.Error("the sent digest was not kept claimed")
+	}
+	if telegram.isClaimed(2, day) {
+		t.Error("the failed digest was not released")
+	}
+
+	// The next round skips the delivered digest and retries the failed one.
+	api.mu.Lock()
+	api.blocked[second] = false
+	api.mu.Unlock()
+	bot.SendDigests(ctx, day)
+
+	sent = api.messages()
+	if len(sent) != 2 || sent[1].ChatID != second {
+		t.Fatalf("sent = %+v, want a retried digest to chat %d only", sent, second)
+	}
+	if !telegram.isClaimed(2, day) {
+		t.Error("the retried digest was not claimed")
+	}
+}
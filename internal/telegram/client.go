@@ -0,0 +1,107 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const API_URL = "https://api.telegram.org"
+
+// Client talks to the Telegram Bot API.
+type Client struct {
+	token   string
+	baseURL string
+	http    *http.Client
+}
+
+func NewClient(token string) *Client {
+	return NewClientWithURL(token, API_URL)
+}
+
+// NewClientWithURL talks to a Bot API at baseURL instead, such as a local
+// fake server in tests.
+func NewClientWithURL(token, baseURL string) *Client {
+	return &Client{token: token, baseURL: baseURL, http: &http.Client{Timeout: POLL_TIMEOUT + 15*time.Second}}
+}
+
+// Update is an incoming update. The bot only asks for messages.
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	Chat      Chat   `json:"chat"`
+	From      *User  `json:"from"`
+	Text      string `json:"text"`
+}
+
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+type User struct {
+	ID           int64  `json:"id"`
+	LanguageCode string `json:"language_code"`
+}
+
+// GetUpdates long-polls for updates after offset, waiting up to timeout.
+func (c *Client) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	params := map[string]any{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message"},
+	}
+
+	var updates []Update
+	err := c.call(ctx, "getUpdates", params, &updates)
+	return updates, err
+}
+
+// SendMessage sends plain text to a chat.
+func (c *Client) SendMessage(ctx context.Context, chatID int64, text string) error {
+	return c.call(ctx, "sendMessage", map[string]any{"chat_id": chatID, "text": text}, nil)
+}
+
+func (c *Client) call(ctx context.Context, method string, params any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/bot"+c.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// The error carries the URL, which carries the token.
+		return fmt.Errorf("telegram %s: request failed", method)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("telegram %s: %s", method, resp.Status)
+	}
+	if !envelope.OK {
+		return fmt.Errorf("telegram %s: %s", method, envelope.Description)
+	}
+
+	if result != nil {
+		return json.Unmarshal(envelope.Result, result)
+	}
+	return nil
+}
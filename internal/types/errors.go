@@ -1,7 +1,119 @@
 package types
 
+import (
+	"net/http"
+)
+
 const (
 	BALANCE_NO_ENOUGH_MONEY    = "HISOBDA YETARLIK MABLAG' MAVJUD EMAS"
 	DEBTOR_NO_ENOUGH_MONEY     = "QARZDORDA YETARLIK MABLAG' MAVJUD EMAS"
 	BALANCE_CURRENCY_NOT_FOUND = "BUNDAY VALYUTALIK HISOB MAVJUD EMAS"
 )
+
+// AppError is a domain error with a stable machine readable code. The code
+// is what clients branch on; the message is for people and may change. The
+// API replaces Message with the i18n catalog entry for Code when there is one.
+type AppError struct {
+	Status  int               `json:"-"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	Err     error             `json:"-"`
+}
+
+func NewError(status int, code, message string) *AppError {
+	return &AppError{Status: status, Code: code, Message: message}
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches on code, so copies made by WithField or Wrap still compare
+// equal to the sentinel they came from.
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Code == e.Code
+}
+
+// WithField returns a copy of the error with a detail about one field.
+func (e *AppError) WithField(name, detail string) *AppError {
+	c := *e
+	c.Fields = make(map[string]string, len(e.Fields)+1)
+	for k, v := range e.Fields {
+		c.Fields[k] = v
+	}
+	c.Fields[name] = detail
+	return &c
+}
+
+// Wrap returns a copy of the error carrying the underlying cause. The cause
+// is logged but never sent to the client.
+func (e *AppError) Wrap(err error) *AppError {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+var (
+	ErrInvalidRequest = NewError(http.StatusBadRequest, "INVALID_REQUEST", "invalid request")
+	ErrInvalidField   = NewError(http.StatusBadRequest, "INVALID_FIELD", "invalid field name")
+	ErrInvalidCursor  = NewError(http.StatusBadRequest, "INVALID_CURSOR", "invalid cursor")
+	ErrInvalidSort    = NewError(http.StatusBadRequest, "INVALID_SORT", "invalid order_by")
+	ErrInvalidFilter  = NewError(http.StatusBadRequest, "INVALID_FILTER", "invalid filter value")
+	ErrUnknownType    = NewError(http.StatusBadRequest, "UNKNOWN_TYPE", "FOUND UNKNOWN TYPE")
+	ErrUnauthorized   = NewError(http.StatusUnauthorized, "UNAUTHORIZED", "Unauthorized method used")
+	ErrForbidden      = NewError(http.StatusForbidden, "FORBIDDEN", "access denied")
+	ErrNotFound       = NewError(http.StatusNotFound, "NOT_FOUND", "NOT FOUND")
+	ErrConflict       = NewError(http.StatusConflict, "CONFLICT", "conflict")
+	ErrRateLimited    = NewError(http.StatusTooManyRequests, "RATE_LIMITED", "too many requests")
+	ErrValidation     = NewError(http.StatusUnprocessableEntity, "VALIDATION_FAILED", "validation failed")
+	ErrInternal       = NewError(http.StatusInternalServerError, "INTERNAL_ERROR", "internal server error")
+
+	ErrBalanceNoEnoughMoney    = NewError(http.StatusUnprocessableEntity, "BALANCE_NO_ENOUGH_MONEY", BALANCE_NO_ENOUGH_MONEY)
+	ErrDebtorNoEnoughMoney     = NewError(http.StatusUnprocessableEntity, "DEBTOR_NO_ENOUGH_MONEY", DEBTOR_NO_ENOUGH_MONEY)
+	ErrBalanceCurrencyNotFound = NewError(http.StatusUnprocessableEntity, "BALANCE_CURRENCY_NOT_FOUND", BALANCE_CURRENCY_NOT_FOUND)
+	ErrBalanceAlreadyExists    = NewError(http.StatusConflict, "BALANCE_ALREADY_EXISTS", "ALREADY EXIST WITH CURRENCY")
+	ErrCurrencyMismatch        = NewError(http.StatusUnprocessableEntity, "CURRENCY_MISMATCH", "debted currencies do not match")
+	ErrEmptyIncomes            = NewError(http.StatusUnprocessableEntity, "EMPTY_INCOMES", "received incomes cannot be empty")
+
+	ErrTransactionNotFound         = NewError(http.StatusNotFound, "TRANSACTION_NOT_FOUND", "TRANSACTION NOT FOUND")
+	ErrTransactionAlreadyCompleted = NewError(http.StatusConflict, "TRANSACTION_ALREADY_COMPLETED", "BUYURTMA ALLAQACHON YAKUNLANGAN")
+	ErrExchangeNotFound            = NewError(http.StatusNotFound, "EXCHANGE_NOT_FOUND", "EXCHANGE NOT FOUND")
+	ErrBalanceNotFound             = NewError(http.StatusNotFound, "BALANCE_NOT_FOUND", "BALANCE NOT FOUND")
+	ErrBalanceRecordNotFound       = NewError(http.StatusNotFound, "BALANCE_RECORD_NOT_FOUND", "BALANCE RECORD NOT FOUND")
+	ErrDebtNotFound                = NewError(http.StatusNotFound, "DEBT_NOT_FOUND", "debt not found")
+	ErrDebtorNotFound              = NewError(http.StatusNotFound, "DEBTOR_NOT_FOUND", "DEBTORS NOT FOUND")
+	ErrUserNotFound                = NewError(http.StatusNotFound, "USER_NOT_FOUND", "user not found")
+	ErrCompanyNotFound             = NewError(http.StatusNotFound, "COMPANY_NOT_FOUND", "company not found")
+
+	ErrChangeRequestNotFound = NewError(http.StatusNotFound, "CHANGE_REQUEST_NOT_FOUND", "change request not found")
+	ErrChangeRequestReviewed = NewError(http.StatusConflict, "CHANGE_REQUEST_ALREADY_REVIEWED", "change request already reviewed")
+
+	ErrNotificationNotFound = NewError(http.StatusNotFound, "NOTIFICATION_NOT_FOUND", "notification not found")
+
+	ErrWebhookNotFound         = NewError(http.StatusNotFound, "WEBHOOK_NOT_FOUND", "webhook not found")
+	ErrWebhookDeliveryNotFound = NewError(http.StatusNotFound, "WEBHOOK_DELIVERY_NOT_FOUND", "webhook delivery not found or already queued")
+
+	ErrBalanceThresholdNotFound = NewError(http.StatusNotFound, "BALANCE_THRESHOLD_NOT_FOUND", "balance threshold not found")
+	ErrBalanceThresholdRange    = NewError(http.StatusUnprocessableEntity, "BALANCE_THRESHOLD_RANGE", "min_balance must not be greater than max_balance")
+
+	ErrReceiptsUnavailable = NewError(http.StatusServiceUnavailable, "RECEIPTS_UNAVAILABLE", "receipt font is not installed")
+	ErrReceiptNotFound     = NewError(http.StatusNotFound, "RECEIPT_NOT_FOUND", "receipt is not genuine or no longer valid")
+
+	ErrStatementRange     = NewError(http.StatusUnprocessableEntity, "STATEMENT_RANGE", "from must not be after to")
+	ErrStatementNotFound  = NewError(http.StatusNotFound, "STATEMENT_NOT_FOUND", "statement link is invalid or has expired")
+	ErrSharingUnavailable = NewError(http.StatusServiceUnavailable, "SHARING_UNAVAILABLE", "PUBLIC_URL or RECEIPT_SIGNING_KEYS is not set")
+
+	ErrDiscrepancyNotFound = NewError(http.StatusNotFound, "DISCREPANCY_NOT_FOUND", "discrepancy not found")
+	ErrDiscrepancyResolved = NewError(http.StatusConflict, "DISCREPANCY_RESOLVED", "the balance already matches its records or was repaired")
+
+	ErrSnapshotRange = NewError(http.StatusUnprocessableEntity, "SNAPSHOT_RANGE", "snapshots can only be taken for days that have ended, up to a year at a time")
+)
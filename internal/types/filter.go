@@ -0,0 +1,48 @@
+package types
+
+// Filter is the body of every /search endpoint. Conditions are joined with
+// AND; Search is matched against the text columns of the resource.
+//
+//	{
+//	  "where": [
+//	    {"field": "currency", "op": "in", "value": ["USD", "EUR"]},
+//	    {"field": "amount", "op": "between", "value": [100, 500]},
+//	    {"field": "created_at", "op": "date", "value": "2025-02-18"}
+//	  ],
+//	  "search": "ali"
+//	}
+type Filter struct {
+	Where  []Condition `json:"where" validate:"dive"`
+	Search *string     `json:"search"`
+}
+
+type Condition struct {
+	Field string `json:"field" validate:"required"`
+	Op    string `json:"op" validate:"required,oneof=eq ne gt gte lt lte in between date contains"`
+	Value any    `json:"value"`
+}
+
+const (
+	FILTER_EQ       = "eq"
+	FILTER_NE       = "ne"
+	FILTER_GT       = "gt"
+	FILTER_GTE      = "gte"
+	FILTER_LT       = "lt"
+	FILTER_LTE      = "lte"
+	FILTER_IN       = "in"
+	FILTER_BETWEEN  = "between"
+	FILTER_DATE     = "date"
+	FILTER_CONTAINS = "contains"
+)
+
+// Where starts a filter with a single equality, which is what the older
+// field_name/field_value endpoints send.
+func Where(field string, value any) *Filter {
+	return &Filter{Where: []Condition{{Field: field, Op: FILTER_EQ, Value: value}}}
+}
+
+// And adds a condition and returns the filter so calls can be chained.
+func (f *Filter) And(field, op string, value any) *Filter {
+	f.Where = append(f.Where, Condition{Field: field, Op: op, Value: value})
+	return f
+}
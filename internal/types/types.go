@@ -1,5 +1,12 @@
 package types
 
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type BalanceRecordPayload struct {
 	ID               int64  `json:"id"`
 	ReceivedMoney    int64  `json:"received_money"`
@@ -37,6 +44,53 @@ type Pagination struct {
 	ProductCollectionId int         `json:"productCollectionId"`
 	UserId              int64       `json:"user_id"`
 	Language            *string     `json:"language"`
+
+	// Cursor continues a keyset page after the last (created_at, id) seen.
+	// It is only honoured when the list is sorted by created_at.
+	Cursor     *Cursor `json:"-"`
+	CountTotal bool    `json:"-"`
+
+	// Filled in by the store once the page has been read.
+	Keyset     bool    `json:"-"`
+	HasMore    bool    `json:"has_more"`
+	NextCursor *string `json:"next_cursor"`
+	Total      int64   `json:"total"`
+}
+
+const (
+	DEFAULT_LIMIT = 10
+	MAX_LIMIT     = 100
+)
+
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(encoded string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &Cursor{}
+	if c.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
 }
 
 const (
@@ -0,0 +1,200 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mubashshir3767/currencyExchange/internal/store"
+)
+
+const (
+	SIGNATURE_HEADER = "X-Webhook-Signature"
+	EVENT_HEADER     = "X-Webhook-Event"
+	DELIVERY_HEADER  = "X-Webhook-Delivery"
+)
+
+const (
+	// MAX_ATTEMPTS is how many times a delivery is tried before it is moved
+	// to the dead-letter list.
+	MAX_ATTEMPTS = 10
+	BATCH_SIZE   = 50
+	POLL_EVERY   = 5 * time.Second
+	SEND_TIMEOUT = 10 * time.Second
+	BASE_BACKOFF = 30 * time.Second
+	MAX_BACKOFF  = time.Hour
+)
+
+// Store is the part of store.Storage the dispatcher uses.
+type Store interface {
+	Fanout(context.Context, int) (int64, error)
+	ClaimDeliveries(context.Context, int, time.Duration) ([]store.PendingDelivery, error)
+	MarkDelivered(context.Context, int64, int) error
+	MarkFailed(context.Context, int64, *int, string, *time.Time) error
+}
+
+// Payload is the JSON body partners receive. ID is the outbox event, so a
+// partner can drop duplicates when a delivery is retried.
+type Payload struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CompanyID int64           `json:"company_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Dispatcher moves outbox events to webhook deliveries and sends the ones
+// that are due.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+}
+
+func NewDispatcher(st Store) *Dispatcher {
+	return &Dispatcher{store: st, client: &http.Client{Timeout: SEND_TIMEOUT}}
+}
+
+// Run polls until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(POLL_EVERY)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.store.Fanout(ctx, BATCH_SIZE); err != nil {
+			log.Printf("webhooks: %v", err)
+		}
+		d.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue claims a batch and sends it all at once. Every send is over
+// within SEND_TIMEOUT, so the lease, twice that, is not up before the batch
+// is marked and no other replica sends the same delivery again.
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	deliveries, err := d.store.ClaimDeliveries(ctx, BATCH_SIZE, 2*SEND_TIMEOUT)
+	if err != nil {
+		log.Printf("webhooks: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Go(func() { d.deliver(ctx, delivery) })
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery store.PendingDelivery) {
+	status, err := d.send(ctx, delivery)
+	if err == nil {
+		if err := d.store.MarkDelivered(ctx, delivery.ID, status); err != nil {
+			log.Printf("webhooks: delivery %d: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	var responseStatus *int
+	if status != 0 {
+		responseStatus = &status
+	}
+
+	var retryAt *time.Time
+	if delivery.Attempts < MAX_ATTEMPTS {
+		next := time.Now().Add(Backoff(delivery.Attempts))
+		retryAt = &next
+	}
+
+	if err := d.store.MarkFailed(ctx, delivery.ID, responseStatus, err.Error(), retryAt); err != nil {
+		log.Printf("webhooks: delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send posts the delivery and returns the response status. Anything but a
+// 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, delivery store.PendingDelivery) (int, error) {
+	body, err := json.Marshal(Payload{
+		ID:        delivery.OutboxEventID,
+		Type:      delivery.EventType,
+		CompanyID: delivery.CompanyID,
+		CreatedAt: delivery.EventCreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EVENT_HEADER, delivery.EventType)
+	req.Header.Set(DELIVERY_HEADER, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SIGNATURE_HEADER, Sign(delivery.Secret, time.Now().Unix(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value "t=<unix>,v1=<hex>", where v1 is
+// the HMAC-SHA256 of "<unix>.<body>" keyed with the endpoint secret.
+// Including the timestamp lets partners reject replayed requests.
+func Sign(secret string, timestamp int64, body []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is the wait after the given failed attempt: 30s, 1m, 2m, ...
+// capped at an hour.
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	wait := BASE_BACKOFF
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if wait >= MAX_BACKOFF {
+			return MAX_BACKOFF
+		}
+	}
+	return wait
+}
+
+// NewSecret returns a random signing secret for a new endpoint.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}